@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The shell hook printed by `tidyup hook zsh|bash|fish` appends one line to
+// activity.log in the state directory whenever the prompt shows in a new
+// directory or with a newly activated environment:
+//
+//	1767225600	cd	/home/fred/dev/website
+//	1767225600	activate	/home/fred/dev/website/.venv
+//
+// With -shims, the hook also wraps package managers in shell functions that
+// log each run before handing over to the real command:
+//
+//	1767225600	run:npm	/home/fred/dev/website/frontend
+//
+// Scans take an item's last use from this log when it is more recent than
+// what the item's markers say: an activation dates the environment itself,
+// a directory change dates everything in the project it lands in, and a
+// package manager run dates that manager's items in the project (see
+// shimTypes) -- use, not just the last install that marker mtimes show.
+
+// activityLimit is the size above which loadActivity rewrites the log
+// with only the latest line per event and path.
+const activityLimit = 1 << 20
+
+// shimTypes maps the package managers -shims wraps to the types their runs
+// count as use of.
+var shimTypes = map[string][]string{
+	"uv": {"venv"}, "pip": {"venv"}, "pip3": {"venv"}, "poetry": {"venv"},
+	"pipenv": {"venv"}, "pdm": {"venv"}, "hatch": {"venv"},
+	"npm": {"node_modules"}, "npx": {"node_modules"}, "pnpm": {"node_modules"},
+	"yarn": {"node_modules"}, "bun": {"node_modules"},
+}
+
+// activityLog is what the shell hook has recorded, latest first.
+type activityLog struct {
+	activated map[string]time.Time            // by environment
+	visited   map[string]time.Time            // by directory
+	ran       map[string]map[string]time.Time // by package manager, then directory
+}
+
+// activityPath is where the shell hook records activity.
+func activityPath() (string, error) {
+	return statePath("activity.log")
+}
+
+// loadActivity reads the activity log; without one (no hook installed) it
+// returns nil.
+func loadActivity() (*activityLog, error) {
+	path, err := activityPath()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	a := parseActivity(f)
+	if info, err := f.Stat(); err == nil && info.Size() > activityLimit {
+		if err := a.write(path); err != nil {
+			stderr.warnf("could not compact %s: %v", path, err)
+		}
+	}
+	return a, nil
+}
+
+// parseActivity reads activity lines, skipping any it cannot parse (a line
+// cut short by a full disk, say).
+func parseActivity(r io.Reader) *activityLog {
+	a := &activityLog{activated: map[string]time.Time{}, visited: map[string]time.Time{}, ran: map[string]map[string]time.Time{}}
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		fields := strings.SplitN(sc.Text(), "\t", 3)
+		if len(fields) != 3 || !filepath.IsAbs(fields[2]) {
+			continue
+		}
+		sec, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		var m map[string]time.Time
+		switch tool, isRun := strings.CutPrefix(fields[1], "run:"); {
+		case fields[1] == "activate":
+			m = a.activated
+		case fields[1] == "cd":
+			m = a.visited
+		case isRun && shimTypes[tool] != nil:
+			if a.ran[tool] == nil {
+				a.ran[tool] = map[string]time.Time{}
+			}
+			m = a.ran[tool]
+		default:
+			continue
+		}
+		path, t := filepath.Clean(fields[2]), time.Unix(sec, 0)
+		if t.After(m[path]) {
+			m[path] = t
+		}
+	}
+	return a
+}
+
+// write replaces the log at path with the latest line per event and path.
+func (a *activityLog) write(path string) error {
+	unlock := lockState()
+	defer unlock()
+	var lines []string
+	events := map[string]map[string]time.Time{"activate": a.activated, "cd": a.visited}
+	for tool, m := range a.ran {
+		events["run:"+tool] = m
+	}
+	for event, m := range events {
+		for p, t := range m {
+			lines = append(lines, fmt.Sprintf("%d\t%s\t%s\n", t.Unix(), event, p))
+		}
+	}
+	sort.Strings(lines)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strings.Join(lines, "")), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// lastUsed returns when the item of type typeName at path was last used
+// according to the log, and how: its own activation, a shell in its
+// project, or a run of its package manager there. A project that is the
+// home directory or the filesystem root is too broad to date anything by
+// where the shell was.
+func (a *activityLog) lastUsed(path, typeName string) (time.Time, string, bool) {
+	if a == nil {
+		return time.Time{}, "", false
+	}
+	var latest time.Time
+	how := ""
+	if t, ok := a.activated[filepath.Clean(path)]; ok {
+		latest, how = t, "activated"
+	}
+	project := projectDir(path)
+	home, _ := os.UserHomeDir()
+	if project == filepath.Dir(project) || project == filepath.Clean(home) {
+		return latest, how, how != ""
+	}
+	for dir, t := range a.visited {
+		if within(dir, project) && t.After(latest) {
+			latest, how = t, "shell in "+project
+		}
+	}
+	for tool, dirs := range a.ran {
+		counts := false
+		for _, typ := range shimTypes[tool] {
+			counts = counts || typ == typeName
+		}
+		if !counts {
+			continue
+		}
+		for dir, t := range dirs {
+			if within(dir, project) && t.After(latest) {
+				latest, how = t, tool+" ran in "+project
+			}
+		}
+	}
+	return latest, how, how != ""
+}
+
+// hookScripts are the shell hooks, with %[1]s the quoted log path and %[2]s
+// its quoted directory.
+var hookScripts = map[string]string{
+	"bash": `# tidyup activity hook: eval "$(tidyup hook bash)" in ~/.bashrc
+__tidyup_log=%[1]s
+command mkdir -p %[2]s 2>/dev/null
+__tidyup_hook() {
+  local env="${VIRTUAL_ENV:-$CONDA_PREFIX}" now
+  if [ "$PWD" != "$__tidyup_pwd" ] || [ "$env" != "$__tidyup_env" ]; then
+    now=${EPOCHSECONDS:-$(date +%%s)}
+    printf '%%s\tcd\t%%s\n' "$now" "$PWD" >> "$__tidyup_log" 2>/dev/null
+    [ -n "$env" ] && printf '%%s\tactivate\t%%s\n' "$now" "$env" >> "$__tidyup_log" 2>/dev/null
+    __tidyup_pwd=$PWD
+    __tidyup_env=$env
+  fi
+}
+case ";$PROMPT_COMMAND;" in
+  *";__tidyup_hook;"*) ;;
+  *) PROMPT_COMMAND="__tidyup_hook${PROMPT_COMMAND:+;$PROMPT_COMMAND}" ;;
+esac
+`,
+	"zsh": `# tidyup activity hook: eval "$(tidyup hook zsh)" in ~/.zshrc
+__tidyup_log=%[1]s
+command mkdir -p %[2]s 2>/dev/null
+zmodload -F zsh/datetime p:EPOCHSECONDS 2>/dev/null
+__tidyup_hook() {
+  local env="${VIRTUAL_ENV:-$CONDA_PREFIX}" now
+  if [[ "$PWD" != "$__tidyup_pwd" || "$env" != "$__tidyup_env" ]]; then
+    now=${EPOCHSECONDS:-$(date +%%s)}
+    printf '%%s\tcd\t%%s\n' "$now" "$PWD" >> "$__tidyup_log" 2>/dev/null
+    [[ -n "$env" ]] && printf '%%s\tactivate\t%%s\n' "$now" "$env" >> "$__tidyup_log" 2>/dev/null
+    __tidyup_pwd=$PWD
+    __tidyup_env=$env
+  fi
+}
+autoload -Uz add-zsh-hook
+add-zsh-hook precmd __tidyup_hook
+`,
+	"fish": `# tidyup activity hook: tidyup hook fish | source, in ~/.config/fish/config.fish
+set -g __tidyup_log %[1]s
+command mkdir -p %[2]s 2>/dev/null
+function __tidyup_hook --on-event fish_prompt
+    set -l env $VIRTUAL_ENV
+    test -z "$env"; and set env $CONDA_PREFIX
+    if test "$PWD" != "$__tidyup_pwd"; or test "$env" != "$__tidyup_env"
+        set -l now (date +%%s)
+        printf '%%s\tcd\t%%s\n' $now $PWD >> $__tidyup_log 2>/dev/null
+        test -n "$env"; and printf '%%s\tactivate\t%%s\n' $now $env >> $__tidyup_log 2>/dev/null
+        set -g __tidyup_pwd $PWD
+        set -g __tidyup_env "$env"
+    end
+end
+`,
+}
+
+// shellQuote single-quotes s for the given shell.
+func shellQuote(shell, s string) string {
+	if shell == "fish" {
+		return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// shimScripts define the logging function -shims wrappers call, per shell.
+var shimScripts = map[string]string{
+	"bash": `__tidyup_ran() {
+  printf '%s\trun:%s\t%s\n' "${EPOCHSECONDS:-$(date +%s)}" "$1" "$PWD" >> "$__tidyup_log" 2>/dev/null
+}
+`,
+	"fish": `function __tidyup_ran
+    printf '%s\trun:%s\t%s\n' (date +%s) $argv[1] $PWD >> $__tidyup_log 2>/dev/null
+end
+`,
+}
+
+// shimWrapper is one package manager's wrapper, with %[1]s its name.
+var shimWrapper = map[string]string{
+	"bash": "function %[1]s { __tidyup_ran %[1]s; command %[1]s \"$@\"; }\n",
+	"fish": "function %[1]s --wraps %[1]s\n    __tidyup_ran %[1]s\n    command %[1]s $argv\nend\n",
+}
+
+// hookScript returns the hook for shell, recording into the state
+// directory's activity log; with shims, package managers are wrapped too.
+func hookScript(shell string, shims bool) (string, error) {
+	path, err := activityPath()
+	if err != nil {
+		return "", err
+	}
+	script := fmt.Sprintf(hookScripts[shell], shellQuote(shell, path), shellQuote(shell, filepath.Dir(path)))
+	if !shims {
+		return script, nil
+	}
+	dialect := shell
+	if dialect == "zsh" {
+		dialect = "bash"
+	}
+	var tools []string
+	for tool := range shimTypes {
+		tools = append(tools, tool)
+	}
+	sort.Strings(tools)
+	script += shimScripts[dialect]
+	for _, tool := range tools {
+		script += fmt.Sprintf(shimWrapper[dialect], tool)
+	}
+	return script, nil
+}
+
+// runHook implements `tidyup hook [-shims] zsh|bash|fish`.
+func runHook(args []string) int {
+	flags := flag.NewFlagSet("hook", flag.ContinueOnError)
+	shims := flags.Bool("shims", false, "Also wrap package managers (uv, pip, poetry, npm, pnpm, yarn, ...) to log their runs")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tidyup hook [-shims] zsh|bash|fish\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if flags.NArg() != 1 || hookScripts[flags.Arg(0)] == "" {
+		flags.Usage()
+		return exitError
+	}
+	script, err := hookScript(flags.Arg(0), *shims)
+	if err != nil {
+		stderr.errorf("%v", err)
+		return exitError
+	}
+	fmt.Print(script)
+	return exitOK
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseActivity(t *testing.T) {
+	a := parseActivity(strings.NewReader(strings.Join([]string{
+		"1767225600\tcd\t/srv/dev/web/src",
+		"1767225500\tactivate\t/srv/dev/web/.venv",
+		"1767225700\tactivate\t/srv/dev/web/.venv/",
+		"1767225000\tactivate\t/srv/dev/web/.venv",
+		"garbage",
+		"1767225800\tcd\trelative/dir",
+		"1767225900\tbogus\t/srv/dev/web",
+		"1767225950\trun:npm\t/srv/dev/web/frontend",
+		"1767225960\trun:rm\t/srv/dev/web",
+		"176722",
+	}, "\n")))
+
+	if got := a.activated["/srv/dev/web/.venv"]; got.Unix() != 1767225700 {
+		t.Errorf("activation = %v, want the latest", got)
+	}
+	if len(a.visited) != 1 || a.visited["/srv/dev/web/src"].Unix() != 1767225600 {
+		t.Errorf("visited = %v", a.visited)
+	}
+	if len(a.ran) != 1 || a.ran["npm"]["/srv/dev/web/frontend"].Unix() != 1767225950 {
+		t.Errorf("ran = %v, want only the npm run", a.ran)
+	}
+}
+
+func TestActivityLog_LastUsed(t *testing.T) {
+	t.Setenv("HOME", "/srv/home")
+	a := &activityLog{
+		activated: map[string]time.Time{"/srv/dev/web/.venv": time.Unix(100, 0)},
+		visited: map[string]time.Time{
+			"/srv/dev/web/src": time.Unix(200, 0),
+			"/srv/dev/webapp":  time.Unix(300, 0),
+			"/srv/home":        time.Unix(400, 0),
+		},
+		ran: map[string]map[string]time.Time{
+			"npm": {"/srv/dev/web/frontend": time.Unix(500, 0)},
+		},
+	}
+	if got, how, ok := a.lastUsed("/srv/dev/web/.venv", "venv"); !ok || got.Unix() != 200 || !strings.Contains(how, "shell in /srv/dev/web") {
+		t.Errorf("venv = %v, %q, %v; want the later cd into the project", got, how, ok)
+	}
+	if got, how, ok := a.lastUsed("/srv/dev/web/node_modules", "node_modules"); !ok || got.Unix() != 500 || how != "npm ran in /srv/dev/web" {
+		t.Errorf("node_modules = %v, %q, %v; want the npm run", got, how, ok)
+	}
+	if got, how, ok := a.lastUsed("/srv/dev/api/node_modules", "node_modules"); ok {
+		t.Errorf("unvisited project = %v, %q", got, how)
+	}
+	if _, _, ok := a.lastUsed("/srv/home/.venv", "venv"); ok {
+		t.Error("a cd into $HOME should not date items whose project is $HOME")
+	}
+	if _, _, ok := (*activityLog)(nil).lastUsed("/srv/dev/web/.venv", "venv"); ok {
+		t.Error("no log should date nothing")
+	}
+}
+
+func TestLoadActivity_Compacts(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	path, _ := activityPath()
+	if a, err := loadActivity(); a != nil || err != nil {
+		t.Fatalf("no log: got %v, %v", a, err)
+	}
+	os.MkdirAll(filepath.Dir(path), 0700)
+	line := "1767225600\tcd\t/srv/dev/web\n"
+	os.WriteFile(path, []byte(strings.Repeat(line, activityLimit/len(line)+1)), 0600)
+
+	a, err := loadActivity()
+	if err != nil || a.visited["/srv/dev/web"].Unix() != 1767225600 {
+		t.Fatalf("loadActivity = %v, %v", a, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != line {
+		t.Errorf("compacted log = %q, want %q", data, line)
+	}
+}
+
+func TestScanRoots_ShellActivity(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	root := t.TempDir()
+	venv := filepath.Join(root, "proj", ".venv")
+	makeVenv(t, venv, time.Now().AddDate(0, 0, -90))
+	opts := &options{minAge: 30, maxDepth: 5, scanTypes: map[string]bool{"venv": true}}
+	if records, _ := scanRoots([]string{root}, opts); len(records) != 1 {
+		t.Fatalf("without activity: got %d records, want 1", len(records))
+	}
+
+	path, _ := activityPath()
+	os.MkdirAll(filepath.Dir(path), 0700)
+	recent := time.Now().AddDate(0, 0, -2).Unix()
+	os.WriteFile(path, []byte(fmt.Sprintf("%d\tactivate\t%s\n", recent, venv)), 0600)
+	if records, _ := scanRoots([]string{root}, opts); len(records) != 0 {
+		t.Errorf("activated 2 days ago: got %+v, want nothing", records)
+	}
+}
+
+func TestRunHook_Scripts(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", "/srv/it's state")
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		path, _ := activityPath()
+		script, err := hookScript(shell, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(script, "__tidyup_ran npm") {
+			t.Errorf("%s: -shims should wrap npm:\n%s", shell, script)
+		}
+		if !strings.Contains(script, shellQuote(shell, path)) {
+			t.Errorf("%s: script does not name %s:\n%s", shell, path, script)
+		}
+		if bin, err := exec.LookPath(shell); err == nil && shell != "fish" {
+			if out, err := exec.Command(bin, "-n", "-c", script).CombinedOutput(); err != nil {
+				t.Errorf("%s -n: %v\n%s", shell, err, out)
+			}
+		}
+	}
+	if plain, _ := hookScript("zsh", false); strings.Contains(plain, "__tidyup_ran") {
+		t.Errorf("without -shims, nothing should be wrapped:\n%s", plain)
+	}
+	if code := runHook([]string{"tcsh"}); code != exitError {
+		t.Errorf("unknown shell: exit %d, want %d", code, exitError)
+	}
+}
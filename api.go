@@ -0,0 +1,200 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// The REST API lets dotfile managers and menu-bar apps drive `tidyup serve`
+// without parsing CLI output. It lives under /api/v1/, speaks the -json
+// schema, and requires the bearer token tidyup writes to serve.token in the
+// state directory (readable only by the user) at startup:
+//
+//	GET  /api/v1/records  the last scan, as -json output; ?type=, ?q=, ?sort=
+//	POST /api/v1/scan     rescan, then answer like GET /records
+//	POST /api/v1/delete   {"paths": [...]} with an Idempotency-Key header
+//
+// A delete repeated with the same Idempotency-Key is answered from the first
+// attempt instead of being run again, so clients can retry safely.
+
+// apiMaxReplays bounds how many delete responses are kept for replay.
+const apiMaxReplays = 256
+
+// deleteRequest is the body of POST /api/v1/delete.
+type deleteRequest struct {
+	Paths []string `json:"paths"`
+}
+
+// deleteResponse reports what happened to each requested path.
+type deleteResponse struct {
+	Removed []string          `json:"removed"`
+	Failed  []string          `json:"failed"`
+	Skipped map[string]string `json:"skipped"` // path -> why it was not attempted
+}
+
+// apiError is every API error body.
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// serveTokenPath is where serve publishes the API token.
+func serveTokenPath() (string, error) {
+	return statePath("serve.token")
+}
+
+// writeServeToken creates a fresh API token and writes it for clients.
+func writeServeToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	path, err := serveTokenPath()
+	if err == nil {
+		err = writeFileAtomic(path, []byte(token+"\n"), 0600)
+	}
+	return token, err
+}
+
+// writeAPIJSON writes v as the response body.
+func writeAPIJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// apiAuth wraps an API handler with the bearer token and method checks.
+func (s *server) apiAuth(method string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if s.apiToken == "" || req.Header.Get("Authorization") != "Bearer "+s.apiToken {
+			writeAPIJSON(w, http.StatusUnauthorized, apiError{"missing or wrong bearer token (see serve.token in the state directory)"})
+			return
+		}
+		if req.Method != method {
+			w.Header().Set("Allow", method)
+			writeAPIJSON(w, http.StatusMethodNotAllowed, apiError{"use " + method})
+			return
+		}
+		h(w, req)
+	}
+}
+
+// apiRecords answers with the last scan in the -json schema.
+func (s *server) apiRecords(w http.ResponseWriter, req *http.Request) {
+	q := req.URL.Query()
+	sortField := q.Get("sort")
+	if sortField == "" {
+		sortField = "size"
+	}
+	groups, _, _ := s.view(q.Get("type"), q.Get("q"), sortField, false)
+	records := []Record{}
+	for _, row := range groups[0].Rows {
+		records = append(records, row.Record)
+	}
+	total := totalSize(records)
+	writeAPIJSON(w, http.StatusOK, JSONOutput{
+		SchemaVersion:  schemaVersion,
+		Count:          len(records),
+		TotalBytes:     total,
+		TotalHuman:     formatBytes(total),
+		TotalDiskBytes: totalDiskSize(records),
+		TotalDiskHuman: formatBytes(totalDiskSize(records)),
+		Records:        records,
+		DryRun:         true,
+	})
+}
+
+func (s *server) apiScan(w http.ResponseWriter, req *http.Request) {
+	s.endIdleWatch()
+	s.scan()
+	s.apiRecords(w, req)
+}
+
+func (s *server) apiDelete(w http.ResponseWriter, req *http.Request) {
+	if why := readonlyReason(s.opts); why != "" {
+		writeAPIJSON(w, http.StatusForbidden, apiError{"read-only: " + why})
+		return
+	}
+	key := req.Header.Get("Idempotency-Key")
+	if key == "" {
+		writeAPIJSON(w, http.StatusBadRequest, apiError{"an Idempotency-Key header is required"})
+		return
+	}
+	// Hold the replay lock across the deletion so a concurrent retry with
+	// the same key waits for the first attempt's answer.
+	s.replayMu.Lock()
+	defer s.replayMu.Unlock()
+	if resp, ok := s.replays[key]; ok {
+		w.Header().Set("Idempotent-Replayed", "true")
+		writeAPIJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	var body deleteRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeAPIJSON(w, http.StatusBadRequest, apiError{"decoding request: " + err.Error()})
+		return
+	}
+	resp := deleteResponse{Removed: []string{}, Failed: []string{}, Skipped: map[string]string{}}
+	records := s.selected(body.Paths)
+	chosen := map[string]bool{}
+	for _, r := range records {
+		chosen[r.Path] = true
+	}
+	for _, p := range body.Paths {
+		if !chosen[p] {
+			resp.Skipped[p] = s.whyNotDeletable(p)
+		}
+	}
+	if len(records) > 0 {
+		removed := s.remove(records)
+		for _, r := range records {
+			if removed[r.Path] {
+				resp.Removed = append(resp.Removed, r.Path)
+			} else {
+				resp.Failed = append(resp.Failed, r.Path)
+			}
+		}
+	}
+
+	if s.replays == nil || len(s.replays) >= apiMaxReplays {
+		s.replays = map[string]deleteResponse{}
+	}
+	s.replays[key] = resp
+	writeAPIJSON(w, http.StatusOK, resp)
+}
+
+// whyNotDeletable explains why selected left out path.
+func (s *server) whyNotDeletable(path string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.records {
+		if r.Path != path {
+			continue
+		}
+		switch {
+		case r.Advisory:
+			return "advisory"
+		case r.Review != "":
+			return "review required: " + r.Review
+		}
+	}
+	return "not in the last scan"
+}
+
+// apiHandler returns the API's routes.
+func (s *server) apiHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/records", s.apiAuth(http.MethodGet, s.apiRecords))
+	mux.HandleFunc("/api/v1/scan", s.apiAuth(http.MethodPost, s.apiScan))
+	mux.HandleFunc("/api/v1/delete", s.apiAuth(http.MethodPost, s.apiDelete))
+	mux.HandleFunc("/api/", func(w http.ResponseWriter, req *http.Request) {
+		writeAPIJSON(w, http.StatusNotFound, apiError{"no such endpoint: " + strings.TrimPrefix(req.URL.Path, "/api")})
+	})
+	return mux
+}
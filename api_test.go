@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func apiRequest(s *server, method, path, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, testURL+path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+s.apiToken)
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	rec := httptest.NewRecorder()
+	s.handler().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAPI_RequiresToken(t *testing.T) {
+	s, _ := testServer(t, Record{Type: "venv", Path: "/p/a/.venv"})
+	s.apiToken = "secret"
+	rec := httptest.NewRecorder()
+	s.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, testURL+"/api/v1/records", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("no token: status %d, want 401", rec.Code)
+	}
+	if rec := apiRequest(s, http.MethodGet, "/api/v1/records", "", ""); rec.Code != http.StatusOK {
+		t.Errorf("with token: status %d", rec.Code)
+	}
+	if rec := apiRequest(s, http.MethodGet, "/api/v1/delete", "", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET /delete: status %d, want 405", rec.Code)
+	}
+}
+
+func TestAPI_Records(t *testing.T) {
+	s, _ := testServer(t,
+		Record{Type: "venv", Path: "/p/a/.venv", Size: 300},
+		Record{Type: "node_modules", Path: "/p/a/node_modules", Size: 200},
+	)
+	s.apiToken = "secret"
+	rec := apiRequest(s, http.MethodGet, "/api/v1/records?type=node_modules", "", "")
+	var doc JSONOutput
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.SchemaVersion != schemaVersion || doc.Count != 1 || doc.Records[0].Path != "/p/a/node_modules" {
+		t.Errorf("records = %+v", doc)
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// "Keep my machine like it was after the last cleanup" is a question about
+// growth, not about age. `tidyup baseline save NAME` records every item
+// tidyup can find under the given roots, stale or not (the scan runs with
+// -age 0), in baselines/NAME.json in the state directory; a later run with
+// -baseline NAME reports only what is new since then or has grown, and by
+// how much.
+
+// baseline is a saved snapshot of the items under some roots.
+type baseline struct {
+	Name  string                  `json:"name"`
+	Time  string                  `json:"time"` // RFC3339
+	Roots []string                `json:"roots"`
+	Items map[string]baselineItem `json:"items"` // by absolute path
+}
+
+// baselineItem is one item as the baseline saw it.
+type baselineItem struct {
+	Type string `json:"type"`
+	Size int64  `json:"size_bytes"`
+}
+
+// validBaselineName keeps names usable as file names.
+var validBaselineName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// baselinePath is where the baseline called name is kept.
+func baselinePath(name string) (string, error) {
+	if !validBaselineName.MatchString(name) {
+		return "", fmt.Errorf("invalid baseline name %q (letters, digits, '.', '_', '-')", name)
+	}
+	return statePath("baselines", name+".json")
+}
+
+// newBaseline snapshots records.
+func newBaseline(name string, roots []string, records []Record, now time.Time) *baseline {
+	b := &baseline{Name: name, Time: now.Format(time.RFC3339), Roots: roots, Items: map[string]baselineItem{}}
+	for _, r := range records {
+		b.Items[r.Path] = baselineItem{Type: r.Type, Size: r.Size}
+	}
+	return b
+}
+
+// loadBaseline reads the baseline called name.
+func loadBaseline(name string) (*baseline, error) {
+	path, err := baselinePath(name)
+	if err != nil {
+		return nil, err
+	}
+	var b baseline
+	if err := loadState(path, &b); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("no baseline %q; save one with 'tidyup baseline save %s'", name, name)
+		}
+		return nil, err
+	}
+	return &b, nil
+}
+
+// sinceBaseline keeps the records that are new since b or larger than they
+// were, setting Growth to the bytes added.
+func sinceBaseline(records []Record, b *baseline) []Record {
+	var kept []Record
+	for _, r := range records {
+		before, seen := b.Items[r.Path]
+		switch {
+		case !seen:
+			r.Growth = r.Size
+			r.Notes = append(r.Notes, "new since baseline "+b.Name)
+		case r.Size > before.Size:
+			r.Growth = r.Size - before.Size
+			r.Notes = append(r.Notes, fmt.Sprintf("grew by %s since baseline %s", formatBytes(r.Growth), b.Name))
+		default:
+			continue
+		}
+		kept = append(kept, r)
+	}
+	return kept
+}
+
+// runBaseline implements `tidyup baseline save|list|delete`.
+func runBaseline(args []string) int {
+	usage := func() {
+		fmt.Fprintf(os.Stderr, "Usage: tidyup baseline save NAME [flags] [paths...]\n")
+		fmt.Fprintf(os.Stderr, "       tidyup baseline list\n")
+		fmt.Fprintf(os.Stderr, "       tidyup baseline delete NAME\n\n")
+		fmt.Fprintf(os.Stderr, "Saves what is on disk now, so 'tidyup -baseline NAME' reports only growth since.\n")
+	}
+	if len(args) == 0 {
+		usage()
+		return exitError
+	}
+	switch args[0] {
+	case "save":
+		return runBaselineSave(args[1:])
+	case "list":
+		return runBaselineList()
+	case "delete":
+		if len(args) != 2 {
+			usage()
+			return exitError
+		}
+		path, err := baselinePath(args[1])
+		if err == nil {
+			err = os.Remove(path)
+		}
+		if err != nil {
+			stderr.errorf("%v", err)
+			return exitError
+		}
+		fmt.Printf("Deleted baseline %s.\n", args[1])
+		return exitOK
+	}
+	usage()
+	return exitError
+}
+
+// runBaselineSave scans like a normal run at -age 0 and saves the result.
+func runBaselineSave(args []string) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		stderr.errorf("baseline save needs a NAME")
+		return exitError
+	}
+	name := args[0]
+	path, err := baselinePath(name)
+	if err != nil {
+		stderr.errorf("%v", err)
+		return exitError
+	}
+	flags := flag.NewFlagSet("baseline save", flag.ContinueOnError)
+	maxDepth := flags.Int("depth", 5, "Scan depth for recursion")
+	typeFlag := flags.String("type", "", "Comma-separated types (default venv)")
+	allTypes := flags.Bool("all", false, "Scan for all supported types")
+	systemScan := flags.Bool("system", false, "Include well-known per-user locations")
+	excludeRaw := flags.String("exclude", "", "Comma-separated path patterns to skip")
+	skipRaw := flags.String("skip", "", "Comma-separated directory names never to enter, in addition to .git, Library, .Trash")
+	noSkipRaw := flags.String("no-skip", "", "Comma-separated default skip names to scan after all (e.g. Library)")
+	if err := flags.Parse(args[1:]); err != nil {
+		return exitError
+	}
+	scanTypes, warnings := parseScanTypes(*typeFlag, *allTypes)
+	for _, w := range warnings {
+		stderr.warnf("%s", w)
+	}
+	opts := &options{
+		maxDepth:        *maxDepth,
+		systemScan:      *systemScan,
+		excludePatterns: splitList(*excludeRaw),
+		skipDirs:        nameSet(splitList(*skipRaw)),
+		noSkipDirs:      nameSet(splitList(*noSkipRaw)),
+		scanTypes:       scanTypes,
+	}
+	roots := flags.Args()
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+	if opts.systemScan {
+		if home, err := os.UserHomeDir(); err == nil {
+			roots = append(roots, systemRoots(home, opts)...)
+		}
+	}
+	for i, root := range roots {
+		if abs, err := filepath.Abs(root); err == nil {
+			roots[i] = abs
+		}
+	}
+
+	records, scanErrors := scanRoots(roots, opts)
+	for _, e := range scanErrors {
+		stderr.warnf("%s", e)
+	}
+	b := newBaseline(name, roots, records, time.Now())
+	if err := saveState(path, b, 0600); err != nil {
+		stderr.errorf("saving baseline: %v", err)
+		return exitError
+	}
+	fmt.Printf("Saved baseline %s: %d items, %s.\n", name, len(records), formatBytes(totalSize(records)))
+	return exitOK
+}
+
+// runBaselineList prints the saved baselines.
+func runBaselineList() int {
+	dir, err := statePath("baselines")
+	if err != nil {
+		stderr.errorf("%v", err)
+		return exitError
+	}
+	entries, _ := os.ReadDir(dir)
+	var names []string
+	for _, e := range entries {
+		if name, ok := strings.CutSuffix(e.Name(), ".json"); ok && validBaselineName.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		fmt.Println("No baselines yet; save one with 'tidyup baseline save NAME [paths...]'.")
+		return exitOK
+	}
+	sort.Strings(names)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSAVED\tITEMS\tSIZE\tROOTS")
+	for _, name := range names {
+		b, err := loadBaseline(name)
+		if err != nil {
+			stderr.warnf("%v", err)
+			continue
+		}
+		var total int64
+		for _, it := range b.Items {
+			total += it.Size
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", name, b.Time, len(b.Items), formatBytes(total), strings.Join(b.Roots, " "))
+	}
+	tw.Flush()
+	return exitOK
+}
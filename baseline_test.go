@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSinceBaseline(t *testing.T) {
+	b := &baseline{Name: "clean", Items: map[string]baselineItem{
+		"/p/a/.venv":        {Type: "venv", Size: 100},
+		"/p/b/node_modules": {Type: "node_modules", Size: 500},
+	}}
+	records := []Record{
+		{Path: "/p/a/.venv", Size: 100},        // unchanged
+		{Path: "/p/b/node_modules", Size: 800}, // grew
+		{Path: "/p/c/.venv", Size: 40},         // new
+	}
+	got := sinceBaseline(records, b)
+	if len(got) != 2 || got[0].Path != "/p/b/node_modules" || got[0].Growth != 300 || got[1].Path != "/p/c/.venv" || got[1].Growth != 40 {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestBaseline_SaveAndCompare(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	root := t.TempDir()
+	makeVenv(t, filepath.Join(root, "a", ".venv"), time.Now().AddDate(0, 0, -1))
+
+	if code := runBaselineSave([]string{"post-cleanup", root}); code != exitOK {
+		t.Fatalf("save: exit %d", code)
+	}
+	b, err := loadBaseline("post-cleanup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := b.Items[filepath.Join(root, "a", ".venv")]; !ok || len(b.Items) != 1 {
+		t.Errorf("baseline items = %v; want the fresh venv too", b.Items)
+	}
+
+	makeVenv(t, filepath.Join(root, "b", ".venv"), time.Now().AddDate(0, 0, -90))
+	records, _ := scanRoots([]string{root}, &options{maxDepth: 5, scanTypes: map[string]bool{"venv": true}})
+	if got := sinceBaseline(records, b); len(got) != 1 || got[0].Path != filepath.Join(root, "b", ".venv") {
+		t.Errorf("since baseline: %+v", got)
+	}
+
+	if _, err := loadBaseline("missing"); err == nil {
+		t.Error("missing baseline: want an error")
+	}
+	if _, err := baselinePath("../escape"); err == nil {
+		t.Error("path in name: want an error")
+	}
+	if runBaseline([]string{"delete", "post-cleanup"}) != exitOK {
+		t.Error("delete failed")
+	}
+	if path, _ := baselinePath("post-cleanup"); fileExists(path) {
+		t.Error("baseline still there after delete")
+	}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
@@ -0,0 +1,45 @@
+//go:build dragonfly || freebsd || netbsd
+
+package main
+
+import (
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// The calls behind removeBeneath (beneath_unix.go) on FreeBSD, NetBSD, and
+// DragonFly.
+
+// atRemovedir is AT_REMOVEDIR.
+var atRemovedir = map[string]int{"dragonfly": 0x2, "freebsd": 0x800, "netbsd": 0x800}[runtime.GOOS]
+
+// openat opens name in dirfd.
+func openat(dirfd int, name string, flags int, perm uint32) (int, error) {
+	p, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return -1, err
+	}
+	fd, _, errno := syscall.Syscall6(syscall.SYS_OPENAT, uintptr(dirfd), uintptr(unsafe.Pointer(p)), uintptr(flags), uintptr(perm), 0, 0)
+	if errno != 0 {
+		return -1, errno
+	}
+	return int(fd), nil
+}
+
+// unlinkat removes name in dirfd; flags may be atRemovedir.
+func unlinkat(dirfd int, name string, flags int) error {
+	p, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return err
+	}
+	if _, _, errno := syscall.Syscall(syscall.SYS_UNLINKAT, uintptr(dirfd), uintptr(unsafe.Pointer(p)), uintptr(flags)); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// openBeneath has no single call here: the caller walks the path.
+func openBeneath(int, string, int) (int, error) {
+	return -1, syscall.ENOSYS
+}
@@ -0,0 +1,27 @@
+//go:build darwin || openbsd
+
+package main
+
+import (
+	"runtime"
+	"syscall"
+	_ "unsafe" // for go:linkname
+)
+
+// The calls behind removeBeneath (beneath_unix.go) on macOS and OpenBSD,
+// where system calls go through libc: the syscall package's own wrappers,
+// which it leaves unexported.
+
+// atRemovedir is AT_REMOVEDIR.
+var atRemovedir = map[string]int{"darwin": 0x80, "openbsd": 0x08}[runtime.GOOS]
+
+//go:linkname openat syscall.openat
+func openat(dirfd int, name string, flags int, perm uint32) (int, error)
+
+//go:linkname unlinkat syscall.unlinkat
+func unlinkat(dirfd int, name string, flags int) error
+
+// openBeneath has no single call here: the caller walks the path.
+func openBeneath(int, string, int) (int, error) {
+	return -1, syscall.ENOSYS
+}
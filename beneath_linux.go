@@ -0,0 +1,54 @@
+package main
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// The calls behind removeBeneath (beneath_unix.go) on Linux, which can open
+// a whole path with no symlink along it in one openat2 call.
+
+const (
+	sysOpenat2          = 437 // the same number on every architecture
+	resolveNoMagiclinks = 0x02
+	resolveNoSymlinks   = 0x04
+	resolveBeneath      = 0x08
+	atRemovedir         = 0x200
+)
+
+// openHow is struct open_how.
+type openHow struct {
+	flags, mode, resolve uint64
+}
+
+// openBeneath opens path beneath dirfd without following any symlink:
+// ENOSYS on kernels before 5.6.
+func openBeneath(dirfd int, path string, flags int) (int, error) {
+	p, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return -1, err
+	}
+	how := openHow{flags: uint64(flags), resolve: resolveBeneath | resolveNoSymlinks | resolveNoMagiclinks}
+	fd, _, errno := syscall.Syscall6(sysOpenat2, uintptr(dirfd), uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(&how)), unsafe.Sizeof(how), 0, 0)
+	if errno != 0 {
+		return -1, errno
+	}
+	return int(fd), nil
+}
+
+// unlinkat removes name in dirfd; flags may be atRemovedir.
+func unlinkat(dirfd int, name string, flags int) error {
+	p, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return err
+	}
+	if _, _, errno := syscall.Syscall(syscall.SYS_UNLINKAT, uintptr(dirfd), uintptr(unsafe.Pointer(p)), uintptr(flags)); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// openat opens name in dirfd.
+func openat(dirfd int, name string, flags int, perm uint32) (int, error) {
+	return syscall.Openat(dirfd, name, flags, perm)
+}
@@ -0,0 +1,60 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// removeBeneath removes the file or tree at the absolute, clean path after
+// checking that no symlink sits on the way to it. Without descriptor-relative
+// calls (Windows, and the Unix systems beneath_unix.go does not cover), the
+// check and the removal are separate steps; below path, os.RemoveAll never
+// follows symlinks.
+func removeBeneath(path string, file bool) error {
+	if !filepath.IsAbs(path) || filepath.Clean(path) != path || filepath.Dir(path) == path {
+		return fmt.Errorf("refusing to remove %q: not a clean absolute path", path)
+	}
+	parent := filepath.Dir(path)
+	resolved, err := filepath.EvalSymlinks(parent)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if resolved != parent {
+		return fmt.Errorf("%s: a symlink now sits on the path to it", path)
+	}
+	if file {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if ioThrottle.Load() != nil {
+		return removeAllPaced(path)
+	}
+	return os.RemoveAll(path)
+}
+
+// removeAllPaced removes path like os.RemoveAll, but entry by entry,
+// deepest first, at the -nice pace.
+func removeAllPaced(path string) error {
+	var entries []string
+	_ = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && p != path {
+			entries = append(entries, p)
+		}
+		return nil
+	})
+	for i := len(entries) - 1; i >= 0; i-- {
+		pace()
+		os.Remove(entries[i])
+	}
+	// Whatever is left (permissions, a racing writer) gets the usual treatment.
+	return os.RemoveAll(path)
+}
@@ -0,0 +1,122 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// Unix systems remove trees relative to directory file descriptors. The
+// parent of the target is opened from "/" one O_NOFOLLOW component at a
+// time (on Linux 5.6 and later, in one openat2(RESOLVE_NO_SYMLINKS) call),
+// so a symlink swapped into the path cannot redirect the removal; below
+// it, every directory is opened with O_NOFOLLOW relative to its parent's
+// descriptor and emptied with unlinkat, one entry at the -nice pace. The
+// per-system calls are in beneath_linux.go, beneath_libc.go (macOS,
+// OpenBSD), and beneath_bsd.go.
+
+const dirFlags = syscall.O_RDONLY | syscall.O_DIRECTORY | syscall.O_NOFOLLOW | syscall.O_CLOEXEC
+
+// openDirNoFollow opens the absolute directory dir with no symlink
+// anywhere along it.
+func openDirNoFollow(dir string) (int, error) {
+	root, err := syscall.Open("/", dirFlags, 0)
+	if err != nil {
+		return -1, err
+	}
+	rel := strings.TrimPrefix(dir, "/")
+	if rel == "" {
+		return root, nil
+	}
+	fd, err := openBeneath(root, rel, dirFlags)
+	if !errors.Is(err, syscall.ENOSYS) && !errors.Is(err, syscall.EPERM) {
+		syscall.Close(root)
+		return fd, err
+	}
+	// No single call for it (or a seccomp filter refusing it): walk down
+	// one component at a time.
+	fd = root
+	for _, name := range strings.Split(rel, "/") {
+		next, err := openat(fd, name, dirFlags, 0)
+		syscall.Close(fd)
+		if err != nil {
+			return -1, err
+		}
+		fd = next
+	}
+	return fd, nil
+}
+
+// removeBeneath removes the file or tree at the absolute, clean path
+// without following symlinks, neither on the way to it nor inside it. A
+// symlink at path itself is removed, not followed, like os.RemoveAll.
+func removeBeneath(path string, file bool) error {
+	if !filepath.IsAbs(path) || filepath.Clean(path) != path || path == "/" {
+		return fmt.Errorf("refusing to remove %q: not a clean absolute path", path)
+	}
+	parent, err := openDirNoFollow(filepath.Dir(path))
+	if err != nil {
+		if errors.Is(err, syscall.ELOOP) || errors.Is(err, syscall.ENOTDIR) || errors.Is(err, syscall.EXDEV) {
+			return fmt.Errorf("%s: a symlink now sits on the path to it", path)
+		}
+		if errors.Is(err, syscall.ENOENT) {
+			return nil
+		}
+		return &os.PathError{Op: "open", Path: filepath.Dir(path), Err: err}
+	}
+	defer syscall.Close(parent)
+	name := filepath.Base(path)
+	if file {
+		pace()
+		err = unlinkat(parent, name, 0)
+	} else {
+		err = removeAt(parent, name)
+	}
+	if err != nil && !errors.Is(err, syscall.ENOENT) {
+		return &os.PathError{Op: "remove", Path: path, Err: err}
+	}
+	return nil
+}
+
+// removeAt removes name in dirfd: a directory with everything below it,
+// anything else (including a symlink to a directory) by itself.
+func removeAt(dirfd int, name string) error {
+	fd, err := openat(dirfd, name, dirFlags, 0)
+	if errors.Is(err, syscall.ENOTDIR) || errors.Is(err, syscall.ELOOP) {
+		return unlinkat(dirfd, name, 0)
+	}
+	if err != nil {
+		return err
+	}
+	dir := os.NewFile(uintptr(fd), name)
+	defer dir.Close()
+	for {
+		names, err := dir.Readdirnames(256)
+		for _, child := range names {
+			pace()
+			// unlink(2) of a directory fails with EISDIR on Linux and EPERM
+			// elsewhere; either way, empty it first.
+			if uerr := unlinkat(fd, child, 0); uerr != nil && !errors.Is(uerr, syscall.ENOENT) {
+				if !errors.Is(uerr, syscall.EISDIR) && !errors.Is(uerr, syscall.EPERM) {
+					return uerr
+				}
+				if rerr := removeAt(fd, child); rerr != nil {
+					return rerr
+				}
+			}
+		}
+		if err != nil || len(names) == 0 {
+			break
+		}
+		// Entries were removed under the open directory; read from the start.
+		if _, err := dir.Seek(0, 0); err != nil {
+			return err
+		}
+	}
+	return unlinkat(dirfd, name, atRemovedir)
+}
@@ -0,0 +1,49 @@
+//go:build darwin || freebsd || netbsd
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// birthTime returns when path was created, from st_birthtime.
+func birthTime(path string) (time.Time, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || st.Birthtimespec.Sec == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(st.Birthtimespec.Sec), int64(st.Birthtimespec.Nsec)), true
+}
+
+// changeTime returns the inode change time (ctime), which a copy or restore
+// cannot preserve.
+func changeTime(path string) (time.Time, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(st.Ctimespec.Sec), int64(st.Ctimespec.Nsec)), true
+}
+
+// accessTime returns the last access time (atime).
+func accessTime(path string) (time.Time, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(st.Atimespec.Sec), int64(st.Atimespec.Nsec)), true
+}
@@ -0,0 +1,104 @@
+//go:build linux
+
+package main
+
+import (
+	"runtime"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// statxSyscall maps GOARCH to the statx(2) syscall number, which the
+// syscall package does not export.
+var statxSyscall = map[string]uintptr{
+	"amd64":   332,
+	"386":     383,
+	"arm64":   291,
+	"arm":     397,
+	"riscv64": 291,
+	"loong64": 291,
+	"ppc64le": 383,
+	"ppc64":   383,
+	"s390x":   379,
+}
+
+const (
+	atFDCWD    = -100
+	statxAtime = 0x20
+	statxCtime = 0x80
+	statxBtime = 0x800
+)
+
+type statxTimestamp struct {
+	Sec  int64
+	Nsec uint32
+	_    int32
+}
+
+// statxBuf mirrors struct statx up to stx_ctime; the kernel buffer is 256 bytes.
+type statxBuf struct {
+	Mask           uint32
+	Blksize        uint32
+	Attributes     uint64
+	Nlink          uint32
+	UID            uint32
+	GID            uint32
+	Mode           uint16
+	_              uint16
+	Ino            uint64
+	Size           uint64
+	Blocks         uint64
+	AttributesMask uint64
+	Atime          statxTimestamp
+	Btime          statxTimestamp
+	Ctime          statxTimestamp
+	_              [256 - 112]byte
+}
+
+// statx calls statx(2) on path requesting the given mask.
+func statx(path string, mask uint32) (statxBuf, bool) {
+	var buf statxBuf
+	nr, ok := statxSyscall[runtime.GOARCH]
+	if !ok {
+		return buf, false
+	}
+	p, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return buf, false
+	}
+	fd := atFDCWD
+	_, _, errno := syscall.Syscall6(nr, uintptr(fd), uintptr(unsafe.Pointer(p)),
+		0, uintptr(mask), uintptr(unsafe.Pointer(&buf)), 0)
+	return buf, errno == 0
+}
+
+// birthTime returns when path was created, via statx(STATX_BTIME). Not all
+// filesystems record it (e.g. older ext3, some network filesystems).
+func birthTime(path string) (time.Time, bool) {
+	buf, ok := statx(path, statxBtime)
+	if !ok || buf.Mask&statxBtime == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(buf.Btime.Sec, int64(buf.Btime.Nsec)), true
+}
+
+// changeTime returns the inode change time (ctime), which a copy or restore
+// cannot preserve.
+func changeTime(path string) (time.Time, bool) {
+	buf, ok := statx(path, statxCtime)
+	if !ok || buf.Mask&statxCtime == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(buf.Ctime.Sec, int64(buf.Ctime.Nsec)), true
+}
+
+// accessTime returns the last access time (atime). Under relatime it is
+// refreshed at most daily, which is enough to tell a used tool from an idle one.
+func accessTime(path string) (time.Time, bool) {
+	buf, ok := statx(path, statxAtime)
+	if !ok || buf.Mask&statxAtime == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(buf.Atime.Sec, int64(buf.Atime.Nsec)), true
+}
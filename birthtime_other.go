@@ -0,0 +1,20 @@
+//go:build !(darwin || freebsd || netbsd || linux || windows)
+
+package main
+
+import "time"
+
+// birthTime is unavailable on this platform.
+func birthTime(path string) (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// changeTime is unavailable on this platform.
+func changeTime(path string) (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// accessTime is unavailable on this platform.
+func accessTime(path string) (time.Time, bool) {
+	return time.Time{}, false
+}
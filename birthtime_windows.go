@@ -0,0 +1,40 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// birthTime returns when path was created, from the NTFS creation time.
+func birthTime(path string) (time.Time, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	attrs, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(0, attrs.CreationTime.Nanoseconds()), true
+}
+
+// changeTime is not exposed by the Windows file attribute data.
+func changeTime(path string) (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// accessTime returns the NTFS last access time (updated lazily by Windows).
+func accessTime(path string) (time.Time, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	attrs, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(0, attrs.LastAccessTime.Nanoseconds()), true
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// macOS names per-app data after bundle identifiers (com.google.Chrome).
+// appIndex maps the identifiers of installed apps to their names, so that
+// data can be attributed to an app -- or flagged as orphaned when no
+// installed app claims it.
+
+// appInfo is an installed application.
+type appInfo struct {
+	name string // CFBundleDisplayName, CFBundleName, or the .app basename
+	path string // the .app bundle
+}
+
+// appIndex lists installed apps by bundle identifier. It is built on first
+// use, once per run.
+type appIndex struct {
+	dirs []string
+	once sync.Once
+	apps map[string]appInfo
+}
+
+// appDirs are where macOS apps are installed.
+func appDirs(home string) []string {
+	return []string{
+		"/Applications",
+		"/Applications/Utilities",
+		"/System/Applications",
+		"/System/Applications/Utilities",
+		filepath.Join(home, "Applications"),
+	}
+}
+
+// newAppIndex returns an index of the apps in dirs and one level below
+// (vendor folders such as /Applications/Setapp).
+func newAppIndex(dirs []string) *appIndex {
+	return &appIndex{dirs: dirs}
+}
+
+// installedApps is the index used by scans; tests replace it.
+var installedApps = func() *appIndex {
+	home, _ := os.UserHomeDir()
+	return newAppIndex(appDirs(home))
+}()
+
+func (ix *appIndex) load() {
+	ix.apps = map[string]appInfo{}
+	for _, dir := range ix.dirs {
+		top, _ := filepath.Glob(filepath.Join(dir, "*.app"))
+		nested, _ := filepath.Glob(filepath.Join(dir, "*", "*.app"))
+		for _, app := range append(top, nested...) {
+			info := readInfoPlist(filepath.Join(app, "Contents", "Info.plist"))
+			id := info["CFBundleIdentifier"]
+			if id == "" {
+				continue
+			}
+			name := info["CFBundleDisplayName"]
+			if name == "" {
+				name = info["CFBundleName"]
+			}
+			if name == "" {
+				name = strings.TrimSuffix(filepath.Base(app), ".app")
+			}
+			ix.apps[id] = appInfo{name: name, path: app}
+		}
+	}
+}
+
+// lookup finds the installed app owning a bundle identifier. Helpers and
+// updaters use the app's identifier as a prefix (com.microsoft.VSCode.ShipIt),
+// so the longest installed prefix counts too.
+func (ix *appIndex) lookup(id string) (appInfo, bool) {
+	ix.once.Do(ix.load)
+	for candidate := id; ; {
+		if app, ok := ix.apps[candidate]; ok {
+			return app, true
+		}
+		i := strings.LastIndexByte(candidate, '.')
+		if i <= 0 {
+			return appInfo{}, false
+		}
+		candidate = candidate[:i]
+	}
+}
+
+// bundleIDPattern matches reverse-DNS bundle identifiers.
+var bundleIDPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9-]*(\.[A-Za-z0-9_-]+){2,}$`)
+
+// isBundleID reports whether name looks like a bundle identifier.
+func isBundleID(name string) bool {
+	return bundleIDPattern.MatchString(name)
+}
+
+// isOrphanedBundle reports whether id names a third-party app that is no
+// longer installed. Apple's identifiers are skipped: many belong to system
+// daemons that have no .app to find.
+func isOrphanedBundle(id string) bool {
+	if !isBundleID(id) || strings.HasPrefix(id, "com.apple.") {
+		return false
+	}
+	_, installed := installedApps.lookup(id)
+	return !installed
+}
+
+// readInfoPlist returns the top-level string values of an Info.plist.
+// Binary plists are converted with plutil, which only exists on macOS.
+func readInfoPlist(path string) map[string]string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	if bytes.HasPrefix(data, []byte("bplist")) {
+		if data, err = exec.Command("plutil", "-convert", "xml1", "-o", "-", path).Output(); err != nil {
+			return nil
+		}
+	}
+	return parsePlistStrings(data)
+}
+
+// parsePlistStrings extracts <key>/<string> pairs from the top-level dict
+// of an XML plist.
+func parsePlistStrings(data []byte) map[string]string {
+	values := map[string]string{}
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	depth := 0
+	var key string
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return values
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			if depth != 3 { // plist > dict > key/string
+				continue
+			}
+			var text string
+			if dec.DecodeElement(&text, &t) != nil {
+				return values
+			}
+			depth--
+			switch t.Name.Local {
+			case "key":
+				key = text
+			case "string":
+				if key != "" {
+					values[key] = text
+				}
+				key = ""
+			default:
+				key = ""
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// inFlightWindow is how recently a file must have been written for a
+// stale-looking tree to count as being modified right now.
+const inFlightWindow = 10 * time.Minute
+
+// rsyncTempName matches rsync's in-progress temp files: ".name.XXXXXX".
+var rsyncTempName = regexp.MustCompile(`^\..+\.[A-Za-z0-9]{6}$`)
+
+// partialFileSuffixes are suffixes used by sync clients and downloaders for
+// files that are still being transferred.
+var partialFileSuffixes = []string{
+	".!sync",      // Resilio Sync
+	".crdownload", // Chrome
+	".part",       // Firefox, curl, Nextcloud
+	".partial",    // Safari
+	".unison.tmp", // Unison
+}
+
+// isPartialFile reports whether name is a transfer temp file, whatever its mtime.
+func isPartialFile(name string) bool {
+	// Syncthing: ".syncthing.<name>.tmp" (Unix), "~syncthing~<name>.tmp" (Windows).
+	if (strings.HasPrefix(name, ".syncthing.") || strings.HasPrefix(name, "~syncthing~")) &&
+		strings.HasSuffix(name, ".tmp") {
+		return true
+	}
+	for _, suffix := range partialFileSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// inFlightReason explains why a file suggests its tree is being written by
+// another tool right now, or returns "".
+func inFlightReason(name string, info fs.FileInfo, now time.Time) string {
+	if isPartialFile(name) {
+		return "partial transfer file " + name
+	}
+	if info.ModTime().After(now.Add(-inFlightWindow)) {
+		if rsyncTempName.MatchString(name) {
+			return "rsync temp file " + name
+		}
+		return "file written in the last few minutes: " + name
+	}
+	return ""
+}
+
+// syncClientMarkers are files or directories a sync client places at the
+// root of the folder it manages.
+var syncClientMarkers = map[string]string{
+	".dropbox":          "Dropbox",
+	".stfolder":         "Syncthing",
+	".sync":             "Resilio Sync",
+	".owncloudsync.log": "Nextcloud/ownCloud",
+}
+
+// syncClientFor returns the sync client managing path, checking each ancestor
+// for a client's root marker, or "".
+func syncClientFor(path string) string {
+	for dir := path; ; {
+		for marker, client := range syncClientMarkers {
+			if _, err := os.Lstat(filepath.Join(dir, marker)); err == nil {
+				return client
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsPartialFile(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"model.bin.crdownload", true},
+		{"wheel.whl.part", true},
+		{".syncthing.index.js.tmp", true},
+		{"~syncthing~index.js.tmp", true},
+		{"data.!sync", true},
+		{"index.js", false},
+		{"partial.py", false},
+		{"tmp", false},
+	}
+	for _, tt := range tests {
+		if got := isPartialFile(tt.name); got != tt.want {
+			t.Errorf("isPartialFile(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestInFlightReason(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	write := func(name string, mtime time.Time) os.FileInfo {
+		p := filepath.Join(dir, name)
+		os.WriteFile(p, []byte("x"), 0644)
+		os.Chtimes(p, mtime, mtime)
+		info, err := os.Stat(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info
+	}
+
+	old := now.AddDate(0, 0, -90)
+	if r := inFlightReason("index.js", write("index.js", old), now); r != "" {
+		t.Errorf("old ordinary file: got %q, want idle", r)
+	}
+	if r := inFlightReason(".index.js.Ab3xQ9", write(".index.js.Ab3xQ9", now), now); !strings.Contains(r, "rsync") {
+		t.Errorf("fresh rsync temp: got %q", r)
+	}
+	if r := inFlightReason("index.js", write("index.js", now), now); r == "" {
+		t.Error("file written just now should count as in flight")
+	}
+	if r := inFlightReason("a.crdownload", write("a.crdownload", old), now); r == "" {
+		t.Error("partial download should count regardless of mtime")
+	}
+}
+
+func TestSyncClientFor(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, ".dropbox"), []byte("{}"), 0644)
+	nested := filepath.Join(root, "proj", ".venv")
+	os.MkdirAll(nested, 0755)
+	if got := syncClientFor(nested); got != "Dropbox" {
+		t.Errorf("syncClientFor = %q, want Dropbox", got)
+	}
+}
+
+func TestScanRoots_DefersTreeBeingWritten(t *testing.T) {
+	root := t.TempDir()
+	lastUsed := time.Now().AddDate(0, 0, -90)
+	venv := filepath.Join(root, ".venv")
+	makeVenv(t, venv, lastUsed)
+
+	opts := &options{minAge: 30, maxDepth: 5, scanTypes: map[string]bool{"venv": true}}
+	if records, _ := scanRoots([]string{root}, opts); len(records) != 1 {
+		t.Fatalf("idle venv: got %d records, want 1", len(records))
+	}
+
+	// rsync is mid-transfer into the venv.
+	os.WriteFile(filepath.Join(venv, "bin", ".python.Xy12ab"), []byte("x"), 0644)
+	records, warnings := scanRoots([]string{root}, opts)
+	if len(records) != 0 {
+		t.Fatalf("busy venv: got %d records, want 0", len(records))
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "deferred "+venv) {
+		t.Errorf("warnings = %v, want one deferral for %s", warnings, venv)
+	}
+}
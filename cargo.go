@@ -0,0 +1,42 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// isCargoTarget reports whether path is a Rust build directory: target/
+// beside the Cargo.toml of the crate or workspace that built it.
+func isCargoTarget(path string) bool {
+	if filepath.Base(path) != "target" {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(filepath.Dir(path), "Cargo.toml"))
+	return err == nil && info.Mode().IsRegular()
+}
+
+// getCargoUsage dates a target/ by the newest file cargo built into its
+// debug/ and release/ profiles; doc/ and the package cache say little about
+// when the crate was last built. A target/ with neither (only cross-compiled
+// triples, say) falls back to its newest file.
+func getCargoUsage(path string) (time.Time, bool) {
+	var latest time.Time
+	for _, profile := range []string{"debug", "release"} {
+		_ = filepath.WalkDir(filepath.Join(path, profile), func(p string, d fs.DirEntry, err error) error {
+			pace()
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			if info, err := d.Info(); err == nil && info.ModTime().After(latest) {
+				latest = info.ModTime()
+			}
+			return nil
+		})
+	}
+	if !latest.IsZero() {
+		return latest, true
+	}
+	return getCacheUsage(path)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestScanRoots_CargoTarget(t *testing.T) {
+	root := t.TempDir()
+	old := time.Now().AddDate(0, 0, -90)
+	write := func(rel string, mtime time.Time) {
+		p := filepath.Join(root, rel)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte("x"), 0644)
+		os.Chtimes(p, mtime, mtime)
+	}
+	write("crate/Cargo.toml", old)
+	write("crate/target/debug/crate", old)
+	write("crate/target/release/crate", old.AddDate(0, 0, 10))
+	write("crate/target/doc/index.html", time.Now().AddDate(0, 0, -40))
+	write("site/target/index.html", old) // no Cargo.toml: not a cargo target
+
+	target := filepath.Join(root, "crate", "target")
+	if got, ok := getCargoUsage(target); !ok || !got.Equal(old.AddDate(0, 0, 10)) {
+		t.Errorf("getCargoUsage = %v, %v; want the release build's mtime", got, ok)
+	}
+
+	opts := &options{minAge: 30, maxDepth: 5, scanTypes: map[string]bool{"cargo": true}}
+	records, _ := scanRoots([]string{root}, opts)
+	if len(records) != 1 || records[0].Path != target || records[0].Type != "cargo" {
+		t.Errorf("got %+v, want only %s", records, target)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Conda environments (conda, miniconda, mamba, micromamba) are recognized
+// by their conda-meta/ directory. A base installation holds conda itself
+// and the named environments in envs/; it is never reported, only the
+// environments inside it.
+
+// isCondaBase reports whether the conda environment at path is a base
+// installation rather than an environment created from one.
+func isCondaBase(path string) bool {
+	for _, marker := range []string{"condabin", "envs", "bin/conda", "bin/mamba"} {
+		if _, err := os.Stat(filepath.Join(path, marker)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// condaEnvs lists the environments in a base installation's envs/.
+func condaEnvs(base string) []string {
+	entries, err := os.ReadDir(filepath.Join(base, "envs"))
+	if err != nil {
+		return nil
+	}
+	var envs []string
+	for _, e := range entries {
+		p := filepath.Join(base, "envs", e.Name())
+		if e.IsDir() && venvKind(p) == "conda" {
+			envs = append(envs, p)
+		}
+	}
+	return envs
+}
+
+// condaHomes lists the standard conda environment directories under home,
+// plus $CONDA_ENVS_PATH and $MAMBA_ROOT_PREFIX/envs, added as roots by
+// -system when scanning for conda.
+func condaHomes(home string) []string {
+	var dirs []string
+	for _, base := range []string{"miniconda3", "anaconda3", "miniforge3", "mambaforge", "micromamba", ".conda", "opt/miniconda3", "opt/anaconda3"} {
+		dirs = append(dirs, filepath.Join(home, base, "envs"))
+	}
+	for _, dir := range filepath.SplitList(os.Getenv("CONDA_ENVS_PATH")) {
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	if dir := os.Getenv("MAMBA_ROOT_PREFIX"); dir != "" {
+		dirs = append(dirs, filepath.Join(dir, "envs"))
+	}
+	return dirs
+}
+
+// condaRemoveCommand is conda's own removal command for the environment at
+// path, which also drops it from `conda env list`.
+func condaRemoveCommand(path string) string {
+	tool := "conda"
+	if strings.Contains(filepath.ToSlash(path), "/micromamba/") {
+		tool = "micromamba"
+	}
+	return tool + ` env remove -y -p "` + path + `"`
+}
+
+// annotateConda adds the removal command and, when the project beside the
+// environment can recreate it, says how.
+func annotateConda(r *Record) {
+	r.Command = condaRemoveCommand(r.Path)
+	interp, _ := venvInterpreter(r.Path)
+	r.Python, r.Interpreter, r.InterpreterGone = interp.version, interp.path, interp.missing
+	for _, spec := range []string{"environment.yml", "environment.yaml"} {
+		if _, err := os.Stat(filepath.Join(filepath.Dir(r.Path), spec)); err == nil {
+			r.Notes = append(r.Notes, "recreate with: conda env create -f "+spec)
+			break
+		}
+	}
+}
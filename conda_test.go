@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// makeCondaEnv creates a conda environment last installed into at mtime.
+func makeCondaEnv(t *testing.T, dir string, mtime time.Time, extra ...string) {
+	t.Helper()
+	for _, f := range append([]string{"conda-meta/history", "bin/python"}, extra...) {
+		p := filepath.Join(dir, f)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte("x"), 0644)
+		os.Chtimes(p, mtime, mtime)
+	}
+}
+
+func TestScanRoots_Conda(t *testing.T) {
+	root := t.TempDir()
+	old := time.Now().AddDate(0, 0, -90)
+	projEnv := filepath.Join(root, "analysis", "env")
+	makeCondaEnv(t, projEnv, old)
+	os.WriteFile(filepath.Join(root, "analysis", "environment.yml"), []byte("name: analysis\n"), 0644)
+	base := filepath.Join(root, "miniconda3")
+	makeCondaEnv(t, base, old, "condabin/conda")
+	named := filepath.Join(base, "envs", "ml")
+	makeCondaEnv(t, named, old)
+
+	opts := &options{minAge: 30, maxDepth: 5, scanTypes: map[string]bool{"conda": true}}
+	records, _ := scanRoots([]string{root}, opts)
+	found := map[string]Record{}
+	for _, r := range records {
+		found[r.Path] = r
+	}
+	if len(records) != 2 || found[projEnv].Type != "conda" || found[named].Type != "conda" {
+		t.Fatalf("got %+v, want the project env and envs/ml, not the base", records)
+	}
+	if r := found[projEnv]; !strings.Contains(r.Command, "env remove -y -p") || !strings.Contains(strings.Join(r.Notes, "\n"), "environment.yml") {
+		t.Errorf("project env: command %q, notes %v", r.Command, r.Notes)
+	}
+
+	opts.scanTypes = map[string]bool{"venv": true}
+	if records, _ := scanRoots([]string{root}, opts); len(records) != 0 {
+		t.Errorf("-type venv: got %+v, want no conda environments", records)
+	}
+}
+
+func TestIsActiveVenv_CondaPrefix(t *testing.T) {
+	t.Setenv("VIRTUAL_ENV", "")
+	t.Setenv("CONDA_PREFIX", "/srv/miniconda3/envs/ml")
+	if !isActiveVenv("/srv/miniconda3/envs/ml/") {
+		t.Error("the activated conda environment should be active")
+	}
+	if !containsActiveVenv("/srv/miniconda3/envs") || containsActiveVenv("/srv/miniconda3/envs/ml") {
+		t.Error("containsActiveVenv should match only strict parents of $CONDA_PREFIX")
+	}
+}
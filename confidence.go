@@ -0,0 +1,272 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// lowConfidence is the score below which a record is listed separately and
+// held for review: the staleness call rests on too little evidence.
+const lowConfidence = 0.4
+
+// lowConfidencePrefix starts the Review reason of low-confidence records, so
+// they stay recognizable after a JSON round trip through `tidyup import`.
+const lowConfidencePrefix = "low confidence: "
+
+// isLowConfidence reports whether r was held for review for lack of evidence.
+func isLowConfidence(r Record) bool {
+	return strings.HasPrefix(r.Review, lowConfidencePrefix)
+}
+
+// usageMarkers names the independent usage signals found for an item: each
+// marker file or lockfile that dated it, site-packages contents, or plain
+// file mtimes. An empty result means only the directory's own mtime was
+// available.
+func usageMarkers(r *Record) []string {
+	var found []string
+	exists := func(rel string) bool {
+		_, err := os.Lstat(filepath.Join(r.Path, rel))
+		return err == nil
+	}
+	switch r.Type {
+	case "venv", "conda", "tools", "nox":
+		for _, m := range []string{"pyvenv.cfg", "bin/activate", "bin/python", "Scripts/activate", "Scripts/python.exe", "conda-meta/history"} {
+			if exists(m) {
+				found = append(found, m)
+			}
+		}
+		if _, ok := getSitePackagesUsage(r.Path); ok {
+			found = append(found, "site-packages")
+		}
+	case "tox":
+		if logs, _ := filepath.Glob(filepath.Join(r.Path, "log", "*.log")); len(logs) > 0 {
+			found = append(found, "log/*.log")
+		}
+		if exists("pyvenv.cfg") {
+			found = append(found, "pyvenv.cfg")
+		}
+	case "node_modules":
+		if exists(".package-lock.json") {
+			found = append(found, ".package-lock.json")
+		}
+		for _, name := range []string{"package-lock.json", "yarn.lock", "pnpm-lock.yaml", "bun.lockb"} {
+			if exists(filepath.Join("..", name)) {
+				found = append(found, name)
+			}
+		}
+	case "latex":
+		found = append(found, "file mtime", "source .tex")
+	case "stale_repo":
+		for _, m := range []string{".git/logs/HEAD", ".git/FETCH_HEAD"} {
+			if exists(m) {
+				found = append(found, m)
+			}
+		}
+		found = append(found, "working tree mtimes")
+	case "cargo":
+		for _, profile := range []string{"debug", "release"} {
+			if exists(profile) {
+				found = append(found, profile+"/ file mtimes")
+			}
+		}
+	default:
+		if r.Size > 0 {
+			found = append(found, "file mtimes")
+		}
+	}
+	return found
+}
+
+// lastCommit returns when the last commit touching dir in repo was made, or
+// false without git or such commits. Limiting it to dir keeps a dotfiles
+// repository at $HOME from making every project under it look active.
+func lastCommit(repo, dir string) (time.Time, bool) {
+	out, err := exec.Command("git", "-C", repo, "log", "-1", "--format=%ct", "--", dir).Output()
+	if err != nil {
+		return time.Time{}, false
+	}
+	sec, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(sec, 0), true
+}
+
+// procRef is a running process and the paths it is tied to: its working
+// directory, executable, command-line arguments, and $VIRTUAL_ENV.
+type procRef struct {
+	pid   int
+	name  string
+	paths []string
+}
+
+// runningProcesses lists processes from /proc. Elsewhere it returns nil:
+// there is no cheap equivalent, and the check is a hint, not a guard.
+func runningProcesses() []procRef {
+	if runtime.GOOS != "linux" {
+		return nil
+	}
+	entries, _ := os.ReadDir("/proc")
+	var procs []procRef
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil || pid == os.Getpid() {
+			continue
+		}
+		dir := filepath.Join("/proc", e.Name())
+		p := procRef{pid: pid}
+		if comm, err := os.ReadFile(filepath.Join(dir, "comm")); err == nil {
+			p.name = strings.TrimSpace(string(comm))
+		}
+		for _, link := range []string{"cwd", "exe"} {
+			if target, err := os.Readlink(filepath.Join(dir, link)); err == nil {
+				p.paths = append(p.paths, target)
+			}
+		}
+		if cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline")); err == nil {
+			for _, arg := range strings.Split(string(cmdline), "\x00") {
+				if filepath.IsAbs(arg) {
+					p.paths = append(p.paths, arg)
+				}
+			}
+		}
+		if environ, err := os.ReadFile(filepath.Join(dir, "environ")); err == nil {
+			for _, kv := range strings.Split(string(environ), "\x00") {
+				if v, ok := strings.CutPrefix(kv, "VIRTUAL_ENV="); ok {
+					p.paths = append(p.paths, v)
+				}
+			}
+		}
+		procs = append(procs, p)
+	}
+	return procs
+}
+
+// processUsing returns a process tied to path or anything inside it.
+func processUsing(path string, procs []procRef) (procRef, bool) {
+	for _, p := range procs {
+		for _, q := range p.paths {
+			if q == path || strings.HasPrefix(q, path+string(filepath.Separator)) {
+				return p, true
+			}
+		}
+	}
+	return procRef{}, false
+}
+
+// evidence caches per-scan inputs to scoring that many records share: the
+// process list (read once) and each project's last commit.
+type evidence struct {
+	once    sync.Once
+	procs   []procRef
+	mu      sync.Mutex
+	commits map[string]*time.Time // by project dir; nil entry: no commits
+	owners  map[string]gitOwner   // by repository root
+}
+
+func (ev *evidence) processes() []procRef {
+	ev.once.Do(func() { ev.procs = runningProcesses() })
+	return ev.procs
+}
+
+// projectCommit returns when the project containing path (its parent
+// directory) was last committed to, or false outside a repository or when
+// git has no commits for it.
+func (ev *evidence) projectCommit(path string) (time.Time, bool) {
+	project := filepath.Dir(path)
+	repo := gitRepoRoot(project)
+	if repo == "" {
+		return time.Time{}, false
+	}
+	ev.mu.Lock()
+	defer ev.mu.Unlock()
+	if ev.commits == nil {
+		ev.commits = map[string]*time.Time{}
+	}
+	if t, seen := ev.commits[project]; seen {
+		if t == nil {
+			return time.Time{}, false
+		}
+		return *t, true
+	}
+	t, ok := lastCommit(repo, project)
+	if !ok {
+		ev.commits[project] = nil
+		return time.Time{}, false
+	}
+	ev.commits[project] = &t
+	return t, true
+}
+
+// repoOwner returns the owner and upstream of the repository containing
+// path, or a zero gitOwner outside one.
+func (ev *evidence) repoOwner(path string) gitOwner {
+	repo := gitRepoRoot(path)
+	if repo == "" {
+		return gitOwner{}
+	}
+	ev.mu.Lock()
+	defer ev.mu.Unlock()
+	if o, seen := ev.owners[repo]; seen {
+		return o
+	}
+	if ev.owners == nil {
+		ev.owners = map[string]gitOwner{}
+	}
+	o := readGitOwner(repo)
+	ev.owners[repo] = o
+	return o
+}
+
+// scoreConfidence rates how well the evidence supports calling r stale, from
+// 0 to 1, and explains the score in a note. Agreeing usage markers raise it;
+// a repository with recent commits or a recently rewritten lockfile lowers
+// it; a running process tied to the item zeroes it. Low scores are held for review.
+func scoreConfidence(r *Record, opts *options, ev *evidence) {
+	markers := usageMarkers(r)
+	score := 0.4 + 0.15*float64(min(len(markers), 3))
+	reasons := []string{fmt.Sprintf("%d usage markers agree", len(markers))}
+	if len(markers) == 1 {
+		reasons = []string{"1 usage marker"}
+	}
+	if len(markers) == 0 {
+		score = 0.2
+		reasons = []string{"only the directory mtime"}
+	}
+
+	if commit, ok := ev.projectCommit(r.Path); ok {
+		if days := opts.ageDays(commit); days < float64(opts.minAge) {
+			score -= 0.3
+			reasons = append(reasons, fmt.Sprintf("project committed to %.0f days ago", days))
+		} else {
+			score += 0.1
+			reasons = append(reasons, "no recent commits")
+		}
+	}
+
+	if lock, written, ok := lockfileActivity(projectDir(r.Path)); ok {
+		if days := opts.ageDays(written); days < float64(opts.minAge) {
+			score -= 0.2
+			reasons = append(reasons, fmt.Sprintf("%s updated %.0f days ago", lock, days))
+		}
+	}
+
+	if p, ok := processUsing(r.Path, ev.processes()); ok {
+		score = 0
+		reasons = append(reasons, fmt.Sprintf("in use by process %d (%s)", p.pid, p.name))
+	}
+
+	r.Confidence = math.Round(math.Max(0, math.Min(1, score))*100) / 100
+	r.Notes = append(r.Notes, fmt.Sprintf("confidence %.2f: %s", r.Confidence, strings.Join(reasons, "; ")))
+	if r.Confidence < lowConfidence && r.Review == "" {
+		r.Review = lowConfidencePrefix + strings.Join(reasons, "; ")
+	}
+}
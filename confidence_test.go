@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScoreConfidence(t *testing.T) {
+	root := t.TempDir()
+	old := time.Now().AddDate(0, 0, -90)
+	venv := filepath.Join(root, "proj", ".venv")
+	makeVenv(t, venv, old)
+	bare := filepath.Join(root, "web", "node_modules")
+	os.MkdirAll(bare, 0755)
+	opts := &options{minAge: 30}
+
+	strong := Record{Type: "venv", Path: venv}
+	scoreConfidence(&strong, opts, &evidence{})
+	if strong.Confidence < 0.7 || strong.Review != "" {
+		t.Errorf("venv with three markers: confidence %.2f, review %q", strong.Confidence, strong.Review)
+	}
+
+	weak := Record{Type: "node_modules", Path: bare}
+	scoreConfidence(&weak, opts, &evidence{})
+	if weak.Confidence >= lowConfidence || !isLowConfidence(weak) {
+		t.Errorf("node_modules without lockfiles: confidence %.2f, review %q", weak.Confidence, weak.Review)
+	}
+
+	// A running process tied to the item outweighs any marker.
+	ev := &evidence{}
+	ev.once.Do(func() {
+		ev.procs = []procRef{{pid: 42, name: "python", paths: []string{filepath.Join(venv, "bin", "python")}}}
+	})
+	busy := Record{Type: "venv", Path: venv}
+	scoreConfidence(&busy, opts, ev)
+	if busy.Confidence != 0 || !strings.Contains(busy.Review, "process 42 (python)") {
+		t.Errorf("in use: confidence %.2f, review %q", busy.Confidence, busy.Review)
+	}
+}
+
+func TestScoreConfidence_RecentCommitsLowerIt(t *testing.T) {
+	repo := filepath.Join(t.TempDir(), "proj")
+	initRepo(t, repo)
+	venv := filepath.Join(repo, ".venv")
+	makeVenv(t, venv, time.Now().AddDate(0, 0, -90))
+	opts := &options{minAge: 30}
+
+	r := Record{Type: "venv", Path: venv}
+	scoreConfidence(&r, opts, &evidence{})
+	if r.Confidence > 0.6 || !strings.Contains(strings.Join(r.Notes, ";"), "committed to 0 days ago") {
+		t.Errorf("active project: confidence %.2f, notes %v", r.Confidence, r.Notes)
+	}
+
+	// The same commit, seen 60 days later, agrees with the stale venv.
+	opts.now = func() time.Time { return time.Now().AddDate(0, 0, 60) }
+	r = Record{Type: "venv", Path: venv}
+	scoreConfidence(&r, opts, &evidence{})
+	if r.Confidence < 0.9 {
+		t.Errorf("dormant project: confidence %.2f, notes %v", r.Confidence, r.Notes)
+	}
+}
+
+func TestPrintText_LowConfidenceSection(t *testing.T) {
+	records := []Record{
+		{Type: "venv", Path: "/p/.venv", Size: 10, Confidence: 0.85},
+		{Type: "node_modules", Path: "/q/node_modules", Size: 20, Confidence: 0.2, Review: lowConfidencePrefix + "only the directory mtime"},
+	}
+	var buf bytes.Buffer
+	printText(&buf, records, 30, &options{})
+	out := buf.String()
+	section := strings.Index(out, "Low confidence")
+	if section < 0 || strings.Index(out, "/p/.venv") > section || strings.Index(out, "/q/node_modules") < section {
+		t.Errorf("low-confidence record not listed separately:\n%s", out)
+	}
+	if strings.Contains(out, "review required: low confidence") {
+		t.Errorf("low-confidence reason printed twice:\n%s", out)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// console serializes everything tidyup writes to stderr besides usage text:
+// warnings, errors, -verbose progress, and the "\r" status counter. Sizing
+// goroutines report concurrently, so every line goes out whole under one
+// lock, and a pending status line is cleared before a regular line replaces
+// it. With -timestamps each line starts with the time of day. With -json,
+// warnings are collected for the output's warnings array instead.
+type console struct {
+	mu         sync.Mutex
+	w          io.Writer
+	timestamps bool
+	statusLen  int       // width of the status line on screen; 0 if none
+	collecting bool      // warnings go to warnings, not w
+	warnings   []Warning // collected while collecting
+}
+
+// Warning is one warning in -json output: what kind it is (active_venv,
+// protected_path, tidyup_state, mount_point, invalid_venv, scan_error, or
+// general), the path concerned if any, and the text stderr would show.
+type Warning struct {
+	Kind    string `json:"kind"`
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+// stderr is the process's console.
+var stderr = &console{w: os.Stderr}
+
+// clearStatus blanks a status line in place. Callers hold mu.
+func (c *console) clearStatus() {
+	if c.statusLen > 0 {
+		fmt.Fprintf(c.w, "\r%s\r", strings.Repeat(" ", c.statusLen))
+		c.statusLen = 0
+	}
+}
+
+func (c *console) stamp() string {
+	if !c.timestamps {
+		return ""
+	}
+	return time.Now().Format("15:04:05.000") + " "
+}
+
+// line writes one complete line: [time ]prefix+message.
+func (c *console) line(prefix, format string, args ...any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clearStatus()
+	fmt.Fprintf(c.w, "%s%s%s\n", c.stamp(), prefix, fmt.Sprintf(format, args...))
+}
+
+// warnf writes "Warning: ..." lines.
+func (c *console) warnf(format string, args ...any) { c.warn("general", "", format, args...) }
+
+// warn writes a "Warning: ..." line, or while collecting, adds it to the
+// warnings with its kind and path.
+func (c *console) warn(kind, path, format string, args ...any) {
+	c.mu.Lock()
+	if c.collecting {
+		c.warnings = append(c.warnings, Warning{Kind: kind, Path: path, Message: fmt.Sprintf(format, args...)})
+		c.mu.Unlock()
+		return
+	}
+	c.mu.Unlock()
+	c.line("Warning: ", format, args...)
+}
+
+// note adds a warning only while collecting: things text output shows only
+// with -verbose, such as skipped invalid venvs, but automation may want.
+func (c *console) note(kind, path, format string, args ...any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.collecting {
+		c.warnings = append(c.warnings, Warning{Kind: kind, Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+}
+
+// collect starts collecting warnings instead of writing them.
+func (c *console) collect() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.collecting = true
+}
+
+// takeWarnings returns the collected warnings, never nil, and goes back to
+// writing them.
+func (c *console) takeWarnings() []Warning {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w := c.warnings
+	if w == nil {
+		w = []Warning{}
+	}
+	c.warnings, c.collecting = nil, false
+	return w
+}
+
+// flushWarnings writes out warnings collected for output that never came,
+// as when a -json run fails first.
+func (c *console) flushWarnings() {
+	for _, w := range c.takeWarnings() {
+		c.line("Warning: ", "%s", w.Message)
+	}
+}
+
+// errorf writes "Error: ..." lines.
+func (c *console) errorf(format string, args ...any) { c.line("Error: ", format, args...) }
+
+// verbosef writes indented -verbose progress lines.
+func (c *console) verbosef(format string, args ...any) { c.line("  ", format, args...) }
+
+// printf writes a line with its own wording.
+func (c *console) printf(format string, args ...any) { c.line("", format, args...) }
+
+// status replaces the status line, which regular lines clear.
+func (c *console) status(format string, args ...any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	msg := c.stamp() + fmt.Sprintf(format, args...)
+	pad := max(c.statusLen-len(msg), 0)
+	fmt.Fprintf(c.w, "\r%s%s", msg, strings.Repeat(" ", pad))
+	c.statusLen = len(msg)
+}
+
+// endStatus removes the status line once its work is done.
+func (c *console) endStatus() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clearStatus()
+}
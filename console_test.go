@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestConsole_LinesStayWholeAndClearStatus(t *testing.T) {
+	var buf bytes.Buffer
+	c := &console{w: &buf}
+
+	c.status("  found %d stale items so far...", 12)
+	c.warnf("could not read %s", "/x")
+	if !strings.HasPrefix(buf.String(), "\r  found 12 stale items so far...\r") ||
+		!strings.HasSuffix(buf.String(), "\rWarning: could not read /x\n") {
+		t.Errorf("status not cleared before the warning: %q", buf.String())
+	}
+
+	buf.Reset()
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.verbosef("skipping (no markers): %s", "/some/long/path/.venv")
+			c.status("  found %d stale items so far...", 3)
+		}()
+	}
+	wg.Wait()
+	c.endStatus()
+	for _, line := range strings.Split(buf.String(), "\n") {
+		line = line[strings.LastIndexByte(line, '\r')+1:]
+		if line != "" && line != "  skipping (no markers): /some/long/path/.venv" {
+			t.Fatalf("interleaved line %q", line)
+		}
+	}
+}
+
+func TestConsole_Timestamps(t *testing.T) {
+	var buf bytes.Buffer
+	c := &console{w: &buf, timestamps: true}
+	c.errorf("boom")
+	if got := buf.String(); len(got) < 13 || got[2] != ':' || !strings.HasSuffix(got, " Error: boom\n") {
+		t.Errorf("timestamped line = %q", got)
+	}
+}
+
+func TestConsole_CollectsWarnings(t *testing.T) {
+	var buf bytes.Buffer
+	c := &console{w: &buf}
+	c.note("invalid_venv", "/srv/a", "dropped")
+	c.collect()
+	c.warnf("flag ignored")
+	c.warn("protected_path", "/srv/home", "skipping protected path: %s", "/srv/home")
+	c.note("invalid_venv", "/srv/b/.venv", "skipping (invalid venv, no bin/Scripts): %s", "/srv/b/.venv")
+	if buf.Len() != 0 {
+		t.Errorf("collected warnings were written: %q", buf.String())
+	}
+	got := c.takeWarnings()
+	want := []Warning{
+		{Kind: "general", Message: "flag ignored"},
+		{Kind: "protected_path", Path: "/srv/home", Message: "skipping protected path: /srv/home"},
+		{Kind: "invalid_venv", Path: "/srv/b/.venv", Message: "skipping (invalid venv, no bin/Scripts): /srv/b/.venv"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("warnings = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("warning %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	c.warnf("after the output")
+	if buf.String() != "Warning: after the output\n" {
+		t.Errorf("after takeWarnings, got %q", buf.String())
+	}
+	if w := c.takeWarnings(); w == nil || len(w) != 0 {
+		t.Errorf("nothing collected = %#v, want an empty slice", w)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Debugging sessions leave gigabytes behind without anyone noticing: core
+// dumps in the working directory or /cores, crash reports, JVM fatal error
+// logs, and application logs that were never rotated. The crash type
+// reports them as file-level records, found by the walk in project trees
+// and, with -system, in the system's own crash locations.
+
+// crashLogMinSize is how large a plain .log file must be to count as debris.
+const crashLogMinSize = 100 << 20
+
+// buildLogDirs are the directories of a project that hold its build and
+// test output, logs included.
+var buildLogDirs = map[string]bool{"build": true, "target": true, "out": true, "logs": true, "log": true, "tmp": true}
+
+// isProjectLog reports whether a log is a project's output: it sits in the
+// project root (a build marker beside it) or in one of its build
+// directories. Anywhere else, a large log may be the data itself.
+func isProjectLog(path string) bool {
+	dir := filepath.Dir(path)
+	return hasBuildParent(path) || (buildLogDirs[filepath.Base(dir)] && hasBuildParent(dir))
+}
+
+// crashReportSuffixes are macOS crash and diagnostic reports.
+var crashReportSuffixes = []string{".crash", ".ips"}
+
+// Kinds of crash debris, as reported in the record's notes.
+const (
+	crashCore   = "core dump"
+	crashReport = "crash report"
+	crashLog    = "oversized log file"
+)
+
+// crashKind classifies a file found by the walk, or returns "".
+func crashKind(path string, d fs.DirEntry) string {
+	name := filepath.Base(path)
+	switch {
+	case name == "core" || strings.HasPrefix(name, "core."):
+		if isCoreDump(path) {
+			return crashCore
+		}
+		return ""
+	case strings.HasPrefix(name, "hs_err_pid") && strings.HasSuffix(name, ".log"):
+		return crashReport // JVM fatal error log
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, suffix := range crashReportSuffixes {
+		if ext == suffix {
+			return crashReport
+		}
+	}
+	if ext == ".log" && latexSource(path) == "" {
+		if info, err := d.Info(); err == nil && info.Size() >= crashLogMinSize {
+			return crashLog
+		}
+	}
+	return ""
+}
+
+// isCoreDump reports whether path starts with an ELF or Mach-O core file
+// header, so a source file or directory entry named "core" is never taken
+// for one.
+func isCoreDump(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	var hdr [20]byte
+	if _, err := io.ReadFull(f, hdr[:]); err != nil {
+		return false
+	}
+	// ELF: e_type ET_CORE (4) at offset 16, in the byte order at EI_DATA.
+	if bytes.HasPrefix(hdr[:], []byte("\x7fELF")) {
+		var order binary.ByteOrder = binary.LittleEndian
+		if hdr[5] == 2 {
+			order = binary.BigEndian
+		}
+		return order.Uint16(hdr[16:]) == 4
+	}
+	// Mach-O (little-endian, 32 or 64 bit): filetype MH_CORE (4) at offset 12.
+	switch binary.LittleEndian.Uint32(hdr[:]) {
+	case 0xfeedface, 0xfeedfacf:
+		return binary.LittleEndian.Uint32(hdr[12:]) == 4
+	}
+	return false
+}
+
+// findCrashFiles returns core dumps in /cores (macOS) and the user's crash
+// reports in ~/Library/Logs/DiagnosticReports.
+func findCrashFiles(home string) []string {
+	var files []string
+	cores, _ := filepath.Glob("/cores/core.*")
+	for _, p := range cores {
+		if isCoreDump(p) {
+			files = append(files, p)
+		}
+	}
+	reports, _ := os.ReadDir(filepath.Join(home, "Library", "Logs", "DiagnosticReports"))
+	for _, e := range reports {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		p := filepath.Join(home, "Library", "Logs", "DiagnosticReports", e.Name())
+		if crashKind(p, e) == crashReport {
+			files = append(files, p)
+		}
+	}
+	return files
+}
+
+// annotateCrash notes what kind of debris a record is. An oversized log
+// outside a project is reported for review, never deleted.
+func annotateCrash(r *Record) {
+	info, err := os.Lstat(r.Path)
+	if err != nil {
+		return
+	}
+	kind := crashKind(r.Path, fs.FileInfoToDirEntry(info))
+	if kind != "" {
+		r.Notes = append(r.Notes, kind)
+	}
+	if kind == crashLog && !isProjectLog(r.Path) {
+		r.Advisory = true
+		r.Review = "large log outside a project or build directory; it may be data"
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+// elfCore is the start of a little-endian ELF core file header.
+var elfCore = []byte("\x7fELF\x02\x01\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x04\x00\x3e\x00")
+
+func TestScanRoots_CrashDebris(t *testing.T) {
+	root := t.TempDir()
+	old := time.Now().AddDate(0, 0, -90)
+	write := func(name string, data []byte) string {
+		p := filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, data, 0644)
+		return p
+	}
+	write("app/core", elfCore)
+	write("app/core.1234", elfCore)
+	write("app/core.py", []byte("import os\n")) // a module, not a dump
+	write("app/hs_err_pid4242.log", []byte("# A fatal error"))
+	write("app/App-2026-01-01.ips", []byte("{}"))
+	write("app/small.log", []byte("ok"))
+	write("app/package.json", []byte("{}"))
+	big := write("app/server.log", nil)
+	os.Truncate(big, crashLogMinSize) // sparse: large apparent size
+	buildLog := write("app/build/test.log", nil)
+	os.Truncate(buildLog, crashLogMinSize)
+	dataLog := write("data/experiment.log", nil)
+	os.Truncate(dataLog, crashLogMinSize) // not a project's: held for review
+	write("paper/paper.tex", []byte("\\documentclass{article}"))
+	paperLog := write("paper/paper.log", nil)
+	os.Truncate(paperLog, crashLogMinSize) // LaTeX's, not crash debris
+	filepath.Walk(root, func(p string, _ os.FileInfo, _ error) error {
+		os.Chtimes(p, old, old)
+		return nil
+	})
+
+	opts := &options{minAge: 30, maxDepth: 5, scanTypes: map[string]bool{"crash": true}}
+	records, _ := scanRoots([]string{root}, opts)
+	var got []string
+	for _, r := range records {
+		got = append(got, filepath.Base(r.Path))
+		if !r.File || len(r.Notes) == 0 {
+			t.Errorf("%s: file %v, notes %v", r.Path, r.File, r.Notes)
+		}
+		if held := r.Review != "" && r.Advisory; held != (filepath.Base(r.Path) == "experiment.log") {
+			t.Errorf("%s: review %q, advisory %v", r.Path, r.Review, r.Advisory)
+		}
+	}
+	sort.Strings(got)
+	want := []string{"App-2026-01-01.ips", "core", "core.1234", "experiment.log", "hs_err_pid4242.log", "server.log", "test.log"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("record %d = %s, want %s", i, got[i], want[i])
+		}
+	}
+}
+
+func TestIsCoreDump_MachO(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "core.77")
+	// MH_MAGIC_64, cputype, cpusubtype, filetype MH_CORE.
+	os.WriteFile(p, []byte("\xcf\xfa\xed\xfe\x0c\x00\x00\x01\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00"), 0644)
+	if !isCoreDump(p) {
+		t.Error("Mach-O core not recognized")
+	}
+	// MH_EXECUTE is a program, not a dump.
+	os.WriteFile(p, []byte("\xcf\xfa\xed\xfe\x0c\x00\x00\x01\x00\x00\x00\x00\x02\x00\x00\x00\x00\x00\x00\x00"), 0644)
+	if isCoreDump(p) {
+		t.Error("Mach-O executable taken for a core dump")
+	}
+}
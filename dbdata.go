@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Local database data directories are reported, never deleted: unlike a
+// cache, nothing regenerates them, and a tutorial's throwaway Postgres looks
+// exactly like one holding the only copy of someone's data.
+
+// dbDataKind identifies a database server's data directory by its layout and
+// returns a label such as "PostgreSQL 14 data directory", or "".
+func dbDataKind(path string) string {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(path, name))
+		return err == nil
+	}
+	switch {
+	case exists("PG_VERSION") && exists("base"):
+		version, _ := os.ReadFile(filepath.Join(path, "PG_VERSION"))
+		return strings.TrimSpace("PostgreSQL "+strings.TrimSpace(string(version))) + " data directory"
+	case exists("ibdata1") || exists("mysql.ibd"):
+		return "MySQL/MariaDB data directory"
+	case exists("WiredTiger") && exists("storage.bson"):
+		return "MongoDB data directory"
+	case exists("node.lock") && exists("indices"), exists(filepath.Join("nodes", "0", "node.lock")):
+		return "Elasticsearch data directory"
+	}
+	return ""
+}
+
+// dbServerRunning reports whether the data directory's server looks like it
+// is running, going by the lock or pid file it holds while up.
+func dbServerRunning(path string) bool {
+	if _, err := os.Stat(filepath.Join(path, "postmaster.pid")); err == nil {
+		return true
+	}
+	if info, err := os.Stat(filepath.Join(path, "mongod.lock")); err == nil && info.Size() > 0 {
+		return true
+	}
+	pids, _ := filepath.Glob(filepath.Join(path, "*.pid"))
+	return len(pids) > 0
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDBDataKind(t *testing.T) {
+	root := t.TempDir()
+	mk := func(dir string, files ...string) string {
+		p := filepath.Join(root, dir)
+		for _, f := range files {
+			os.MkdirAll(filepath.Dir(filepath.Join(p, f)), 0755)
+			os.WriteFile(filepath.Join(p, f), []byte("14\n"), 0644)
+		}
+		return p
+	}
+	tests := map[string]string{
+		mk("pgdata", "PG_VERSION", "base/1/112"):       "PostgreSQL 14 data directory",
+		mk(".mysql_data", "ibdata1", "mysql/user.frm"): "MySQL/MariaDB data directory",
+		mk("mongo", "WiredTiger", "storage.bson"):      "MongoDB data directory",
+		mk("es8", "node.lock", "indices/abc/0/x"):      "Elasticsearch data directory",
+		mk("es7", "nodes/0/node.lock"):                 "Elasticsearch data directory",
+		mk("docs", "PG_VERSION"):                       "",
+		mk("src", "main.go"):                           "",
+	}
+	for path, want := range tests {
+		if got := dbDataKind(path); got != want {
+			t.Errorf("dbDataKind(%s) = %q, want %q", filepath.Base(path), got, want)
+		}
+	}
+}
+
+func TestScanRoots_DBDataIsAdvisory(t *testing.T) {
+	root := t.TempDir()
+	data := filepath.Join(root, "tutorial", "pgdata")
+	old := time.Now().AddDate(0, 0, -200)
+	for _, f := range []string{"PG_VERSION", "base/1/112", "postmaster.pid"} {
+		p := filepath.Join(data, f)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte("15\n"), 0644)
+		os.Chtimes(p, old, old)
+	}
+
+	opts := &options{minAge: 30, maxDepth: 5, scanTypes: map[string]bool{"db_data": true}}
+	records, _ := scanRoots([]string{root}, opts)
+	if len(records) != 1 {
+		t.Fatalf("got %d records, want 1", len(records))
+	}
+	r := records[0]
+	if !r.Advisory || r.Review != "PostgreSQL 15 data directory" || !strings.Contains(strings.Join(r.Notes, "\n"), "running") {
+		t.Errorf("got %+v, want an advisory PostgreSQL record noting the running server", r)
+	}
+	if safe, skipped := filterSafeRecords(records); len(safe) != 0 || skipped[ruleAdvisory] != 1 {
+		t.Errorf("db_data must never be deleted: safe=%v skipped=%v", safe, skipped)
+	}
+}
@@ -2,34 +2,141 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 )
 
-// moveToTrash moves a path to ~/.Trash with collision-safe naming.
-// Appends a timestamp suffix if the basename already exists in Trash.
-func moveToTrash(path string) error {
+// Seams for the integration harness; production code never reassigns them.
+// removersFunc hands newExecutor the removal primitives, and nothing else
+// calls it: code that removes anything goes through an executor.
+var (
+	stdin          io.Reader = os.Stdin
+	removersFunc             = newRemovers
+	trashSupported           = runtime.GOOS == "darwin"
+	useFinderTrash           = runtime.GOOS == "darwin"
+)
+
+// runShellCommand runs a delegated removal command (see Record.Command)
+// through the platform shell, with its output going to the terminal.
+func runShellCommand(command string) error {
+	cmd := exec.Command("sh", "-c", command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	}
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+// copyTree recursively copies src to dst, preserving modes, mtimes, and symlinks.
+// Used when a rename crosses filesystems.
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, p)
+		target := filepath.Join(dst, rel)
+
+		info, err := os.Lstat(p)
+		if err != nil {
+			return err
+		}
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case d.IsDir():
+			if err := os.MkdirAll(target, info.Mode().Perm()|0700); err != nil {
+				return err
+			}
+		default:
+			in, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, info.Mode().Perm())
+			if err != nil {
+				in.Close()
+				return err
+			}
+			_, err = io.Copy(out, in)
+			in.Close()
+			if cerr := out.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return os.Chtimes(target, info.ModTime(), info.ModTime())
+	})
+}
+
+// moveTree renames src to dst, falling back to copy+remove when they live on
+// different filesystems (e.g., a project on an external disk and ~/.Trash).
+func (x *executor) moveTree(src, dst string) error {
+	err := x.rm.rename(src, dst)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	if err := copyTree(src, dst); err != nil {
+		// Leave the source untouched; drop the partial copy.
+		_ = x.rm.removeAll(dst)
+		return fmt.Errorf("cross-device copy: %w", err)
+	}
+	return x.rm.removeAll(src)
+}
+
+// moveToTrash moves a path to the Trash and returns where it ended up. On
+// macOS it asks Finder, which records "Put Back" information and uses the
+// item's own volume's Trash; if Finder refuses (no automation permission,
+// no GUI session) it falls back to a rename into ~/.Trash, appending a
+// timestamp suffix if the basename already exists there.
+func (x *executor) moveToTrash(path string) (string, error) {
+	if useFinderTrash {
+		dest, err := x.rm.finderTrash(path)
+		if err == nil {
+			return dest, nil
+		}
+		finderFallback.Do(func() {
+			stderr.warnf("Finder could not trash items (%v); moving them into ~/.Trash directly, without Put Back.", err)
+		})
+	}
+
 	home := os.Getenv("HOME")
 	if home == "" {
-		return fmt.Errorf("HOME not set")
+		return "", fmt.Errorf("HOME not set")
 	}
 
 	trashDir := filepath.Join(home, ".Trash")
 	base := filepath.Base(path)
 	dest := filepath.Join(trashDir, base)
 
-	// If destination already exists, append a timestamp to avoid collision.
+	// If destination already exists, append a timestamp to avoid collision,
+	// before the extension for files so they still open by type.
 	if _, err := os.Stat(dest); err == nil {
 		stamp := time.Now().Format("20060102-150405")
-		dest = filepath.Join(trashDir, fmt.Sprintf("%s_%s", base, stamp))
+		ext := ""
+		if info, err := os.Lstat(path); err == nil && info.Mode().IsRegular() {
+			ext = filepath.Ext(base)
+		}
+		dest = filepath.Join(trashDir, fmt.Sprintf("%s_%s%s", strings.TrimSuffix(base, ext), stamp, ext))
 	}
 
-	return os.Rename(path, dest)
+	return dest, x.moveTree(path, dest)
 }
 
 // parseSelection parses user input like "1,3,5-8" into a set of 0-based indices.
@@ -94,12 +201,35 @@ func parseSelection(input string, max int) (map[int]bool, error) {
 	return result, nil
 }
 
+// selectOlderVenvs selects every venv that has a more recently used sibling
+// in its project -- "keep the newest, delete the rest".
+func selectOlderVenvs(records []Record) map[int]bool {
+	selected := make(map[int]bool)
+	for i, r := range records {
+		if r.NewestSibling != "" {
+			selected[i] = true
+		}
+	}
+	return selected
+}
+
+// Above pagedThreshold records, selection switches to paged review: a list
+// of hundreds of lines followed by "enter numbers" is unusable.
+const (
+	pagedThreshold = 40
+	pageSize       = 20
+)
+
 // promptSelection shows numbered records and returns the user-selected subset.
 // Returns nil if the user cancels.
 func promptSelection(records []Record, opts *options) []Record {
+	if len(records) > pagedThreshold {
+		return pagedSelection(records, opts)
+	}
 	fmt.Println()
+	_, width := usedColumn(opts)
 	for i, r := range records {
-		fmt.Printf("  %2d. %-12s %-10s %4.0fd ago  %s\n", i+1, "["+r.Type+"]", r.SizeHuman, r.AgeDays, r.Path)
+		fmt.Printf("  %2d. %-12s %-10s %-*s  %s\n", i+1, "["+r.Type+"]", opts.locale.bytes(r.Size), width, usedLabel(r, opts), displayPath(r, opts))
 	}
 	fmt.Println()
 
@@ -108,15 +238,32 @@ func promptSelection(records []Record, opts *options) []Record {
 		action = "move to Trash"
 	}
 
-	reader := bufio.NewReader(os.Stdin)
+	hasOlder := false
+	for _, r := range records {
+		if r.NewestSibling != "" {
+			hasOlder = true
+		}
+	}
+	shortcuts := "'all' or 'none'"
+	if hasOlder {
+		shortcuts = "'all', 'older' (venvs other than each project's newest) or 'none'"
+	}
+
+	reader := bufio.NewReader(stdin)
 	for {
-		fmt.Printf("Select items to %s (e.g., 1,3 or 1-3 or 'all' or 'none'): ", action)
+		fmt.Printf("Select items to %s (e.g., 1,3 or 1-3 or %s): ", action, shortcuts)
 		response, _ := reader.ReadString('\n')
 		response = strings.TrimSpace(response)
 
-		selected, err := parseSelection(response, len(records))
+		var selected map[int]bool
+		var err error
+		if hasOlder && strings.EqualFold(response, "older") {
+			selected = selectOlderVenvs(records)
+		} else {
+			selected, err = parseSelection(response, len(records))
+		}
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Invalid selection: %v. Try again.\n", err)
+			stderr.printf("Invalid selection: %v. Try again.", err)
 			continue
 		}
 
@@ -134,50 +281,216 @@ func promptSelection(records []Record, opts *options) []Record {
 	}
 }
 
-// filterSafeRecords removes records that fail safety checks (active venv, protected paths).
-// Returns the safe subset and prints warnings for filtered-out records.
-func filterSafeRecords(records []Record) []Record {
+// pagedSelection reviews records pageSize at a time, keeping a running
+// selection across pages. Numbers stay global so they mean the same thing
+// on every page and under every filter. Returns nil if the user cancels.
+func pagedSelection(records []Record, opts *options) []Record {
+	_, width := usedColumn(opts)
+	action := "PERMANENTLY DELETE"
+	if opts.useTrash {
+		action = "move to Trash"
+	}
+	selected := map[int]bool{}
+	filter := ""
+	page := 0
+	reader := bufio.NewReader(stdin)
+	for {
+		var view []int
+		for i, r := range records {
+			if filter == "" || strings.Contains(r.Path, filter) || r.Type == filter {
+				view = append(view, i)
+			}
+		}
+		pages := max(1, (len(view)+pageSize-1)/pageSize)
+		page = max(0, min(page, pages-1))
+		shown := view[page*pageSize : min(len(view), (page+1)*pageSize)]
+
+		fmt.Println()
+		if filter != "" {
+			fmt.Printf("Filter %q: %d of %d items\n", filter, len(view), len(records))
+		}
+		for _, i := range shown {
+			mark := " "
+			if selected[i] {
+				mark = "x"
+			}
+			r := records[i]
+			fmt.Printf("  [%s] %3d. %-12s %-10s %-*s  %s\n", mark, i+1, "["+r.Type+"]", opts.locale.bytes(r.Size), width, usedLabel(r, opts), displayPath(r, opts))
+		}
+		var total int64
+		for i := range selected {
+			total += records[i].Size
+		}
+		fmt.Printf("\nPage %d/%d -- %d of %d selected (%s) to %s.\n", page+1, pages, len(selected), len(records), opts.locale.bytes(total), action)
+		fmt.Printf("Numbers/ranges toggle, 'page' or 'all' select shown items, 'none' clears, n/p next/previous, /text filters, 'done' continues, 'q' cancels: ")
+
+		response, err := reader.ReadString('\n')
+		response = strings.TrimSpace(response)
+		if err != nil && response == "" {
+			return nil
+		}
+		switch strings.ToLower(response) {
+		case "", "n":
+			page++
+		case "p":
+			page--
+		case "q", "quit":
+			return nil
+		case "done", "d":
+			if len(selected) == 0 {
+				return nil
+			}
+			var result []Record
+			for i, r := range records {
+				if selected[i] {
+					result = append(result, r)
+				}
+			}
+			return result
+		case "page":
+			for _, i := range shown {
+				selected[i] = true
+			}
+		case "all":
+			for _, i := range view {
+				selected[i] = true
+			}
+		case "none":
+			selected = map[int]bool{}
+		case "older":
+			for i := range selectOlderVenvs(records) {
+				selected[i] = true
+			}
+		default:
+			if strings.HasPrefix(response, "/") {
+				filter, page = strings.TrimPrefix(response, "/"), 0
+				continue
+			}
+			toggled, err := parseSelection(response, len(records))
+			if err != nil {
+				stderr.printf("Invalid selection: %v. Try again.", err)
+				continue
+			}
+			for i := range toggled {
+				if selected[i] {
+					delete(selected, i)
+				} else {
+					selected[i] = true
+				}
+			}
+		}
+	}
+}
+
+// checkDeleteCaps reports whether deleting records would exceed
+// -max-delete-items or -max-delete-bytes.
+func checkDeleteCaps(records []Record, opts *options) error {
+	if opts.maxDeleteItems > 0 && len(records) > opts.maxDeleteItems {
+		return fmt.Errorf("%d items selected, more than -max-delete-items %d", len(records), opts.maxDeleteItems)
+	}
+	if total := totalSize(records); opts.maxDeleteBytes > 0 && total > opts.maxDeleteBytes {
+		return fmt.Errorf("%s selected, more than -max-delete-bytes %s", formatBytes(total), formatBytes(opts.maxDeleteBytes))
+	}
+	return nil
+}
+
+// filterSafeRecords removes records that fail safety checks (active venv or
+// one inside, protected paths, tidyup's own state, mounts, review required,
+// advisory) and counts how many each rule removed.
+func filterSafeRecords(records []Record) ([]Record, map[string]int) {
 	var safe []Record
+	skipped := map[string]int{}
+	mounts, _ := mountTableFunc()
+	own := ownStateDirs()
 	for _, r := range records {
-		if isActiveVenv(r.Path) {
-			fmt.Fprintf(os.Stderr, "Warning: skipping active venv ($VIRTUAL_ENV): %s\n", r.Path)
+		if rule, msg := safetyCheck(r, mounts, own); rule != "" {
+			stderr.warn(warningKind(rule), r.Path, "%s", msg)
+			skipped[rule]++
 			continue
 		}
-		if isProtectedPath(r.Path) {
-			fmt.Fprintf(os.Stderr, "Warning: skipping protected path: %s\n", r.Path)
-			continue
+		safe = append(safe, pinRecord(r))
+	}
+	return safe, skipped
+}
+
+// safetyCheck returns the rule that keeps r from being deleted and the
+// warning explaining it, or "" if r passes every check.
+func safetyCheck(r Record, mounts, own []string) (rule, msg string) {
+	switch {
+	case r.Advisory && r.Command == "":
+		return ruleAdvisory, fmt.Sprintf("skipping advisory item (review manually): %s", r.Path)
+	case r.Advisory:
+		return ruleAdvisory, fmt.Sprintf("skipping advisory item (use %q): %s", r.Command, r.Path)
+	case isActiveVenv(r.Path):
+		return ruleActiveVenv, fmt.Sprintf("skipping active venv ($VIRTUAL_ENV): %s", r.Path)
+	case containsActiveVenv(r.Path):
+		return ruleActiveVenv, fmt.Sprintf("skipping %s: it contains the active venv ($VIRTUAL_ENV)", r.Path)
+	case isProtectedPath(r.Path) && !isTmpEntry(r):
+		return ruleProtectedPath, fmt.Sprintf("skipping protected path: %s", r.Path)
+	}
+	if dir := ownStateConflict(r.Path, own); dir != "" {
+		return ruleOwnState, fmt.Sprintf("skipping %s: tidyup's own state (%s)", r.Path, dir)
+	}
+	if why := mountConflict(r.Path, mounts); why != "" {
+		return ruleMount, fmt.Sprintf("skipping %s: %s; unmount it first", r.Path, why)
+	}
+	if r.Review != "" {
+		return ruleReview, fmt.Sprintf("skipping (review required: %s): %s", r.Review, r.Path)
+	}
+	return "", ""
+}
+
+// warnSafety reports, without filtering, the records -delete would skip.
+// -json output carries these as warnings; advisory and review-required
+// items are left out since their records already say so.
+func warnSafety(records []Record) {
+	mounts, _ := mountTableFunc()
+	own := ownStateDirs()
+	for _, r := range records {
+		if rule, msg := safetyCheck(r, mounts, own); rule != "" && rule != ruleAdvisory && rule != ruleReview {
+			stderr.warn(warningKind(rule), r.Path, "%s", msg)
 		}
-		safe = append(safe, r)
 	}
-	return safe
+}
+
+// warningKind names a safety rule in -json warnings.
+func warningKind(rule string) string {
+	return strings.ReplaceAll(rule, " ", "_")
 }
 
 // deleteRecords handles the interactive or confirmed deletion of records.
 func deleteRecords(records []Record, opts *options) int {
+	code, _ := deleteSelected(records, opts)
+	return code
+}
+
+// deleteSelected is deleteRecords, also returning the records it removed:
+// the executor's own results, for callers that cannot tell from the
+// filesystem (docker items have no path to check).
+func deleteSelected(records []Record, opts *options) (int, []Record) {
 	// Validate --trash on non-macOS.
-	if opts.useTrash && runtime.GOOS != "darwin" {
-		fmt.Fprintf(os.Stderr, "Warning: -trash is only supported on macOS. Using permanent delete.\n")
+	if opts.useTrash && !trashSupported {
+		stderr.warnf("-trash is only supported on macOS. Using permanent delete.")
 		opts.useTrash = false
 	}
 
 	// Safety filtering before any user interaction.
-	records = filterSafeRecords(records)
+	records, skipped := filterSafeRecords(records)
 	if len(records) == 0 {
 		fmt.Println("No safe records to delete after safety checks.")
-		return exitOK
+		return exitOK, nil
 	}
+	printSafetySummary(os.Stdout, summarizeSafety(records, skipped), opts)
 
-	// Open log file if requested.
-	var logWriter *os.File
-	if opts.logFile != "" {
+	// Open log file if requested; plans and proposals delete nothing to log.
+	var logWriter *deletionLog
+	if opts.planFile == "" && !opts.propose {
 		var err error
-		logWriter, err = os.OpenFile(opts.logFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error opening log file: %v\n", err)
-			return exitError
+		if logWriter, err = openDeletionLog(opts, time.Now()); err != nil {
+			stderr.printf("Error opening log file: %v", err)
+			return exitError, nil
 		}
 		defer logWriter.Close()
-		fmt.Fprintf(logWriter, "# tidyup deletion log -- %s\n", time.Now().Format(time.RFC3339))
 	}
 
 	// Interactive selection unless --confirm is set.
@@ -185,39 +498,203 @@ func deleteRecords(records []Record, opts *options) int {
 		selected := promptSelection(records, opts)
 		if selected == nil || len(selected) == 0 {
 			fmt.Println("Cleanup cancelled.")
-			return exitFound
+			return exitFound, nil
 		}
+		// Leaving items out of a partial selection is an explicit keep.
+		chosen := map[string]bool{}
+		for _, r := range selected {
+			chosen[r.Path] = true
+		}
+		var kept []Record
+		for _, r := range records {
+			if !chosen[r.Path] {
+				kept = append(kept, r)
+			}
+		}
+		recordDecisions(decisionKept, kept, time.Now())
 		records = selected
 	}
 
+	// Hard caps apply to -confirm runs too: better to stop than let a
+	// misconfigured filter select half the home directory.
+	if err := checkDeleteCaps(records, opts); err != nil {
+		stderr.errorf("%v; nothing was deleted. Raise the cap to override.", err)
+		return exitError, nil
+	}
+
+	if opts.planFile != "" {
+		if err := writePlan(records, opts, time.Now()); err != nil {
+			stderr.errorf("writing plan: %v", err)
+			return exitError, nil
+		}
+		fmt.Printf("\nPlan with %d items (%s) written to %s. Nothing was deleted.\n", len(records), formatBytes(totalSize(records)), opts.planFile)
+		fmt.Printf("Run 'tidyup apply %s' to execute it.\n", opts.planFile)
+		return exitFound, nil
+	}
+	if opts.propose {
+		return proposeRecords(records, opts), nil
+	}
+
+	x, err := newExecutor(opts, logWriter)
+	if err == nil {
+		err = x.approve(records)
+	}
+	if err != nil {
+		stderr.errorf("%v; nothing was deleted.", err)
+		return exitError, nil
+	}
+	defer x.close()
+
+	// Work through one filesystem at a time, so a slow external disk is
+	// not interleaved with the internal one.
+	volumes := groupByFilesystem(records)
+	freed := map[string]*volumeFreed{}
+	for _, v := range volumes {
+		freed[v.mount] = &volumeFreed{}
+	}
+
 	var deletedCount int
+	var removed []Record
+	defer func() { recordDecisions(decisionDeleted, removed, time.Now()) }()
+	for _, v := range volumes {
+		if len(volumes) > 1 {
+			fmt.Printf("\n%s (%d items):\n", volumeLabel(v.mount), len(v.records))
+		}
+		for _, r := range v.records {
+			if x.remove(r) {
+				deletedCount++
+				removed = append(removed, r)
+				freed[v.mount].add(r)
+			}
+		}
+	}
+	fmt.Printf("\nCleanup complete. Removed %d items.\n", deletedCount)
+	if len(volumes) > 1 {
+		printFreedByVolume(os.Stdout, volumes, freed, opts)
+	}
+	if opts.useTrash && deletedCount > 0 {
+		printTrashNote(os.Stdout, opts)
+	}
+	return exitFound, removed
+}
+
+// checkKind refuses a file record whose path is no longer a plain file: a
+// directory (or a symlink to one) created in its place since the scan was
+// never reviewed, and removing a file must never turn recursive. A path
+// that is gone is left to the removal, which treats it as done.
+func checkKind(r Record) error {
+	if !r.File {
+		return nil
+	}
+	info, err := os.Lstat(r.Path)
+	if err != nil || info.Mode().IsRegular() {
+		return nil
+	}
+	what := "a directory"
+	if info.Mode()&os.ModeSymlink != 0 {
+		what = "a symlink"
+	} else if !info.IsDir() {
+		what = "a special file"
+	}
+	return fmt.Errorf("was a file when scanned but is now %s", what)
+}
+
+// volumeGroup is the records of one deletion run that live on one
+// filesystem.
+type volumeGroup struct {
+	mount   string // mount point, or "" when unknown
+	records []Record
+}
+
+// groupByFilesystem splits records by filesystem, volumes in order of first
+// appearance and records in their original order within each.
+func groupByFilesystem(records []Record) []volumeGroup {
+	var groups []volumeGroup
+	index := map[string]int{}
 	for _, r := range records {
-		var err error
-		if opts.useTrash {
-			err = moveToTrash(r.Path)
-		} else {
-			err = os.RemoveAll(r.Path)
+		m := mountPoint(r.Path)
+		if isDockerPath(r.Path) {
+			m = dockerVolumeLabel
+		}
+		i, ok := index[m]
+		if !ok {
+			i = len(groups)
+			index[m] = i
+			groups = append(groups, volumeGroup{mount: m})
 		}
+		groups[i].records = append(groups[i].records, r)
+	}
+	return groups
+}
 
-		if err == nil {
-			action := "Deleted"
-			if opts.useTrash {
-				action = "Trashed"
-			}
-			fmt.Printf("%s: %s\n", action, r.Path)
-			deletedCount++
-			if logWriter != nil {
-				fmt.Fprintf(logWriter, "%s %s %s %s\n",
-					time.Now().Format(time.RFC3339), action, formatBytes(r.Size), r.Path)
-			}
-		} else {
-			fmt.Fprintf(os.Stderr, "Error removing %s: %v\n", r.Path, err)
-			if logWriter != nil {
-				fmt.Fprintf(logWriter, "%s ERROR %s: %v\n",
-					time.Now().Format(time.RFC3339), r.Path, err)
+// volumeLabel names a mount point for output.
+func volumeLabel(mount string) string {
+	if mount == "" {
+		return "unknown filesystem"
+	}
+	return mount
+}
+
+// volumeFreed tallies what a deletion run removed from one filesystem.
+type volumeFreed struct {
+	items int
+	bytes int64 // allocated size: what df will show coming back
+}
+
+func (v *volumeFreed) add(r Record) {
+	v.items++
+	if r.DiskSize > 0 {
+		v.bytes += r.DiskSize
+	} else {
+		v.bytes += r.Size
+	}
+}
+
+// volumeSpaceFunc reports a filesystem's size and free space; a seam for
+// tests.
+var volumeSpaceFunc = volumeSpace
+
+// printVolumePreview reports, before anything is deleted, what deleting
+// records would free on each filesystem and how that moves its free space.
+// Advisory items are left out: tidyup never deletes them.
+func printVolumePreview(w io.Writer, records []Record, opts *options) {
+	var deletable []Record
+	for _, r := range records {
+		if !r.Advisory {
+			deletable = append(deletable, r)
+		}
+	}
+	if len(deletable) == 0 {
+		return
+	}
+	loc := opts.locale
+	fmt.Fprintf(w, "Would free by volume:\n")
+	for _, v := range groupByFilesystem(deletable) {
+		f := &volumeFreed{}
+		for _, r := range v.records {
+			f.add(r)
+		}
+		line := fmt.Sprintf("  %-30s %s items, %s", volumeLabel(v.mount), loc.integer(int64(f.items)), loc.bytes(f.bytes))
+		if total, free, ok := volumeSpaceFunc(v.mount); v.mount != "" && ok && total > 0 {
+			after := free + uint64(f.bytes)
+			if after > total {
+				after = total
 			}
+			line += fmt.Sprintf("; free %s -> %s", loc.percent(100*float64(free)/float64(total)), loc.percent(100*float64(after)/float64(total)))
 		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+// printFreedByVolume reports per-filesystem totals after a deletion run.
+func printFreedByVolume(w io.Writer, volumes []volumeGroup, freed map[string]*volumeFreed, opts *options) {
+	verb := "Freed"
+	if opts.useTrash {
+		verb = "Moved to Trash"
+	}
+	fmt.Fprintf(w, "%s by volume:\n", verb)
+	for _, v := range volumes {
+		f := freed[v.mount]
+		fmt.Fprintf(w, "  %-30s %s items, %s\n", volumeLabel(v.mount), opts.locale.integer(int64(f.items)), opts.locale.bytes(f.bytes))
 	}
-	fmt.Printf("\nCleanup complete. Removed %d items.\n", deletedCount)
-	return exitFound
 }
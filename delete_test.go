@@ -1,6 +1,11 @@
 package main
 
 import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -153,3 +158,141 @@ func TestParseSelection_WhitespaceHandling(t *testing.T) {
 		}
 	}
 }
+
+func TestSelectOlderVenvs(t *testing.T) {
+	records := []Record{
+		{Type: "venv", Path: "/p/venv", NewestSibling: "/p/.venv"},
+		{Type: "node_modules", Path: "/p/node_modules"},
+		{Type: "venv", Path: "/q/.venv"},
+		{Type: "venv", Path: "/p/.tox/py311", NewestSibling: "/p/.venv"},
+	}
+	got := selectOlderVenvs(records)
+	if len(got) != 2 || !got[0] || !got[3] {
+		t.Errorf("selectOlderVenvs = %v, want {0, 3}", got)
+	}
+}
+
+func TestPromptSelection_PagedForLargeSets(t *testing.T) {
+	origStdin := stdin
+	t.Cleanup(func() { stdin = origStdin })
+
+	var records []Record
+	for i := 1; i <= 45; i++ {
+		typ := "pycache"
+		if i%10 == 0 {
+			typ = "venv"
+		}
+		records = append(records, Record{Type: typ, Path: fmt.Sprintf("/p/item%02d", i)})
+	}
+	// Toggle 2 and 44 (44 is on page 3), untoggle 2, page forward and back,
+	// select every venv via a filter, clear the filter, finish.
+	stdin = strings.NewReader("2,44\n2\nn\np\n/venv\nall\n/\ndone\n")
+	got := promptSelection(records, &options{})
+	var paths []string
+	for _, r := range got {
+		paths = append(paths, r.Path)
+	}
+	want := []string{"/p/item10", "/p/item20", "/p/item30", "/p/item40", "/p/item44"}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Errorf("selected %v, want %v", paths, want)
+	}
+
+	// End of input cancels instead of paging forever.
+	stdin = strings.NewReader("1\n")
+	if got := promptSelection(records, &options{}); got != nil {
+		t.Errorf("EOF: got %d records, want cancel", len(got))
+	}
+}
+
+func TestCheckDeleteCaps(t *testing.T) {
+	records := []Record{{Path: "/a", Size: 600 << 20}, {Path: "/b", Size: 600 << 20}}
+	if err := checkDeleteCaps(records, &options{}); err != nil {
+		t.Errorf("no caps: %v", err)
+	}
+	if err := checkDeleteCaps(records, &options{maxDeleteItems: 1}); err == nil {
+		t.Error("2 items passed -max-delete-items 1")
+	}
+	if err := checkDeleteCaps(records, &options{maxDeleteBytes: 1 << 30}); err == nil {
+		t.Error("1.2 GB passed -max-delete-bytes 1G")
+	}
+	if err := checkDeleteCaps(records, &options{maxDeleteItems: 2, maxDeleteBytes: 2 << 30}); err != nil {
+		t.Errorf("within caps: %v", err)
+	}
+}
+
+func TestGroupByFilesystem(t *testing.T) {
+	tmp := t.TempDir()
+	other := "/proc/self"
+	if mountPoint(other) == "" || mountPoint(other) == mountPoint(tmp) {
+		t.Skip("no second filesystem to group by")
+	}
+	a, b := filepath.Join(tmp, "a"), filepath.Join(tmp, "b")
+	os.Mkdir(a, 0755)
+	os.Mkdir(b, 0755)
+
+	groups := groupByFilesystem([]Record{{Path: a}, {Path: other}, {Path: b}})
+	if len(groups) != 2 {
+		t.Fatalf("got %d groups, want 2", len(groups))
+	}
+	if len(groups[0].records) != 2 || groups[0].records[0].Path != a || groups[0].records[1].Path != b {
+		t.Errorf("first volume = %v, want [a b] in order", groups[0].records)
+	}
+	if groups[1].records[0].Path != other {
+		t.Errorf("second volume = %v, want %s", groups[1].records, other)
+	}
+}
+
+func TestPrintVolumePreview(t *testing.T) {
+	tmp := t.TempDir()
+	mount := mountPoint(tmp)
+	if mount == "" {
+		t.Skip("no device numbers to find the volume by")
+	}
+	orig := volumeSpaceFunc
+	defer func() { volumeSpaceFunc = orig }()
+	volumeSpaceFunc = func(string) (uint64, uint64, bool) { return 1000, 250, true }
+
+	for _, name := range []string{"a", "b", "c"} {
+		os.Mkdir(filepath.Join(tmp, name), 0755)
+	}
+	records := []Record{
+		{Path: filepath.Join(tmp, "a"), Size: 100},
+		{Path: filepath.Join(tmp, "b"), Size: 80, DiskSize: 150},
+		{Path: filepath.Join(tmp, "c"), Size: 500, Advisory: true},
+	}
+	var buf strings.Builder
+	printVolumePreview(&buf, records, &options{})
+	want := fmt.Sprintf("  %-30s 2 items, 250 B; free 25.0%% -> 50.0%%\n", mount)
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("preview = %q, want a line %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	printVolumePreview(&buf, records, &options{locale: knownLocales["de"]})
+	if !strings.Contains(buf.String(), "free 25,0% -> 50,0%") {
+		t.Errorf("de preview = %q, want comma decimals", buf.String())
+	}
+}
+
+func TestWarnSafety_JSONWarnings(t *testing.T) {
+	saved, savedMounts := stderr, mountTableFunc
+	t.Cleanup(func() { stderr, mountTableFunc = saved, savedMounts })
+	stderr = &console{w: io.Discard}
+	mountTableFunc = func() ([]string, bool) { return nil, true }
+	t.Setenv("HOME", "/srv/home")
+	t.Setenv("VIRTUAL_ENV", "/srv/dev/web/.venv")
+
+	stderr.collect()
+	warnSafety([]Record{
+		{Type: "venv", Path: "/srv/dev/web/.venv"},
+		{Type: "venv", Path: "/srv/home"},
+		{Type: "venv", Path: "/srv/dev/api/.venv", Review: "in a git worktree"},
+		{Type: "tools", Path: "/srv/home/.local/pipx/venvs/black", Advisory: true},
+		{Type: "venv", Path: "/srv/dev/old/.venv"},
+	})
+	got := stderr.takeWarnings()
+	if len(got) != 2 || got[0].Kind != "active_venv" || got[0].Path != "/srv/dev/web/.venv" ||
+		got[1].Kind != "protected_path" || got[1].Path != "/srv/home" {
+		t.Errorf("warnings = %+v, want the active venv and the protected path only", got)
+	}
+}
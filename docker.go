@@ -0,0 +1,340 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Docker keeps what it reclaims behind its daemon, not in directories a
+// walk can size: dangling images (untagged layers no container uses),
+// stopped containers, and volumes no container mounts. -type docker asks
+// the daemon over its API socket for all three (GET /system/df, the data
+// behind `docker system df`) and reports each as an item with a
+// docker:// path: docker://image/<id>, docker://container/<id>,
+// docker://volume/<name>. Deleting one asks the daemon to remove it; with
+// -delegate, the docker CLI's own command runs instead. Volumes are only
+// reported, like db_data: they often hold a database's data, which nothing
+// regenerates. The socket is
+// $DOCKER_HOST when that is a unix:// address, else /var/run/docker.sock,
+// else Docker Desktop's ~/.docker/run/docker.sock; tcp:// hosts and
+// Windows named pipes are not supported.
+
+// dockerScheme prefixes the paths of docker items.
+const dockerScheme = "docker://"
+
+// dockerVolumeLabel groups docker items in per-volume totals: the daemon
+// decides where their space lives.
+const dockerVolumeLabel = "Docker daemon"
+
+// isDockerPath reports whether path names a docker item rather than a file.
+func isDockerPath(path string) bool {
+	return strings.HasPrefix(path, dockerScheme)
+}
+
+// dockerSocket returns the daemon's unix socket.
+func dockerSocket(home string) (string, error) {
+	if host := os.Getenv("DOCKER_HOST"); host != "" {
+		if sock, ok := strings.CutPrefix(host, "unix://"); ok {
+			return sock, nil
+		}
+		return "", fmt.Errorf("DOCKER_HOST %s: only unix:// sockets are supported", host)
+	}
+	for _, sock := range []string{"/var/run/docker.sock", filepath.Join(home, ".docker", "run", "docker.sock")} {
+		if info, err := os.Stat(sock); err == nil && info.Mode()&os.ModeSocket != 0 {
+			return sock, nil
+		}
+	}
+	return "", fmt.Errorf("no daemon socket found (set DOCKER_HOST to a unix:// address)")
+}
+
+// dockerSocketFunc locates the daemon; a seam for tests.
+var dockerSocketFunc = dockerSocket
+
+// dockerClient talks to the daemon's HTTP API over its socket.
+type dockerClient struct {
+	http *http.Client
+}
+
+func newDockerClient(sock string) *dockerClient {
+	return &dockerClient{http: &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", sock)
+			},
+		},
+	}}
+}
+
+// do sends one request and decodes a JSON reply into out, if given. A
+// status of 400 or over is an error carrying the daemon's message.
+func (c *dockerClient) do(method, path string, out interface{}) (int, error) {
+	req, err := http.NewRequest(method, "http://docker"+path, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		var msg struct {
+			Message string `json:"message"`
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if json.Unmarshal(body, &msg) != nil || msg.Message == "" {
+			msg.Message = resp.Status
+		}
+		return resp.StatusCode, fmt.Errorf("%s", msg.Message)
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.StatusCode, err
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// dockerDF is the part of GET /system/df tidyup reads.
+type dockerDF struct {
+	Images []struct {
+		ID         string   `json:"Id"`
+		RepoTags   []string `json:"RepoTags"`
+		Created    int64    `json:"Created"`
+		Size       int64    `json:"Size"`
+		SharedSize int64    `json:"SharedSize"` // -1 when not computed
+		Containers int64    `json:"Containers"`
+	} `json:"Images"`
+	Containers []struct {
+		ID      string   `json:"Id"`
+		Names   []string `json:"Names"`
+		Image   string   `json:"Image"`
+		State   string   `json:"State"`
+		Created int64    `json:"Created"`
+		SizeRw  int64    `json:"SizeRw"`
+	} `json:"Containers"`
+	Volumes []struct {
+		Name      string `json:"Name"`
+		CreatedAt string `json:"CreatedAt"`
+		UsageData *struct {
+			Size     int64 `json:"Size"`
+			RefCount int64 `json:"RefCount"`
+		} `json:"UsageData"`
+	} `json:"Volumes"`
+}
+
+// dockerItem is one reclaimable object before it becomes a Record.
+type dockerItem struct {
+	kind     string // image, container, volume
+	id       string // short ID, or the volume's name
+	size     int64
+	lastUsed time.Time
+	tool     string
+	command  string
+	note     string
+	dated    float64 // confidence in lastUsed
+	advisory bool    // shown, never removed by tidyup
+}
+
+// shortDockerID trims an ID to the 12 characters docker prints.
+func shortDockerID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// dangling reports whether an image has no tag left.
+func dangling(tags []string) bool {
+	for _, t := range tags {
+		if t != "<none>:<none>" {
+			return false
+		}
+	}
+	return true
+}
+
+// dockerItems lists the daemon's dangling images, stopped containers, and
+// unused volumes.
+func (c *dockerClient) dockerItems() ([]dockerItem, error) {
+	var df dockerDF
+	if _, err := c.do("GET", "/system/df", &df); err != nil {
+		return nil, err
+	}
+	var items []dockerItem
+	for _, img := range df.Images {
+		if !dangling(img.RepoTags) || img.Containers > 0 {
+			continue
+		}
+		size := img.Size
+		if img.SharedSize > 0 {
+			size -= img.SharedSize
+		}
+		id := shortDockerID(img.ID)
+		items = append(items, dockerItem{
+			kind:     "image",
+			id:       id,
+			size:     size,
+			lastUsed: time.Unix(img.Created, 0),
+			tool:     "dangling image",
+			command:  "docker rmi " + id,
+			note:     "dated by when the image was built",
+			dated:    1,
+		})
+	}
+	for _, ctr := range df.Containers {
+		switch ctr.State {
+		case "exited", "created", "dead":
+		default:
+			continue
+		}
+		id := shortDockerID(ctr.ID)
+		name := id
+		if len(ctr.Names) > 0 {
+			name = strings.TrimPrefix(ctr.Names[0], "/")
+		}
+		lastUsed, note := time.Unix(ctr.Created, 0), "never started; dated by when it was created"
+		var inspect struct {
+			State struct {
+				FinishedAt time.Time `json:"FinishedAt"`
+			} `json:"State"`
+		}
+		if _, err := c.do("GET", "/containers/"+ctr.ID+"/json", &inspect); err == nil && inspect.State.FinishedAt.After(lastUsed) {
+			lastUsed, note = inspect.State.FinishedAt, "dated by when it last stopped"
+		}
+		items = append(items, dockerItem{
+			kind:     "container",
+			id:       id,
+			size:     ctr.SizeRw,
+			lastUsed: lastUsed,
+			tool:     fmt.Sprintf("container %s (%s)", name, ctr.Image),
+			command:  "docker rm " + id,
+			note:     ctr.State + " container; " + note,
+			dated:    1,
+		})
+	}
+	for _, vol := range df.Volumes {
+		if vol.UsageData == nil || vol.UsageData.RefCount != 0 {
+			continue
+		}
+		created, _ := time.Parse(time.RFC3339, vol.CreatedAt)
+		size := vol.UsageData.Size
+		if size < 0 {
+			size = 0
+		}
+		items = append(items, dockerItem{
+			kind:     "volume",
+			id:       vol.Name,
+			size:     size,
+			lastUsed: created,
+			tool:     "volume " + vol.Name,
+			command:  "docker volume rm " + vol.Name,
+			note:     "no container mounts it; dated by when it was created, as Docker does not record use; often database data, so review it and remove it by hand",
+			dated:    0.6,
+			advisory: true,
+		})
+	}
+	return items, nil
+}
+
+// scanDocker adds a record for each docker item older than -age. It
+// returns an error when the daemon cannot be asked.
+func (s *scanner) scanDocker(home string) error {
+	opts := s.opts
+	sock, err := dockerSocketFunc(home)
+	if err != nil {
+		return fmt.Errorf("docker: %v", err)
+	}
+	items, err := newDockerClient(sock).dockerItems()
+	if err != nil {
+		return fmt.Errorf("docker: %s: %v", sock, err)
+	}
+	for _, it := range items {
+		age := opts.ageDays(it.lastUsed)
+		if age < float64(opts.minAge) || it.size < opts.minSize || it.dated < opts.minConfidence {
+			continue
+		}
+		s.mu.Lock()
+		s.add(Record{
+			Type:            "docker",
+			Path:            dockerScheme + it.kind + "/" + it.id,
+			Size:            it.size,
+			SizeHuman:       formatBytes(it.size),
+			DiskSize:        it.size,
+			DiskHuman:       formatBytes(it.size),
+			LastUsed:        it.lastUsed.Truncate(time.Second).Format(time.RFC3339),
+			LastUsedDisplay: it.lastUsed.Format("2006-01-02"),
+			AgeDays:         age,
+			Notes:           []string{it.note},
+			Tool:            it.tool,
+			Command:         it.command,
+			Confidence:      it.dated,
+			Advisory:        it.advisory,
+		})
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+// dockerEndpoint returns the API path of the item at a docker:// path and
+// a client for its daemon.
+func dockerEndpoint(path string) (string, *dockerClient, error) {
+	kind, id, ok := strings.Cut(strings.TrimPrefix(path, dockerScheme), "/")
+	if !ok || id == "" {
+		return "", nil, fmt.Errorf("not a docker item")
+	}
+	endpoints := map[string]string{"image": "/images/", "container": "/containers/", "volume": "/volumes/"}
+	endpoint, ok := endpoints[kind]
+	if !ok {
+		return "", nil, fmt.Errorf("unknown docker item kind %q", kind)
+	}
+	home, _ := os.UserHomeDir()
+	sock, err := dockerSocketFunc(home)
+	if err != nil {
+		return "", nil, err
+	}
+	return endpoint + url.PathEscape(id), newDockerClient(sock), nil
+}
+
+// statDockerItem is os.Lstat for a docker:// path: nil while the daemon
+// still has the item, an os.ErrNotExist error once it does not.
+func statDockerItem(path string) error {
+	endpoint, c, err := dockerEndpoint(path)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(endpoint, "/volumes/") {
+		endpoint += "/json"
+	}
+	status, err := c.do("GET", endpoint, nil)
+	if status == http.StatusNotFound {
+		return fmt.Errorf("no longer on the Docker daemon: %w", os.ErrNotExist)
+	}
+	return err
+}
+
+// removeDockerItem asks the daemon to remove the item at a docker:// path.
+// One already gone counts as removed.
+func removeDockerItem(path string) error {
+	endpoint, c, err := dockerEndpoint(path)
+	if err != nil {
+		return err
+	}
+	status, err := c.do("DELETE", endpoint, nil)
+	if status == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDocker serves a canned /system/df on a unix socket, records DELETE
+// requests, and points dockerSocketFunc at itself.
+func fakeDocker(t *testing.T, df string, finished map[string]string) *[]string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "dk") // short: socket paths are length-limited
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	sock := filepath.Join(dir, "docker.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	var mu sync.Mutex
+	deleted := &[]string{}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/system/df":
+			fmt.Fprint(w, df)
+		case r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/containers/"):
+			id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/containers/"), "/json")
+			fmt.Fprintf(w, `{"State":{"FinishedAt":%q}}`, finished[id])
+		case r.Method == "GET" && strings.Contains(r.URL.Path, "/gone"):
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == "GET" && (strings.HasPrefix(r.URL.Path, "/images/") || strings.HasPrefix(r.URL.Path, "/volumes/")):
+			fmt.Fprint(w, `{}`)
+		case r.Method == "DELETE" && r.URL.Path == "/volumes/busy":
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprint(w, `{"message":"volume is in use"}`)
+		case r.Method == "DELETE" && r.URL.Path == "/images/gone":
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == "DELETE":
+			mu.Lock()
+			*deleted = append(*deleted, r.URL.Path)
+			mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	})}
+	go srv.Serve(l)
+	t.Cleanup(func() { srv.Close() })
+	saved := dockerSocketFunc
+	dockerSocketFunc = func(string) (string, error) { return sock, nil }
+	t.Cleanup(func() { dockerSocketFunc = saved })
+	return deleted
+}
+
+func TestScanRoots_Docker(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	old := time.Now().AddDate(0, 0, -90)
+	recent := time.Now().AddDate(0, 0, -2)
+	df := fmt.Sprintf(`{
+	  "Images": [
+	    {"Id": "sha256:aaaaaaaaaaaa1111", "RepoTags": ["<none>:<none>"], "Created": %d, "Size": 5000, "SharedSize": 1000, "Containers": 0},
+	    {"Id": "sha256:bbbbbbbbbbbb2222", "RepoTags": ["app:latest"], "Created": %d, "Size": 9000, "SharedSize": -1, "Containers": 0},
+	    {"Id": "sha256:cccccccccccc3333", "RepoTags": [], "Created": %d, "Size": 7000, "SharedSize": -1, "Containers": 1}
+	  ],
+	  "Containers": [
+	    {"Id": "dddddddddddd4444", "Names": ["/old-db"], "Image": "postgres:16", "State": "exited", "Created": %d, "SizeRw": 3000},
+	    {"Id": "eeeeeeeeeeee5555", "Names": ["/web"], "Image": "nginx", "State": "running", "Created": %d, "SizeRw": 100},
+	    {"Id": "ffffffffffff6666", "Names": ["/just-stopped"], "Image": "nginx", "State": "exited", "Created": %d, "SizeRw": 100}
+	  ],
+	  "Volumes": [
+	    {"Name": "orphan", "CreatedAt": %q, "UsageData": {"Size": 4000, "RefCount": 0}},
+	    {"Name": "mounted", "CreatedAt": %q, "UsageData": {"Size": 4000, "RefCount": 1}}
+	  ]
+	}`, old.Unix(), old.Unix(), old.Unix(), old.Unix(), old.Unix(), old.Unix(),
+		old.Format(time.RFC3339), old.Format(time.RFC3339))
+	fakeDocker(t, df, map[string]string{
+		"dddddddddddd4444": old.AddDate(0, 0, 10).Format(time.RFC3339Nano),
+		"ffffffffffff6666": recent.Format(time.RFC3339Nano),
+	})
+
+	opts := &options{minAge: 30, scanTypes: map[string]bool{"docker": true}}
+	records, errs := scanRoots(nil, opts)
+	if len(errs) != 0 {
+		t.Fatalf("scan errors: %v", errs)
+	}
+	got := map[string]Record{}
+	for _, r := range records {
+		got[r.Path] = r
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %v, want the dangling image, the old container, and the orphaned volume", got)
+	}
+	if img := got["docker://image/aaaaaaaaaaaa"]; img.Size != 4000 || img.Command != "docker rmi aaaaaaaaaaaa" {
+		t.Errorf("image = %+v, want 4000 bytes not shared, removed with docker rmi", img)
+	}
+	ctr := got["docker://container/dddddddddddd"]
+	if ctr.Tool != "container old-db (postgres:16)" || ctr.LastUsedDisplay != old.AddDate(0, 0, 10).Format("2006-01-02") {
+		t.Errorf("container = %+v, want old-db dated by when it stopped", ctr)
+	}
+	if vol := got["docker://volume/orphan"]; vol.Size != 4000 || vol.Confidence >= 1 || !vol.Advisory {
+		t.Errorf("volume = %+v, want 4000 bytes with less than full confidence, advisory only", vol)
+	}
+	if img := got["docker://image/aaaaaaaaaaaa"]; img.Advisory {
+		t.Error("dangling image marked advisory")
+	}
+}
+
+func TestScanRoots_DockerUnreachable(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	t.Setenv("DOCKER_HOST", "tcp://127.0.0.1:2375")
+	records, errs := scanRoots(nil, &options{scanTypes: map[string]bool{"docker": true}})
+	if len(records) != 0 || len(errs) != 1 || !strings.Contains(errs[0], "unix://") {
+		t.Errorf("got %v, %v; want one error about unix:// sockets", records, errs)
+	}
+}
+
+func TestRemoveDockerItem(t *testing.T) {
+	deleted := fakeDocker(t, `{}`, nil)
+	for _, p := range []string{"docker://image/aaaaaaaaaaaa", "docker://container/dddddddddddd", "docker://image/gone"} {
+		if err := removeDockerItem(p); err != nil {
+			t.Errorf("removeDockerItem(%s): %v", p, err)
+		}
+	}
+	if err := removeDockerItem("docker://volume/busy"); err == nil || !strings.Contains(err.Error(), "in use") {
+		t.Errorf("removing a volume in use: err = %v, want the daemon's message", err)
+	}
+	if err := removeDockerItem("docker://network/x"); err == nil {
+		t.Error("removing an unknown kind succeeded")
+	}
+	want := []string{"/images/aaaaaaaaaaaa", "/containers/dddddddddddd"}
+	if strings.Join(*deleted, " ") != strings.Join(want, " ") {
+		t.Errorf("deleted %v, want %v", *deleted, want)
+	}
+
+	// Docker items pass the path-based safety checks and are grouped apart.
+	r := Record{Type: "docker", Path: "docker://volume/orphan", Size: 10}
+	if safe, _ := filterSafeRecords([]Record{r}); len(safe) != 1 {
+		t.Errorf("filterSafeRecords dropped a docker item")
+	}
+	if groups := groupByFilesystem([]Record{r}); len(groups) != 1 || groups[0].mount != dockerVolumeLabel {
+		t.Errorf("groupByFilesystem = %+v, want the %s group", groups, dockerVolumeLabel)
+	}
+}
+
+func TestRevalidateRecords_Docker(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	old := time.Now().AddDate(0, 0, -90)
+	fakeDocker(t, fmt.Sprintf(`{
+	  "Images": [{"Id": "sha256:aaaaaaaaaaaa1111", "RepoTags": [], "Created": %d, "Size": 5000, "SharedSize": -1}],
+	  "Volumes": [{"Name": "orphan", "CreatedAt": %q, "UsageData": {"Size": 4000, "RefCount": 0}}]
+	}`, old.Unix(), old.Format(time.RFC3339)), nil)
+
+	records, _, err := loadRecords(strings.NewReader(`{"schema_version": 2, "records": [
+		{"type": "docker", "path": "docker://image/aaaaaaaaaaaa", "command": "touch /tmp/pwned"},
+		{"type": "docker", "path": "docker://volume/orphan"},
+		{"type": "docker", "path": "docker://image/gone"}
+	]}`))
+	if err != nil {
+		t.Fatalf("loading docker records: %v", err)
+	}
+	kept, warnings := revalidateRecords(records, time.Now())
+	if len(kept) != 2 || len(warnings) != 1 || !strings.Contains(warnings[0], "docker://image/gone") {
+		t.Fatalf("kept %v, warnings %v; want the gone image dropped", kept, warnings)
+	}
+	if kept[0].Command != "docker rmi aaaaaaaaaaaa" {
+		t.Errorf("command = %q, want the daemon's, not the file's", kept[0].Command)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Installers pile up in ~/Downloads: once an app is installed its .dmg or
+// .pkg is dead weight. The downloads type reports them as file-level
+// records, top level of the Downloads folder only.
+
+// installerSuffixes are the installer and disk image formats reported.
+var installerSuffixes = []string{".dmg", ".pkg", ".iso", ".zip"}
+
+// downloadsDir returns the user's Downloads folder: $XDG_DOWNLOAD_DIR, else
+// ~/Downloads.
+func downloadsDir(home string) string {
+	if dir := os.Getenv("XDG_DOWNLOAD_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join(home, "Downloads")
+}
+
+// isInstaller reports whether name has an installer suffix.
+func isInstaller(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, suffix := range installerSuffixes {
+		if ext == suffix {
+			return true
+		}
+	}
+	return false
+}
+
+// findDownloads returns the installers at the top of the Downloads folder.
+func findDownloads(home string) []string {
+	dir := downloadsDir(home)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for _, e := range entries {
+		if e.Type().IsRegular() && isInstaller(e.Name()) {
+			files = append(files, filepath.Join(dir, e.Name()))
+		}
+	}
+	return files
+}
+
+// getDownloadUsage dates a download by the newer of its mtime (when it was
+// downloaded) and atime (when it was last opened or mounted).
+func getDownloadUsage(path string) (time.Time, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	lastUsed := info.ModTime()
+	if at, ok := accessTime(path); ok && at.After(lastUsed) {
+		lastUsed = at
+	}
+	return lastUsed, true
+}
+
+// annotateDownload holds zip archives for review: unlike disk images and
+// packages, a zip is as likely to hold documents as an installer.
+func annotateDownload(r *Record) {
+	if strings.EqualFold(filepath.Ext(r.Path), ".zip") {
+		r.Review = "zip archive: check it is only an installer"
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestScanRoots_Downloads(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_DOWNLOAD_DIR", "")
+	dl := filepath.Join(home, "Downloads")
+	os.MkdirAll(filepath.Join(dl, "sub"), 0755)
+	old, recent := time.Now().AddDate(0, 0, -90), time.Now().AddDate(0, 0, -2)
+	for name, mtime := range map[string]time.Time{
+		"Docker.dmg":     old,
+		"Tool.PKG":       old,
+		"fresh.pkg":      recent,
+		"archive.zip":    old,
+		"notes.txt":      old,
+		"sub/ubuntu.iso": old,
+	} {
+		p := filepath.Join(dl, name)
+		os.WriteFile(p, []byte("x"), 0644)
+		os.Chtimes(p, mtime, mtime)
+	}
+
+	opts := &options{minAge: 30, maxDepth: 5, systemScan: true, scanTypes: map[string]bool{"downloads": true}}
+	records, _ := scanRoots(nil, opts)
+	sort.Slice(records, func(i, j int) bool { return records[i].Path < records[j].Path })
+	var names []string
+	for _, r := range records {
+		names = append(names, filepath.Base(r.Path))
+	}
+	if len(names) != 3 || names[0] != "Docker.dmg" || names[1] != "Tool.PKG" || names[2] != "archive.zip" {
+		t.Fatalf("downloads = %v, want Docker.dmg, Tool.PKG, archive.zip", names)
+	}
+	if records[0].Review != "" || records[2].Review == "" {
+		t.Errorf("review: dmg %q, zip %q; want only the zip held", records[0].Review, records[2].Review)
+	}
+}
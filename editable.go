@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// An editable install (pip install -e, uv pip install -e, setup.py develop)
+// leaves a back-reference from one environment into another project's
+// source tree. Deleting that project's venv or build directory can break
+// the other environment, e.g. when extension modules were built in place
+// or a meson/scikit-build editable install points into build/. After a
+// scan, records of those types list the environments, among all those the
+// scan saw, with editable installs pointing into their project; the safety
+// summary shows them before anything is deleted.
+
+// editableTypes are the record types whose removal can break an editable
+// install of their project.
+var editableTypes = map[string]bool{"venv": true, "build": true, "dist": true}
+
+// directURL is the part of a dist-info direct_url.json (PEP 610) that
+// identifies an editable install.
+type directURL struct {
+	URL     string `json:"url"`
+	DirInfo struct {
+		Editable bool `json:"editable"`
+	} `json:"dir_info"`
+}
+
+// editableTargets returns the source directories a venv has editable
+// installs of: direct_url.json entries marked editable, and plain paths in
+// .pth files (setup.py develop, older pip).
+func editableTargets(venv string) []string {
+	var targets []string
+	for _, sp := range sitePackagesDirs(venv) {
+		urls, _ := filepath.Glob(filepath.Join(sp, "*.dist-info", "direct_url.json"))
+		for _, f := range urls {
+			data, err := os.ReadFile(f)
+			if err != nil {
+				continue
+			}
+			var du directURL
+			if json.Unmarshal(data, &du) != nil || !du.DirInfo.Editable {
+				continue
+			}
+			if p := fileURLPath(du.URL); p != "" {
+				targets = append(targets, p)
+			}
+		}
+		pths, _ := filepath.Glob(filepath.Join(sp, "*.pth"))
+		for _, f := range pths {
+			targets = append(targets, pthPaths(f)...)
+		}
+	}
+	return targets
+}
+
+// fileURLPath converts a file:// URL to a local path, or returns "".
+func fileURLPath(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	p := u.Path
+	// file:///C:/src on Windows.
+	if len(p) > 2 && p[0] == '/' && p[2] == ':' {
+		p = p[1:]
+	}
+	return filepath.Clean(filepath.FromSlash(p))
+}
+
+// pthPaths returns the absolute directory paths listed in a .pth file;
+// import lines and relative entries are not back-references.
+func pthPaths(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var paths []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "import") || !filepath.IsAbs(line) {
+			continue
+		}
+		paths = append(paths, filepath.Clean(line))
+	}
+	return paths
+}
+
+// itemProject returns the project a venv, build, or dist record belongs to.
+func itemProject(r Record) string {
+	if r.Type == "venv" {
+		return venvProject(r.Path)
+	}
+	return filepath.Dir(r.Path)
+}
+
+// within reports whether path is dir or inside it.
+func within(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+// noteEditables remembers the editable installs of a venv the scan saw,
+// stale or not, for editableUsers. Only venvs that have any are kept.
+func (s *scanner) noteEditables(venv string) {
+	if t := editableTargets(venv); len(t) > 0 {
+		if s.editables == nil {
+			s.editables = map[string][]string{}
+		}
+		s.editables[venv] = t
+	}
+}
+
+// editableUsers returns, for a venv, build, or dist record, the
+// environments elsewhere seen in the same scan that have an editable
+// install of its project. The project's own environments do not count.
+func (s *scanner) editableUsers(r Record) []string {
+	if !editableTypes[r.Type] || len(s.editables) == 0 {
+		return nil
+	}
+	project := itemProject(r)
+	var users []string
+	for venv, sources := range s.editables {
+		if within(venv, project) {
+			continue // the project's own environments
+		}
+		for _, src := range sources {
+			if within(src, project) {
+				users = append(users, venv)
+				break
+			}
+		}
+	}
+	sort.Strings(users)
+	return users
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScanRoots_EditableUsers(t *testing.T) {
+	root := t.TempDir()
+	old := time.Now().AddDate(0, 0, -90)
+	lib := filepath.Join(root, "lib")
+	app := filepath.Join(root, "app")
+
+	// lib: a stale venv and an in-place build/.
+	os.MkdirAll(lib, 0755)
+	os.WriteFile(filepath.Join(lib, "pyproject.toml"), []byte("[project]\nname = \"lib\"\n"), 0644)
+	makeVenv(t, filepath.Join(lib, ".venv"), old)
+	build := filepath.Join(lib, "build", "cp311")
+	os.MkdirAll(build, 0755)
+	os.WriteFile(filepath.Join(build, "ext.so"), []byte("x"), 0644)
+	os.Chtimes(filepath.Join(build, "ext.so"), old, old)
+
+	// app's venv has lib installed in editable mode, the PEP 610 way...
+	makeVenv(t, filepath.Join(app, ".venv"), time.Now())
+	sp := filepath.Join(app, ".venv", "lib", "python3.11", "site-packages")
+	os.MkdirAll(filepath.Join(sp, "lib-0.1.dist-info"), 0755)
+	os.WriteFile(filepath.Join(sp, "lib-0.1.dist-info", "direct_url.json"),
+		[]byte(`{"url": "file://`+filepath.ToSlash(lib)+`", "dir_info": {"editable": true}}`), 0644)
+	// ...and lib's own second venv has it too, which does not count.
+	makeVenv(t, filepath.Join(lib, "venv2"), time.Now())
+	sp2 := filepath.Join(lib, "venv2", "lib", "python3.11", "site-packages")
+	os.MkdirAll(sp2, 0755)
+	os.WriteFile(filepath.Join(sp2, "easy-install.pth"), []byte("import sys\n"+lib+"\n"), 0644)
+
+	opts := &options{minAge: 30, maxDepth: 5, scanTypes: map[string]bool{"venv": true, "build": true}}
+	records, _ := scanRoots([]string{root}, opts)
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want lib/.venv and lib/build", len(records))
+	}
+	want := filepath.Join(app, ".venv")
+	for _, r := range records {
+		if len(r.EditableUsers) != 1 || r.EditableUsers[0] != want {
+			t.Errorf("%s: editable users %v, want [%s]", r.Path, r.EditableUsers, want)
+		}
+	}
+}
+
+func TestEditableTargets_Pth(t *testing.T) {
+	venv := t.TempDir()
+	sp := filepath.Join(venv, "lib", "python3.12", "site-packages")
+	os.MkdirAll(sp, 0755)
+	os.WriteFile(filepath.Join(sp, "__editable__.pkg-1.0.pth"), []byte("import __editable___pkg_1_0_finder; __editable___pkg_1_0_finder.install()\n"), 0644)
+	os.WriteFile(filepath.Join(sp, "easy-install.pth"), []byte("/src/legacy\n./relative\n# comment\n"), 0644)
+	got := editableTargets(venv)
+	if len(got) != 1 || got[0] != filepath.Clean("/src/legacy") {
+		t.Errorf("editableTargets = %v, want [/src/legacy]", got)
+	}
+}
+
+func TestPrintSafetySummary_Editable(t *testing.T) {
+	records := []Record{{Type: "build", Path: "/p/lib/build", EditableUsers: []string{"/p/app/.venv"}}}
+	var buf bytes.Buffer
+	printSafetySummary(&buf, summarizeSafety(records, nil), &options{})
+	if !strings.Contains(buf.String(), "/p/lib/build: used by /p/app/.venv") {
+		t.Errorf("summary does not list the editable user:\n%s", buf.String())
+	}
+}
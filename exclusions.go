@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Some candidates are false positives for one type only, in ways -exclude
+// cannot say: a node_modules inside another is part of its parent install,
+// and a venv inside site-packages is package data. defaultExclusions are
+// those rules, maintained here. exclusions.json in the config directory
+// turns them off by name and adds per-type -exclude patterns --
+//
+//	{
+//	  "node_modules": {"disable": ["nested-node-modules"]},
+//	  "build": {"patterns": ["/srv/releases/", "keep-*"]},
+//	  "*": {"patterns": ["/mnt/archive/"]}
+//	}
+//
+// "*" applies to every type. Patterns match as -exclude's do.
+
+// typeExclusion is a built-in rule: items of its types that match are
+// never flagged.
+type typeExclusion struct {
+	name  string
+	types []string // nil: every type
+	why   string
+	match func(path string) bool
+}
+
+// insideDir reports whether some directory above path is called name.
+func insideDir(name string) func(path string) bool {
+	return func(path string) bool {
+		parent := filepath.ToSlash(filepath.Dir(path))
+		return strings.Contains(parent+"/", "/"+name+"/")
+	}
+}
+
+var defaultExclusions = []typeExclusion{
+	{
+		name:  "nested-node-modules",
+		types: []string{"node_modules"},
+		why:   "inside another node_modules, part of its install",
+		match: insideDir("node_modules"),
+	},
+	{
+		name:  "env-in-site-packages",
+		types: []string{"venv", "conda", "tox", "nox", "direnv"},
+		why:   "inside site-packages, package data rather than an environment",
+		match: insideDir("site-packages"),
+	},
+	{
+		name:  "vendored-build",
+		types: []string{"dist", "build", "cargo", "gradle", "maven", "wheel"},
+		why:   "inside an installed package (node_modules or site-packages)",
+		match: func(path string) bool {
+			return insideDir("node_modules")(path) || insideDir("site-packages")(path)
+		},
+	},
+	{
+		name:  "git-modules",
+		why:   "inside .git/modules, a submodule's repository",
+		match: func(path string) bool { return strings.Contains(filepath.ToSlash(path)+"/", "/.git/modules/") },
+	},
+}
+
+// exclusionRule is one type's entry in exclusions.json.
+type exclusionRule struct {
+	Disable  []string `json:"disable,omitempty"`  // names of built-in rules to turn off
+	Patterns []string `json:"patterns,omitempty"` // -exclude patterns for the type
+}
+
+// exclusionsConfig maps type names, or "*", to rules.
+type exclusionsConfig map[string]exclusionRule
+
+// exclusionsPath is where the exclusions configuration lives.
+func exclusionsPath() (string, error) {
+	dir, err := configDir()
+	return filepath.Join(dir, "exclusions.json"), err
+}
+
+// loadExclusions reads and checks exclusions.json; a missing file is no
+// configuration.
+func loadExclusions() (exclusionsConfig, error) {
+	path, err := exclusionsPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cfg exclusionsConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if err := cfg.check(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// check rejects unknown types and rule names.
+func (cfg exclusionsConfig) check() error {
+	types := nameSet(allScanTypes)
+	rules := map[string]bool{}
+	var names []string
+	for _, ex := range defaultExclusions {
+		rules[ex.name] = true
+		names = append(names, ex.name)
+	}
+	var keys []string
+	for k := range cfg {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, typeName := range keys {
+		if typeName != "*" && !types[typeName] {
+			return fmt.Errorf("unknown type %q", typeName)
+		}
+		for _, name := range cfg[typeName].Disable {
+			if !rules[name] {
+				return fmt.Errorf("%s: unknown rule %q (built in: %s)", typeName, name, strings.Join(names, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// excludedBy returns why an item of typeName at path is never flagged, or
+// "": a built-in rule not disabled for the type, or a configured pattern.
+func (cfg exclusionsConfig) excludedBy(path, typeName string) string {
+	disabled := func(name string) bool {
+		for _, key := range []string{typeName, "*"} {
+			for _, d := range cfg[key].Disable {
+				if d == name {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	for _, ex := range defaultExclusions {
+		if ex.types != nil && !nameSet(ex.types)[typeName] {
+			continue
+		}
+		if !disabled(ex.name) && ex.match(path) {
+			return ex.why + " (" + ex.name + ")"
+		}
+	}
+	for _, key := range []string{typeName, "*"} {
+		if matchesExclude(path, cfg[key].Patterns) {
+			return "matches an exclusions.json pattern for " + key
+		}
+	}
+	return ""
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadExclusions(t *testing.T) {
+	cfgDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", cfgDir)
+
+	if cfg, err := loadExclusions(); err != nil || cfg != nil {
+		t.Fatalf("no file: got %v, %v", cfg, err)
+	}
+
+	path := filepath.Join(cfgDir, "tidyup", "exclusions.json")
+	os.MkdirAll(filepath.Dir(path), 0755)
+	tests := []struct {
+		data, wantErr string
+	}{
+		{`{"node_modules": {"disable": ["nested-node-modules"]}, "*": {"patterns": ["/srv/keep/"]}}`, ""},
+		{`{"node_module": {"patterns": ["x"]}}`, `unknown type "node_module"`},
+		{`{"venv": {"disable": ["nested"]}}`, `unknown rule "nested"`},
+		{`{"venv": [`, "unexpected end"},
+	}
+	for _, tt := range tests {
+		os.WriteFile(path, []byte(tt.data), 0644)
+		_, err := loadExclusions()
+		if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: err = %v, want %q", tt.data, err, tt.wantErr)
+		}
+	}
+}
+
+func TestExclusions_ExcludedBy(t *testing.T) {
+	cfg := exclusionsConfig{
+		"venv":  {Disable: []string{"env-in-site-packages"}},
+		"build": {Patterns: []string{"/srv/releases/"}},
+		"*":     {Patterns: []string{"keep-*"}},
+	}
+	tests := []struct {
+		path, typeName string
+		want           string // rule name or pattern owner in the reason; "" for none
+	}{
+		{"/p/node_modules/a/node_modules", "node_modules", "nested-node-modules"},
+		{"/p/node_modules", "node_modules", ""},
+		{"/p/.venv/lib/python3.12/site-packages/pkg/env", "conda", "env-in-site-packages"},
+		{"/p/.venv/lib/python3.12/site-packages/pkg/env", "venv", ""}, // disabled for venv
+		{"/p/node_modules/lib/dist", "dist", "vendored-build"},
+		{"/p/.git/modules/sub/node_modules", "node_modules", "git-modules"},
+		{"/srv/releases/app/build", "build", "for build"},
+		{"/srv/releases/app/dist", "dist", ""},
+		{"/p/keep-me", "cargo", "for *"},
+	}
+	for _, tt := range tests {
+		got := cfg.excludedBy(tt.path, tt.typeName)
+		if tt.want == "" && got != "" || tt.want != "" && !strings.Contains(got, tt.want) {
+			t.Errorf("excludedBy(%s, %s) = %q, want %q", tt.path, tt.typeName, got, tt.want)
+		}
+	}
+	if got := exclusionsConfig(nil).excludedBy("/p/node_modules/a/node_modules", "node_modules"); got == "" {
+		t.Error("built-in rules should apply without a configuration")
+	}
+}
+
+func TestScanRoots_NestedNodeModules(t *testing.T) {
+	cfgDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", cfgDir)
+	// Scanning from inside an install reaches the nested node_modules.
+	root := filepath.Join(t.TempDir(), "node_modules", "pkg")
+	old := time.Now().AddDate(0, 0, -90)
+	lock := filepath.Join(root, "node_modules", ".package-lock.json")
+	os.MkdirAll(filepath.Dir(lock), 0755)
+	os.WriteFile(lock, []byte("{}"), 0644)
+	os.Chtimes(lock, old, old)
+
+	opts := &options{minAge: 30, maxDepth: 5, scanTypes: map[string]bool{"node_modules": true}}
+	if records, _ := scanRoots([]string{root}, opts); len(records) != 0 {
+		t.Errorf("nested node_modules flagged: %+v", records)
+	}
+
+	path := filepath.Join(cfgDir, "tidyup", "exclusions.json")
+	os.MkdirAll(filepath.Dir(path), 0755)
+	os.WriteFile(path, []byte(`{"node_modules": {"disable": ["nested-node-modules"]}}`), 0644)
+	if records, _ := scanRoots([]string{root}, opts); len(records) != 1 {
+		t.Errorf("with the rule disabled: got %d records, want 1", len(records))
+	}
+}
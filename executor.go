@@ -0,0 +1,221 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// tidyup is split into an analyzer and an executor. Scanning, sizing, and
+// reporting -- scanRoots and everything it calls, the output formats, plans
+// and proposals -- only read what they look at. Removing anything -- a
+// record, an item in the Trash, a restore's cross-device move -- takes an
+// *executor, the only holder of the removal primitives, and the only way to
+// get one is newExecutor, which refuses under -readonly and in binaries
+// built with -tags readonly. Those builds also compile the primitives to
+// refusals (executor_readonly.go), so no code path in them can delete.
+
+// errReadonlyBuild is what every removal primitive returns in a readonly
+// build.
+var errReadonlyBuild = errors.New("this tidyup binary is read-only (built with -tags readonly)")
+
+// removers are the removal primitives, from newRemovers in
+// executor_rw.go or executor_readonly.go.
+type removers struct {
+	rename       func(oldpath, newpath string) error
+	removeAll    func(path string) error // symlink-proof, see removeBeneath
+	remove       func(path string) error // one file, symlink-proof
+	finderTrash  func(path string) (string, error)
+	runCommand   func(command string) error
+	removeDocker func(path string) error
+}
+
+// executor removes records, logging each outcome to log (which may be nil).
+type executor struct {
+	opts   *options
+	log    *deletionLog
+	rm     removers
+	helper *removeHelper // -sandbox: removes instead of this process
+}
+
+// Between the safety checks and the removal -- the selection prompt can take
+// minutes -- someone else on the machine may swap a directory on the path
+// for a symlink to somewhere else. filterSafeRecords therefore pins each
+// record: its path with the symlinks in its parent resolved, and the inode
+// found there. Removal goes to the pinned path through removeBeneath, which
+// follows no symlink on the way or inside, and refuses if a different file
+// or directory has taken the item's place.
+
+// resolveParent resolves the symlinks in path's parent, leaving the last
+// element alone: a symlink item is removed, not followed.
+func resolveParent(path string) string {
+	dir, err := filepath.EvalSymlinks(filepath.Dir(path))
+	if err != nil {
+		return path
+	}
+	return filepath.Join(dir, filepath.Base(path))
+}
+
+// pinRecord records where r resolves to and which inode is there.
+func pinRecord(r Record) Record {
+	if isDockerPath(r.Path) {
+		return r
+	}
+	r.pinned = resolveParent(r.Path)
+	if info, err := os.Lstat(r.pinned); err == nil {
+		r.pinnedID, _ = identity(info)
+	}
+	return r
+}
+
+// removalTarget returns the path to remove r by: the pinned one, as long as
+// the item there is still the one the safety checks saw.
+func removalTarget(r Record) (string, error) {
+	if r.pinned == "" {
+		return r.Path, nil
+	}
+	if r.pinnedID != (fileID{}) {
+		if info, err := os.Lstat(r.pinned); err == nil {
+			if id, ok := identity(info); ok && id != r.pinnedID {
+				return "", fmt.Errorf("replaced by a different %s since the safety checks", kindOf(info))
+			}
+		}
+	}
+	return r.pinned, nil
+}
+
+// kindOf names what info describes, for messages.
+func kindOf(info os.FileInfo) string {
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		return "symlink"
+	case info.IsDir():
+		return "directory"
+	}
+	return "file"
+}
+
+// readonlyReason returns why this process may not remove anything, or "".
+func readonlyReason(opts *options) string {
+	switch {
+	case readonlyBuild:
+		return errReadonlyBuild.Error()
+	case opts.readonly:
+		return "-readonly is set"
+	}
+	return ""
+}
+
+// newExecutor returns an executor, unless removal is disabled.
+func newExecutor(opts *options, log *deletionLog) (*executor, error) {
+	if why := readonlyReason(opts); why != "" {
+		return nil, errors.New(why)
+	}
+	return &executor{opts: opts, log: log, rm: removersFunc()}, nil
+}
+
+// approve starts the -sandbox removal helper, allowed to remove exactly
+// records. Without -sandbox it does nothing.
+func (x *executor) approve(records []Record) error {
+	if !x.opts.sandbox {
+		return nil
+	}
+	h, err := startRemoveHelper(helperApprovalFor(records))
+	if err != nil {
+		return err
+	}
+	x.helper = h
+	return nil
+}
+
+// helperApprovalFor lists the removal targets of records for the helper,
+// with the tmp entries among them and whether -nice is in force.
+func helperApprovalFor(records []Record) helperApproval {
+	approval := helperApproval{Nice: ioThrottle.Load() != nil}
+	for _, r := range records {
+		if target, err := removalTarget(r); err == nil {
+			approval.Approved = append(approval.Approved, target)
+			if isTmpEntry(r) {
+				approval.Tmp = append(approval.Tmp, target)
+			}
+		}
+	}
+	return approval
+}
+
+// close stops the removal helper, if any.
+func (x *executor) close() {
+	if x.helper != nil {
+		if err := x.helper.close(); err != nil {
+			stderr.warnf("removal helper: %v", err)
+		}
+		x.helper = nil
+	}
+}
+
+// removeBeneath removes path, a file when file is set, without following
+// symlinks; the -sandbox helper's removal.
+func (x *executor) removeBeneath(path string, file bool) error {
+	if file {
+		return x.rm.remove(path)
+	}
+	return x.rm.removeAll(path)
+}
+
+// remove deletes, trashes, or delegates one record and logs the outcome.
+// It reports whether the record was removed.
+func (x *executor) remove(r Record) bool {
+	opts, logWriter := x.opts, x.log
+	var rc receipt
+	if opts.receipts {
+		rc = buildReceipt(r)
+	}
+	err := checkKind(r)
+	var target string
+	if err == nil {
+		target, err = removalTarget(r)
+	}
+	action := "Deleted"
+	switch {
+	case err != nil: // refused by checkKind or removalTarget
+	case opts.delegate && r.Command != "":
+		action = "Delegated"
+		fmt.Printf("Running: %s\n", r.Command)
+		err = x.rm.runCommand(r.Command)
+	case isDockerPath(r.Path):
+		err = x.rm.removeDocker(r.Path)
+	case opts.useTrash:
+		action = "Trashed"
+		var dest string
+		if dest, err = x.moveToTrash(target); err == nil {
+			recordTrashed(r, dest, time.Now())
+		}
+	case x.helper != nil:
+		pace()
+		err = x.helper.remove(target, r.File)
+	case r.File:
+		pace()
+		err = x.rm.remove(target)
+		if os.IsNotExist(err) {
+			err = nil
+		}
+	default:
+		err = x.rm.removeAll(target)
+	}
+
+	if err != nil {
+		stderr.printf("Error removing %s: %v", r.Path, err)
+		logWriter.failed(r, err)
+		tally.failed(1)
+		return false
+	}
+	fmt.Printf("%s: %s\n", action, r.Path)
+	logWriter.removed(action, r)
+	tally.removed(r)
+	if opts.receipts {
+		recordReceipt(rc, action, time.Now())
+	}
+	return true
+}
@@ -0,0 +1,19 @@
+//go:build readonly
+
+package main
+
+// readonlyBuild is set by -tags readonly.
+const readonlyBuild = true
+
+// newRemovers returns removal primitives that all refuse: in a readonly
+// build nothing can remove, whatever path leads here.
+func newRemovers() removers {
+	return removers{
+		rename:       func(string, string) error { return errReadonlyBuild },
+		removeAll:    func(string) error { return errReadonlyBuild },
+		remove:       func(string) error { return errReadonlyBuild },
+		finderTrash:  func(string) (string, error) { return "", errReadonlyBuild },
+		runCommand:   func(string) error { return errReadonlyBuild },
+		removeDocker: func(string) error { return errReadonlyBuild },
+	}
+}
@@ -0,0 +1,148 @@
+//go:build readonly
+
+package main
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A readonly build must refuse every removal, whichever way it is asked
+// for; these run real paths, not fakes substituted through removersFunc.
+
+// readonlyTree writes an old node_modules with one file in it, outside the
+// protected temp directory.
+func readonlyTree(t *testing.T) string {
+	t.Helper()
+	wd, _ := os.Getwd()
+	base, err := os.MkdirTemp(wd, "tidyup-readonly-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(base) })
+	dir := filepath.Join(base, "proj", "node_modules")
+	os.MkdirAll(filepath.Join(dir, "pkg"), 0755)
+	file := filepath.Join(dir, "pkg", "index.js")
+	lock := filepath.Join(dir, ".package-lock.json")
+	os.WriteFile(file, []byte("x"), 0644)
+	os.WriteFile(lock, []byte("{}"), 0644)
+	old := time.Now().AddDate(0, 0, -100)
+	os.Chtimes(file, old, old)
+	os.Chtimes(lock, old, old)
+	return dir
+}
+
+// assertIntact fails unless path still holds what readonlyTree wrote.
+func assertIntact(t *testing.T, path string) {
+	t.Helper()
+	if _, err := os.Stat(filepath.Join(path, "pkg", "index.js")); err != nil {
+		t.Errorf("readonly build removed %s: %v", path, err)
+	}
+}
+
+func TestReadonly_PrimitivesRefuse(t *testing.T) {
+	dir := readonlyTree(t)
+	file := filepath.Join(dir, "pkg", "index.js")
+	rm := newRemovers()
+	_, trashErr := rm.finderTrash(dir)
+	for name, err := range map[string]error{
+		"rename":    rm.rename(dir, dir+".moved"),
+		"removeAll": rm.removeAll(dir),
+		"remove":    rm.remove(file),
+		"trash":     trashErr,
+		"command":   rm.runCommand("rm -rf " + dir),
+		"docker":    rm.removeDocker("docker://volume/orphan"),
+	} {
+		if err != errReadonlyBuild {
+			t.Errorf("%s: err = %v, want %v", name, err, errReadonlyBuild)
+		}
+	}
+	if _, err := (&executor{opts: &options{}, rm: rm}).moveToTrash(dir); err == nil {
+		t.Error("moveToTrash succeeded")
+	}
+	assertIntact(t, dir)
+}
+
+func TestReadonly_DeleteRecordsRemovesNothing(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	dir := readonlyTree(t)
+	r := Record{Type: "node_modules", Path: dir, Size: 1}
+	for _, opts := range []*options{{confirm: true}, {confirm: true, useTrash: true}, {confirm: true, sandbox: true}} {
+		if code := deleteRecords([]Record{r}, opts); code != exitError {
+			t.Errorf("deleteRecords(%+v) = %d, want exitError", opts, code)
+		}
+	}
+	assertIntact(t, dir)
+}
+
+func TestReadonly_ServeRefusesDeletion(t *testing.T) {
+	dir := readonlyTree(t)
+	s, _ := testServer(t, Record{Type: "node_modules", Path: dir, Size: 1})
+	serveDeleteFunc = deleteSelected // the real executor, not the fake
+	s.apiToken = "secret"
+
+	form := map[string][]string{"path": {dir}, "token": {s.token}, "confirm": {"yes"}}
+	if rec := post(s, "/delete", form); rec.Code != http.StatusForbidden {
+		t.Errorf("POST /delete: status %d, want 403", rec.Code)
+	}
+	if rec := apiRequest(s, http.MethodPost, "/api/v1/delete", "k1", `{"paths": ["`+dir+`"]}`); rec.Code != http.StatusForbidden {
+		t.Errorf("API delete: status %d, want 403", rec.Code)
+	}
+	assertIntact(t, dir)
+}
+
+func TestReadonly_HelperRefuses(t *testing.T) {
+	dir := readonlyTree(t)
+	reqR, reqW := io.Pipe()
+	repR, repW := io.Pipe()
+	go func() {
+		serveHelper(reqR, repW)
+		repW.Close()
+	}()
+	h := newRemoveHelper(reqW, repR)
+	if err := h.enc.Encode(helperApproval{Approved: []string{dir}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.remove(dir, false); err == nil || !strings.Contains(err.Error(), "read-only") {
+		t.Errorf("helper remove: err = %v, want the readonly refusal", err)
+	}
+	h.close()
+	assertIntact(t, dir)
+}
+
+func TestReadonly_RunApprovedKeepsQueue(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	dir := readonlyTree(t)
+	now := time.Now()
+	q := &approvalQueue{Version: queueVersion, NextID: 1}
+	q.propose([]Record{{Type: "node_modules", Path: dir, Size: 1}}, &options{}, "ci", now)
+	q.decide([]string{"all"}, queueApproved, "alice", now)
+
+	if code := runApproved(q, &options{}, false, false); code != exitError {
+		t.Errorf("runApproved = %d, want exitError", code)
+	}
+	if len(q.Items) != 1 || q.Items[0].Status != queueApproved {
+		t.Errorf("queue = %+v, want the item still approved", q.Items)
+	}
+	assertIntact(t, dir)
+}
+
+func TestReadonly_RestoreMovesNothing(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	dir := t.TempDir()
+	in := filepath.Join(dir, "Trash", "foo-venv")
+	os.MkdirAll(in, 0755)
+	recordTrashed(Record{Type: "venv", Path: filepath.Join(dir, "dev", "foo", ".venv")}, in, time.Now())
+
+	if code := runRestore([]string{"-type", "venv"}); code == exitOK {
+		t.Error("restore succeeded in a readonly build")
+	}
+	if _, err := os.Stat(in); err != nil {
+		t.Errorf("trashed item moved: %v", err)
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !readonly
+
+package main
+
+import "os"
+
+// readonlyBuild is set by -tags readonly.
+const readonlyBuild = false
+
+// newRemovers returns the removal primitives. Only newExecutor (through the
+// removersFunc seam) calls it.
+func newRemovers() removers {
+	return removers{
+		rename:       os.Rename,
+		removeAll:    func(path string) error { return removeBeneath(path, false) },
+		remove:       func(path string) error { return removeBeneath(path, true) },
+		finderTrash:  finderTrash,
+		runCommand:   runShellCommand,
+		removeDocker: removeDockerItem,
+	}
+}
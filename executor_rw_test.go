@@ -0,0 +1,406 @@
+//go:build !readonly
+
+package main
+
+// The tests here remove, trash, or restore for real, which a readonly
+// build refuses; executor_readonly_test.go checks that refusal instead.
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestAPI_DeleteIsIdempotent(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a", ".venv"), filepath.Join(dir, "b", ".venv")
+	os.MkdirAll(a, 0755)
+	s, deleted := testServer(t,
+		Record{Type: "venv", Path: a},
+		Record{Type: "venv", Path: b, Review: "uncommitted files"},
+	)
+	body := `{"paths": ["` + a + `", "` + b + `", "/etc"]}`
+
+	if rec := apiRequest(s, http.MethodPost, "/api/v1/delete", "", body); rec.Code != http.StatusBadRequest {
+		t.Errorf("no Idempotency-Key: status %d, want 400", rec.Code)
+	}
+
+	first := apiRequest(s, http.MethodPost, "/api/v1/delete", "k1", body)
+	var resp deleteResponse
+	if err := json.Unmarshal(first.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(*deleted) != 1 || len(resp.Removed) != 1 || resp.Skipped["/etc"] != "not in the last scan" || !strings.HasPrefix(resp.Skipped[b], "review required") {
+		t.Errorf("first delete: %+v, deleted %v", resp, *deleted)
+	}
+
+	again := apiRequest(s, http.MethodPost, "/api/v1/delete", "k1", body)
+	if again.Header().Get("Idempotent-Replayed") != "true" || again.Body.String() != first.Body.String() {
+		t.Errorf("retry was not replayed:\n%s", again.Body.String())
+	}
+	if len(*deleted) != 1 {
+		t.Errorf("retry deleted again: %v", *deleted)
+	}
+}
+
+// testExecutor returns an executor with the real removal primitives.
+func testExecutor() *executor {
+	return &executor{opts: &options{}, rm: newRemovers()}
+}
+
+func TestMoveTree_CrossDeviceFallback(t *testing.T) {
+	x := testExecutor()
+	x.rm.rename = func(oldpath, newpath string) error {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EXDEV}
+	}
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	os.MkdirAll(filepath.Join(src, "sub"), 0755)
+	os.WriteFile(filepath.Join(src, "sub", "f.txt"), []byte("hello"), 0644)
+	os.Symlink("sub/f.txt", filepath.Join(src, "link"))
+
+	dst := filepath.Join(dir, "dst")
+	if err := x.moveTree(src, dst); err != nil {
+		t.Fatalf("moveTree: %v", err)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Error("expected source removed after fallback copy")
+	}
+	if data, err := os.ReadFile(filepath.Join(dst, "sub", "f.txt")); err != nil || string(data) != "hello" {
+		t.Errorf("copied file = %q, %v", data, err)
+	}
+	if link, err := os.Readlink(filepath.Join(dst, "link")); err != nil || link != "sub/f.txt" {
+		t.Errorf("symlink not preserved: %q, %v", link, err)
+	}
+}
+
+func TestExecutorRemove_File(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "paper.aux")
+	os.WriteFile(file, []byte("x"), 0644)
+	x := testExecutor()
+	if !x.remove(Record{Type: "latex", Path: file, File: true}) {
+		t.Error("removing a file record failed")
+	}
+	if _, err := os.Lstat(file); err == nil {
+		t.Error("file record not removed")
+	}
+	// Gone already: nothing left to do.
+	if !x.remove(Record{Type: "latex", Path: file, File: true}) {
+		t.Error("missing file reported as a failure")
+	}
+
+	// A directory created where the file was is not what was scanned.
+	os.MkdirAll(filepath.Join(file, "keep"), 0755)
+	if x.remove(Record{Type: "latex", Path: file, File: true}) {
+		t.Error("file record replaced by a directory reported as removed")
+	}
+	if _, err := os.Stat(filepath.Join(file, "keep")); err != nil {
+		t.Error("file record removed a directory")
+	}
+}
+
+func TestRunApproved_DockerResults(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	old := time.Now().AddDate(0, 0, -90)
+	fakeDocker(t, fmt.Sprintf(`{
+	  "Images": [{"Id": "sha256:aaaaaaaaaaaa1111", "RepoTags": [], "Created": %d, "Size": 5000, "SharedSize": -1}],
+	  "Volumes": [{"Name": "busy", "CreatedAt": %q, "UsageData": {"Size": 4000, "RefCount": 0}}]
+	}`, old.Unix(), old.Format(time.RFC3339)), nil)
+	now := time.Now()
+	q := &approvalQueue{Version: queueVersion, NextID: 1}
+	q.propose([]Record{
+		{Type: "docker", Path: "docker://image/aaaaaaaaaaaa", Size: 1},
+		{Type: "docker", Path: "docker://volume/busy", Size: 1},
+	}, &options{}, "ci", now)
+	q.decide([]string{"all"}, queueApproved, "alice", now)
+
+	runApproved(q, &options{}, false, false)
+	// The volume is advisory, never removed: it stays approved in the queue.
+	if len(q.Items) != 1 || q.Items[0].Path != "docker://volume/busy" || q.Items[0].Status != queueApproved {
+		t.Errorf("queue after run: %+v, want only the volume", q.Items)
+	}
+}
+
+func TestExecutorRemove_PinnedAtSafetyCheck(t *testing.T) {
+	base := realTempDir(t)
+	realProject := filepath.Join(base, "real")
+	os.MkdirAll(filepath.Join(realProject, "node_modules", "pkg"), 0755)
+	os.Symlink(realProject, filepath.Join(base, "link"))
+	x := testExecutor()
+
+	// A record found through a symlinked directory is removed where it
+	// resolved to when checked.
+	r := pinRecord(Record{Type: "node_modules", Path: filepath.Join(base, "link", "node_modules")})
+	if r.pinned != filepath.Join(realProject, "node_modules") {
+		t.Fatalf("pinned = %q", r.pinned)
+	}
+	if !x.remove(r) {
+		t.Fatal("remove failed")
+	}
+	if _, err := os.Lstat(filepath.Join(realProject, "node_modules")); !os.IsNotExist(err) {
+		t.Error("node_modules not removed")
+	}
+
+	// Something else moved into its place after the check stays.
+	target := filepath.Join(realProject, "build")
+	os.MkdirAll(target, 0755)
+	r = pinRecord(Record{Type: "build", Path: target})
+	os.Rename(target, filepath.Join(base, "moved"))
+	os.MkdirAll(filepath.Join(target, "precious"), 0755)
+	if x.remove(r) {
+		t.Error("removed a directory that replaced the checked one")
+	}
+	if _, err := os.Stat(filepath.Join(target, "precious")); err != nil {
+		t.Error("replacement directory was removed")
+	}
+}
+
+func TestRemoveHelper_OnlyApprovedPaths(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	wd, _ := os.Getwd()
+	sandbox, err := os.MkdirTemp(wd, "tidyup-helper-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(sandbox) })
+	approvedDir := filepath.Join(sandbox, "a", "node_modules")
+	otherDir := filepath.Join(sandbox, "b", "node_modules")
+	os.MkdirAll(approvedDir, 0755)
+	os.MkdirAll(otherDir, 0755)
+	// -tmp-delete: an entry directly inside a temp root, protected as it is.
+	tmpEntry, err := os.MkdirTemp("/tmp", "tidyup-helper-")
+	if err != nil {
+		t.Skipf("no /tmp: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(tmpEntry) })
+
+	reqR, reqW := io.Pipe()
+	repR, repW := io.Pipe()
+	go func() {
+		serveHelper(reqR, repW)
+		repW.Close()
+	}()
+	h := newRemoveHelper(reqW, repR)
+	if err := h.enc.Encode(helperApproval{
+		Approved: []string{approvedDir, "/tmp/x/node_modules", sandbox + "/a/../b/node_modules", tmpEntry},
+		Tmp:      []string{"/tmp/x/node_modules", tmpEntry}, // only a direct entry is exempt
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path, wantErr string
+	}{
+		{otherDir, "not approved"},
+		{"/tmp/x/node_modules", "protected"},
+		{sandbox + "/a/../b/node_modules", "not a clean absolute path"},
+		{approvedDir, ""},
+		{tmpEntry, ""},
+	}
+	for _, tt := range tests {
+		err := h.remove(tt.path, false)
+		if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("remove(%s) = %v, want %q", tt.path, err, tt.wantErr)
+		}
+	}
+	h.close()
+	if _, err := os.Stat(approvedDir); !os.IsNotExist(err) {
+		t.Error("approved path not removed")
+	}
+	if _, err := os.Stat(tmpEntry); !os.IsNotExist(err) {
+		t.Error("approved tmp entry not removed")
+	}
+	if _, err := os.Stat(otherDir); err != nil {
+		t.Error("unapproved path removed")
+	}
+}
+
+func TestExecutorRemove_Paced(t *testing.T) {
+	ioThrottle.Store(newThrottle(1000))
+	t.Cleanup(func() { ioThrottle.Store(nil) })
+
+	dir := filepath.Join(t.TempDir(), "node_modules")
+	os.MkdirAll(filepath.Join(dir, "a", "b"), 0755)
+	os.WriteFile(filepath.Join(dir, "a", "b", "index.js"), []byte("x"), 0644)
+	os.Symlink(filepath.Join(dir, "a"), filepath.Join(dir, "link"))
+
+	if !testExecutor().remove(Record{Type: "node_modules", Path: dir}) {
+		t.Fatal("remove failed")
+	}
+	if _, err := os.Lstat(dir); !os.IsNotExist(err) {
+		t.Errorf("%s still exists (err %v)", dir, err)
+	}
+}
+
+func TestRunRestore_FromTrash(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	dir := t.TempDir()
+	trash := filepath.Join(dir, "Trash")
+	os.Mkdir(trash, 0755)
+	now := time.Now()
+	for _, name := range []string{"foo", "bar"} {
+		in := filepath.Join(trash, name+"-venv")
+		os.MkdirAll(in, 0755)
+		os.WriteFile(filepath.Join(in, "marker"), []byte(name), 0644)
+		recordTrashed(Record{Type: "venv", Path: filepath.Join(dir, "dev", name, ".venv")}, in, now)
+	}
+	// foo has been recreated since.
+	os.MkdirAll(filepath.Join(dir, "dev", "foo", ".venv"), 0755)
+
+	if code := runRestore(nil); code != exitError {
+		t.Errorf("no filter: exit %d, want %d", code, exitError)
+	}
+	if code := runRestore([]string{"-type", "venv", "-project", filepath.Join(dir, "dev", "foo")}); code != exitOK {
+		t.Fatalf("exit %d", code)
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, "dev", "foo", ".venv.restored-*", "marker"))
+	if len(matches) != 1 {
+		t.Fatalf("foo not restored beside the recreated venv: %v", matches)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "dev", "bar", ".venv")); err == nil {
+		t.Error("bar restored despite -project")
+	}
+
+	path, _ := trashedPath()
+	items, _ := loadTrashed(path)
+	if len(items) != 1 || !strings.HasSuffix(items[0].Trash, "bar-venv") {
+		t.Errorf("manifest after restore = %+v", items)
+	}
+}
+
+func TestServe_DeleteNeedsTokenAndConfirmation(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a", ".venv"), filepath.Join(dir, "b", ".venv")
+	os.MkdirAll(a, 0755)
+	os.MkdirAll(b, 0755)
+	s, deleted := testServer(t,
+		Record{Type: "venv", Path: a, Size: 300},
+		Record{Type: "venv", Path: b, Size: 100, Advisory: true},
+	)
+	form := url.Values{"path": {a, b, "/etc"}}
+
+	if rec := post(s, "/delete", form); rec.Code != http.StatusForbidden {
+		t.Errorf("delete without token: status %d, want 403", rec.Code)
+	}
+
+	form.Set("token", s.token)
+	rec := post(s, "/delete", form)
+	if !strings.Contains(rec.Body.String(), "Delete 1 items") || len(*deleted) != 0 {
+		t.Errorf("first POST should only ask for confirmation:\n%s", rec.Body.String())
+	}
+
+	// Only the scanned, deletable record is acted on.
+	form.Set("confirm", "yes")
+	post(s, "/delete", form)
+	if len(*deleted) != 1 || (*deleted)[0].Path != a {
+		t.Errorf("deleted %+v; want only %s", *deleted, a)
+	}
+}
+
+func TestServe_SkipsWhatWasUsedSinceTheScan(t *testing.T) {
+	dir := t.TempDir()
+	venv := filepath.Join(dir, ".venv")
+	makeVenv(t, venv, time.Now().AddDate(0, 0, -90))
+	s, deleted := testServer(t, Record{Type: "venv", Path: venv, Size: 1})
+	s.scanned = time.Now().Add(-time.Hour)
+	os.Chtimes(filepath.Join(venv, "pyvenv.cfg"), time.Now(), time.Now())
+
+	if removed := s.remove(s.selected([]string{venv})); len(removed) != 0 || len(*deleted) != 0 {
+		t.Errorf("removed %v, deleted %+v; want the venv used since the scan skipped", removed, *deleted)
+	}
+}
+
+func TestEmptyTrash_OnlyOldTidyupItems(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	trash := t.TempDir()
+	old, recent, foreign := filepath.Join(trash, "old"), filepath.Join(trash, "recent"), filepath.Join(trash, "foreign")
+	for _, p := range []string{old, recent, foreign} {
+		os.Mkdir(p, 0755)
+	}
+	now := time.Now()
+	recordTrashed(Record{Path: "/src/old", DiskSize: 4096}, old, now.Add(-10*24*time.Hour))
+	recordTrashed(Record{Path: "/src/recent", Size: 100}, recent, now.Add(-time.Hour))
+	recordTrashed(Record{Path: "/src/emptied"}, filepath.Join(trash, "emptied"), now.Add(-30*24*time.Hour))
+
+	count, freed, err := testExecutor().emptyTrash(7*24*time.Hour, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 || freed != 4096 {
+		t.Errorf("emptied %d items, %d bytes; want 1, 4096", count, freed)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Error("item trashed 10 days ago survived -empty-trash-after 7d")
+	}
+	for _, p := range []string{recent, foreign} {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("%s was removed: %v", p, err)
+		}
+	}
+
+	path, _ := trashedPath()
+	items, _ := loadTrashed(path)
+	if len(items) != 1 || items[0].Trash != recent {
+		t.Errorf("manifest after emptying = %+v, want only the recent item", items)
+	}
+}
+
+func TestMoveToTrash_FinderThenFallback(t *testing.T) {
+	orig := useFinderTrash
+	t.Cleanup(func() { useFinderTrash = orig })
+	useFinderTrash = true
+	x := testExecutor()
+	home := t.TempDir()
+	os.Mkdir(filepath.Join(home, ".Trash"), 0755)
+	t.Setenv("HOME", home)
+
+	src := filepath.Join(t.TempDir(), "node_modules")
+	os.Mkdir(src, 0755)
+	x.rm.finderTrash = func(path string) (string, error) {
+		return "/Volumes/Ext/.Trashes/501/node_modules", nil
+	}
+	if dest, err := x.moveToTrash(src); err != nil || dest != "/Volumes/Ext/.Trashes/501/node_modules" {
+		t.Errorf("Finder trash: dest %q, err %v", dest, err)
+	}
+	if _, err := os.Stat(src); err != nil {
+		t.Error("Finder succeeded, but the item was also moved by rename")
+	}
+
+	x.rm.finderTrash = func(path string) (string, error) {
+		return "", errors.New("not authorized to send Apple events to Finder")
+	}
+	dest, err := x.moveToTrash(src)
+	if err != nil || dest != filepath.Join(home, ".Trash", "node_modules") {
+		t.Errorf("fallback: dest %q, err %v", dest, err)
+	}
+	if _, err := os.Stat(dest); err != nil {
+		t.Errorf("fallback did not move into ~/.Trash: %v", err)
+	}
+}
+
+func TestMoveToTrash_FileCollisionKeepsExtension(t *testing.T) {
+	orig := useFinderTrash
+	t.Cleanup(func() { useFinderTrash = orig })
+	useFinderTrash = false
+	home := t.TempDir()
+	os.MkdirAll(filepath.Join(home, ".Trash", "Setup.dmg"), 0755)
+	t.Setenv("HOME", home)
+
+	src := filepath.Join(t.TempDir(), "Setup.dmg")
+	os.WriteFile(src, []byte("img"), 0644)
+	dest, err := testExecutor().moveToTrash(src)
+	if err != nil || !strings.HasPrefix(filepath.Base(dest), "Setup_") || filepath.Ext(dest) != ".dmg" {
+		t.Errorf("dest %q, err %v", dest, err)
+	}
+}
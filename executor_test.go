@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewExecutor_Readonly(t *testing.T) {
+	if _, err := newExecutor(&options{readonly: true}, nil); err == nil {
+		t.Error("newExecutor under -readonly should refuse")
+	}
+	x, err := newExecutor(&options{}, nil)
+	if readonlyBuild {
+		if err == nil {
+			t.Error("newExecutor in a readonly build should refuse")
+		}
+		return
+	}
+	if err != nil || x == nil {
+		t.Fatalf("newExecutor: %v", err)
+	}
+}
+
+func TestDeleteRecords_ReadonlyRemovesNothing(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	saved, savedRemovers := mountTableFunc, removersFunc
+	t.Cleanup(func() { mountTableFunc, removersFunc = saved, savedRemovers })
+	mountTableFunc = func() ([]string, bool) { return nil, true }
+	removersFunc = func() removers {
+		rm := savedRemovers()
+		rm.removeAll = func(path string) error {
+			t.Errorf("read-only run removed %s", path)
+			return nil
+		}
+		return rm
+	}
+
+	opts := &options{confirm: true, readonly: true}
+	if code := deleteRecords([]Record{{Type: "node_modules", Path: "/srv/app/node_modules"}}, opts); code != exitError {
+		t.Errorf("deleteRecords = %d, want exitError", code)
+	}
+}
+
+func TestRunEmptyTrash_Readonly(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	item := filepath.Join(t.TempDir(), "old")
+	os.Mkdir(item, 0755)
+	recordTrashed(Record{Path: "/src/old"}, item, time.Now().AddDate(0, 0, -30))
+
+	runEmptyTrash(&options{readonly: true, emptyTrashAfter: 24 * time.Hour})
+	if _, err := os.Stat(item); err != nil {
+		t.Errorf("-readonly emptied the Trash: %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// Decisions users make about flagged items are the only ground truth for the
+// staleness heuristics: an item someone deliberately keeps, or deletes and
+// then brings back, was a false positive. They are appended to
+// decisions.jsonl in the state directory and summarized by `tidyup stats`.
+
+const (
+	decisionDeleted  = "deleted"  // removed (deleted, trashed, or delegated)
+	decisionKept     = "kept"     // shown in a selection but deliberately not selected
+	decisionRestored = "restored" // deleted earlier, found in use again
+)
+
+// decision is one line of decisions.jsonl.
+type decision struct {
+	Time       string  `json:"time"` // RFC3339
+	Decision   string  `json:"decision"`
+	Type       string  `json:"type"`
+	Path       string  `json:"path"`
+	AgeDays    float64 `json:"age_days"`
+	Confidence float64 `json:"confidence,omitempty"`
+}
+
+// decisionsPath is the decision log inside the state directory.
+func decisionsPath() (string, error) {
+	return statePath("decisions.jsonl")
+}
+
+// recordDecisions appends one decision per record. Failures are reported
+// but never affect the deletion they describe.
+func recordDecisions(kind string, records []Record, now time.Time) {
+	if len(records) == 0 {
+		return
+	}
+	path, err := decisionsPath()
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0700)
+	}
+	var f *os.File
+	if err == nil {
+		f, err = os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	}
+	if err != nil {
+		stderr.warnf("could not record decisions: %v", err)
+		return
+	}
+	defer f.Close()
+	enc := json.NewEncoder(f)
+	for _, r := range records {
+		enc.Encode(decision{
+			Time:       now.Format(time.RFC3339),
+			Decision:   kind,
+			Type:       r.Type,
+			Path:       r.Path,
+			AgeDays:    r.AgeDays,
+			Confidence: r.Confidence,
+		})
+	}
+}
+
+// loadDecisions reads the decision log, skipping malformed lines.
+func loadDecisions(path string) ([]decision, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var decisions []decision
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var d decision
+		if json.Unmarshal(sc.Bytes(), &d) == nil && d.Decision != "" {
+			decisions = append(decisions, d)
+		}
+	}
+	return decisions, sc.Err()
+}
+
+// detectRestores records a "restored" decision for every deleted item that
+// exists again and has been used since it was deleted -- restored from the
+// Trash or a backup, or recreated because it was still needed.
+func detectRestores(opts *options) {
+	path, err := decisionsPath()
+	if err != nil {
+		return
+	}
+	decisions, err := loadDecisions(path)
+	if err != nil {
+		return
+	}
+	// Only the latest decision per path matters.
+	latest := map[string]decision{}
+	for _, d := range decisions {
+		latest[d.Path] = d
+	}
+	var restored []Record
+	for p, d := range latest {
+		if d.Decision != decisionDeleted {
+			continue
+		}
+		deletedAt, err := time.Parse(time.RFC3339, d.Time)
+		if err != nil {
+			continue
+		}
+		if info, err := os.Stat(p); err == nil && info.ModTime().After(deletedAt) {
+			restored = append(restored, Record{Type: d.Type, Path: p, AgeDays: d.AgeDays, Confidence: d.Confidence})
+		}
+	}
+	sort.Slice(restored, func(i, j int) bool { return restored[i].Path < restored[j].Path })
+	recordDecisions(decisionRestored, restored, opts.currentTime())
+}
+
+// heuristicStats aggregates decisions for one detector.
+type heuristicStats struct {
+	deleted, kept, restored int
+	fpAges                  []float64 // ages of kept and restored items
+}
+
+// falsePositiveRate is the share of decided items the user disagreed with.
+func (h heuristicStats) falsePositiveRate() float64 {
+	if total := h.deleted + h.kept; total > 0 {
+		return float64(h.kept+h.restored) / float64(total)
+	}
+	return 0
+}
+
+// summarizeDecisions groups decisions by scan type.
+func summarizeDecisions(decisions []decision) map[string]*heuristicStats {
+	stats := map[string]*heuristicStats{}
+	for _, d := range decisions {
+		s := stats[d.Type]
+		if s == nil {
+			s = &heuristicStats{}
+			stats[d.Type] = s
+		}
+		switch d.Decision {
+		case decisionDeleted:
+			s.deleted++
+		case decisionKept:
+			s.kept++
+			s.fpAges = append(s.fpAges, d.AgeDays)
+		case decisionRestored:
+			s.restored++
+			s.fpAges = append(s.fpAges, d.AgeDays)
+		}
+	}
+	return stats
+}
+
+// printHeuristicStats writes the per-detector false-positive table.
+func printHeuristicStats(w io.Writer, stats map[string]*heuristicStats) {
+	if len(stats) == 0 {
+		fmt.Fprintln(w, "No decisions recorded yet. Keep or delete items with -delete to build up statistics.")
+		return
+	}
+	types := make([]string, 0, len(stats))
+	for t := range stats {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TYPE\tDELETED\tKEPT\tRESTORED\tFALSE POS.\tMEDIAN AGE OF FALSE POS.")
+	for _, t := range types {
+		s := stats[t]
+		median := "-"
+		if len(s.fpAges) > 0 {
+			sort.Float64s(s.fpAges)
+			median = fmt.Sprintf("%.0fd", s.fpAges[len(s.fpAges)/2])
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%.0f%%\t%s\n", t, s.deleted, s.kept, s.restored, 100*s.falsePositiveRate(), median)
+	}
+	tw.Flush()
+	fmt.Fprintln(w, "\nA high false-positive rate means -age is too low for that type; the median age is a starting point for a better threshold.")
+}
+
+// runStats implements `tidyup stats`.
+func runStats(args []string) int {
+	flags := flag.NewFlagSet("stats", flag.ContinueOnError)
+	heuristics := flags.Bool("heuristics", false, "Per-detector false-positive statistics from keep/delete/restore decisions")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tidyup stats -heuristics\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if !*heuristics || flags.NArg() != 0 {
+		flags.Usage()
+		return exitError
+	}
+
+	path, err := decisionsPath()
+	if err != nil {
+		stderr.errorf("%v", err)
+		return exitError
+	}
+	decisions, err := loadDecisions(path)
+	if err != nil && !os.IsNotExist(err) {
+		stderr.printf("Error reading %s: %v", path, err)
+		return exitError
+	}
+	printHeuristicStats(os.Stdout, summarizeDecisions(decisions))
+	return exitOK
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummarizeDecisions(t *testing.T) {
+	decisions := []decision{
+		{Decision: decisionDeleted, Type: "venv", AgeDays: 40},
+		{Decision: decisionDeleted, Type: "venv", AgeDays: 50},
+		{Decision: decisionDeleted, Type: "venv", AgeDays: 45},
+		{Decision: decisionKept, Type: "venv", AgeDays: 35},
+		{Decision: decisionRestored, Type: "venv", AgeDays: 45},
+		{Decision: decisionDeleted, Type: "pycache", AgeDays: 90},
+	}
+	stats := summarizeDecisions(decisions)
+	venv := stats["venv"]
+	if venv.deleted != 3 || venv.kept != 1 || venv.restored != 1 {
+		t.Fatalf("venv stats = %+v", venv)
+	}
+	if got := venv.falsePositiveRate(); got != 0.5 {
+		t.Errorf("false-positive rate = %v, want 0.5", got)
+	}
+	if got := stats["pycache"].falsePositiveRate(); got != 0 {
+		t.Errorf("pycache false-positive rate = %v, want 0", got)
+	}
+
+	var buf bytes.Buffer
+	printHeuristicStats(&buf, stats)
+	if !strings.Contains(buf.String(), "venv") || !strings.Contains(buf.String(), "50%") {
+		t.Errorf("stats table:\n%s", buf.String())
+	}
+}
+
+func TestDetectRestores(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	root := t.TempDir()
+	back := filepath.Join(root, "back", ".venv")
+	gone := filepath.Join(root, "gone", ".venv")
+	deletedAt := time.Now().Add(-48 * time.Hour)
+	recordDecisions(decisionDeleted, []Record{{Type: "venv", Path: back}, {Type: "venv", Path: gone}}, deletedAt)
+
+	os.MkdirAll(back, 0755) // recreated after the deletion
+	opts := &options{}
+	detectRestores(opts)
+	detectRestores(opts) // already recorded: no duplicate
+
+	path, _ := decisionsPath()
+	decisions, _ := loadDecisions(path)
+	var restored []string
+	for _, d := range decisions {
+		if d.Decision == decisionRestored {
+			restored = append(restored, d.Path)
+		}
+	}
+	if len(restored) != 1 || restored[0] != back {
+		t.Errorf("restored = %v, want only %s", restored, back)
+	}
+}
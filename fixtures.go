@@ -0,0 +1,206 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// fixtureSpec describes one synthetic artifact generated by `tidyup fixtures create`.
+type fixtureSpec struct {
+	Path    string // relative to the fixture root
+	Type    string // scan type the artifact should be detected as
+	AgeDays int    // days between last use and fixture creation
+	Payload int64  // bytes of filler content (markers add a little on top)
+	Decoy   bool   // looks like Type by name but must never be detected
+}
+
+// defaultFixtures is the synthetic tree: a stale and a fresh instance of each
+// type, plus decoys that exercise the false-positive guards.
+var defaultFixtures = []fixtureSpec{
+	{Path: "projects/alpha/.venv", Type: "venv", AgeDays: 120, Payload: 2 << 20},
+	{Path: "projects/beta/.venv", Type: "venv", AgeDays: 5, Payload: 1 << 20},
+	{Path: "projects/web/node_modules", Type: "node_modules", AgeDays: 90, Payload: 3 << 20},
+	{Path: "projects/web-new/node_modules", Type: "node_modules", AgeDays: 2, Payload: 1 << 20},
+	{Path: "projects/alpha/__pycache__", Type: "pycache", AgeDays: 60, Payload: 64 << 10},
+	{Path: "projects/alpha/.pytest_cache", Type: "pytest_cache", AgeDays: 45, Payload: 16 << 10},
+	{Path: "projects/alpha/.mypy_cache", Type: "mypy_cache", AgeDays: 200, Payload: 512 << 10},
+	{Path: "projects/beta/.ruff_cache", Type: "ruff_cache", AgeDays: 10, Payload: 8 << 10},
+	{Path: "projects/alpha/dist", Type: "dist", AgeDays: 75, Payload: 256 << 10},
+	{Path: "projects/alpha/build", Type: "build", AgeDays: 40, Payload: 128 << 10},
+	{Path: "home/.local/pipx/venvs/httpie", Type: "tools", AgeDays: 150, Payload: 256 << 10},
+	{Path: "home/.local/share/uv/tools/ruff", Type: "tools", AgeDays: 3, Payload: 128 << 10},
+	{Path: "home/miniconda3", Type: "conda", AgeDays: 400, Payload: 4 << 10, Decoy: true},
+	{Path: "home/miniconda3/envs/ml", Type: "conda", AgeDays: 180, Payload: 1 << 20},
+	{Path: "projects/alpha/.tox/py311", Type: "tox", AgeDays: 50, Payload: 512 << 10},
+	{Path: "projects/beta/.tox/lint", Type: "tox", AgeDays: 4, Payload: 256 << 10},
+	{Path: "projects/alpha/.nox/tests-3-11", Type: "nox", AgeDays: 70, Payload: 256 << 10},
+	{Path: "projects/rusty/target", Type: "cargo", AgeDays: 120, Payload: 2 << 20},
+	{Path: "projects/droid/build", Type: "gradle", AgeDays: 95, Payload: 1 << 20},
+	{Path: "projects/jvm/target", Type: "maven", AgeDays: 150, Payload: 1 << 20},
+	{Path: "notes/target", Type: "cargo", AgeDays: 400, Payload: 4 << 10, Decoy: true},
+	{Path: "notes/build", Type: "build", AgeDays: 400, Payload: 4 << 10, Decoy: true},
+	{Path: "notes/not-a-venv", Type: "venv", AgeDays: 400, Payload: 4 << 10, Decoy: true},
+}
+
+// fixtureProjectFiles are build-system markers written into project roots so
+// dist/ and build/ pass parent validation and node_modules have lockfiles.
+var fixtureProjectFiles = []string{
+	"projects/alpha/pyproject.toml",
+	"projects/beta/pyproject.toml",
+	"projects/rusty/Cargo.toml",
+	"projects/droid/build.gradle.kts",
+	"projects/jvm/pom.xml",
+	"projects/web/package.json",
+	"projects/web/package-lock.json",
+	"projects/web-new/package.json",
+}
+
+// writeFixtureFile writes size bytes of deterministic filler to path.
+func writeFixtureFile(path string, size int64) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	buf := make([]byte, size)
+	for i := range buf {
+		buf[i] = byte('a' + i%26)
+	}
+	return os.WriteFile(path, buf, 0644)
+}
+
+// fixtureFiles returns the relative files (and their sizes) that make a
+// directory look like the given type to the detectors.
+func fixtureFiles(spec fixtureSpec) map[string]int64 {
+	files := map[string]int64{}
+	switch spec.Type {
+	case "venv", "nox":
+		if spec.Decoy {
+			// pyvenv.cfg without bin/ is rejected by isValidVenv.
+			files["pyvenv.cfg"] = 32
+			files["data.bin"] = spec.Payload
+			return files
+		}
+		files["pyvenv.cfg"] = 32
+		files["bin/activate"] = 64
+		files["bin/python"] = 16
+		files["lib/python3.11/site-packages/fixturepkg/__init__.py"] = spec.Payload
+	case "tools":
+		name := filepath.Base(spec.Path)
+		files["pyvenv.cfg"] = 32
+		files["bin/activate"] = 64
+		files["bin/python"] = 16
+		files["bin/"+name] = 64
+		files["lib/python3.11/site-packages/"+name+"-1.0.0.dist-info/METADATA"] = 32
+		files["lib/python3.11/site-packages/"+name+"/__init__.py"] = spec.Payload
+	case "conda":
+		if spec.Decoy {
+			// A base installation: only its envs are reported.
+			files["conda-meta/history"] = 32
+			files["condabin/conda"] = 64
+			files["pkgs/payload.bin"] = spec.Payload
+			return files
+		}
+		files["conda-meta/history"] = 32
+		files["bin/python"] = 16
+		files["lib/python3.11/site-packages/fixturepkg/__init__.py"] = spec.Payload
+	case "tox":
+		files["pyvenv.cfg"] = 32
+		files["bin/python"] = 16
+		files["log/1-commands[0].log"] = 64
+		files["lib/python3.11/site-packages/fixturepkg/__init__.py"] = spec.Payload
+	case "node_modules":
+		files[".package-lock.json"] = 16
+		files["fixturepkg/index.js"] = spec.Payload
+	case "gradle":
+		files["libs/droid.jar"] = spec.Payload
+		files["tmp/jar/MANIFEST.MF"] = 16
+	case "maven":
+		files["jvm-1.0.jar"] = spec.Payload
+		files["classes/App.class"] = 64
+	case "cargo":
+		files["debug/rusty"] = spec.Payload
+		files["debug/.fingerprint/rusty-0123/bin-rusty"] = 16
+	case "pycache":
+		files["module.cpython-311.pyc"] = spec.Payload
+	default:
+		files["payload.bin"] = spec.Payload
+	}
+	return files
+}
+
+// createFixtures builds the synthetic tree under root, backdating every file
+// and directory of each artifact to now minus its AgeDays.
+func createFixtures(root string, now time.Time) error {
+	for _, f := range fixtureProjectFiles {
+		if err := writeFixtureFile(filepath.Join(root, f), 16); err != nil {
+			return err
+		}
+	}
+
+	for _, spec := range defaultFixtures {
+		dir := filepath.Join(root, spec.Path)
+		for rel, size := range fixtureFiles(spec) {
+			if err := writeFixtureFile(filepath.Join(dir, rel), size); err != nil {
+				return err
+			}
+		}
+
+		mtime := now.AddDate(0, 0, -spec.AgeDays)
+		err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			return os.Chtimes(p, mtime, mtime)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runFixtures implements `tidyup fixtures create <dir>`.
+func runFixtures(args []string) int {
+	flags := flag.NewFlagSet("fixtures", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tidyup fixtures create <dir>\n\n")
+		fmt.Fprintf(os.Stderr, "Generates a synthetic tree of stale and fresh venvs, node_modules,\n")
+		fmt.Fprintf(os.Stderr, "caches, and build dirs for safely trying out scan and delete flows.\n")
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if flags.NArg() != 2 || flags.Arg(0) != "create" {
+		flags.Usage()
+		return exitError
+	}
+
+	root, err := filepath.Abs(flags.Arg(1))
+	if err != nil {
+		stderr.errorf("%v", err)
+		return exitError
+	}
+	// Refuse to mix fixtures into an existing tree.
+	if entries, err := os.ReadDir(root); err == nil && len(entries) > 0 {
+		stderr.errorf("%s is not empty", root)
+		return exitError
+	}
+
+	if err := createFixtures(root, time.Now()); err != nil {
+		stderr.printf("Error creating fixtures: %v", err)
+		return exitError
+	}
+
+	for _, spec := range defaultFixtures {
+		label := spec.Type
+		if spec.Decoy {
+			label = "decoy " + spec.Type
+		}
+		fmt.Printf("  %-18s %4dd old  %s\n", "["+label+"]", spec.AgeDays, filepath.Join(root, spec.Path))
+	}
+	fmt.Printf("\nCreated %d fixtures in %s\nTry: tidyup -all -delete -dry-run %s\n", len(defaultFixtures), root, root)
+	return exitOK
+}
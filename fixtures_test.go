@@ -0,0 +1,54 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCreateFixtures_ScanFindsStaleOnly(t *testing.T) {
+	root := t.TempDir()
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	if err := createFixtures(root, now); err != nil {
+		t.Fatalf("createFixtures: %v", err)
+	}
+
+	scanTypes, _ := parseScanTypes("", true)
+	opts := &options{
+		minAge:    30,
+		maxDepth:  5,
+		scanTypes: scanTypes,
+		now:       func() time.Time { return now },
+	}
+	records, errs := scanRoots([]string{root}, opts)
+	if len(errs) != 0 {
+		t.Fatalf("unexpected scan errors: %v", errs)
+	}
+
+	found := make(map[string]Record)
+	for _, r := range records {
+		found[r.Path] = r
+	}
+
+	for _, spec := range defaultFixtures {
+		p := filepath.Join(root, spec.Path)
+		r, ok := found[p]
+		wantFound := !spec.Decoy && spec.AgeDays >= opts.minAge
+		if ok != wantFound {
+			t.Errorf("%s: found=%v, want %v", spec.Path, ok, wantFound)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if r.Type != spec.Type {
+			t.Errorf("%s: type %q, want %q", spec.Path, r.Type, spec.Type)
+		}
+		if r.AgeDays != float64(spec.AgeDays) {
+			t.Errorf("%s: age %v, want %d", spec.Path, r.AgeDays, spec.AgeDays)
+		}
+		if r.Size < spec.Payload {
+			t.Errorf("%s: size %d smaller than payload %d", spec.Path, r.Size, spec.Payload)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// jvmArtifacts are the extensions of what Gradle and Maven build and
+// download: archives, class files, and dependency metadata. Lock files,
+// journals, and the incremental-build state next to them change on every
+// build, the artifacts only when something was built or fetched.
+var jvmArtifacts = map[string]bool{
+	".jar": true, ".aar": true, ".apk": true, ".aab": true, ".war": true, ".ear": true,
+	".class": true, ".dex": true, ".pom": true, ".module": true,
+}
+
+// isGradleBuild reports whether path is the build/ of a Gradle project, one
+// with a build.gradle or build.gradle.kts beside it.
+func isGradleBuild(path string) bool {
+	if filepath.Base(path) != "build" {
+		return false
+	}
+	for _, script := range []string{"build.gradle", "build.gradle.kts"} {
+		if _, err := os.Stat(filepath.Join(filepath.Dir(path), script)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// isMavenTarget reports whether path is the target/ of a Maven project, one
+// with a pom.xml beside it.
+func isMavenTarget(path string) bool {
+	if filepath.Base(path) != "target" {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(filepath.Dir(path), "pom.xml"))
+	return err == nil && info.Mode().IsRegular()
+}
+
+// findGradleCaches returns the dependency and build caches under
+// $GRADLE_USER_HOME (default ~/.gradle).
+func findGradleCaches(home string) []string {
+	return existingDirs(filepath.Join(gradleUserHome(home), "caches"))
+}
+
+// gradleUserHome is $GRADLE_USER_HOME, or ~/.gradle.
+func gradleUserHome(home string) string {
+	if dir := os.Getenv("GRADLE_USER_HOME"); dir != "" {
+		return dir
+	}
+	return filepath.Join(home, ".gradle")
+}
+
+// getJVMBuildUsage dates a Gradle build/ or cache, or a Maven target/, by
+// its newest artifact, falling back to its newest file where it holds none.
+func getJVMBuildUsage(path string) (time.Time, bool) {
+	var latest time.Time
+	_ = walkTree(path, func(p string, d fs.DirEntry, err error) error {
+		pace()
+		if err != nil || d.IsDir() || !jvmArtifacts[strings.ToLower(filepath.Ext(p))] {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+		return nil
+	})
+	if !latest.IsZero() {
+		return latest, true
+	}
+	return getCacheUsage(path)
+}
+
+// annotateGradle notes what removing the shared cache costs.
+func annotateGradle(r *Record) {
+	if filepath.Base(r.Path) == "caches" {
+		r.Notes = append(r.Notes, "shared by all Gradle projects; the next build of each downloads its dependencies again")
+	}
+}
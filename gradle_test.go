@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestScanRoots_GradleBuild(t *testing.T) {
+	root := t.TempDir()
+	old := time.Now().AddDate(0, 0, -90)
+	write := func(rel string, mtime time.Time) {
+		p := filepath.Join(root, rel)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte("x"), 0644)
+		os.Chtimes(p, mtime, mtime)
+	}
+	write("app/build.gradle.kts", old)
+	write("app/build/libs/app.jar", old)
+	write("app/build/kotlin/compileKotlin/cacheable/last-build.bin", time.Now().AddDate(0, 0, -1))
+	write("docs/build/index.html", old) // no build script: not Gradle's
+
+	build := filepath.Join(root, "app", "build")
+	if got, ok := getJVMBuildUsage(build); !ok || !got.Equal(old) {
+		t.Errorf("getJVMBuildUsage = %v, %v; want the jar's mtime, not the cache file's", got, ok)
+	}
+	opts := &options{minAge: 30, maxDepth: 5, scanTypes: map[string]bool{"gradle": true, "build": true}}
+	records, _ := scanRoots([]string{root}, opts)
+	if len(records) != 1 || records[0].Path != build || records[0].Type != "gradle" {
+		t.Errorf("got %+v, want only %s as gradle", records, build)
+	}
+}
+
+func TestFindGradleCaches(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("GRADLE_USER_HOME", "")
+	if got := findGradleCaches(home); len(got) != 0 {
+		t.Errorf("no ~/.gradle: got %v", got)
+	}
+	os.MkdirAll(filepath.Join(home, ".gradle", "caches", "modules-2"), 0755)
+	if got := findGradleCaches(home); len(got) != 1 || got[0] != filepath.Join(home, ".gradle", "caches") {
+		t.Errorf("got %v, want ~/.gradle/caches", got)
+	}
+	custom := t.TempDir()
+	os.MkdirAll(filepath.Join(custom, "caches"), 0755)
+	t.Setenv("GRADLE_USER_HOME", custom)
+	if got := findGradleCaches(home); len(got) != 1 || got[0] != filepath.Join(custom, "caches") {
+		t.Errorf("$GRADLE_USER_HOME: got %v", got)
+	}
+}
+
+func TestScanRoots_MavenTarget(t *testing.T) {
+	root := t.TempDir()
+	old := time.Now().AddDate(0, 0, -90)
+	for _, rel := range []string{"svc/pom.xml", "svc/target/classes/App.class", "svc/target/svc-1.0.jar", "site/target/index.html"} {
+		p := filepath.Join(root, rel)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte("x"), 0644)
+		os.Chtimes(p, old, old)
+	}
+	target := filepath.Join(root, "svc", "target")
+	opts := &options{minAge: 30, maxDepth: 5, scanTypes: map[string]bool{"maven": true, "cargo": true}}
+	records, _ := scanRoots([]string{root}, opts)
+	if len(records) != 1 || records[0].Path != target || records[0].Type != "maven" {
+		t.Errorf("got %+v, want only %s as maven", records, target)
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// With -sandbox, the executor does not remove records itself: it starts a
+// helper process (`tidyup remove-helper`, not listed in the usage) and hands
+// it the approved paths up front. The helper removes nothing else: every
+// later request must name one of those paths exactly, clean and absolute,
+// and pass the protected-path and own-state guards again (with the same
+// exemption for -tmp-delete's entries as the main process); the removal
+// itself goes through removeBeneath, which does not follow symlinks on the
+// way to the target or inside it. A path-handling bug in the main process
+// can then at worst fail a removal, not widen one.
+//
+// The protocol is JSON lines on the helper's stdin and stdout: first
+// {"approved": [...], "tmp": [...], "nice": ...}, then one {"path": ..., "file": ...} per removal,
+// each answered by {"error": ...} ("" on success).
+
+// helperApproval is the helper's first input line.
+type helperApproval struct {
+	Approved []string `json:"approved"`
+	Tmp      []string `json:"tmp,omitempty"`  // approved paths that are tmp records
+	Nice     bool     `json:"nice,omitempty"` // -nice: pace the removals
+}
+
+// helperRequest asks the helper to remove one approved path.
+type helperRequest struct {
+	Path string `json:"path"`
+	File bool   `json:"file,omitempty"`
+}
+
+// helperReply answers one helperRequest.
+type helperReply struct {
+	Error string `json:"error"`
+}
+
+// runRemoveHelper implements the hidden `tidyup remove-helper` subcommand.
+func runRemoveHelper(args []string) int {
+	if len(args) != 0 {
+		stderr.errorf("remove-helper takes no arguments; it is started by -sandbox")
+		return exitError
+	}
+	// Nothing the helper does depends on the working directory.
+	os.Chdir("/")
+	return serveHelper(os.Stdin, os.Stdout)
+}
+
+// serveHelper answers removal requests from r on w.
+func serveHelper(r io.Reader, w io.Writer) int {
+	dec := json.NewDecoder(bufio.NewReader(r))
+	enc := json.NewEncoder(w)
+	var approval helperApproval
+	if err := dec.Decode(&approval); err != nil {
+		stderr.errorf("remove-helper: reading approved paths: %v", err)
+		return exitError
+	}
+	approved, tmp := map[string]bool{}, map[string]bool{}
+	for _, p := range approval.Approved {
+		approved[p] = true
+	}
+	for _, p := range approval.Tmp {
+		tmp[p] = true
+	}
+	setNice(approval.Nice)
+	own := ownStateDirs()
+	x, xerr := newExecutor(&options{}, nil)
+	for {
+		var req helperRequest
+		if err := dec.Decode(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				return exitOK
+			}
+			stderr.errorf("remove-helper: %v", err)
+			return exitError
+		}
+		var reply helperReply
+		if err := checkHelperScope(req.Path, approved, tmp, own); err != nil {
+			reply.Error = err.Error()
+		} else if xerr != nil {
+			reply.Error = xerr.Error()
+		} else if err := x.removeBeneath(req.Path, req.File); err != nil {
+			reply.Error = err.Error()
+		}
+		if err := enc.Encode(reply); err != nil {
+			return exitError
+		}
+	}
+}
+
+// checkHelperScope refuses anything but an approved, clean, absolute path
+// outside the protected locations and tidyup's own state. A tmp record
+// directly inside a temp root is exempt from the protected locations, as in
+// safetyCheck.
+func checkHelperScope(path string, approved, tmp map[string]bool, own []string) error {
+	switch {
+	case !approved[path]:
+		return fmt.Errorf("%s was not approved for removal", path)
+	case !filepath.IsAbs(path) || filepath.Clean(path) != path || filepath.Dir(path) == path:
+		return fmt.Errorf("%q is not a clean absolute path", path)
+	case isProtectedPath(path) && !(tmp[path] && isTmpEntry(Record{Type: "tmp", Path: path})):
+		return fmt.Errorf("%s is a protected path", path)
+	case ownStateConflict(path, own) != "":
+		return fmt.Errorf("%s holds or is inside tidyup's own state", path)
+	}
+	return nil
+}
+
+// helperCommand returns the command that runs the helper; a seam for tests.
+var helperCommand = func() (*exec.Cmd, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	return exec.Command(exe, "remove-helper"), nil
+}
+
+// removeHelper is the main process's end of a running helper.
+type removeHelper struct {
+	cmd *exec.Cmd // nil when not a child process (tests)
+	in  io.WriteCloser
+	enc *json.Encoder
+	dec *json.Decoder
+}
+
+// startRemoveHelper starts a helper allowed to remove exactly the paths
+// approval names.
+func startRemoveHelper(approval helperApproval) (*removeHelper, error) {
+	cmd, err := helperCommand()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stderr = os.Stderr
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting the removal helper: %w", err)
+	}
+	h := newRemoveHelper(in, out)
+	h.cmd = cmd
+	if err := h.enc.Encode(approval); err != nil {
+		h.close()
+		return nil, fmt.Errorf("starting the removal helper: %w", err)
+	}
+	return h, nil
+}
+
+func newRemoveHelper(in io.WriteCloser, out io.Reader) *removeHelper {
+	return &removeHelper{in: in, enc: json.NewEncoder(in), dec: json.NewDecoder(out)}
+}
+
+// remove has the helper remove path, a file when file is set.
+func (h *removeHelper) remove(path string, file bool) error {
+	if err := h.enc.Encode(helperRequest{Path: path, File: file}); err != nil {
+		return fmt.Errorf("removal helper: %w", err)
+	}
+	var reply helperReply
+	if err := h.dec.Decode(&reply); err != nil {
+		return fmt.Errorf("removal helper: %w", err)
+	}
+	if reply.Error != "" {
+		return errors.New(reply.Error)
+	}
+	return nil
+}
+
+// close ends the helper and waits for it.
+func (h *removeHelper) close() error {
+	h.in.Close()
+	if h.cmd == nil {
+		return nil
+	}
+	return h.cmd.Wait()
+}
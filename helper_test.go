@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// realTempDir is t.TempDir with symlinks resolved (macOS's /var is one).
+func realTempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestRemoveBeneath_DoesNotFollowSymlinks(t *testing.T) {
+	base := realTempDir(t)
+	outside := filepath.Join(base, "outside")
+	os.MkdirAll(outside, 0755)
+	os.WriteFile(filepath.Join(outside, "keep.txt"), []byte("x"), 0644)
+
+	tree := filepath.Join(base, "proj", "node_modules")
+	os.MkdirAll(filepath.Join(tree, "a", "b"), 0755)
+	os.WriteFile(filepath.Join(tree, "a", "b", "index.js"), []byte("x"), 0644)
+	os.Symlink(outside, filepath.Join(tree, "a", "escape"))
+	// More entries than one directory read returns.
+	for i := 0; i < 600; i++ {
+		os.WriteFile(filepath.Join(tree, "a", fmt.Sprintf("f%03d", i)), nil, 0644)
+	}
+
+	if err := removeBeneath(tree, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(tree); !os.IsNotExist(err) {
+		t.Errorf("%s still exists (err %v)", tree, err)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "keep.txt")); err != nil {
+		t.Error("removal followed a symlink out of the tree")
+	}
+	// Gone already: nothing left to do.
+	if err := removeBeneath(tree, false); err != nil {
+		t.Errorf("removing a missing tree: %v", err)
+	}
+}
+
+// Under -nice the removal is paced entry by entry, still through
+// descriptors: a symlink inside the tree is removed, never followed.
+func TestRemoveBeneath_PacedDoesNotFollowSymlinks(t *testing.T) {
+	ioThrottle.Store(newThrottle(10000))
+	t.Cleanup(func() { ioThrottle.Store(nil) })
+	base := realTempDir(t)
+	outside := filepath.Join(base, "outside")
+	os.MkdirAll(outside, 0755)
+	os.WriteFile(filepath.Join(outside, "keep.txt"), []byte("x"), 0644)
+	tree := filepath.Join(base, "proj", "node_modules")
+	os.MkdirAll(filepath.Join(tree, "a"), 0755)
+	os.WriteFile(filepath.Join(tree, "a", "index.js"), []byte("x"), 0644)
+	os.Symlink(outside, filepath.Join(tree, "a", "escape"))
+
+	if err := removeBeneath(tree, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(tree); !os.IsNotExist(err) {
+		t.Errorf("%s still exists (err %v)", tree, err)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "keep.txt")); err != nil {
+		t.Error("paced removal followed a symlink out of the tree")
+	}
+}
+
+func TestRemoveBeneath_RefusesSymlinkedParent(t *testing.T) {
+	base := realTempDir(t)
+	target := filepath.Join(base, "real")
+	os.MkdirAll(filepath.Join(target, "node_modules"), 0755)
+	os.Symlink(target, filepath.Join(base, "swapped"))
+
+	if err := removeBeneath(filepath.Join(base, "swapped", "node_modules"), false); err == nil {
+		t.Error("removal through a symlinked parent should fail")
+	}
+	if _, err := os.Stat(filepath.Join(target, "node_modules")); err != nil {
+		t.Error("removal went through the symlink")
+	}
+	if err := removeBeneath("relative/node_modules", false); err == nil {
+		t.Error("relative path accepted")
+	}
+}
+
+func TestHelperApprovalFor(t *testing.T) {
+	tmpEntry := filepath.Join("/tmp", "tidyup-build-1234")
+	records := []Record{
+		{Type: "node_modules", Path: "/home/u/p/node_modules"},
+		{Type: "tmp", Path: tmpEntry},
+	}
+	approval := helperApprovalFor(records)
+	if len(approval.Approved) != 2 || len(approval.Tmp) != 1 || approval.Tmp[0] != tmpEntry || approval.Nice {
+		t.Errorf("approval = %+v, want both paths, the tmp entry marked, not nice", approval)
+	}
+	ioThrottle.Store(newThrottle(niceOpsPerSec))
+	t.Cleanup(func() { ioThrottle.Store(nil) })
+	if !helperApprovalFor(records).Nice {
+		t.Error("-nice not passed to the helper")
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Each type dates an item from its own markers (see getVenvActivity and
+// friends). heuristics.json in the config directory recomposes that per
+// type: which signals count, and how they combine --
+//
+//	{
+//	  "venv": {"signals": ["markers", "site-packages", "git"], "combine": "max"},
+//	  "node_modules": {"signals": ["markers", "atime"], "combine": "weighted",
+//	                   "weights": {"markers": 3, "atime": 1}},
+//	  "*": {"signals": ["markers", "lockfiles"]}
+//	}
+//
+// "max" (the default) takes the most recent signal; "weighted" averages the
+// signals' times by weight, so one noisy signal cannot make an item look
+// fresh on its own. "*" applies to types without their own entry. Types
+// without a rule keep the built-in behavior.
+
+// heuristicSignals date an item at path; markers is the type's built-in
+// usage function.
+var heuristicSignals = map[string]func(s *scanner, path string, markers usageFunc) (time.Time, bool){
+	"markers": func(s *scanner, path string, markers usageFunc) (time.Time, bool) {
+		return markers(path)
+	},
+	"site-packages": func(s *scanner, path string, markers usageFunc) (time.Time, bool) {
+		return getSitePackagesUsage(path)
+	},
+	"lockfiles": func(s *scanner, path string, markers usageFunc) (time.Time, bool) {
+		return projectLockfileTime(projectDir(path))
+	},
+	"git": func(s *scanner, path string, markers usageFunc) (time.Time, bool) {
+		return s.evidence.projectCommit(path)
+	},
+	"atime": func(s *scanner, path string, markers usageFunc) (time.Time, bool) {
+		return accessTime(path)
+	},
+	"spotlight": func(s *scanner, path string, markers usageFunc) (time.Time, bool) {
+		return spotlightLastUsed(path)
+	},
+}
+
+// heuristicRule is one type's entry in heuristics.json.
+type heuristicRule struct {
+	Signals []string           `json:"signals"`
+	Combine string             `json:"combine,omitempty"` // "max" (default) or "weighted"
+	Weights map[string]float64 `json:"weights,omitempty"` // per signal, default 1
+}
+
+// heuristicsConfig maps type names, or "*", to rules.
+type heuristicsConfig map[string]heuristicRule
+
+// heuristicsPath is where the heuristics configuration lives.
+func heuristicsPath() (string, error) {
+	dir, err := configDir()
+	return filepath.Join(dir, "heuristics.json"), err
+}
+
+// loadHeuristics reads and checks heuristics.json; a missing file is no
+// configuration.
+func loadHeuristics() (heuristicsConfig, error) {
+	path, err := heuristicsPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cfg heuristicsConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if err := cfg.check(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// check rejects unknown types, signals, and combine modes.
+func (cfg heuristicsConfig) check() error {
+	types := nameSet(allScanTypes)
+	var keys []string
+	for k := range cfg {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, typeName := range keys {
+		rule := cfg[typeName]
+		if typeName != "*" && !types[typeName] {
+			return fmt.Errorf("unknown type %q", typeName)
+		}
+		if len(rule.Signals) == 0 {
+			return fmt.Errorf("%s: no signals", typeName)
+		}
+		for _, sig := range rule.Signals {
+			if heuristicSignals[sig] == nil {
+				return fmt.Errorf("%s: unknown signal %q (valid: %s)", typeName, sig, strings.Join(signalNames(), ", "))
+			}
+		}
+		for sig, w := range rule.Weights {
+			if heuristicSignals[sig] == nil || w < 0 {
+				return fmt.Errorf("%s: bad weight for %q", typeName, sig)
+			}
+		}
+		switch rule.Combine {
+		case "", "max", "weighted":
+		default:
+			return fmt.Errorf("%s: combine must be max or weighted, not %q", typeName, rule.Combine)
+		}
+	}
+	return nil
+}
+
+// signalNames lists the known signals, sorted.
+func signalNames() []string {
+	var names []string
+	for name := range heuristicSignals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// rule returns the rule for typeName, falling back to "*".
+func (cfg heuristicsConfig) rule(typeName string) (heuristicRule, bool) {
+	if rule, ok := cfg[typeName]; ok {
+		return rule, true
+	}
+	rule, ok := cfg["*"]
+	return rule, ok
+}
+
+// markerUsage is the "markers" signal where the built-in usage function
+// already folds in another signal: a venv's (and a conda environment's or
+// nox session's) is getVenvActivity, which adds site-packages, and a
+// Hugging Face repository's adds its blobs' atimes.
+var markerUsage = map[string]usageFunc{"venv": getVenvUsage, "conda": getVenvUsage, "nox": getVenvUsage, "huggingface": getCacheUsage}
+
+// usageFor returns the usage function for typeName under the configured
+// heuristics, or builtin when no rule applies.
+func (s *scanner) usageFor(typeName string, builtin usageFunc) usageFunc {
+	rule, ok := s.rules.rule(typeName)
+	if !ok {
+		return builtin
+	}
+	markers := builtin
+	if m := markerUsage[typeName]; m != nil {
+		markers = m
+	}
+	return func(path string) (time.Time, bool) {
+		var latest time.Time
+		var sum, total float64
+		found := false
+		for _, sig := range rule.Signals {
+			t, ok := heuristicSignals[sig](s, path, markers)
+			if !ok || t.IsZero() {
+				continue
+			}
+			found = true
+			if t.After(latest) {
+				latest = t
+			}
+			w, set := rule.Weights[sig]
+			if !set {
+				w = 1
+			}
+			sum += w * float64(t.Unix())
+			total += w
+		}
+		if !found {
+			return time.Time{}, false
+		}
+		if rule.Combine == "weighted" && total > 0 {
+			return time.Unix(int64(sum/total), 0), true
+		}
+		return latest, true
+	}
+}
+
+// projectLockfileTime returns when the newest Python or JavaScript lockfile
+// in dir was written.
+func projectLockfileTime(dir string) (time.Time, bool) {
+	_, latest, found := lockfileActivity(dir)
+	for _, name := range []string{"package-lock.json", "yarn.lock", "pnpm-lock.yaml", "bun.lockb"} {
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil && info.ModTime().After(latest) {
+			latest, found = info.ModTime(), true
+		}
+	}
+	return latest, found
+}
+
+// spotlightLastUsed returns macOS's kMDItemLastUsedDate for path: when it
+// was last opened through Launch Services. Elsewhere, and for items
+// Spotlight has no date for, it returns false.
+func spotlightLastUsed(path string) (time.Time, bool) {
+	if runtime.GOOS != "darwin" {
+		return time.Time{}, false
+	}
+	out, err := exec.Command("mdls", "-raw", "-name", "kMDItemLastUsedDate", path).Output()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return parseMdlsDate(string(out))
+}
+
+// parseMdlsDate reads an `mdls -raw` date such as "2026-03-01 09:30:00 +0000";
+// "(null)" means no date.
+func parseMdlsDate(out string) (time.Time, bool) {
+	t, err := time.Parse("2006-01-02 15:04:05 -0700", strings.TrimSpace(out))
+	return t, err == nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadHeuristics(t *testing.T) {
+	cfgDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", cfgDir)
+
+	if cfg, err := loadHeuristics(); err != nil || cfg != nil {
+		t.Fatalf("no file: got %v, %v", cfg, err)
+	}
+
+	path := filepath.Join(cfgDir, "tidyup", "heuristics.json")
+	os.MkdirAll(filepath.Dir(path), 0755)
+	tests := []struct {
+		data, wantErr string
+	}{
+		{`{"venv": {"signals": ["markers", "git"], "combine": "weighted", "weights": {"git": 2}}}`, ""},
+		{`{"*": {"signals": ["atime"]}}`, ""},
+		{`{"venvs": {"signals": ["markers"]}}`, `unknown type "venvs"`},
+		{`{"venv": {"signals": ["mtime"]}}`, `unknown signal "mtime"`},
+		{`{"venv": {"signals": []}}`, "no signals"},
+		{`{"venv": {"signals": ["markers"], "combine": "min"}}`, "combine must be max or weighted"},
+		{`{"venv": {"signals": ["markers"], "weights": {"markers": -1}}}`, "bad weight"},
+		{`{"venv": [`, "unexpected end"},
+	}
+	for _, tt := range tests {
+		os.WriteFile(path, []byte(tt.data), 0644)
+		_, err := loadHeuristics()
+		if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: err = %v, want %q", tt.data, err, tt.wantErr)
+		}
+	}
+}
+
+func TestUsageFor_Combine(t *testing.T) {
+	project := t.TempDir()
+	venv := filepath.Join(project, ".venv")
+	os.MkdirAll(venv, 0755)
+	lockTime := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	lock := filepath.Join(project, "uv.lock")
+	os.WriteFile(lock, nil, 0644)
+	os.Chtimes(lock, lockTime, lockTime)
+
+	markerTime := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	builtin := func(string) (time.Time, bool) { return markerTime, true }
+
+	s := &scanner{}
+	if got, _ := s.usageFor("node_modules", builtin)(venv); !got.Equal(markerTime) {
+		t.Errorf("no rule: got %v, want the built-in %v", got, markerTime)
+	}
+
+	s.rules = heuristicsConfig{"*": {Signals: []string{"markers", "lockfiles"}}}
+	if got, _ := s.usageFor("dist", builtin)(venv); !got.Equal(lockTime) {
+		t.Errorf("max: got %v, want the lockfile's %v", got, lockTime)
+	}
+
+	s.rules = heuristicsConfig{"dist": {Signals: []string{"markers", "lockfiles"}, Combine: "weighted", Weights: map[string]float64{"markers": 3}}}
+	want := markerTime.Add(lockTime.Sub(markerTime) / 4)
+	if got, _ := s.usageFor("dist", builtin)(venv); !got.Equal(want) {
+		t.Errorf("weighted: got %v, want %v", got, want)
+	}
+
+	s.rules = heuristicsConfig{"dist": {Signals: []string{"spotlight"}}}
+	if _, ok := s.usageFor("dist", builtin)(filepath.Join(project, "missing")); ok {
+		t.Error("no signal available should not date the item")
+	}
+}
+
+func TestParseMdlsDate(t *testing.T) {
+	if got, ok := parseMdlsDate("2026-03-01 09:30:00 +0000"); !ok || !got.Equal(time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)) {
+		t.Errorf("parseMdlsDate = %v, %v", got, ok)
+	}
+	if _, ok := parseMdlsDate("(null)"); ok {
+		t.Error("(null) should not parse")
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// historyVersion is the format version of the size history file.
+const historyVersion = 1
+
+// historySamples is how many scans of each item the history keeps.
+const historySamples = 8
+
+// historyRetention drops items no scan has flagged for this long.
+const historyRetention = 180 * 24 * time.Hour
+
+// sizeSample is one scan's view of an item.
+type sizeSample struct {
+	Time     int64  `json:"t"`         // scan time, Unix seconds
+	Size     int64  `json:"size"`      // apparent size in bytes
+	LastUsed string `json:"last_used"` // RFC3339, as in Record
+}
+
+// sizeHistory is the persistent per-item history of flagged items, keyed by
+// absolute path.
+type sizeHistory struct {
+	Version int                     `json:"version"`
+	Items   map[string][]sizeSample `json:"items"`
+}
+
+// historyPath is the size history file inside the state directory.
+func historyPath() (string, error) {
+	return statePath("history.json")
+}
+
+// loadHistory reads the history file. A missing or unreadable file starts an
+// empty history rather than failing the scan; a corrupt one is set aside.
+func loadHistory(path string) *sizeHistory {
+	h := &sizeHistory{Version: historyVersion, Items: map[string][]sizeSample{}}
+	var stored sizeHistory
+	err := loadState(path, &stored)
+	if errors.Is(err, errStateCorrupt) {
+		setAsideCorrupt(path, err)
+		return h
+	}
+	if err == nil && stored.Version == historyVersion && stored.Items != nil {
+		h.Items = stored.Items
+	}
+	return h
+}
+
+// save writes the history, pruning items not seen within historyRetention.
+func (h *sizeHistory) save(path string, now time.Time) error {
+	for p, samples := range h.Items {
+		if len(samples) == 0 || now.Sub(time.Unix(samples[len(samples)-1].Time, 0)) > historyRetention {
+			delete(h.Items, p)
+		}
+	}
+	return saveState(path, h, 0600)
+}
+
+// record appends this scan's samples and sets each record's Trend from the
+// item's history, including the new sample.
+func (h *sizeHistory) record(records []Record, now time.Time) {
+	for i := range records {
+		r := &records[i]
+		samples := append(h.Items[r.Path], sizeSample{now.Unix(), r.Size, r.LastUsed})
+		if len(samples) > historySamples {
+			samples = samples[len(samples)-historySamples:]
+		}
+		h.Items[r.Path] = samples
+		r.Trend = classifyTrend(samples)
+		r.sizeHistory = make([]int64, len(samples))
+		for j, s := range samples {
+			r.sizeHistory[j] = s.Size
+		}
+	}
+}
+
+// classifyTrend summarizes an item's samples: "untouched" (neither size nor
+// last use changed), "stable" (used, but within 1% of its first size),
+// "growing", or "shrinking". Returns "" until there are two samples.
+func classifyTrend(samples []sizeSample) string {
+	if len(samples) < 2 {
+		return ""
+	}
+	first, last := samples[0], samples[len(samples)-1]
+	touched := false
+	for _, s := range samples[1:] {
+		if s.Size != first.Size || s.LastUsed != first.LastUsed {
+			touched = true
+		}
+	}
+	change := last.Size - first.Size
+	switch {
+	case !touched:
+		return "untouched"
+	case change*100 > first.Size:
+		return "growing"
+	case -change*100 > first.Size:
+		return "shrinking"
+	}
+	return "stable"
+}
+
+// sparkBlocks are the sparkline levels, lowest first.
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline renders sizes scaled between their minimum and maximum; a flat
+// series renders at the lowest level.
+func sparkline(sizes []int64) string {
+	if len(sizes) == 0 {
+		return ""
+	}
+	lo, hi := sizes[0], sizes[0]
+	for _, s := range sizes {
+		lo, hi = min(lo, s), max(hi, s)
+	}
+	var b strings.Builder
+	for _, s := range sizes {
+		level := 0
+		if hi > lo {
+			level = int((s - lo) * int64(len(sparkBlocks)-1) / (hi - lo))
+		}
+		b.WriteRune(sparkBlocks[level])
+	}
+	return b.String()
+}
+
+// trendArrows mark each trend in text output.
+var trendArrows = map[string]string{
+	"growing":   "↑",
+	"shrinking": "↓",
+	"stable":    "→",
+	"untouched": "·",
+}
+
+// trendLine is the text output line for a record's trend, or "".
+func trendLine(r Record, loc localeFormat) string {
+	if r.Trend == "" {
+		return ""
+	}
+	change := r.sizeHistory[len(r.sizeHistory)-1] - r.sizeHistory[0]
+	sign := "+"
+	if change < 0 {
+		sign, change = "-", -change
+	}
+	return fmt.Sprintf("trend %s %s %s (%s%s over %d scans)",
+		sparkline(r.sizeHistory), trendArrows[r.Trend], r.Trend, sign, loc.bytes(change), len(r.sizeHistory))
+}
+
+// updateHistory records this scan in the persistent history and fills in
+// each record's trend. Failures only cost the trend, never the scan.
+func updateHistory(records []Record, opts *options) {
+	path, err := historyPath()
+	if err != nil {
+		stderr.warnf("size history unavailable: %v", err)
+		return
+	}
+	defer lockState()()
+	h := loadHistory(path)
+	now := opts.currentTime()
+	h.record(records, now)
+	if err := h.save(path, now); err != nil {
+		stderr.warnf("could not save size history: %v", err)
+	}
+}
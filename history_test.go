@@ -0,0 +1,79 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClassifyTrend(t *testing.T) {
+	const used, later = "2026-01-01T00:00:00Z", "2026-03-01T00:00:00Z"
+	tests := []struct {
+		name    string
+		samples []sizeSample
+		want    string
+	}{
+		{"first scan", []sizeSample{{1, 100, used}}, ""},
+		{"untouched", []sizeSample{{1, 100, used}, {2, 100, used}, {3, 100, used}}, "untouched"},
+		{"stable but used", []sizeSample{{1, 1000, used}, {2, 1005, later}}, "stable"},
+		{"growing", []sizeSample{{1, 100, used}, {2, 150, later}}, "growing"},
+		{"shrinking", []sizeSample{{1, 100, used}, {2, 50, later}}, "shrinking"},
+	}
+	for _, tt := range tests {
+		if got := classifyTrend(tt.samples); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	if got := sparkline([]int64{0, 50, 100}); got != "▁▄█" {
+		t.Errorf("got %q", got)
+	}
+	if got := sparkline([]int64{7, 7}); got != "▁▁" {
+		t.Errorf("flat: got %q", got)
+	}
+}
+
+func TestSizeHistory_RecordAndPersist(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	path, err := historyPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	opts := &options{now: func() time.Time { return now }}
+	rec := func(size int64) []Record {
+		return []Record{{Path: "/p/.venv", Size: size, LastUsed: "2026-01-01T00:00:00Z"}}
+	}
+
+	first := rec(100)
+	updateHistory(first, opts)
+	if first[0].Trend != "" {
+		t.Errorf("first scan: trend = %q, want none", first[0].Trend)
+	}
+
+	now = now.Add(24 * time.Hour)
+	second := rec(300)
+	updateHistory(second, opts)
+	if second[0].Trend != "growing" {
+		t.Errorf("second scan: trend = %q, want growing", second[0].Trend)
+	}
+	if line := trendLine(second[0], localeFormat{}); !strings.Contains(line, "▁█ ↑ growing") {
+		t.Errorf("trend line = %q", line)
+	}
+
+	// Items not flagged for longer than the retention period are dropped.
+	h := loadHistory(path)
+	h.Items["/gone"] = []sizeSample{{now.Add(-historyRetention - time.Hour).Unix(), 1, ""}}
+	if err := h.save(path, now); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := loadHistory(path).Items["/gone"]; ok {
+		t.Error("stale history entry was not pruned")
+	}
+	if filepath.Base(path) != "history.json" {
+		t.Errorf("history path = %s", path)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// The Hugging Face hub cache keeps one directory per repository it has
+// downloaded from: models--<org>--<name>, datasets--..., spaces--.... Each
+// holds blobs/ (the files, named by hash), snapshots/<revision>/ (symlinks
+// into blobs/, one tree per revision), and refs/ (branch -> revision). The
+// revisions of a repository share blobs, so the repository is the unit that
+// frees space when removed; each is its own item, a 10 GB model deletable
+// without clearing the rest of the cache.
+
+// hfRepoKinds are the repository directory prefixes in a hub cache.
+var hfRepoKinds = []string{"models", "datasets", "spaces"}
+
+// hfHubDirs returns the hub caches: $HF_HUB_CACHE, else $HF_HOME/hub, else
+// $XDG_CACHE_HOME/huggingface/hub or ~/.cache/huggingface/hub; and on macOS
+// ~/Library/Caches/huggingface/hub, where some installs point $HF_HOME.
+func hfHubDirs(home string) []string {
+	var dirs []string
+	switch {
+	case os.Getenv("HF_HUB_CACHE") != "":
+		dirs = append(dirs, os.Getenv("HF_HUB_CACHE"))
+	case os.Getenv("HF_HOME") != "":
+		dirs = append(dirs, filepath.Join(os.Getenv("HF_HOME"), "hub"))
+	case os.Getenv("XDG_CACHE_HOME") != "":
+		dirs = append(dirs, filepath.Join(os.Getenv("XDG_CACHE_HOME"), "huggingface", "hub"))
+	default:
+		dirs = append(dirs, filepath.Join(home, ".cache", "huggingface", "hub"))
+	}
+	dirs = append(dirs, filepath.Join(home, "Library", "Caches", "huggingface", "hub"))
+
+	seen := map[string]bool{}
+	var unique []string
+	for _, d := range existingDirs(dirs...) {
+		if real, err := filepath.EvalSymlinks(d); err == nil && !seen[real] {
+			seen[real] = true
+			unique = append(unique, d)
+		}
+	}
+	return unique
+}
+
+// findHFRepos returns the repository directories in the hub caches.
+func findHFRepos(home string) []string {
+	var repos []string
+	for _, hub := range hfHubDirs(home) {
+		entries, err := os.ReadDir(hub)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if _, _, ok := hfRepoID(e.Name()); ok && e.IsDir() {
+				repos = append(repos, filepath.Join(hub, e.Name()))
+			}
+		}
+	}
+	return repos
+}
+
+// hfRepoID decodes a repository directory name: models--org--name is the
+// model org/name.
+func hfRepoID(name string) (kind, id string, ok bool) {
+	for _, k := range hfRepoKinds {
+		if rest, found := strings.CutPrefix(name, k+"--"); found && rest != "" {
+			return strings.TrimSuffix(k, "s"), strings.ReplaceAll(rest, "--", "/"), true
+		}
+	}
+	return "", "", false
+}
+
+// getHFUsage dates a repository by the newer of its newest file mtime (a
+// download or a refs/ update when a revision was resolved online) and its
+// blobs' newest atime: loading a model reads its blobs through the
+// snapshot symlinks, which a download alone does not date.
+func getHFUsage(path string) (time.Time, bool) {
+	lastUsed, found := getCacheUsage(path)
+	_ = walkTree(filepath.Join(path, "blobs"), func(p string, d fs.DirEntry, err error) error {
+		pace()
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		if at, ok := accessTime(p); ok && at.After(lastUsed) {
+			lastUsed, found = at, true
+		}
+		return nil
+	})
+	return lastUsed, found
+}
+
+// annotateHF names the repository and its cached revisions.
+func annotateHF(r *Record) {
+	kind, id, ok := hfRepoID(filepath.Base(r.Path))
+	if !ok {
+		return
+	}
+	r.Tool = kind + " " + id
+	revs, _ := os.ReadDir(filepath.Join(r.Path, "snapshots"))
+	note := fmt.Sprintf("Hugging Face %s %s", kind, id)
+	switch len(revs) {
+	case 0:
+	case 1:
+		note += ", 1 revision"
+	default:
+		note += fmt.Sprintf(", %d revisions sharing its files", len(revs))
+	}
+	r.Notes = append(r.Notes, note+"; downloaded again on next use")
+}
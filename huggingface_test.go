@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHFRepoID(t *testing.T) {
+	for name, want := range map[string]string{
+		"models--google-bert--bert-base-uncased": "model google-bert/bert-base-uncased",
+		"datasets--squad":                        "dataset squad",
+		"spaces--gradio--hello":                  "space gradio/hello",
+		"version.txt":                            "",
+		"models--":                               "",
+	} {
+		kind, id, ok := hfRepoID(name)
+		got := ""
+		if ok {
+			got = kind + " " + id
+		}
+		if got != want {
+			t.Errorf("hfRepoID(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestFindHFRepos(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HF_HUB_CACHE", "")
+	t.Setenv("HF_HOME", "")
+	t.Setenv("XDG_CACHE_HOME", "")
+	old := time.Now().AddDate(0, 0, -120)
+	hub := filepath.Join(home, ".cache", "huggingface", "hub")
+	model := filepath.Join(hub, "models--org--big")
+	for _, rel := range []string{"blobs/abc123", "refs/main", "snapshots/rev1/config.json", "snapshots/rev2/config.json"} {
+		p := filepath.Join(model, rel)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte("x"), 0644)
+		os.Chtimes(p, old, old)
+	}
+	os.MkdirAll(filepath.Join(hub, "datasets--squad"), 0755)
+	os.WriteFile(filepath.Join(hub, "version.txt"), []byte("1"), 0644)
+	mac := filepath.Join(home, "Library", "Caches", "huggingface", "hub", "models--org--small")
+	os.MkdirAll(mac, 0755)
+
+	repos := findHFRepos(home)
+	want := map[string]bool{model: true, filepath.Join(hub, "datasets--squad"): true, mac: true}
+	if len(repos) != len(want) {
+		t.Fatalf("findHFRepos = %v, want %v", repos, want)
+	}
+	for _, r := range repos {
+		if !want[r] {
+			t.Errorf("unexpected repo %s", r)
+		}
+	}
+
+	t.Setenv("HF_HOME", filepath.Join(home, "hf"))
+	if repos := findHFRepos(home); len(repos) != 1 || repos[0] != mac {
+		t.Errorf("with $HF_HOME elsewhere: %v, want only %s", repos, mac)
+	}
+
+	r := Record{Path: model}
+	annotateHF(&r)
+	if r.Tool != "model org/big" || len(r.Notes) != 1 {
+		t.Errorf("annotateHF: tool %q, notes %v", r.Tool, r.Notes)
+	}
+	if got, ok := getHFUsage(model); !ok || got.Before(old) {
+		t.Errorf("getHFUsage = %v, %v", got, ok)
+	}
+}
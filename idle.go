@@ -0,0 +1,117 @@
+package main
+
+import (
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// `tidyup serve -idle N` starts its unattended scan only once the user has
+// been idle for N minutes, and pauses the walk whenever they come back:
+// pace(), which every walk already calls per entry, blocks while
+// scanPaused is set. Idle time comes from IOKit's HIDIdleTime on macOS and
+// logind's IdleHint on Linux; elsewhere it is unknown and -idle is ignored.
+
+// idlePoll is how often a running idle-triggered scan checks for the user.
+var idlePoll = 5 * time.Second
+
+// scanPaused holds paced walks while the user is active.
+var scanPaused atomic.Bool
+
+// userIdleFunc returns how long the user has been idle, and whether that
+// can be told at all; a seam for tests.
+var userIdleFunc = userIdle
+
+// userIdle asks the platform how long the user has been idle.
+func userIdle() (time.Duration, bool) {
+	switch runtime.GOOS {
+	case "darwin":
+		out, err := exec.Command("ioreg", "-c", "IOHIDSystem", "-d", "4").Output()
+		if err != nil {
+			return 0, false
+		}
+		return parseHIDIdle(string(out))
+	case "linux":
+		out, err := exec.Command("loginctl", "show", "-p", "IdleHint", "-p", "IdleSinceHint").Output()
+		if err != nil {
+			return 0, false
+		}
+		return parseLogindIdle(string(out), time.Now())
+	}
+	return 0, false
+}
+
+// parseHIDIdle reads the "HIDIdleTime" = <nanoseconds> line of ioreg output.
+func parseHIDIdle(out string) (time.Duration, bool) {
+	for _, line := range strings.Split(out, "\n") {
+		if !strings.Contains(line, `"HIDIdleTime"`) {
+			continue
+		}
+		_, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		ns, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err == nil {
+			return time.Duration(ns), true
+		}
+	}
+	return 0, false
+}
+
+// parseLogindIdle reads `loginctl show` IdleHint and IdleSinceHint (in
+// microseconds since the epoch). Without IdleHint=yes the user is active.
+func parseLogindIdle(out string, now time.Time) (time.Duration, bool) {
+	props := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		if key, value, found := strings.Cut(strings.TrimSpace(line), "="); found {
+			props[key] = value
+		}
+	}
+	hint, ok := props["IdleHint"]
+	if !ok {
+		return 0, false
+	}
+	if hint != "yes" {
+		return 0, true
+	}
+	usec, err := strconv.ParseInt(props["IdleSinceHint"], 10, 64)
+	if err != nil || usec == 0 {
+		return 0, false
+	}
+	return now.Sub(time.UnixMicro(usec)), true
+}
+
+// isIdle reports whether the user has been idle for at least d. Unknown
+// idle time counts as idle, so -idle never blocks a scan for good.
+func isIdle(d time.Duration) bool {
+	idle, ok := userIdleFunc()
+	return !ok || idle >= d
+}
+
+// watchIdle pauses paced walks whenever the user has been idle for less
+// than d, until the returned stop function is called.
+func watchIdle(d time.Duration) (stop func()) {
+	done, exited := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(exited)
+		ticker := time.NewTicker(idlePoll)
+		defer ticker.Stop()
+		for {
+			scanPaused.Store(!isIdle(d))
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-exited
+		scanPaused.Store(false)
+	}
+}
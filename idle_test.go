@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseHIDIdle(t *testing.T) {
+	out := "    | |   \"HIDIdleTime\" = 754321000000\n    | |   \"HIDPointerAcceleration\" = 45056\n"
+	if d, ok := parseHIDIdle(out); !ok || d != 754321*time.Millisecond {
+		t.Errorf("got %v, %v", d, ok)
+	}
+	if _, ok := parseHIDIdle("no such key\n"); ok {
+		t.Error("want not ok without HIDIdleTime")
+	}
+}
+
+func TestParseLogindIdle(t *testing.T) {
+	now := time.Unix(1_800_000_000, 0)
+	since := now.Add(-12 * time.Minute).UnixMicro()
+	cases := []struct {
+		out  string
+		want time.Duration
+		ok   bool
+	}{
+		{"IdleHint=yes\nIdleSinceHint=" + strconv.FormatInt(since, 10) + "\n", 12 * time.Minute, true},
+		{"IdleHint=no\nIdleSinceHint=0\n", 0, true},
+		{"", 0, false},
+	}
+	for _, c := range cases {
+		if d, ok := parseLogindIdle(c.out, now); d != c.want || ok != c.ok {
+			t.Errorf("parseLogindIdle(%q) = %v, %v; want %v, %v", c.out, d, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestWatchIdle_PausesWhileActive(t *testing.T) {
+	savedPoll, savedIdle := idlePoll, userIdleFunc
+	t.Cleanup(func() { idlePoll, userIdleFunc = savedPoll, savedIdle })
+	idlePoll = time.Millisecond
+	var mu sync.Mutex
+	idle := time.Duration(0)
+	userIdleFunc = func() (time.Duration, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		return idle, true
+	}
+	waitFor := func(paused bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for scanPaused.Load() != paused {
+			if time.Now().After(deadline) {
+				t.Fatalf("scanPaused never became %v", paused)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	stop := watchIdle(10 * time.Minute)
+	waitFor(true)
+	mu.Lock()
+	idle = 11 * time.Minute
+	mu.Unlock()
+	waitFor(false)
+	mu.Lock()
+	idle = 0
+	mu.Unlock()
+	waitFor(true)
+	stop()
+	if scanPaused.Load() {
+		t.Error("still paused after stop")
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// importSource is what a --json document says about the scan that produced
+// its records; zero for a bare array.
+type importSource struct {
+	scannedAt time.Time
+	host      string
+}
+
+// loadRecords decodes records from a --json document (or a bare array of
+// records, as produced by `jq '.records'`). Documents from a newer, possibly
+// incompatible schema are rejected rather than misread.
+func loadRecords(r io.Reader) ([]Record, importSource, error) {
+	var src importSource
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, src, err
+	}
+	data = bytes.TrimSpace(data)
+
+	var records []Record
+	if len(data) > 0 && data[0] == '[' {
+		if err := json.Unmarshal(data, &records); err != nil {
+			return nil, src, fmt.Errorf("decoding records: %w", err)
+		}
+	} else {
+		var doc JSONOutput
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, src, fmt.Errorf("decoding results: %w", err)
+		}
+		if doc.SchemaVersion > schemaVersion {
+			return nil, src, fmt.Errorf("results use schema_version %d; this tidyup understands up to %d", doc.SchemaVersion, schemaVersion)
+		}
+		if doc.ScannedAt != "" {
+			if src.scannedAt, err = time.Parse(time.RFC3339, doc.ScannedAt); err != nil {
+				return nil, src, fmt.Errorf("results have an invalid scanned_at: %w", err)
+			}
+		}
+		records, src.host = doc.Records, doc.Host
+	}
+
+	for i, rec := range records {
+		if rec.Type == "" {
+			return nil, src, fmt.Errorf("record %d: missing type", i+1)
+		}
+		switch {
+		case isDockerPath(rec.Path):
+			// Not a file path: nothing to make absolute or clean.
+		case !filepath.IsAbs(rec.Path):
+			return nil, src, fmt.Errorf("record %d: path %q is not absolute", i+1, rec.Path)
+		default:
+			records[i].Path = filepath.Clean(rec.Path)
+		}
+		if records[i].SizeHuman == "" {
+			records[i].SizeHuman = formatBytes(rec.Size)
+		}
+		// Producers without disk accounting: fall back to the apparent size.
+		if rec.DiskSize == 0 && rec.DiskHuman == "" {
+			records[i].DiskSize = rec.Size
+			records[i].DiskHuman = formatBytes(rec.Size)
+		}
+	}
+	return records, src, nil
+}
+
+// checkImportSource refuses results scanned on another host: their paths
+// name someone else's files. Deleting also needs the scan's time, to tell
+// what changed since.
+func checkImportSource(src importSource, deleting bool) error {
+	if host, _ := hostnameFunc(); src.host != "" && src.host != host {
+		return fmt.Errorf("results were scanned on %q, not this host (%q)", src.host, host)
+	}
+	if deleting && (src.host == "" || src.scannedAt.IsZero()) {
+		return fmt.Errorf("results carry no host or scanned_at; import the whole -json document to delete")
+	}
+	return nil
+}
+
+// revalidateRecords drops records that no longer hold on this host: the
+// path is gone, was modified after the scan (when its time is known), or
+// is no longer detected as its type, e.g. results reviewed elsewhere or
+// already cleaned by an earlier run. The records kept are the ones detected
+// now: nothing the file says about an item -- its command, holds, or
+// whether it is a file -- is trusted.
+func revalidateRecords(records []Record, scannedAt time.Time) ([]Record, []string) {
+	var present []Record
+	var warnings []string
+	for _, r := range records {
+		var err error
+		switch {
+		case isDockerPath(r.Path):
+			err = statDockerItem(r.Path)
+		case scannedAt.IsZero():
+			_, err = os.Lstat(r.Path)
+		default:
+			err = changedSince(r.Path, scannedAt)
+		}
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("skipping %s: %v", r.Path, err))
+			continue
+		}
+		present = append(present, r)
+	}
+	kept, more := redetectRecords(present)
+	return kept, append(warnings, more...)
+}
+
+// redetectRecords replaces each record with the one detection finds at its
+// path now, and drops those no longer detected as their type.
+func redetectRecords(records []Record) ([]Record, []string) {
+	detected := redetect(records)
+	var kept []Record
+	var warnings []string
+	for _, r := range records {
+		fresh, ok := detected[r.Type+" "+r.Path]
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("skipping %s: no longer detected as %s", r.Path, r.Type))
+			continue
+		}
+		kept = append(kept, fresh)
+	}
+	return kept, warnings
+}
+
+// redetect scans the directories holding records again, for their types
+// alone and whatever their age, and returns the records found by type and
+// path ("venv /p/.venv"). Docker items are asked of the daemon again. A
+// build inside a dist/ or build/ is found with -keep-builds 1: it was older
+// than the newest kept, so older than the newest.
+func redetect(records []Record) map[string]Record {
+	roots := map[string][]string{}
+	seen := map[string]bool{}
+	for _, r := range records {
+		key, dir := r.Type, filepath.Dir(r.Path)
+		switch {
+		case isDockerPath(r.Path):
+			dir = ""
+		case (r.Type == "dist" || r.Type == "build") && filepath.Base(dir) == r.Type:
+			key += " builds"
+		}
+		if !seen[key+" "+dir] {
+			seen[key+" "+dir] = true
+			roots[key] = append(roots[key], dir)
+		}
+	}
+	found := map[string]Record{}
+	for key, dirs := range roots {
+		typeName, builds := strings.CutSuffix(key, " builds")
+		opts := &options{maxDepth: 1, systemScan: true, scanTypes: map[string]bool{typeName: true}}
+		if builds {
+			opts.keepBuilds = 1
+		}
+		if typeName == "docker" {
+			dirs = nil
+		}
+		records, _ := scanRoots(dirs, opts)
+		for _, r := range records {
+			found[r.Type+" "+r.Path] = r
+		}
+	}
+	return found
+}
+
+// runImport implements `tidyup import <results.json>`: loads records produced
+// elsewhere into the normal report/selection/deletion flow. "-" reads stdin.
+func runImport(args []string) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	doDelete := flags.Bool("delete", false, "Delete the imported items")
+	dryRun := flags.Bool("dry-run", false, "Preview what would be deleted (overrides -delete)")
+	jsonOut := flags.Bool("json", false, "Output results as JSON")
+	sortField := flags.String("sort", "size", "Sort by: size, disk, age, path")
+	useTrash := flags.Bool("trash", false, "Move to the Trash (through Finder, so Put Back works) instead of permanent delete (macOS)")
+	emptyTrashAfterRaw := flags.String("empty-trash-after", "", "With -delete, permanently remove items tidyup moved to the Trash longer ago than this (e.g. 7d)")
+	delegate := flags.Bool("delegate", false, "Remove items that have a native command (pipx/uv uninstall, sdkmanager, ...) by running it")
+	logFile := flags.String("log", "", "Write deletion log to this file (\"state\": logs/ in the state directory)")
+	logFormat := flags.String("log-format", logFormatText, "Deletion log format: text or jsonl")
+	logMaxSizeRaw := flags.String("log-max-size", "10M", "Rotate the deletion log once it reaches this size (0: never)")
+	logMaxAgeRaw := flags.String("log-max-age", "", "Rotate the deletion log once its first entry is older than this (e.g. 30d)")
+	logKeep := flags.Int("log-keep", 5, "Rotated deletion logs to keep (0: all)")
+	maxDeleteBytesRaw := flags.String("max-delete-bytes", "", "Abort without deleting anything if the selection exceeds this size (e.g. 20G)")
+	maxDeleteItems := flags.Int("max-delete-items", 0, "Abort without deleting anything if the selection has more items than this")
+	receipts := flags.Bool("receipts", false, "Record the top-level listing of each removed item for 'tidyup receipts'")
+	confirm := flags.Bool("confirm", false, "Skip interactive selection prompt (for automation)")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tidyup import [flags] <results.json|->\n\n")
+		fmt.Fprintf(os.Stderr, "Loads records from 'tidyup -json' output (see 'tidyup schema') and runs\n")
+		fmt.Fprintf(os.Stderr, "them through the normal selection and deletion flow. Results scanned on\n")
+		fmt.Fprintf(os.Stderr, "another host are refused. Records whose path no longer exists, was\n")
+		fmt.Fprintf(os.Stderr, "modified after the scan, or is no longer detected as its type are skipped.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitError
+	}
+
+	var in io.Reader = os.Stdin
+	if name := flags.Arg(0); name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			stderr.errorf("%v", err)
+			return exitError
+		}
+		defer f.Close()
+		in = f
+	}
+
+	maxDeleteBytes, err := parseByteSize(*maxDeleteBytesRaw)
+	if err != nil {
+		stderr.errorf("-max-delete-bytes: %v", err)
+		return exitError
+	}
+	emptyTrashAfter, err := parseRetention(*emptyTrashAfterRaw)
+	if err != nil {
+		stderr.errorf("-empty-trash-after: %v", err)
+		return exitError
+	}
+	logMaxSize, err := parseByteSize(*logMaxSizeRaw)
+	if err != nil {
+		stderr.errorf("-log-max-size: %v", err)
+		return exitError
+	}
+	logMaxAge, err := parseRetention(*logMaxAgeRaw)
+	if err != nil {
+		stderr.errorf("-log-max-age: %v", err)
+		return exitError
+	}
+	switch *logFormat {
+	case logFormatText, logFormatJSONL:
+	default:
+		stderr.errorf("unknown -log-format %q (want text or jsonl)", *logFormat)
+		return exitError
+	}
+
+	records, src, err := loadRecords(in)
+	if err != nil {
+		stderr.errorf("%v", err)
+		return exitError
+	}
+	if err := checkImportSource(src, *doDelete && !*dryRun); err != nil {
+		stderr.errorf("%v", err)
+		return exitError
+	}
+	records, warnings := revalidateRecords(records, src.scannedAt)
+	for _, w := range warnings {
+		stderr.warnf("%s", w)
+	}
+
+	if *dryRun {
+		*doDelete = false
+	}
+	opts := &options{
+		doDelete:        *doDelete,
+		dryRun:          *dryRun,
+		jsonOut:         *jsonOut,
+		sortField:       *sortField,
+		useTrash:        *useTrash,
+		delegate:        *delegate,
+		logFile:         *logFile,
+		logFormat:       *logFormat,
+		logMaxSize:      logMaxSize,
+		logMaxAge:       logMaxAge,
+		logKeep:         *logKeep,
+		receipts:        *receipts,
+		confirm:         *confirm,
+		maxDeleteBytes:  maxDeleteBytes,
+		maxDeleteItems:  *maxDeleteItems,
+		emptyTrashAfter: emptyTrashAfter,
+	}
+	return reportAndDelete(records, opts)
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadRecords_Document(t *testing.T) {
+	doc := `{"schema_version": 1, "count": 1, "records": [
+		{"type": "venv", "path": "/home/user/p/.venv/", "size_bytes": 2048, "size_human": "", "last_used": "2026-01-01", "age_days": 40}
+	]}`
+	records, _, err := loadRecords(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("got %d records, want 1", len(records))
+	}
+	if records[0].Path != "/home/user/p/.venv" {
+		t.Errorf("path not cleaned: %q", records[0].Path)
+	}
+	if records[0].SizeHuman != "2.0 KB" {
+		t.Errorf("size_human not filled in: %q", records[0].SizeHuman)
+	}
+}
+
+func TestLoadRecords_BareArray(t *testing.T) {
+	records, _, err := loadRecords(strings.NewReader(`[{"type": "pycache", "path": "/a/__pycache__"}]`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(records) != 1 || records[0].Type != "pycache" {
+		t.Errorf("got %+v", records)
+	}
+}
+
+func TestLoadRecords_Rejects(t *testing.T) {
+	tests := map[string]string{
+		"newer schema":  `{"schema_version": 99, "records": []}`,
+		"relative path": `[{"type": "venv", "path": "p/.venv"}]`,
+		"missing type":  `[{"path": "/p/.venv"}]`,
+		"not json":      `type=venv`,
+		"bad scan time": `{"schema_version": 2, "scanned_at": "yesterday", "records": []}`,
+	}
+	for name, input := range tests {
+		if _, _, err := loadRecords(strings.NewReader(input)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestRevalidateRecords_DropsMissing(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	dir := t.TempDir()
+	present := filepath.Join(dir, "node_modules")
+	os.MkdirAll(present, 0755)
+
+	kept, warnings := revalidateRecords([]Record{
+		{Type: "node_modules", Path: present},
+		{Type: "venv", Path: filepath.Join(dir, "gone", ".venv")},
+	}, time.Time{})
+	if len(kept) != 1 || kept[0].Path != present {
+		t.Errorf("kept %+v, want only %s", kept, present)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("expected 1 warning, got %v", warnings)
+	}
+}
+
+func TestLoadRecords_Source(t *testing.T) {
+	_, src, err := loadRecords(strings.NewReader(`{"schema_version": 2, "scanned_at": "2026-03-01T10:00:00Z", "host": "build-1", "records": []}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if src.host != "build-1" || !src.scannedAt.Equal(time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("source = %+v, want build-1 at 2026-03-01T10:00:00Z", src)
+	}
+}
+
+func TestCheckImportSource(t *testing.T) {
+	saved := hostnameFunc
+	hostnameFunc = func() (string, error) { return "laptop", nil }
+	t.Cleanup(func() { hostnameFunc = saved })
+
+	scanned := time.Now()
+	if err := checkImportSource(importSource{scannedAt: scanned, host: "laptop"}, true); err != nil {
+		t.Errorf("same host: %v", err)
+	}
+	if err := checkImportSource(importSource{scannedAt: scanned, host: "server"}, false); err == nil || !strings.Contains(err.Error(), "server") {
+		t.Errorf("another host: err = %v, want it refused", err)
+	}
+	// A bare array can be reviewed, not deleted.
+	if err := checkImportSource(importSource{}, false); err != nil {
+		t.Errorf("bare array, no delete: %v", err)
+	}
+	if err := checkImportSource(importSource{}, true); err == nil {
+		t.Error("bare array, -delete: accepted")
+	}
+}
+
+func TestRevalidateRecords_SinceScan(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	dir := t.TempDir()
+	old := time.Now().AddDate(0, 0, -100)
+	scanned := time.Now().AddDate(0, 0, -1)
+	mods := func(name string) string {
+		p := filepath.Join(dir, name, "node_modules")
+		os.MkdirAll(filepath.Join(p, "pkg"), 0755)
+		for _, rel := range []string{".package-lock.json", "pkg/index.js"} {
+			os.WriteFile(filepath.Join(p, rel), []byte("x"), 0644)
+			os.Chtimes(filepath.Join(p, rel), old, old)
+		}
+		return p
+	}
+	untouched, edited := mods("a"), mods("b")
+	os.WriteFile(filepath.Join(edited, "pkg", "index.js"), []byte("y"), 0644)
+	// A plain directory the export called a venv.
+	notVenv := filepath.Join(dir, "c", ".venv")
+	os.MkdirAll(notVenv, 0755)
+	os.Chtimes(notVenv, old, old)
+
+	kept, warnings := revalidateRecords([]Record{
+		{Type: "node_modules", Path: untouched},
+		{Type: "node_modules", Path: edited},
+		{Type: "venv", Path: notVenv},
+	}, scanned)
+	if len(kept) != 1 || kept[0].Path != untouched {
+		t.Errorf("kept %+v, want only %s", kept, untouched)
+	}
+	got := strings.Join(warnings, "\n")
+	if !strings.Contains(got, "modified since") || !strings.Contains(got, "no longer detected as venv") {
+		t.Errorf("warnings = %v, want one modified, one not a venv", warnings)
+	}
+}
+
+func TestRevalidateRecords_RetiredBuild(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	proj := t.TempDir()
+	os.WriteFile(filepath.Join(proj, "pyproject.toml"), []byte("[project]\nname = \"pkg\"\n"), 0644)
+	var wheels []string
+	for i, name := range []string{"pkg-1.0.0-py3-none-any.whl", "pkg-1.1.0-py3-none-any.whl", "pkg-1.2.0-py3-none-any.whl"} {
+		p := filepath.Join(proj, "dist", name)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte("x"), 0644)
+		mtime := time.Now().AddDate(0, 0, -300+100*i)
+		os.Chtimes(p, mtime, mtime)
+		wheels = append(wheels, p)
+	}
+	// Flagged by -keep-builds 2 elsewhere: still an older build here.
+	kept, warnings := revalidateRecords([]Record{{Type: "dist", Path: wheels[0]}}, time.Now())
+	if len(kept) != 1 {
+		t.Errorf("kept %v, warnings %v; want the retired build kept", kept, warnings)
+	}
+}
+
+func TestRevalidateRecords_TrustsDetectionNotFile(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	dir := t.TempDir()
+	venv := filepath.Join(dir, "p", ".venv")
+	makeVenv(t, venv, time.Now().AddDate(0, 0, -100))
+	clone := filepath.Join(dir, "old")
+	makeClone(t, clone, 400)
+
+	// An edited export: a command to run, and the clone's hold cleared.
+	kept, warnings := revalidateRecords([]Record{
+		{Type: "venv", Path: venv, Command: "touch " + filepath.Join(dir, "PWNED"), File: true},
+		{Type: "stale_repo", Path: clone, Advisory: false},
+	}, time.Now())
+	if len(kept) != 2 {
+		t.Fatalf("kept %+v, warnings %v; want both", kept, warnings)
+	}
+	if kept[0].Command != "" || kept[0].File {
+		t.Errorf("venv = %+v, want the file's command and file flag ignored", kept[0])
+	}
+	if !kept[1].Advisory {
+		t.Errorf("stale_repo = %+v, want it advisory again", kept[1])
+	}
+}
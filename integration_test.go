@@ -0,0 +1,555 @@
+//go:build integration && !readonly
+
+// End-to-end scan -> select -> delete (-> restore) cycles against generated
+// fixtures.
+// Run with: make test-integration (go test -tags integration ./...)
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+var fixtureNow = time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// sandboxDir creates a scratch directory under the working directory.
+// t.TempDir lives under /tmp or /var, which isProtectedPath refuses to
+// delete, so the harness would never reach the destructive code.
+func sandboxDir(t *testing.T) string {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := os.MkdirTemp(wd, "tidyup-it-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+// withSeams restores the delete.go seams after the test.
+func withSeams(t *testing.T) {
+	t.Helper()
+	origStdin, origRemovers, origTrash, origFinder := stdin, removersFunc, trashSupported, useFinderTrash
+	t.Cleanup(func() {
+		stdin, removersFunc, trashSupported, useFinderTrash = origStdin, origRemovers, origTrash, origFinder
+	})
+	// The harness exercises the ~/.Trash rename path, never a live Finder.
+	useFinderTrash = false
+	// Keep decision logs out of the real state directory.
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+}
+
+// setRemovers has edit change the removal primitives executors get.
+func setRemovers(edit func(rm *removers)) {
+	removersFunc = func() removers {
+		rm := newRemovers()
+		edit(&rm)
+		return rm
+	}
+}
+
+// scanFixtures generates fixtures and returns the stale records, sorted by path.
+func scanFixtures(t *testing.T, root string) ([]Record, *options) {
+	t.Helper()
+	if err := createFixtures(root, fixtureNow); err != nil {
+		t.Fatalf("createFixtures: %v", err)
+	}
+	scanTypes, _ := parseScanTypes("", true)
+	opts := &options{
+		minAge:    30,
+		maxDepth:  5,
+		scanTypes: scanTypes,
+		now:       func() time.Time { return fixtureNow },
+		doDelete:  true,
+	}
+	records, errs := scanRoots([]string{root}, opts)
+	if len(errs) != 0 {
+		t.Fatalf("scan errors: %v", errs)
+	}
+	sortRecords(records, "path")
+	return records, opts
+}
+
+// staleFixturePaths returns absolute paths of fixtures the scan should flag.
+func staleFixturePaths(root string, minAge int) []string {
+	var paths []string
+	for _, spec := range defaultFixtures {
+		if !spec.Decoy && spec.AgeDays >= minAge {
+			paths = append(paths, filepath.Join(root, spec.Path))
+		}
+	}
+	return paths
+}
+
+func exists(p string) bool {
+	_, err := os.Lstat(p)
+	return err == nil
+}
+
+// assertFreshIntact checks that fresh fixtures and decoys survived.
+func assertFreshIntact(t *testing.T, root string, minAge int) {
+	t.Helper()
+	for _, spec := range defaultFixtures {
+		if spec.Decoy || spec.AgeDays < minAge {
+			if p := filepath.Join(root, spec.Path); !exists(p) {
+				t.Errorf("fresh/decoy fixture was removed: %s", p)
+			}
+		}
+	}
+}
+
+func TestIntegration_ScanSelectDelete(t *testing.T) {
+	withSeams(t)
+	root := sandboxDir(t)
+	records, opts := scanFixtures(t, root)
+
+	stale := staleFixturePaths(root, opts.minAge)
+	if len(records) != len(stale) {
+		t.Fatalf("scanned %d records, want %d", len(records), len(stale))
+	}
+
+	// Select the first and last items interactively.
+	stdin = strings.NewReader(fmt.Sprintf("1,%d\n", len(records)))
+	if code := deleteRecords(records, opts); code != exitFound {
+		t.Fatalf("deleteRecords exit = %d, want %d", code, exitFound)
+	}
+
+	for i, r := range records {
+		selected := i == 0 || i == len(records)-1
+		if exists(r.Path) == selected {
+			t.Errorf("%s: exists=%v, selected=%v", r.Path, exists(r.Path), selected)
+		}
+	}
+	assertFreshIntact(t, root, opts.minAge)
+
+	// The partial selection is recorded: two deletions, the rest kept.
+	path, _ := decisionsPath()
+	decisions, err := loadDecisions(path)
+	if err != nil {
+		t.Fatalf("loadDecisions: %v", err)
+	}
+	counts := map[string]int{}
+	for _, d := range decisions {
+		counts[d.Decision]++
+	}
+	if counts[decisionDeleted] != 2 || counts[decisionKept] != len(records)-2 {
+		t.Errorf("decisions = %v, want 2 deleted and %d kept", counts, len(records)-2)
+	}
+}
+
+func TestIntegration_SelectNoneKeepsEverything(t *testing.T) {
+	withSeams(t)
+	root := sandboxDir(t)
+	records, opts := scanFixtures(t, root)
+
+	stdin = strings.NewReader("none\n")
+	deleteRecords(records, opts)
+	for _, r := range records {
+		if !exists(r.Path) {
+			t.Errorf("removed despite 'none': %s", r.Path)
+		}
+	}
+}
+
+func TestIntegration_DeleteCapAbortsConfirmRun(t *testing.T) {
+	withSeams(t)
+	root := sandboxDir(t)
+	records, opts := scanFixtures(t, root)
+	opts.confirm = true
+	opts.maxDeleteItems = len(records) - 1
+
+	if code := deleteRecords(records, opts); code != exitError {
+		t.Errorf("exit = %d, want %d", code, exitError)
+	}
+	for _, r := range records {
+		if !exists(r.Path) {
+			t.Errorf("removed despite exceeding -max-delete-items: %s", r.Path)
+		}
+	}
+}
+
+func TestIntegration_ConfirmDeletesAllAndLogs(t *testing.T) {
+	withSeams(t)
+	root := sandboxDir(t)
+	records, opts := scanFixtures(t, root)
+	opts.confirm = true
+	opts.logFile = filepath.Join(root, "tidyup.log")
+	opts.receipts = true
+
+	deleteRecords(records, opts)
+	for _, r := range records {
+		if exists(r.Path) {
+			t.Errorf("not removed with -confirm: %s", r.Path)
+		}
+	}
+	assertFreshIntact(t, root, opts.minAge)
+
+	log, err := os.ReadFile(opts.logFile)
+	if err != nil {
+		t.Fatalf("reading log: %v", err)
+	}
+	if n := strings.Count(string(log), " Deleted "); n != len(records) {
+		t.Errorf("log has %d Deleted lines, want %d:\n%s", n, len(records), log)
+	}
+
+	path, _ := receiptsPath()
+	receipts, err := loadReceipts(path)
+	if err != nil || len(receipts) != len(records) {
+		t.Errorf("%d receipts (%v), want %d", len(receipts), err, len(records))
+	}
+}
+
+func TestIntegration_TrashCollision(t *testing.T) {
+	withSeams(t)
+	trashSupported = true
+	root := sandboxDir(t)
+	home := filepath.Join(root, "home")
+	trash := filepath.Join(home, ".Trash")
+	os.MkdirAll(trash, 0755)
+	t.Setenv("HOME", home)
+
+	records, opts := scanFixtures(t, filepath.Join(root, "tree"))
+	opts.confirm = true
+	opts.useTrash = true
+
+	// Pre-seed a Trash entry with the same basename as a stale record.
+	os.MkdirAll(filepath.Join(trash, "node_modules"), 0755)
+
+	deleteRecords(records, opts)
+
+	entries, _ := os.ReadDir(trash)
+	// Pre-seeded entry plus one per trashed record.
+	if len(entries) != len(records)+1 {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Fatalf("trash has %d entries, want %d: %v", len(entries), len(records)+1, names)
+	}
+	collided := false
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), "node_modules_") {
+			collided = true
+		}
+	}
+	if !collided {
+		t.Error("expected timestamp-suffixed node_modules entry after collision")
+	}
+	manifest, _ := trashedPath()
+	if items, _ := loadTrashed(manifest); len(items) != len(records) {
+		t.Errorf("trash manifest has %d items, want %d", len(items), len(records))
+	}
+	for _, r := range records {
+		if exists(r.Path) {
+			t.Errorf("still present after trash: %s", r.Path)
+		}
+	}
+}
+
+func TestIntegration_TrashCrossDeviceFallback(t *testing.T) {
+	withSeams(t)
+	trashSupported = true
+	root := sandboxDir(t)
+	home := filepath.Join(root, "home")
+	os.MkdirAll(filepath.Join(home, ".Trash"), 0755)
+	t.Setenv("HOME", home)
+
+	setRemovers(func(rm *removers) {
+		rm.rename = func(oldpath, newpath string) error {
+			return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EXDEV}
+		}
+	})
+
+	records, opts := scanFixtures(t, filepath.Join(root, "tree"))
+	opts.confirm = true
+	opts.useTrash = true
+	deleteRecords(records, opts)
+
+	for _, r := range records {
+		if exists(r.Path) {
+			t.Errorf("source not removed after cross-device move: %s", r.Path)
+		}
+		if !exists(filepath.Join(home, ".Trash", filepath.Base(r.Path))) &&
+			!hasTrashEntryWithPrefix(t, home, filepath.Base(r.Path)+"_") {
+			t.Errorf("no trash copy for %s", r.Path)
+		}
+	}
+	venv := filepath.Join(home, ".Trash", ".venv", "lib/python3.11/site-packages/fixturepkg/__init__.py")
+	info, err := os.Stat(venv)
+	if err != nil {
+		t.Fatalf("copied venv payload missing: %v", err)
+	}
+	if want := fixtureNow.AddDate(0, 0, -120); !info.ModTime().Equal(want) {
+		t.Errorf("copied mtime %v, want preserved %v", info.ModTime(), want)
+	}
+}
+
+func hasTrashEntryWithPrefix(t *testing.T, home, prefix string) bool {
+	t.Helper()
+	entries, _ := os.ReadDir(filepath.Join(home, ".Trash"))
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestIntegration_PartialFailure(t *testing.T) {
+	withSeams(t)
+	root := sandboxDir(t)
+	records, opts := scanFixtures(t, root)
+	opts.confirm = true
+	opts.logFile = filepath.Join(root, "tidyup.log")
+
+	failing := records[1].Path
+	setRemovers(func(rm *removers) {
+		rm.removeAll = func(p string) error {
+			if p == failing {
+				return errors.New("simulated permission denied")
+			}
+			return os.RemoveAll(p)
+		}
+	})
+
+	deleteRecords(records, opts)
+	for _, r := range records {
+		if want := r.Path == failing; exists(r.Path) != want {
+			t.Errorf("%s: exists=%v, want %v", r.Path, exists(r.Path), want)
+		}
+	}
+
+	log, _ := os.ReadFile(opts.logFile)
+	if !strings.Contains(string(log), "ERROR "+failing) {
+		t.Errorf("log missing ERROR line for %s:\n%s", failing, log)
+	}
+}
+
+// An interrupted run is resumed by re-running: the rescan finds only what is
+// left, and nothing fresh is touched in either pass.
+func TestIntegration_InterruptAndRerun(t *testing.T) {
+	withSeams(t)
+	root := sandboxDir(t)
+	records, opts := scanFixtures(t, root)
+	opts.confirm = true
+
+	calls := 0
+	setRemovers(func(rm *removers) {
+		rm.removeAll = func(p string) error {
+			calls++
+			if calls > 2 {
+				return errors.New("interrupted")
+			}
+			return os.RemoveAll(p)
+		}
+	})
+	deleteRecords(records, opts)
+
+	removersFunc = newRemovers
+	remaining, errs := scanRoots([]string{root}, opts)
+	if len(errs) != 0 {
+		t.Fatalf("rescan errors: %v", errs)
+	}
+	if len(remaining) != len(records)-2 {
+		t.Fatalf("rescan found %d records, want %d", len(remaining), len(records)-2)
+	}
+	deleteRecords(remaining, opts)
+
+	for _, p := range staleFixturePaths(root, opts.minAge) {
+		if exists(p) {
+			t.Errorf("stale fixture survived rerun: %s", p)
+		}
+	}
+	assertFreshIntact(t, root, opts.minAge)
+}
+
+func TestIntegration_DelegateRunsNativeCommand(t *testing.T) {
+	withSeams(t)
+	root := sandboxDir(t)
+	records, opts := scanFixtures(t, root)
+	opts.confirm = true
+	opts.delegate = true
+
+	var ran []string
+	setRemovers(func(rm *removers) {
+		rm.runCommand = func(command string) error {
+			ran = append(ran, command)
+			return nil
+		}
+	})
+	deleteRecords(records, opts)
+
+	for _, r := range records {
+		if r.Command == "" && exists(r.Path) {
+			t.Errorf("no native command, should be deleted: %s", r.Path)
+		}
+		if r.Command != "" && !exists(r.Path) {
+			t.Errorf("delegated item was deleted directly: %s", r.Path)
+		}
+	}
+	condaEnv := filepath.Join(root, "home/miniconda3/envs/ml")
+	want := []string{condaRemoveCommand(condaEnv), "pipx uninstall httpie"}
+	sort.Strings(ran)
+	if len(ran) != len(want) || ran[0] != want[0] || ran[1] != want[1] {
+		t.Errorf("ran %v, want %v", ran, want)
+	}
+}
+
+func TestIntegration_PlanThenApply(t *testing.T) {
+	withSeams(t)
+	root := sandboxDir(t)
+	records, opts := scanFixtures(t, root)
+	opts.confirm = true
+	opts.planFile = filepath.Join(root, "plan.json")
+
+	if code := deleteRecords(records, opts); code != exitFound {
+		t.Fatalf("-plan exit = %d, want %d", code, exitFound)
+	}
+	for _, r := range records {
+		if !exists(r.Path) {
+			t.Fatalf("-plan removed %s", r.Path)
+		}
+	}
+
+	// An item used after planning is no longer what was approved.
+	touched := records[0].Path
+	if err := os.WriteFile(filepath.Join(touched, "used-again"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code := runApply([]string{opts.planFile}); code != exitFound {
+		t.Fatalf("apply exit = %d, want %d", code, exitFound)
+	}
+	for _, r := range records {
+		if exists(r.Path) != (r.Path == touched) {
+			t.Errorf("%s: exists=%v after apply", r.Path, exists(r.Path))
+		}
+	}
+	assertFreshIntact(t, root, opts.minAge)
+}
+
+func TestIntegration_ProposeApproveRun(t *testing.T) {
+	withSeams(t)
+	root := sandboxDir(t)
+	records, opts := scanFixtures(t, root)
+	opts.confirm = true
+	opts.propose = true
+	opts.queueFile = filepath.Join(root, "queue.json")
+
+	if code := deleteRecords(records, opts); code != exitFound {
+		t.Fatalf("-propose exit = %d, want %d", code, exitFound)
+	}
+	// Nothing runs until it is approved.
+	if code := runQueue([]string{"-queue", opts.queueFile, "run"}); code != exitOK {
+		t.Errorf("run with nothing approved: exit = %d", code)
+	}
+	for _, r := range records {
+		if !exists(r.Path) {
+			t.Fatalf("unapproved item removed: %s", r.Path)
+		}
+	}
+
+	if code := runQueue([]string{"-queue", opts.queueFile, "approve", "1", "2"}); code != exitOK {
+		t.Fatalf("approve exit = %d", code)
+	}
+	if code := runQueue([]string{"-queue", opts.queueFile, "run"}); code != exitFound {
+		t.Fatalf("run exit = %d, want %d", code, exitFound)
+	}
+	for i, r := range records {
+		if exists(r.Path) == (i < 2) {
+			t.Errorf("%s: exists=%v, approved=%v", r.Path, exists(r.Path), i < 2)
+		}
+	}
+	q, err := loadQueue(opts.queueFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Items) != len(records)-2 {
+		t.Errorf("queue keeps %d items after run, want %d pending", len(q.Items), len(records)-2)
+	}
+}
+
+// countFiles returns the number of regular files under path.
+func countFiles(path string) int {
+	n := 0
+	filepath.WalkDir(path, func(_ string, d os.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			n++
+		}
+		return nil
+	})
+	return n
+}
+
+// Trashed items come back where they were; one whose path was recreated in
+// the meantime comes back beside it, under a .restored-<time> name.
+func TestIntegration_TrashThenRestore(t *testing.T) {
+	withSeams(t)
+	trashSupported = true
+	root := sandboxDir(t)
+	home := filepath.Join(root, "home")
+	os.MkdirAll(filepath.Join(home, ".Trash"), 0755)
+	t.Setenv("HOME", home)
+
+	tree := filepath.Join(root, "tree")
+	records, opts := scanFixtures(t, tree)
+	opts.confirm = true
+	opts.useTrash = true
+	files := map[string]int{}
+	conflict := ""
+	for _, r := range records {
+		files[r.Path] = countFiles(r.Path)
+		if conflict == "" && !r.File {
+			conflict = r.Path
+		}
+	}
+	deleteRecords(records, opts)
+	for _, r := range records {
+		if exists(r.Path) {
+			t.Fatalf("still present after trash: %s", r.Path)
+		}
+	}
+
+	// Something is recreated at one original path before the restore.
+	os.MkdirAll(conflict, 0755)
+	os.WriteFile(filepath.Join(conflict, "recreated"), []byte("x"), 0644)
+
+	if code := runRestore([]string{"-project", tree}); code != exitOK {
+		t.Fatalf("restore exit = %d", code)
+	}
+	for _, r := range records {
+		if r.Path == conflict {
+			continue
+		}
+		if got := countFiles(r.Path); got != files[r.Path] {
+			t.Errorf("%s: %d files after restore, want %d", r.Path, got, files[r.Path])
+		}
+	}
+	if !exists(filepath.Join(conflict, "recreated")) || countFiles(conflict) != 1 {
+		t.Errorf("the recreated %s was overwritten", conflict)
+	}
+	beside, _ := filepath.Glob(conflict + ".restored-*")
+	if len(beside) != 1 || countFiles(beside[0]) != files[conflict] {
+		t.Errorf("restored beside %s: %v, want one copy with %d files", conflict, beside, files[conflict])
+	}
+	assertFreshIntact(t, tree, opts.minAge)
+
+	// Restored items leave the manifest: a second restore finds nothing.
+	manifest, _ := trashedPath()
+	if items, _ := loadTrashed(manifest); len(items) != 0 {
+		t.Errorf("trash manifest still lists %d items", len(items))
+	}
+	if entries, _ := os.ReadDir(filepath.Join(home, ".Trash")); len(entries) != 0 {
+		t.Errorf("Trash still holds %d entries", len(entries))
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// maxSymlinkHops bounds symlink chain resolution, matching common OS limits.
+const maxSymlinkHops = 40
+
+// interpreterInfo describes the Python a venv was created from.
+type interpreterInfo struct {
+	path    string // final target of the bin/python symlink chain
+	version string // e.g. "3.11.6"; "" when unknown
+	missing bool   // the chain ends at a file that no longer exists
+}
+
+// venvInterpreterNames are the interpreter entry points checked, in order.
+var venvInterpreterNames = []string{"bin/python", "bin/python3", "Scripts/python.exe"}
+
+// venvInterpreter resolves a venv's interpreter. Framework builds on macOS
+// and Homebrew installs reach the real binary through several symlinks
+// (bin/python -> python3.11 -> /opt/homebrew/opt/python@3.11/bin/python3.11
+// -> ../Cellar/...), and upgrades or `brew cleanup` leave the chain dangling.
+func venvInterpreter(venv string) (interpreterInfo, bool) {
+	for _, name := range venvInterpreterNames {
+		entry := filepath.Join(venv, filepath.FromSlash(name))
+		if _, err := os.Lstat(entry); err != nil {
+			continue
+		}
+		target, err := resolveChain(entry)
+		info := interpreterInfo{path: target, missing: err != nil}
+		info.version = pyvenvVersion(venv)
+		if info.version == "" {
+			info.version = versionFromPath(target)
+		}
+		return info, true
+	}
+	return interpreterInfo{}, false
+}
+
+// resolveChain follows symlinks from p one hop at a time, returning the last
+// path reached. Unlike filepath.EvalSymlinks it reports where a broken chain
+// was heading, which is what a user needs to see.
+func resolveChain(p string) (string, error) {
+	for i := 0; i < maxSymlinkHops; i++ {
+		info, err := os.Lstat(p)
+		if err != nil {
+			return p, err
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			return p, nil
+		}
+		link, err := os.Readlink(p)
+		if err != nil {
+			return p, err
+		}
+		if !filepath.IsAbs(link) {
+			link = filepath.Join(filepath.Dir(p), link)
+		}
+		p = filepath.Clean(link)
+	}
+	return p, &os.PathError{Op: "resolve", Path: p, Err: os.ErrInvalid}
+}
+
+// pyvenvVersion reads the interpreter version recorded in pyvenv.cfg:
+// "version" (venv), "version_info" (virtualenv, uv).
+func pyvenvVersion(venv string) string {
+	f, err := os.Open(filepath.Join(venv, "pyvenv.cfg"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		key, value, ok := strings.Cut(sc.Text(), "=")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "version", "version_info":
+			v := strings.TrimSpace(value)
+			// virtualenv writes "3.11.6.final.0".
+			if parts := strings.Split(v, "."); len(parts) > 3 {
+				v = strings.Join(parts[:3], ".")
+			}
+			return v
+		}
+	}
+	return ""
+}
+
+// pathVersion finds a Major.Minor version in interpreter paths such as
+// ".../python3.11" or ".../Python.framework/Versions/3.12/bin/python3".
+var pathVersion = regexp.MustCompile(`(?:python@?|Versions/)(\d+\.\d+)`)
+
+// versionFromPath infers the interpreter version from its resolved path.
+func versionFromPath(p string) string {
+	if m := pathVersion.FindStringSubmatch(filepath.ToSlash(p)); m != nil {
+		return m[1]
+	}
+	return ""
+}
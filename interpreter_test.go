@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// makeBrewVenv builds a venv whose bin/python reaches a Homebrew-style
+// Cellar interpreter through relative and absolute symlinks.
+func makeBrewVenv(t *testing.T, root string) (venv, real string) {
+	t.Helper()
+	cellar := filepath.Join(root, "Cellar", "python@3.11", "3.11.6", "Frameworks",
+		"Python.framework", "Versions", "3.11", "bin")
+	os.MkdirAll(cellar, 0755)
+	real = filepath.Join(cellar, "python3.11")
+	os.WriteFile(real, []byte("#!"), 0755)
+	opt := filepath.Join(root, "opt", "python@3.11", "bin")
+	os.MkdirAll(opt, 0755)
+	os.Symlink(real, filepath.Join(opt, "python3.11"))
+
+	venv = filepath.Join(root, "proj", ".venv")
+	os.MkdirAll(filepath.Join(venv, "bin"), 0755)
+	os.WriteFile(filepath.Join(venv, "pyvenv.cfg"), []byte("home = "+opt+"\n"), 0644)
+	os.Symlink(filepath.Join(opt, "python3.11"), filepath.Join(venv, "bin", "python3.11"))
+	os.Symlink("python3.11", filepath.Join(venv, "bin", "python"))
+	return venv, real
+}
+
+func TestVenvInterpreter_SymlinkChain(t *testing.T) {
+	venv, real := makeBrewVenv(t, t.TempDir())
+	info, ok := venvInterpreter(venv)
+	if !ok {
+		t.Fatal("no interpreter found")
+	}
+	if info.path != real || info.missing {
+		t.Errorf("path=%s missing=%v, want %s present", info.path, info.missing, real)
+	}
+	if info.version != "3.11" {
+		t.Errorf("version = %q, want 3.11 from path", info.version)
+	}
+}
+
+func TestVenvInterpreter_DanglingAfterUpgrade(t *testing.T) {
+	root := t.TempDir()
+	venv, _ := makeBrewVenv(t, root)
+	os.RemoveAll(filepath.Join(root, "Cellar")) // brew cleanup
+
+	info, ok := venvInterpreter(venv)
+	if !ok || !info.missing {
+		t.Fatalf("info=%+v ok=%v, want missing interpreter", info, ok)
+	}
+	if filepath.Base(info.path) != "python3.11" || !filepath.IsAbs(info.path) {
+		t.Errorf("path = %s, want the Cellar target the chain pointed at", info.path)
+	}
+}
+
+func TestPyvenvVersion(t *testing.T) {
+	for cfg, want := range map[string]string{
+		"home = /usr/bin\nversion = 3.12.1\n":              "3.12.1",
+		"home = /usr/bin\nversion_info = 3.11.6.final.0\n": "3.11.6",
+		"home = /usr/bin\n":                                "",
+	} {
+		dir := t.TempDir()
+		os.WriteFile(filepath.Join(dir, "pyvenv.cfg"), []byte(cfg), 0644)
+		if got := pyvenvVersion(dir); got != want {
+			t.Errorf("pyvenvVersion(%q) = %q, want %q", cfg, got, want)
+		}
+	}
+}
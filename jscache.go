@@ -0,0 +1,99 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Project node_modules are only half of what JavaScript package managers
+// keep: each also has a per-user store every install draws from, which
+// only grows. npm's content cache (_cacache), yarn's global cache (classic
+// v6 and Berry's), and pnpm's content-addressable store are each a
+// js_cache item under -system, with the manager's own cleanup command.
+// pnpm hard-links its store into every project's node_modules, so removing
+// the store frees only what no project links; it is advisory, reclaimed
+// with `pnpm store prune`, which drops exactly the unreferenced packages.
+
+// jsCacheDirs lists each manager's store locations: the environment
+// overrides, then the per-OS defaults.
+func jsCacheDirs(home string) map[string][]string {
+	xdgCache := filepath.Join(home, ".cache")
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		xdgCache = dir
+	}
+	xdgData := filepath.Join(home, ".local", "share")
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		xdgData = dir
+	}
+	npm := filepath.Join(home, ".npm")
+	if dir := os.Getenv("npm_config_cache"); dir != "" {
+		npm = dir
+	}
+	yarn := []string{os.Getenv("YARN_CACHE_FOLDER")}
+	if yarn[0] == "" {
+		yarn = []string{
+			filepath.Join(xdgCache, "yarn", "v6"),
+			filepath.Join(home, "Library", "Caches", "Yarn", "v6"),
+			filepath.Join(home, "AppData", "Local", "Yarn", "Cache", "v6"),
+		}
+	}
+	yarn = append(yarn, filepath.Join(home, ".yarn", "berry", "cache"))
+	var pnpm []string
+	for _, base := range []string{
+		filepath.Join(xdgData, "pnpm", "store"),
+		filepath.Join(home, "Library", "pnpm", "store"),
+		filepath.Join(home, "AppData", "Local", "pnpm", "store"),
+	} {
+		// One store per layout version (v3, v10), each complete on its own.
+		versions, _ := filepath.Glob(filepath.Join(base, "v*"))
+		pnpm = append(pnpm, versions...)
+	}
+	return map[string][]string{
+		"npm":  {filepath.Join(npm, "_cacache")},
+		"yarn": yarn,
+		"pnpm": pnpm,
+	}
+}
+
+// findJSCaches returns the package manager stores that exist.
+func findJSCaches(home string) []string {
+	var items []string
+	for _, manager := range []string{"npm", "yarn", "pnpm"} {
+		items = append(items, existingDirs(jsCacheDirs(home)[manager]...)...)
+	}
+	return items
+}
+
+// jsCacheManager tells which package manager a js_cache item belongs to.
+func jsCacheManager(path string) string {
+	slash := strings.ToLower(filepath.ToSlash(path))
+	switch {
+	case filepath.Base(path) == "_cacache":
+		return "npm"
+	case strings.Contains(slash, "/pnpm/"):
+		return "pnpm"
+	default:
+		return "yarn"
+	}
+}
+
+// annotateJSCache names the manager and its cleanup command.
+func annotateJSCache(r *Record) {
+	manager := jsCacheManager(r.Path)
+	r.Tool = manager
+	switch manager {
+	case "npm":
+		r.Command = "npm cache clean --force"
+	case "yarn":
+		if strings.Contains(filepath.ToSlash(r.Path), "/berry/") {
+			r.Command = "yarn cache clean --mirror"
+		} else {
+			r.Command = "yarn cache clean"
+		}
+	case "pnpm":
+		r.Command = "pnpm store prune"
+		r.Advisory = true
+		r.Notes = append(r.Notes, "projects hard-link into the store; pruning removes only packages none of them use")
+	}
+}
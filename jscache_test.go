@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestScanRoots_JSCaches(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	for _, v := range []string{"XDG_CACHE_HOME", "XDG_DATA_HOME", "npm_config_cache", "YARN_CACHE_FOLDER"} {
+		t.Setenv(v, "")
+	}
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	old := time.Now().AddDate(0, 0, -90)
+	for _, rel := range []string{
+		".npm/_cacache/index-v5/ab/cd/entry",
+		".npm/_logs/debug.log", // not the cache
+		".cache/yarn/v6/npm-left-pad-1.3.0/package.json",
+		".local/share/pnpm/store/v10/files/00/abc",
+	} {
+		p := filepath.Join(home, rel)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte("x"), 0644)
+		os.Chtimes(p, old, old)
+	}
+
+	opts := &options{minAge: 30, maxDepth: 1, systemScan: true, scanTypes: map[string]bool{"js_cache": true}}
+	records, _ := scanRoots(nil, opts)
+	got := map[string]Record{}
+	for _, r := range records {
+		got[r.Tool] = r
+	}
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want npm, yarn, pnpm: %+v", len(records), records)
+	}
+	if r := got["npm"]; r.Path != filepath.Join(home, ".npm", "_cacache") || r.Command != "npm cache clean --force" || r.Advisory {
+		t.Errorf("npm: %+v", r)
+	}
+	if r := got["yarn"]; r.Command != "yarn cache clean" {
+		t.Errorf("yarn: %+v", r)
+	}
+	if r := got["pnpm"]; r.Path != filepath.Join(home, ".local", "share", "pnpm", "store", "v10") || r.Command != "pnpm store prune" || !r.Advisory {
+		t.Errorf("pnpm: %+v", r)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Dragging an app to the Trash leaves its data behind in ~/Library. The
+// app_leftovers type reports entries named after a bundle identifier that
+// no installed app claims (see bundles.go). They are review-only: an app
+// may live outside the searched folders, so tidyup never deletes them.
+
+// leftoverDirs are the ~/Library folders apps keep per-app data in, and
+// whether their entries are files (<bundle-id>.plist) rather than folders.
+var leftoverDirs = []struct {
+	dir   string
+	files bool
+}{
+	{"Application Support", false},
+	{"Caches", false},
+	{"Containers", false},
+	{"Preferences", true},
+}
+
+// leftoverBundleID returns the bundle identifier an entry is named after.
+func leftoverBundleID(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), ".plist")
+}
+
+// findAppLeftovers returns per-app data in ~/Library whose app is gone.
+func findAppLeftovers(home string) []string {
+	var items []string
+	for _, loc := range leftoverDirs {
+		dir := filepath.Join(home, "Library", loc.dir)
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name := e.Name()
+			if loc.files != !e.IsDir() || (loc.files && !strings.HasSuffix(name, ".plist")) {
+				continue
+			}
+			if isOrphanedBundle(leftoverBundleID(name)) {
+				items = append(items, filepath.Join(dir, name))
+			}
+		}
+	}
+	return items
+}
+
+// annotateAppLeftover holds a leftover for review and names the missing app.
+func annotateAppLeftover(r *Record) {
+	id := leftoverBundleID(r.Path)
+	r.Tool = id + " (not installed)"
+	r.Review = "app " + id + " is not installed; confirm it is gone for good"
+	r.Notes = append(r.Notes, "no app in /Applications, /System/Applications, or ~/Applications has bundle id "+id)
+}
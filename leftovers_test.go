@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestFindAppLeftovers(t *testing.T) {
+	home := t.TempDir()
+	apps := t.TempDir()
+	fakeApp(t, apps, "Kept", "com.example.Kept")
+	withApps(t, apps)
+
+	lib := filepath.Join(home, "Library")
+	for _, dir := range []string{
+		"Application Support/com.example.Gone",
+		"Application Support/com.example.Kept",
+		"Application Support/Slack", // named after the app, not attributable
+		"Containers/com.example.Gone",
+		"Caches/com.apple.Safari",
+	} {
+		os.MkdirAll(filepath.Join(lib, dir), 0755)
+	}
+	os.MkdirAll(filepath.Join(lib, "Preferences"), 0755)
+	for _, f := range []string{"com.example.Gone.plist", "com.example.Kept.plist", ".GlobalPreferences.plist"} {
+		os.WriteFile(filepath.Join(lib, "Preferences", f), []byte("x"), 0644)
+	}
+
+	got := findAppLeftovers(home)
+	sort.Strings(got)
+	want := []string{
+		filepath.Join(lib, "Application Support/com.example.Gone"),
+		filepath.Join(lib, "Containers/com.example.Gone"),
+		filepath.Join(lib, "Preferences/com.example.Gone.plist"),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("leftovers = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("leftover %d = %s, want %s", i, got[i], want[i])
+		}
+	}
+
+	r := Record{Path: want[2]}
+	annotateAppLeftover(&r)
+	if r.Review == "" || r.Tool != "com.example.Gone (not installed)" {
+		t.Errorf("annotated leftover = %+v, want review-only with the bundle id", r)
+	}
+}
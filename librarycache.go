@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// ~/Library is skipped wholesale because most of it is app data, but
+// ~/Library/Caches holds tens of GB of regenerable per-app caches. With
+// -include-library-caches, the subdirectories named below and the caches of
+// apps that are no longer installed (see bundles.go) are reported as
+// library_cache records, attributed to their app.
+
+// libraryCacheApps is the allowlist of ~/Library/Caches entries known to be
+// safe to delete, keyed by directory name (usually a bundle identifier),
+// with the app they belong to.
+var libraryCacheApps = map[string]string{
+	"pip":                         "pip",
+	"pypoetry":                    "Poetry",
+	"Homebrew":                    "Homebrew",
+	"Yarn":                        "Yarn",
+	"go-build":                    "Go build cache",
+	"node-gyp":                    "node-gyp",
+	"typescript":                  "TypeScript",
+	"ms-playwright":               "Playwright browsers",
+	"Cypress":                     "Cypress",
+	"CocoaPods":                   "CocoaPods",
+	"org.carthage.CarthageKit":    "Carthage",
+	"deno":                        "Deno",
+	"electron":                    "Electron downloads",
+	"electron-builder":            "electron-builder",
+	"JetBrains":                   "JetBrains IDEs",
+	"com.apple.dt.Xcode":          "Xcode",
+	"com.google.Chrome":           "Google Chrome",
+	"com.microsoft.VSCode":        "Visual Studio Code",
+	"com.microsoft.VSCode.ShipIt": "Visual Studio Code updater",
+	"com.tinyspeck.slackmacgap":   "Slack",
+	"com.spotify.client":          "Spotify",
+	"com.docker.docker":           "Docker Desktop",
+	"us.zoom.xos":                 "Zoom",
+}
+
+// libraryCachesDir is the per-user cache directory on macOS.
+func libraryCachesDir(home string) string {
+	return filepath.Join(home, "Library", "Caches")
+}
+
+// findLibraryCaches returns the subdirectories of ~/Library/Caches that are
+// allowlisted or belong to an app that is no longer installed.
+func findLibraryCaches(home string) []string {
+	entries, err := os.ReadDir(libraryCachesDir(home))
+	if err != nil {
+		return nil
+	}
+	var dirs []string
+	for _, e := range entries {
+		if _, ok := libraryCacheApps[e.Name()]; (ok || isOrphanedBundle(e.Name())) && e.IsDir() {
+			dirs = append(dirs, filepath.Join(libraryCachesDir(home), e.Name()))
+		}
+	}
+	return dirs
+}
+
+// annotateLibraryCache attributes a cache to its app, by the installed app's
+// own name where it can be resolved, and flags caches of uninstalled apps.
+func annotateLibraryCache(r *Record) {
+	id := filepath.Base(r.Path)
+	name, known := libraryCacheApps[id]
+	if !isBundleID(id) {
+		r.Tool = name
+		return
+	}
+	app, installed := installedApps.lookup(id)
+	switch {
+	case installed:
+		r.Tool = app.name + " (" + id + ")"
+		r.Notes = append(r.Notes, "quit "+app.name+" before deleting; it rebuilds the cache on demand")
+	case isOrphanedBundle(id):
+		if !known {
+			name = id
+		}
+		r.Tool = name + " (not installed)"
+		r.Notes = append(r.Notes, "orphan: no installed app has bundle id "+id)
+	default:
+		r.Tool = name + " (" + id + ")"
+	}
+}
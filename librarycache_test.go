@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeApp installs a minimal .app bundle with an XML Info.plist.
+func fakeApp(t *testing.T, dir, name, id string) {
+	t.Helper()
+	contents := filepath.Join(dir, name+".app", "Contents")
+	os.MkdirAll(contents, 0755)
+	plist := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>CFBundleIdentifier</key>
+	<string>` + id + `</string>
+	<key>CFBundleDocumentTypes</key>
+	<array><dict><key>CFBundleName</key><string>nested, ignored</string></dict></array>
+	<key>CFBundleName</key>
+	<string>` + name + `</string>
+</dict>
+</plist>`
+	os.WriteFile(filepath.Join(contents, "Info.plist"), []byte(plist), 0644)
+}
+
+// withApps points installedApps at a fresh index of dir for one test.
+func withApps(t *testing.T, dir string) {
+	t.Helper()
+	orig := installedApps
+	installedApps = newAppIndex([]string{dir})
+	t.Cleanup(func() { installedApps = orig })
+}
+
+func TestAppIndex_Lookup(t *testing.T) {
+	apps := t.TempDir()
+	fakeApp(t, apps, "Visual Studio Code", "com.microsoft.VSCode")
+	fakeApp(t, filepath.Join(apps, "Setapp"), "CleanShot X", "pl.maketheweb.cleanshotx-setapp")
+	withApps(t, apps)
+
+	if app, ok := installedApps.lookup("com.microsoft.VSCode.ShipIt"); !ok || app.name != "Visual Studio Code" {
+		t.Errorf("helper id: got %+v, %v", app, ok)
+	}
+	if _, ok := installedApps.lookup("pl.maketheweb.cleanshotx-setapp"); !ok {
+		t.Error("app in a vendor folder not indexed")
+	}
+	if !isOrphanedBundle("com.example.Gone") || isOrphanedBundle("com.apple.Safari") || isOrphanedBundle("pip") {
+		t.Error("orphan detection: want only com.example.Gone")
+	}
+}
+
+func TestScanRoots_LibraryCaches(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	apps := t.TempDir()
+	fakeApp(t, apps, "Google Chrome", "com.google.Chrome")
+	withApps(t, apps)
+
+	old := time.Now().AddDate(0, 0, -90)
+	for _, name := range []string{"com.google.Chrome", "pip", "com.apple.Safari", "com.example.Gone", "com.example.Installed"} {
+		f := filepath.Join(libraryCachesDir(home), name, "data")
+		os.MkdirAll(filepath.Dir(f), 0755)
+		os.WriteFile(f, []byte("cache"), 0644)
+		os.Chtimes(f, old, old)
+	}
+	fakeApp(t, apps, "Installed", "com.example.Installed")
+
+	opts := &options{minAge: 30, maxDepth: 5, scanTypes: map[string]bool{"library_cache": true}}
+	records, _ := scanRoots([]string{t.TempDir()}, opts)
+	sort.Slice(records, func(i, j int) bool { return records[i].Path < records[j].Path })
+	var tools []string
+	for _, r := range records {
+		tools = append(tools, r.Tool)
+	}
+	// Safari is Apple's and com.example.Installed is neither allowlisted
+	// nor orphaned.
+	want := "com.example.Gone (not installed)|Google Chrome (com.google.Chrome)|pip"
+	if strings.Join(tools, "|") != want {
+		t.Errorf("attribution = %q, want %q", strings.Join(tools, "|"), want)
+	}
+	if !strings.Contains(strings.Join(records[0].Notes, ";"), "orphan") {
+		t.Errorf("orphan notes = %v", records[0].Notes)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// localeFormat controls number and date rendering in human-readable output.
+// The zero value is the C locale: "." decimal, no grouping, ISO dates.
+// JSON output never uses it.
+type localeFormat struct {
+	decimal    string // decimal separator
+	group      string // thousands separator ("" for none)
+	dateLayout string // time.Format layout for calendar dates
+}
+
+// knownLocales maps language_TERRITORY (or bare language) to its conventions.
+var knownLocales = map[string]localeFormat{
+	"C":     {".", "", "2006-01-02"},
+	"POSIX": {".", "", "2006-01-02"},
+	"en":    {".", ",", "2006-01-02"},
+	"en_US": {".", ",", "01/02/2006"},
+	"en_GB": {".", ",", "02/01/2006"},
+	"en_AU": {".", ",", "02/01/2006"},
+	"en_CA": {".", ",", "2006-01-02"},
+	"de":    {",", ".", "02.01.2006"},
+	"de_CH": {".", "’", "02.01.2006"},
+	"fr":    {",", " ", "02/01/2006"},
+	"fr_CA": {",", " ", "2006-01-02"},
+	"es":    {",", ".", "02/01/2006"},
+	"it":    {",", ".", "02/01/2006"},
+	"nl":    {",", ".", "02-01-2006"},
+	"pt":    {",", ".", "02/01/2006"},
+	"sv":    {",", " ", "2006-01-02"},
+	"pl":    {",", " ", "02.01.2006"},
+	"ru":    {",", " ", "02.01.2006"},
+	"ja":    {".", ",", "2006/01/02"},
+	"zh":    {".", ",", "2006/01/02"},
+	"ko":    {".", ",", "2006. 01. 02."},
+}
+
+// lookupLocale resolves a POSIX locale name such as "de_DE.UTF-8@euro",
+// trying language_TERRITORY first, then the bare language.
+func lookupLocale(name string) (localeFormat, bool) {
+	if i := strings.IndexAny(name, ".@"); i >= 0 {
+		name = name[:i]
+	}
+	name = strings.ReplaceAll(name, "-", "_")
+	if lf, ok := knownLocales[name]; ok {
+		return lf, true
+	}
+	if i := strings.Index(name, "_"); i > 0 {
+		if lf, ok := knownLocales[name[:i]]; ok {
+			return lf, true
+		}
+	}
+	return localeFormat{}, false
+}
+
+// envLocale returns the effective POSIX locale for a category, following
+// the LC_ALL > LC_<category> > LANG precedence.
+func envLocale(category string) string {
+	for _, key := range []string{"LC_ALL", category, "LANG"} {
+		if v := os.Getenv(key); v != "" {
+			return v
+		}
+	}
+	return "C"
+}
+
+// parseLocale converts the -locale flag into a localeFormat. "" keeps the C
+// locale; "auto" honors LC_NUMERIC and LC_TIME separately.
+func parseLocale(value string) (localeFormat, error) {
+	switch value {
+	case "":
+		return localeFormat{}, nil
+	case "auto":
+		numeric, _ := lookupLocale(envLocale("LC_NUMERIC"))
+		dates, _ := lookupLocale(envLocale("LC_TIME"))
+		numeric.dateLayout = dates.dateLayout
+		return numeric, nil
+	}
+	lf, ok := lookupLocale(value)
+	if !ok {
+		return localeFormat{}, fmt.Errorf("unsupported locale %q", value)
+	}
+	return lf, nil
+}
+
+// integer formats n with the locale's thousands separator.
+func (l localeFormat) integer(n int64) string {
+	s := fmt.Sprintf("%d", n)
+	if l.group == "" {
+		return s
+	}
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	var b strings.Builder
+	for i, c := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteString(l.group)
+		}
+		b.WriteRune(c)
+	}
+	if neg {
+		return "-" + b.String()
+	}
+	return b.String()
+}
+
+// bytes is the locale-aware counterpart of formatBytes.
+func (l localeFormat) bytes(b int64) string {
+	s := formatBytes(b)
+	num, unit, _ := strings.Cut(s, " ")
+	whole, frac, hasFrac := strings.Cut(num, ".")
+	var n int64
+	fmt.Sscanf(whole, "%d", &n)
+	out := l.integer(n)
+	if hasFrac {
+		dec := l.decimal
+		if dec == "" {
+			dec = "."
+		}
+		out += dec + frac
+	}
+	return out + " " + unit
+}
+
+// days formats an age in whole days.
+func (l localeFormat) days(age float64) string {
+	return l.integer(int64(age+0.5)) + "d"
+}
+
+// percent formats a percentage with one decimal.
+func (l localeFormat) percent(p float64) string {
+	s := fmt.Sprintf("%.1f%%", p)
+	if l.decimal != "" && l.decimal != "." {
+		s = strings.Replace(s, ".", l.decimal, 1)
+	}
+	return s
+}
+
+// date formats a calendar date in the locale's layout.
+func (l localeFormat) date(t time.Time) string {
+	layout := l.dateLayout
+	if layout == "" {
+		layout = "2006-01-02"
+	}
+	return t.Format(layout)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLocaleInteger(t *testing.T) {
+	tests := []struct {
+		locale string
+		n      int64
+		want   string
+	}{
+		{"C", 1234567, "1234567"},
+		{"en_US", 1234567, "1,234,567"},
+		{"de_DE", 1234567, "1.234.567"},
+		{"en_US", 999, "999"},
+		{"en_US", -1234, "-1,234"},
+	}
+	for _, tt := range tests {
+		lf, err := parseLocale(tt.locale)
+		if err != nil {
+			t.Fatalf("parseLocale(%q): %v", tt.locale, err)
+		}
+		if got := lf.integer(tt.n); got != tt.want {
+			t.Errorf("%s integer(%d) = %q, want %q", tt.locale, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestLocaleBytes(t *testing.T) {
+	de, _ := parseLocale("de_DE.UTF-8")
+	if got := de.bytes(1610612736); got != "1,5 GB" {
+		t.Errorf("de_DE bytes = %q, want %q", got, "1,5 GB")
+	}
+	us, _ := parseLocale("en_US")
+	if got := us.bytes(1023); got != "1,023 B" {
+		t.Errorf("en_US bytes = %q, want %q", got, "1,023 B")
+	}
+	var c localeFormat
+	if got := c.bytes(1048576); got != formatBytes(1048576) {
+		t.Errorf("zero locale bytes = %q, want formatBytes output %q", got, formatBytes(1048576))
+	}
+}
+
+func TestLocaleDate(t *testing.T) {
+	d := time.Date(2025, 11, 3, 0, 0, 0, 0, time.UTC)
+	tests := map[string]string{
+		"":      "2025-11-03",
+		"en_US": "11/03/2025",
+		"de_DE": "03.11.2025",
+		"ja_JP": "2025/11/03",
+	}
+	for locale, want := range tests {
+		lf, _ := parseLocale(locale)
+		if got := lf.date(d); got != want {
+			t.Errorf("%q date = %q, want %q", locale, got, want)
+		}
+	}
+}
+
+func TestParseLocale_AutoHonorsCategories(t *testing.T) {
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LANG", "en_US.UTF-8")
+	t.Setenv("LC_NUMERIC", "de_DE.UTF-8")
+	t.Setenv("LC_TIME", "")
+
+	lf, err := parseLocale("auto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lf.decimal != "," {
+		t.Errorf("decimal = %q, want LC_NUMERIC's ','", lf.decimal)
+	}
+	if lf.dateLayout != "01/02/2006" {
+		t.Errorf("dateLayout = %q, want LANG's en_US layout", lf.dateLayout)
+	}
+}
+
+func TestParseLocale_Unknown(t *testing.T) {
+	if _, err := parseLocale("xx_YY"); err == nil {
+		t.Error("expected error for unknown locale")
+	}
+}
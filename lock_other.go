@@ -0,0 +1,12 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// lockFile is a no-op where flock is unavailable; state updates there are
+// not serialized between processes.
+func lockFile(f *os.File) error { return nil }
+
+// unlockFile releases lockFile's lock.
+func unlockFile(f *os.File) error { return nil }
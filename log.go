@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The -log file is rotated instead of growing forever: when it passes
+// -log-max-size or its first entry is older than -log-max-age it is renamed
+// to <name>.<timestamp>, and only the newest -log-keep rotated files are
+// kept. "-log state" puts it in the state directory, for scheduled runs that
+// have nowhere better to log to.
+
+const (
+	logFormatText  = "text"
+	logFormatJSONL = "jsonl"
+)
+
+// logInState is the -log value that selects the state directory.
+const logInState = "state"
+
+// logStampFormat suffixes rotated log files; it sorts chronologically.
+const logStampFormat = "20060102-150405"
+
+// logEntry is one line of a JSONL deletion log.
+type logEntry struct {
+	Time  string `json:"time"` // RFC3339
+	Event string `json:"event"`
+	Type  string `json:"type,omitempty"`
+	Path  string `json:"path,omitempty"`
+	Size  int64  `json:"size,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// deletionLog is an open -log file. Its methods do nothing on a nil log, so
+// callers need not check whether -log was given.
+type deletionLog struct {
+	f      *os.File
+	format string
+}
+
+// logPath resolves -log, mapping "state" to the state directory.
+func logPath(opts *options) (string, error) {
+	if opts.logFile != logInState {
+		return opts.logFile, nil
+	}
+	name := "deletions.log"
+	if opts.logFormat == logFormatJSONL {
+		name = "deletions.jsonl"
+	}
+	return statePath("logs", name)
+}
+
+// openDeletionLog rotates the -log file if due and opens it for appending,
+// or returns nil without -log.
+func openDeletionLog(opts *options, now time.Time) (*deletionLog, error) {
+	if opts.logFile == "" {
+		return nil, nil
+	}
+	path, err := logPath(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if err := rotateLog(path, opts, now); err != nil {
+		stderr.warnf("could not rotate %s: %v", path, err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	l := &deletionLog{f: f, format: opts.logFormat}
+	if l.format == logFormatJSONL {
+		l.write(logEntry{Time: now.Format(time.RFC3339), Event: "start"})
+	} else {
+		fmt.Fprintf(f, "# tidyup deletion log -- %s\n", now.Format(time.RFC3339))
+	}
+	return l, nil
+}
+
+func (l *deletionLog) write(e logEntry) {
+	data, _ := json.Marshal(e)
+	l.f.Write(append(data, '\n'))
+}
+
+// removed logs a successful delete, trash, or delegation.
+func (l *deletionLog) removed(action string, r Record) {
+	if l == nil {
+		return
+	}
+	now := time.Now().Format(time.RFC3339)
+	if l.format == logFormatJSONL {
+		l.write(logEntry{Time: now, Event: strings.ToLower(action), Type: r.Type, Path: r.Path, Size: r.Size})
+		return
+	}
+	fmt.Fprintf(l.f, "%s %s %s %s\n", now, action, formatBytes(r.Size), r.Path)
+}
+
+// failed logs a removal that did not happen.
+func (l *deletionLog) failed(r Record, err error) {
+	if l == nil {
+		return
+	}
+	now := time.Now().Format(time.RFC3339)
+	if l.format == logFormatJSONL {
+		l.write(logEntry{Time: now, Event: "error", Type: r.Type, Path: r.Path, Error: err.Error()})
+		return
+	}
+	fmt.Fprintf(l.f, "%s ERROR %s: %v\n", now, r.Path, err)
+}
+
+func (l *deletionLog) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.f.Close()
+}
+
+// logStarted returns the time of a log file's first entry: the text
+// header's timestamp or the first JSONL entry's time.
+func logStarted(path string) (time.Time, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	defer f.Close()
+	line, _ := bufio.NewReader(f).ReadString('\n')
+	stamp := strings.TrimSpace(strings.TrimPrefix(line, "# tidyup deletion log -- "))
+	var e logEntry
+	if json.Unmarshal([]byte(line), &e) == nil {
+		stamp = e.Time
+	}
+	t, err := time.Parse(time.RFC3339, stamp)
+	return t, err == nil
+}
+
+// rotateLog renames path aside when it exceeds -log-max-size or started
+// more than -log-max-age ago, then prunes rotated files beyond -log-keep.
+func rotateLog(path string, opts *options, now time.Time) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil // nothing to rotate yet
+	}
+	due := opts.logMaxSize > 0 && info.Size() >= opts.logMaxSize
+	if started, ok := logStarted(path); ok && opts.logMaxAge > 0 && now.Sub(started) >= opts.logMaxAge {
+		due = true
+	}
+	if !due {
+		return nil
+	}
+	if err := os.Rename(path, path+"."+now.Format(logStampFormat)); err != nil {
+		return err
+	}
+	return pruneLogs(path, opts.logKeep)
+}
+
+// pruneLogs deletes the oldest rotated copies of path beyond keep.
+func pruneLogs(path string, keep int) error {
+	rotated, err := filepath.Glob(path + ".*")
+	if err != nil || keep <= 0 || len(rotated) <= keep {
+		return err
+	}
+	sort.Strings(rotated)
+	for _, old := range rotated[:len(rotated)-keep] {
+		if err := os.Remove(old); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOpenDeletionLog_RotatesBySizeAndKeeps(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tidyup.log")
+	opts := &options{logFile: path, logMaxSize: 64, logKeep: 2}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 4; i++ {
+		l, err := openDeletionLog(opts, now.Add(time.Duration(i)*time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.removed("Deleted", Record{Path: "/p/.venv", Size: 1 << 20})
+		l.Close()
+	}
+	rotated, _ := filepath.Glob(path + ".*")
+	if len(rotated) != 2 {
+		t.Errorf("rotated logs = %v, want the newest 2", rotated)
+	}
+	data, _ := os.ReadFile(path)
+	if n := strings.Count(string(data), " Deleted "); n != 1 {
+		t.Errorf("current log has %d entries after rotation, want 1:\n%s", n, data)
+	}
+}
+
+func TestOpenDeletionLog_RotatesByAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tidyup.log")
+	opts := &options{logFile: path, logMaxAge: 7 * 24 * time.Hour}
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	l, _ := openDeletionLog(opts, start)
+	l.Close()
+	l, _ = openDeletionLog(opts, start.Add(24*time.Hour))
+	l.Close()
+	if rotated, _ := filepath.Glob(path + ".*"); len(rotated) != 0 {
+		t.Errorf("rotated a 1-day-old log: %v", rotated)
+	}
+	l, _ = openDeletionLog(opts, start.Add(8*24*time.Hour))
+	l.Close()
+	if rotated, _ := filepath.Glob(path + ".*"); len(rotated) != 1 {
+		t.Errorf("8-day-old log not rotated: %v", rotated)
+	}
+}
+
+func TestDeletionLog_JSONLInStateDir(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	opts := &options{logFile: logInState, logFormat: logFormatJSONL}
+	l, err := openDeletionLog(opts, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.removed("Trashed", Record{Type: "venv", Path: "/p/.venv", Size: 42})
+	l.failed(Record{Type: "pycache", Path: "/p/__pycache__"}, errors.New("permission denied"))
+	l.Close()
+
+	path, _ := logPath(opts)
+	if filepath.Base(path) != "deletions.jsonl" {
+		t.Errorf("state log path = %s", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var e logEntry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("not JSON: %q", line)
+		}
+		events = append(events, e.Event)
+	}
+	if strings.Join(events, ",") != "start,trashed,error" {
+		t.Errorf("events = %v", events)
+	}
+}
+
+func TestDeletionLog_NilIsNoop(t *testing.T) {
+	var l *deletionLog
+	l.removed("Deleted", Record{})
+	l.failed(Record{}, errors.New("x"))
+	if err := l.Close(); err != nil {
+		t.Error(err)
+	}
+}
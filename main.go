@@ -4,11 +4,14 @@
 package main
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // version is set at build time via -ldflags.
@@ -23,26 +26,172 @@ const (
 
 // allScanTypes lists every type tidyup knows how to detect.
 var allScanTypes = []string{
-	"venv", "node_modules", "pycache", "pytest_cache",
-	"mypy_cache", "ruff_cache", "dist", "build",
+	"venv", "conda", "tox", "nox", "node_modules", "pycache", "pytest_cache",
+	"mypy_cache", "ruff_cache", "dist", "build", "cargo", "gradle", "maven", "tools", "direnv", "nix",
+	"android_sdk", "pub_cache", "pip_cache", "js_cache", "gradle_wrapper", "huggingface", "unity", "renv", "julia", "latex",
+	"db_data", "vm_image", "app_leftovers", "downloads", "crash", "wheel", "media", "tmp", "docker", "stale_repo",
 }
 
+// optInTypes are only scanned when named in -type, never by -all.
+var optInTypes = map[string]bool{"media": true, "tmp": true, "docker": true, "stale_repo": true}
+
+// systemTypes are the types -system adds well-known per-user locations for.
+var systemTypes = []string{"venv", "conda", "tools", "nix", "android_sdk", "pub_cache", "pip_cache", "js_cache", "gradle_wrapper", "gradle", "huggingface", "renv", "julia", "vm_image", "app_leftovers", "downloads", "crash"}
+
 // options holds all parsed CLI flags.
 type options struct {
-	minAge          int
-	maxDepth        int
-	doDelete        bool
-	dryRun          bool
-	systemScan      bool
-	jsonOut         bool
-	verbose         bool
-	excludePatterns []string
-	minSize         int64
-	sortField       string
-	useTrash        bool
-	logFile         string
-	confirm         bool
-	scanTypes       map[string]bool
+	minAge            int
+	pipCacheAges      map[string]int // -pip-wheels-age, -pip-http-age: thresholds for the pip cache's parts, by part (0: -age)
+	repoMonths        int            // -repo-months: threshold for stale_repo, in months
+	keepBuilds        int            // -keep-builds: builds kept per dist/ and build/ (0: judge the directory by -age)
+	maxDepth          int
+	doDelete          bool
+	dryRun            bool
+	systemScan        bool
+	jsonOut           bool
+	verbose           bool
+	excludePatterns   []string
+	skipDirs          map[string]bool // -skip: extra directory names never entered
+	noSkipDirs        map[string]bool // -no-skip: default skip names to enter after all
+	mediaDirs         []string        // -media-dirs: extra capture folders for the media type
+	tmpDelete         bool            // -tmp-delete: tmp records may be deleted, not just reported
+	venvNames         map[string]bool // -venv-names: extra directory names treated as venvs
+	minSize           int64
+	minConfidence     float64 // -min-confidence: drop records scored below this
+	limit             int     // -limit, -first-match: stop scanning after this many items (0: no limit)
+	sortField         string
+	useTrash          bool
+	delegate          bool // -delegate: run Record.Command instead of deleting files
+	logFile           string
+	logFormat         string        // -log-format: text or jsonl
+	logMaxSize        int64         // -log-max-size: rotate the log at this size (0: never)
+	logMaxAge         time.Duration // -log-max-age: rotate the log once its first entry is this old (0: never)
+	logKeep           int           // -log-keep: rotated logs to keep (0: all)
+	maxDeleteBytes    int64         // -max-delete-bytes: abort a deletion larger than this (0: no cap)
+	maxDeleteItems    int           // -max-delete-items: abort a deletion of more items than this (0: no cap)
+	emptyTrashAfter   time.Duration // -empty-trash-after: purge tidyup's own Trash items older than this
+	receipts          bool          // -receipts: record a top-level listing of each removed tree
+	planFile          string        // -plan: write the selection to this file instead of deleting
+	propose           bool          // -propose: add the selection to the approval queue instead of deleting
+	queueFile         string        // -queue: approval queue file; empty means the state directory's
+	confirm           bool
+	scanTypes         map[string]bool
+	asOf              time.Time        // -as-of reference date; zero means "now"
+	now               func() time.Time // clock for staleness evaluation; nil means time.Now
+	locale            localeFormat     // human output number/date conventions
+	dateStyle         string           // -dates: relative, absolute, or both
+	quiet             bool             // -quiet: summary line only in text output
+	summaryOnly       bool             // -summary-only: JSON without records
+	stream            bool             // -stream: JSON Lines, records spilled to the scan index instead of held in memory
+	tagFilter         map[string]bool  // -tag: only items carrying one of these tags.json tags
+	noPager           bool             // -no-pager: never pipe listings through $PAGER
+	pathStyle         string           // -path-style: absolute, home, or relative
+	minCreationAge    int              // -min-creation-age: grace period in days after birth time
+	trustCreationTime bool             // -trust-creation-time: detect restored trees with preserved mtimes
+	explain           bool             // -explain: show heuristic notes under each record
+	noHistory         bool             // -no-history: skip the size history and restore detection
+	nice              bool             // -nice: low priority and paced I/O (see setNice)
+	monorepo          bool             // -monorepo: attribute records to workspace packages and report per package
+	baseline          string           // -baseline: report only growth since this saved baseline
+	readonly          bool             // -readonly: never remove anything (see executor.go)
+	sandbox           bool             // -sandbox: remove through a helper process confined to the approved paths
+}
+
+// currentTime returns the reference time for staleness evaluation.
+// -as-of pins it to a fixed date; tests can inject a fake clock via now.
+func (o *options) currentTime() time.Time {
+	if !o.asOf.IsZero() {
+		return o.asOf
+	}
+	if o.now != nil {
+		return o.now()
+	}
+	return time.Now()
+}
+
+// ageDays returns how many days before the reference time lastUsed falls.
+func (o *options) ageDays(lastUsed time.Time) float64 {
+	return o.currentTime().Sub(lastUsed).Hours() / 24
+}
+
+// parseAsOf parses the -as-of flag value (YYYY-MM-DD, local time).
+func parseAsOf(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid -as-of date %q (want YYYY-MM-DD)", value)
+	}
+	return t, nil
+}
+
+// systemRoots returns the existing standard uv and Poetry venv, conda
+// environment, and pipx/uv tool directories that -system adds as walk roots
+// for the selected types.
+func systemRoots(home string, opts *options) []string {
+	var extra, roots []string
+	if opts.scanTypes["venv"] {
+		extra = append(extra,
+			filepath.Join(home, ".local/share/uv/venvs"),
+			filepath.Join(home, "Library/Caches/uv/venvs"))
+		extra = append(extra, poetryHomes(home)...)
+	}
+	if opts.scanTypes["conda"] {
+		extra = append(extra, condaHomes(home)...)
+	}
+	if opts.scanTypes["tools"] {
+		extra = append(extra, toolHomes(home)...)
+	}
+	for _, p := range extra {
+		if _, err := os.Stat(p); err == nil {
+			roots = append(roots, p)
+		}
+	}
+	return roots
+}
+
+// parseByteSize parses a size such as 500M, 20G, 1.5T, or plain bytes.
+// Units are binary (1K = 1024), matching how sizes are printed.
+func parseByteSize(value string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	if s == "" {
+		return 0, nil
+	}
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I")
+	mult := int64(1)
+	if s == "" {
+		return 0, fmt.Errorf("invalid size %q (want bytes or e.g. 500M, 20G)", value)
+	}
+	if i := strings.IndexByte("KMGTP", s[len(s)-1]); i >= 0 && len(s) > 1 {
+		mult = int64(1) << (10 * (i + 1))
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q (want bytes or e.g. 500M, 20G)", value)
+	}
+	return int64(n * float64(mult)), nil
+}
+
+// splitList parses a comma-separated flag value, dropping blanks.
+func splitList(raw string) []string {
+	var items []string
+	for _, p := range strings.Split(raw, ",") {
+		if trimmed := strings.TrimSpace(p); trimmed != "" {
+			items = append(items, trimmed)
+		}
+	}
+	return items
+}
+
+// nameSet turns a parsed list flag into a set.
+func nameSet(names []string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, n := range names {
+		set[n] = true
+	}
+	return set
 }
 
 // parseScanTypes converts the --type flag and --all flag into a type map.
@@ -51,7 +200,9 @@ func parseScanTypes(typeFlag string, allTypes bool) (map[string]bool, []string)
 	if allTypes {
 		m := make(map[string]bool, len(allScanTypes))
 		for _, t := range allScanTypes {
-			m[t] = true
+			if !optInTypes[t] {
+				m[t] = true
+			}
 		}
 		return m, nil
 	}
@@ -92,27 +243,128 @@ func main() {
 }
 
 func run() int {
+	start := time.Now()
+	migrateState()
+
+	// Subcommands. Pass a scan root named like a subcommand as ./name.
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "fixtures":
+			return runFixtures(os.Args[2:])
+		case "schema":
+			return runSchema(os.Args[2:])
+		case "import":
+			return runImport(os.Args[2:])
+		case "hook":
+			return runHook(os.Args[2:])
+		case "baseline":
+			return runBaseline(os.Args[2:])
+		case "remove-helper":
+			return runRemoveHelper(os.Args[2:])
+		case "apply":
+			return runApply(os.Args[2:])
+		case "queue":
+			return runQueue(os.Args[2:])
+		case "serve":
+			return runServe(os.Args[2:])
+		case "status":
+			return runStatus(os.Args[2:])
+		case "paths":
+			return runPaths(os.Args[2:])
+		case "receipts":
+			return runReceipts(os.Args[2:])
+		case "restore":
+			return runRestore(os.Args[2:])
+		case "stats":
+			return runStats(os.Args[2:])
+		case "usage":
+			return runUsage(os.Args[2:])
+		case "whatif":
+			return runWhatif(os.Args[2:])
+		}
+	}
+
 	// Flags.
 	minAge := flag.Int("age", 30, "Min days since last use")
+	pipWheelsAge := flag.Int("pip-wheels-age", 0, "Min days since last use for pip's built-wheel cache (0: -age)")
+	pipHTTPAge := flag.Int("pip-http-age", 0, "Min days since last use for pip's HTTP download cache (0: -age)")
+	repoMonths := flag.Int("repo-months", 6, "Months without commits, fetches, or edits before a clone is a stale_repo")
+	keepBuilds := flag.Int("keep-builds", 0, "Keep the newest N builds in each dist/ and build/ and flag the older ones, whatever their age (0: off)")
 	maxDepth := flag.Int("depth", 5, "Scan depth for recursion")
+	trustCreationTime := flag.Bool("trust-creation-time", false, "Use creation time when usage mtimes predate it (restored/copied trees)")
+	explain := flag.Bool("explain", false, "Show why each item was flagged (heuristic notes)")
+	minCreationAge := flag.Int("min-creation-age", 0, "Never flag items created (birth time) within this many days")
 	doDelete := flag.Bool("delete", false, "Delete the identified items")
 	dryRun := flag.Bool("dry-run", false, "Preview what would be deleted (overrides -delete)")
-	systemScan := flag.Bool("system", false, "Include standard uv cache locations (~/.local/share/uv)")
+	receipts := flag.Bool("receipts", false, "Record the top-level listing of each removed item for 'tidyup receipts'")
+	planFile := flag.String("plan", "", "Select items as -delete would, but write them to this file for 'tidyup apply' instead of deleting")
+	propose := flag.Bool("propose", false, "Select items as -delete would, but add them to the approval queue ('tidyup queue') instead of deleting")
+	queueFile := flag.String("queue", "", "Approval queue file for -propose (default: queue.json in the state directory)")
+	systemScan := flag.Bool("system", false, "Include well-known per-user locations (uv/Poetry venvs, conda envs, pipx/uv tools, Nix profiles, SDK caches)")
 	showVersion := flag.Bool("version", false, "Print version and exit")
 	jsonOut := flag.Bool("json", false, "Output results as JSON")
 	verbose := flag.Bool("verbose", false, "Show scan progress on stderr")
+	timestamps := flag.Bool("timestamps", false, "Prefix warnings and -verbose progress on stderr with the time of day")
 	excludeRaw := flag.String("exclude", "", "Comma-separated path patterns to skip")
+	skipRaw := flag.String("skip", "", "Comma-separated directory names never to enter, in addition to .git, Library, .Trash")
+	noSkipRaw := flag.String("no-skip", "", "Comma-separated default skip names to scan after all (e.g. Library)")
+	venvNamesRaw := flag.String("venv-names", "", "Comma-separated directory names to treat as venvs when they contain a Python interpreter")
 	minSize := flag.Int64("min-size", 0, "Only report items above this size in bytes")
-	sortField := flag.String("sort", "size", "Sort by: size, age, path")
-	useTrash := flag.Bool("trash", false, "Move to ~/.Trash instead of permanent delete (macOS)")
-	logFile := flag.String("log", "", "Write deletion log to this file")
+	limit := flag.Int("limit", 0, "Stop scanning once this many stale items are found (0: no limit)")
+	firstMatch := flag.Bool("first-match", false, "Stop scanning at the first stale item (-limit 1), for checks that only need the exit code")
+	minConfidence := flag.Float64("min-confidence", 0, "Only report items whose staleness confidence (0-1) is at least this")
+	sortField := flag.String("sort", "size", "Sort by: size, disk, age, path, confidence")
+	useTrash := flag.Bool("trash", false, "Move to the Trash (through Finder, so Put Back works) instead of permanent delete (macOS)")
+	emptyTrashAfterRaw := flag.String("empty-trash-after", "", "With -delete, permanently remove items tidyup moved to the Trash longer ago than this (e.g. 7d)")
+	delegate := flag.Bool("delegate", false, "Remove items that have a native command (pipx/uv uninstall, sdkmanager, ...) by running it")
+	logFile := flag.String("log", "", "Write deletion log to this file (\"state\": logs/ in the state directory)")
+	logFormat := flag.String("log-format", logFormatText, "Deletion log format: text or jsonl")
+	logMaxSizeRaw := flag.String("log-max-size", "10M", "Rotate the deletion log once it reaches this size (0: never)")
+	logMaxAgeRaw := flag.String("log-max-age", "", "Rotate the deletion log once its first entry is older than this (e.g. 30d)")
+	logKeep := flag.Int("log-keep", 5, "Rotated deletion logs to keep (0: all)")
+	maxDeleteBytesRaw := flag.String("max-delete-bytes", "", "Abort without deleting anything if the selection exceeds this size (e.g. 20G)")
+	maxDeleteItems := flag.Int("max-delete-items", 0, "Abort without deleting anything if the selection has more items than this")
 	confirm := flag.Bool("confirm", false, "Skip interactive selection prompt (for automation)")
-	typeFlag := flag.String("type", "", "Comma-separated types: venv,node_modules,pycache,pytest_cache,mypy_cache,ruff_cache,dist,build")
+	typeFlag := flag.String("type", "", "Comma-separated types (default venv): "+strings.Join(allScanTypes, ","))
 	allTypes := flag.Bool("all", false, "Scan for all supported types")
+	mediaDirsRaw := flag.String("media-dirs", "", "Comma-separated extra folders (screen recordings, OBS output) for -type media")
+	tmpDelete := flag.Bool("tmp-delete", false, "Allow deleting -type tmp entries (reported only by default)")
+	includeLibraryCaches := flag.Bool("include-library-caches", false, "Also report allowlisted per-app caches in ~/Library/Caches (macOS; type library_cache)")
+	localeRaw := flag.String("locale", "", "Number/date format for text output: auto (from LC_NUMERIC/LC_TIME), C, en_US, de_DE, ...")
+	quiet := flag.Bool("quiet", false, "Print only the summary line (exit code still reflects findings)")
+	summaryOnly := flag.Bool("summary-only", false, "JSON output with totals only, no records (implies -json)")
+	tagRaw := flag.String("tag", "", "Comma-separated tags (from tags.json): only report items carrying one of them")
+	stream := flag.Bool("stream", false, "Output JSON Lines, spilling records to the scan index rather than holding them all in memory (for very large trees)")
+	noPager := flag.Bool("no-pager", false, "Do not pipe long listings through $PAGER")
+	pathStyle := flag.String("path-style", pathAbsolute, "Paths in text output: absolute, home (~/...), relative (to scan root); JSON is always absolute")
+	dateStyle := flag.String("dates", datesRelative, "Last-used column in text output: relative, absolute, both")
+	noHistory := flag.Bool("no-history", false, "Do not record this scan in the size history or check it for restored items")
+	nice := flag.Bool("nice", false, "Run in the background: lower CPU/I/O priority and pace file system operations (for scheduled runs)")
+	baselineName := flag.String("baseline", "", "Report only items new or grown since this baseline ('tidyup baseline save NAME')")
+	monorepo := flag.Bool("monorepo", false, "Attribute items to workspace packages (uv, pnpm, npm/yarn, Cargo, go.work) and report reclaimable space per package")
+	force := flag.Bool("force", false, "With -nice, run even on battery power or under thermal pressure")
+	sandbox := flag.Bool("sandbox", false, "Delete through a helper process that may only remove the approved paths and never follows symlinks")
+	readonly := flag.Bool("readonly", false, "Never remove anything: -delete is refused (-plan and -propose still work)")
+	asOfRaw := flag.String("as-of", "", "Evaluate staleness as of this date (YYYY-MM-DD) instead of today")
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "tidyup: Locates and cleans up unused environments, caches, and build artifacts.\n\n")
-		fmt.Fprintf(os.Stderr, "Usage: tidyup [flags] [paths...]\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: tidyup [flags] [paths...]\n")
+		fmt.Fprintf(os.Stderr, "       tidyup fixtures create <dir>\n")
+		fmt.Fprintf(os.Stderr, "       tidyup hook [-shims] zsh|bash|fish\n")
+		fmt.Fprintf(os.Stderr, "       tidyup import [flags] <results.json>\n")
+		fmt.Fprintf(os.Stderr, "       tidyup apply [flags] <plan.json>\n")
+		fmt.Fprintf(os.Stderr, "       tidyup baseline [save NAME [paths...]|list|delete NAME]\n")
+		fmt.Fprintf(os.Stderr, "       tidyup queue [list|approve|reject|run]\n")
+		fmt.Fprintf(os.Stderr, "       tidyup paths [-json]\n")
+		fmt.Fprintf(os.Stderr, "       tidyup receipts [PATTERN]\n")
+		fmt.Fprintf(os.Stderr, "       tidyup restore [-type T] [-project DIR] [-since 7d] [-all]\n")
+		fmt.Fprintf(os.Stderr, "       tidyup schema\n")
+		fmt.Fprintf(os.Stderr, "       tidyup serve [-addr 127.0.0.1:7777] [paths...]\n")
+		fmt.Fprintf(os.Stderr, "       tidyup status [-json]\n")
+		fmt.Fprintf(os.Stderr, "       tidyup stats -heuristics\n")
+		fmt.Fprintf(os.Stderr, "       tidyup usage [-json] <path>\n")
+		fmt.Fprintf(os.Stderr, "       tidyup whatif -age 14,30,60,90 [paths...]\n\n")
 		flag.PrintDefaults()
 		fmt.Fprintf(os.Stderr, "\nSupported types: %s\n", strings.Join(allScanTypes, ", "))
 		fmt.Fprintf(os.Stderr, "\nExamples:\n")
@@ -126,9 +378,15 @@ func run() int {
 		fmt.Fprintf(os.Stderr, "  tidyup -all ~                         Scan for everything\n")
 		fmt.Fprintf(os.Stderr, "  tidyup -type node_modules,pycache ~   Scan for specific types\n")
 		fmt.Fprintf(os.Stderr, "  tidyup -all -delete -trash ~          Clean all types, move to Trash\n")
+		fmt.Fprintf(os.Stderr, "  tidyup -all -as-of 2026-03-01 ~       Preview what will be stale on a date\n")
 		fmt.Fprintf(os.Stderr, "\nExit codes: 0=nothing found, 1=stale items found, 2=error\n")
 	}
 	flag.Parse()
+	stderr.timestamps = *timestamps
+	if *jsonOut || *summaryOnly || *stream {
+		stderr.collect()
+		defer stderr.flushWarnings()
+	}
 
 	if *showVersion {
 		fmt.Printf("tidyup %s\n", version)
@@ -138,39 +396,205 @@ func run() int {
 	// Parse scan types.
 	scanTypes, typeWarnings := parseScanTypes(*typeFlag, *allTypes)
 	for _, w := range typeWarnings {
-		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+		stderr.warnf("%s", w)
+	}
+	if *includeLibraryCaches {
+		scanTypes["library_cache"] = true
 	}
 
-	// Parse exclude patterns.
-	var excludePatterns []string
-	if *excludeRaw != "" {
-		for _, p := range strings.Split(*excludeRaw, ",") {
-			if trimmed := strings.TrimSpace(p); trimmed != "" {
-				excludePatterns = append(excludePatterns, trimmed)
-			}
+	excludePatterns := splitList(*excludeRaw)
+	venvNames := nameSet(splitList(*venvNamesRaw))
+	for _, name := range splitList(*noSkipRaw) {
+		if !defaultSkipDirs[name] {
+			stderr.warnf("-no-skip %q is not skipped by default; ignored", name)
 		}
 	}
 
+	asOf, err := parseAsOf(*asOfRaw)
+	if err != nil {
+		stderr.errorf("%v", err)
+		return exitError
+	}
+	// Deleting based on a simulated date would act on items that are not
+	// actually stale yet, so -as-of is always a preview.
+	if !asOf.IsZero() && *doDelete {
+		stderr.warnf("-as-of is a simulation; ignoring -delete.")
+		*doDelete = false
+	}
+
+	if *limit < 0 {
+		stderr.errorf("-limit: must not be negative")
+		return exitError
+	}
+	if *keepBuilds < 0 {
+		stderr.errorf("-keep-builds: must not be negative")
+		return exitError
+	}
+	if *firstMatch {
+		*limit = 1
+	}
+
+	maxDeleteBytes, err := parseByteSize(*maxDeleteBytesRaw)
+	if err != nil {
+		stderr.errorf("-max-delete-bytes: %v", err)
+		return exitError
+	}
+	emptyTrashAfter, err := parseRetention(*emptyTrashAfterRaw)
+	if err != nil {
+		stderr.errorf("-empty-trash-after: %v", err)
+		return exitError
+	}
+	logMaxSize, err := parseByteSize(*logMaxSizeRaw)
+	if err != nil {
+		stderr.errorf("-log-max-size: %v", err)
+		return exitError
+	}
+	logMaxAge, err := parseRetention(*logMaxAgeRaw)
+	if err != nil {
+		stderr.errorf("-log-max-age: %v", err)
+		return exitError
+	}
+	switch *logFormat {
+	case logFormatText, logFormatJSONL:
+	default:
+		stderr.errorf("unknown -log-format %q (want text or jsonl)", *logFormat)
+		return exitError
+	}
+
+	locale, err := parseLocale(*localeRaw)
+	if err != nil {
+		stderr.warnf("%v; using C locale", err)
+	}
+
+	switch *dateStyle {
+	case datesRelative, datesAbsolute, datesBoth:
+	default:
+		stderr.warnf("unknown -dates value %q; using %s", *dateStyle, datesRelative)
+		*dateStyle = datesRelative
+	}
+
+	switch *pathStyle {
+	case pathAbsolute, pathHome, pathRelative:
+	default:
+		stderr.warnf("unknown -path-style value %q; using %s", *pathStyle, pathAbsolute)
+		*pathStyle = pathAbsolute
+	}
+
 	// --dry-run overrides --delete.
 	if *dryRun {
 		*doDelete = false
 	}
+	// -plan and -propose run the deletion flow up to the point of acting.
+	if *planFile != "" || *propose {
+		if *planFile != "" && *propose {
+			stderr.errorf("-plan and -propose are alternatives; pick one")
+			return exitError
+		}
+		if !asOf.IsZero() {
+			stderr.errorf("-plan and -propose cannot be combined with -as-of")
+			return exitError
+		}
+		if *jsonOut || *summaryOnly {
+			stderr.errorf("-plan and -propose write their own JSON; drop -json")
+			return exitError
+		}
+		if *propose && *queueFile != "" && *delegate {
+			stderr.errorf("-propose -queue cannot be combined with -delegate: others who can write the shared queue could change the commands it runs")
+			return exitError
+		}
+		*doDelete = true
+	}
+	// -stream never holds the whole result, which deleting, comparing with
+	// a baseline, and grouping by workspace all need.
+	if *stream {
+		for _, c := range []struct {
+			set  bool
+			flag string
+		}{
+			{*jsonOut || *summaryOnly, "-json"},
+			{*doDelete, "-delete"},
+			{*baselineName != "", "-baseline"},
+			{*monorepo, "-monorepo"},
+		} {
+			if c.set {
+				stderr.errorf("-stream cannot be combined with %s", c.flag)
+				return exitError
+			}
+		}
+	}
 
 	opts := &options{
-		minAge:          *minAge,
-		maxDepth:        *maxDepth,
-		doDelete:        *doDelete,
-		dryRun:          *dryRun,
-		systemScan:      *systemScan,
-		jsonOut:         *jsonOut,
-		verbose:         *verbose,
-		excludePatterns: excludePatterns,
-		minSize:         *minSize,
-		sortField:       *sortField,
-		useTrash:        *useTrash,
-		logFile:         *logFile,
-		confirm:         *confirm,
-		scanTypes:       scanTypes,
+		minAge:            *minAge,
+		pipCacheAges:      map[string]int{"wheels": *pipWheelsAge, "http": *pipHTTPAge},
+		repoMonths:        *repoMonths,
+		keepBuilds:        *keepBuilds,
+		maxDepth:          *maxDepth,
+		doDelete:          *doDelete,
+		dryRun:            *dryRun,
+		systemScan:        *systemScan,
+		jsonOut:           *jsonOut,
+		verbose:           *verbose,
+		excludePatterns:   excludePatterns,
+		skipDirs:          nameSet(splitList(*skipRaw)),
+		noSkipDirs:        nameSet(splitList(*noSkipRaw)),
+		mediaDirs:         splitList(*mediaDirsRaw),
+		tmpDelete:         *tmpDelete,
+		venvNames:         venvNames,
+		minSize:           *minSize,
+		minConfidence:     *minConfidence,
+		limit:             *limit,
+		sortField:         *sortField,
+		useTrash:          *useTrash,
+		delegate:          *delegate,
+		logFile:           *logFile,
+		logFormat:         *logFormat,
+		logMaxSize:        logMaxSize,
+		logMaxAge:         logMaxAge,
+		logKeep:           *logKeep,
+		maxDeleteBytes:    maxDeleteBytes,
+		maxDeleteItems:    *maxDeleteItems,
+		emptyTrashAfter:   emptyTrashAfter,
+		receipts:          *receipts,
+		planFile:          *planFile,
+		propose:           *propose,
+		queueFile:         *queueFile,
+		confirm:           *confirm,
+		scanTypes:         scanTypes,
+		asOf:              asOf,
+		locale:            locale,
+		dateStyle:         *dateStyle,
+		quiet:             *quiet,
+		summaryOnly:       *summaryOnly,
+		stream:            *stream,
+		tagFilter:         nameSet(splitList(*tagRaw)),
+		noPager:           *noPager,
+		pathStyle:         *pathStyle,
+		minCreationAge:    *minCreationAge,
+		trustCreationTime: *trustCreationTime,
+		explain:           *explain,
+		noHistory:         *noHistory,
+		nice:              *nice,
+		monorepo:          *monorepo,
+		baseline:          *baselineName,
+		readonly:          *readonly,
+		sandbox:           *sandbox,
+	}
+	if opts.summaryOnly {
+		opts.jsonOut = true
+	}
+	if opts.doDelete && opts.planFile == "" && !opts.propose {
+		if why := readonlyReason(opts); why != "" {
+			stderr.errorf("-delete: %s; -plan and -propose still work", why)
+			return exitError
+		}
+	}
+	setNice(opts.nice)
+	// A -nice run is background work: it waits for a better time.
+	if opts.nice && !*force {
+		if why := backgroundBlocker(); why != "" {
+			stderr.warnf("-nice: skipping this run: %s (-force runs anyway)", why)
+			return exitOK
+		}
 	}
 
 	// Collect root paths.
@@ -179,66 +603,114 @@ func run() int {
 		roots = []string{"."}
 	}
 
-	// Include standard uv locations (only relevant when scanning venvs).
+	// Include standard uv venv and tool locations.
 	if opts.systemScan {
-		if !opts.scanTypes["venv"] {
-			fmt.Fprintf(os.Stderr, "Warning: -system only adds uv cache paths for venv scanning; ignored for other types.\n")
+		relevant := false
+		for _, t := range systemTypes {
+			relevant = relevant || opts.scanTypes[t]
+		}
+		if !relevant {
+			stderr.warnf("-system only adds locations for types %s; ignored for other types.", strings.Join(systemTypes, ", "))
 		} else {
 			home, err := os.UserHomeDir()
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: could not determine home directory: %v\n", err)
+				stderr.errorf("could not determine home directory: %v", err)
 				return exitError
 			}
-			extra := []string{
-				filepath.Join(home, ".local/share/uv/venvs"),
-				filepath.Join(home, "Library/Caches/uv/venvs"),
-			}
-			for _, p := range extra {
-				if _, err := os.Stat(p); err == nil {
-					roots = append(roots, p)
-				}
-			}
+			roots = append(roots, systemRoots(home, opts)...)
 		}
 	}
 
 	if opts.verbose {
-		fmt.Fprintf(os.Stderr, "Scanning roots: %v\n", roots)
+		stderr.printf("Scanning roots: %v", roots)
 		var typeNames []string
 		for _, t := range allScanTypes {
 			if opts.scanTypes[t] {
 				typeNames = append(typeNames, t)
 			}
 		}
-		fmt.Fprintf(os.Stderr, "Scanning for types: %v\n", typeNames)
+		stderr.printf("Scanning for types: %v", typeNames)
+	}
+
+	var base *baseline
+	if opts.baseline != "" {
+		if base, err = loadBaseline(opts.baseline); err != nil {
+			stderr.errorf("-baseline: %v", err)
+			return exitError
+		}
+	}
+
+	if opts.stream {
+		sp, err := streamRoots(os.Stdout, roots, opts)
+		if err != nil {
+			stderr.errorf("-stream: %v", err)
+			return exitError
+		}
+		stderr.printf("%s", tally.summaryCounts(sp.count, sp.total, time.Since(start)))
+		if sp.count == 0 {
+			return exitOK
+		}
+		return exitFound
 	}
 
 	// Scan.
 	records, scanErrors := scanRoots(roots, opts)
+	if opts.monorepo {
+		attributeWorkspaces(records)
+	}
 
 	for _, e := range scanErrors {
-		fmt.Fprintf(os.Stderr, "Warning: %s\n", e)
+		stderr.warn("scan_error", "", "%s", e)
+	}
+	tally.failed(len(scanErrors))
+
+	// A simulated -as-of scan is not an observation worth keeping.
+	if !opts.noHistory && opts.asOf.IsZero() {
+		updateHistory(records, opts)
+		detectRestores(opts)
 	}
+	if base != nil {
+		records = sinceBaseline(records, base)
+	}
+
+	code := reportAndDelete(records, opts)
+	stderr.printf("%s", tally.summaryLine(records, time.Since(start)))
+	return code
+}
 
-	// Sort and total.
+// reportAndDelete sorts and prints records, then runs the deletion flow when
+// -delete is set. Shared by scans and `tidyup import`.
+func reportAndDelete(records []Record, opts *options) int {
 	sortRecords(records, opts.sortField)
 	total := totalSize(records)
 
 	// Output.
 	if opts.jsonOut {
-		return printJSON(records, total, !opts.doDelete)
+		warnSafety(records)
+		return printJSON(records, total, !opts.doDelete, opts)
 	}
 
-	printText(records, total)
-
-	if len(records) == 0 {
-		return exitOK
-	}
-
-	// Deletion.
+	// Deletion: the selection prompt follows, so never page.
 	if opts.doDelete {
+		if opts.emptyTrashAfter > 0 && opts.planFile == "" && !opts.propose {
+			runEmptyTrash(opts)
+		}
+		printText(os.Stdout, records, total, opts)
+		if len(records) == 0 {
+			return exitOK
+		}
 		return deleteRecords(records, opts)
 	}
 
-	fmt.Println("\nRun with '-delete' to reclaim this space.")
+	var buf bytes.Buffer
+	printText(&buf, records, total, opts)
+	if len(records) > 0 && !opts.quiet {
+		fmt.Fprintln(&buf, "\nRun with '-delete' to reclaim this space.")
+	}
+	pageOutput(buf.Bytes(), opts.noPager)
+
+	if len(records) == 0 {
+		return exitOK
+	}
 	return exitFound
 }
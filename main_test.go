@@ -68,3 +68,41 @@ func TestParseScanTypes_WhitespaceHandling(t *testing.T) {
 		t.Error("expected both types to be set")
 	}
 }
+
+func TestParseAsOf(t *testing.T) {
+	got, err := parseAsOf("2026-03-01")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Year() != 2026 || got.Month() != 3 || got.Day() != 1 {
+		t.Errorf("got %v, want 2026-03-01", got)
+	}
+	if _, err := parseAsOf("03/01/2026"); err == nil {
+		t.Error("expected error for non-ISO date")
+	}
+	if got, err := parseAsOf(""); err != nil || !got.IsZero() {
+		t.Errorf("empty value should yield zero time, got %v, %v", got, err)
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := map[string]int64{
+		"":      0,
+		"4096":  4096,
+		"500M":  500 << 20,
+		"20G":   20 << 30,
+		"20GB":  20 << 30,
+		"20GiB": 20 << 30,
+		"1.5t":  3 << 39,
+	}
+	for in, want := range tests {
+		if got, err := parseByteSize(in); err != nil || got != want {
+			t.Errorf("parseByteSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"B", "lots", "-1G"} {
+		if _, err := parseByteSize(bad); err == nil {
+			t.Errorf("parseByteSize(%q) accepted", bad)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Screen recordings, OBS captures, and screenshot folders are often the
+// answer to "where did my disk go", but they are user content: the media
+// type only ever reports them. It is opt-in (-type media, never -all) and
+// looks in the usual capture folders plus any given with -media-dirs.
+
+// mediaMinSize is the smallest item the media type reports; a handful of
+// screenshots is not worth a line.
+const mediaMinSize = 100 << 20
+
+// mediaSuffixes are video and image formats written by capture tools.
+var mediaSuffixes = map[string]bool{
+	".mov": true, ".mp4": true, ".m4v": true, ".mkv": true, ".webm": true, ".avi": true, ".flv": true,
+	".png": true, ".jpg": true, ".jpeg": true, ".heic": true, ".gif": true,
+}
+
+// isMediaFile reports whether name has a media suffix.
+func isMediaFile(name string) bool {
+	return mediaSuffixes[strings.ToLower(filepath.Ext(name))]
+}
+
+// mediaRoots returns the capture folders that exist: ~/Movies (macOS screen
+// recordings, OBS), ~/Videos (OBS elsewhere), ~/Pictures/Screenshots, and
+// the -media-dirs entries.
+func mediaRoots(home string, extra []string) []string {
+	dirs := []string{
+		filepath.Join(home, "Movies"),
+		filepath.Join(home, "Videos"),
+		filepath.Join(home, "Pictures", "Screenshots"),
+	}
+	for _, d := range extra {
+		if rest, ok := strings.CutPrefix(d, "~/"); ok {
+			d = filepath.Join(home, rest)
+		}
+		dirs = append(dirs, d)
+	}
+	return existingDirs(dirs...)
+}
+
+// hasMedia reports whether dir contains any media file.
+func hasMedia(dir string) bool {
+	found := false
+	filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && isMediaFile(d.Name()) {
+			found = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return found
+}
+
+// findMedia returns the large entries of each capture folder: media files
+// and subfolders holding media, each at least mediaMinSize.
+func findMedia(home string, extra []string) []string {
+	var items []string
+	for _, root := range mediaRoots(home, extra) {
+		entries, _ := os.ReadDir(root)
+		for _, e := range entries {
+			p := filepath.Join(root, e.Name())
+			switch {
+			case e.Type().IsRegular() && isMediaFile(e.Name()):
+				if info, err := e.Info(); err == nil && info.Size() >= mediaMinSize {
+					items = append(items, p)
+				}
+			case e.IsDir() && !strings.HasSuffix(e.Name(), ".photoslibrary") && hasMedia(p):
+				if size, _ := dirSize(p); size >= mediaMinSize {
+					items = append(items, p)
+				}
+			}
+		}
+	}
+	return items
+}
+
+// annotateMedia marks media records report-only.
+func annotateMedia(r *Record) {
+	r.Advisory = true
+	r.Notes = append(r.Notes, "user content: review and remove by hand")
+}
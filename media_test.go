@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestScanRoots_Media(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	old := time.Now().AddDate(0, 0, -120)
+	for name, size := range map[string]int64{
+		"Movies/OBS/2025-01-01 10-00-00.mkv": 120 << 20,
+		"Movies/Screen Recording.mov":        150 << 20,
+		"Movies/clip.mov":                    1 << 10,
+		"Movies/notes/readme.txt":            200 << 20,
+		"Captures/Simulator/shot.png":        100 << 20,
+	} {
+		p := filepath.Join(home, name)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, nil, 0644)
+		os.Truncate(p, size) // sparse: apparent size only
+		os.Chtimes(p, old, old)
+	}
+
+	opts := &options{minAge: 30, maxDepth: 5, scanTypes: map[string]bool{"media": true}, mediaDirs: []string{"~/Captures"}}
+	records, _ := scanRoots(nil, opts)
+	sort.Slice(records, func(i, j int) bool { return records[i].Path < records[j].Path })
+	want := []string{
+		filepath.Join(home, "Captures/Simulator"),
+		filepath.Join(home, "Movies/OBS"),
+		filepath.Join(home, "Movies/Screen Recording.mov"),
+	}
+	if len(records) != len(want) {
+		t.Fatalf("got %d records, want %v: %+v", len(records), want, records)
+	}
+	for i, r := range records {
+		if r.Path != want[i] || !r.Advisory || r.Command != "" {
+			t.Errorf("record %d = %s (advisory %v), want report-only %s", i, r.Path, r.Advisory, want[i])
+		}
+	}
+}
+
+func TestParseScanTypes_AllSkipsOptIn(t *testing.T) {
+	m, _ := parseScanTypes("", true)
+	if m["media"] {
+		t.Error("-all enabled the opt-in media type")
+	}
+	if m, _ = parseScanTypes("media", false); !m["media"] {
+		t.Error("-type media not enabled")
+	}
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// In a large monorepo a flat listing is hundreds of node_modules, target/,
+// and .venv directories. With -monorepo, tidyup reads the workspace
+// manifest above each record -- uv workspaces, pnpm-workspace.yaml, npm and
+// yarn workspaces, Cargo workspaces, go.work -- attributes the record to
+// the member package it sits in, and reports reclaimable space per package
+// instead. JSON records carry the attribution as workspace and package.
+
+// workspace is a monorepo root and its member package directories.
+type workspace struct {
+	root    string
+	members []string
+}
+
+// workspaceManifests are tried in order at each directory.
+var workspaceManifests = []struct {
+	file  string
+	parse func(data string) []string // member patterns, relative to the root
+}{
+	{"pnpm-workspace.yaml", pnpmMembers},
+	{"pyproject.toml", func(data string) []string { return tomlMembers(data, "[tool.uv.workspace]") }},
+	{"Cargo.toml", func(data string) []string { return tomlMembers(data, "[workspace]") }},
+	{"go.work", goWorkMembers},
+	{"package.json", npmMembers},
+}
+
+// loadWorkspace returns the workspace rooted at dir, or nil.
+func loadWorkspace(dir string) *workspace {
+	for _, m := range workspaceManifests {
+		data, err := os.ReadFile(filepath.Join(dir, m.file))
+		if err != nil {
+			continue
+		}
+		patterns := m.parse(string(data))
+		if len(patterns) == 0 {
+			continue
+		}
+		ws := &workspace{root: dir}
+		seen := map[string]bool{}
+		for _, pat := range patterns {
+			// A recursive "**" matches nested packages; one level covers the
+			// usual packages/* layout.
+			pat = strings.TrimSuffix(strings.TrimSuffix(pat, "/**"), "/")
+			if strings.HasSuffix(pat, "**") {
+				pat = strings.TrimSuffix(pat, "**") + "*"
+			}
+			matches, _ := filepath.Glob(filepath.Join(dir, filepath.FromSlash(pat)))
+			for _, match := range matches {
+				if info, err := os.Stat(match); err == nil && info.IsDir() && !seen[match] {
+					seen[match] = true
+					ws.members = append(ws.members, filepath.Clean(match))
+				}
+			}
+		}
+		return ws
+	}
+	return nil
+}
+
+// member returns the member package path is in, relative to the root, or
+// "." when it belongs to the workspace root itself.
+func (ws *workspace) member(path string) string {
+	best := ""
+	for _, m := range ws.members {
+		if within(path, m) && len(m) > len(best) {
+			best = m
+		}
+	}
+	if best == "" {
+		return "."
+	}
+	rel, err := filepath.Rel(ws.root, best)
+	if err != nil {
+		return best
+	}
+	return filepath.ToSlash(rel)
+}
+
+// quoted returns the single- or double-quoted strings in s.
+func quoted(s string) []string {
+	var out []string
+	for {
+		i := strings.IndexAny(s, `"'`)
+		if i < 0 {
+			return out
+		}
+		j := strings.IndexByte(s[i+1:], s[i])
+		if j < 0 {
+			return out
+		}
+		out = append(out, s[i+1:i+1+j])
+		s = s[i+j+2:]
+	}
+}
+
+// tomlMembers reads the members array of a TOML section, which may span
+// several lines.
+func tomlMembers(data, section string) []string {
+	in := false
+	var members []string
+	sc := bufio.NewScanner(strings.NewReader(data))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if strings.HasPrefix(line, "[") {
+			in = line == section
+			continue
+		}
+		if !in {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found || strings.TrimSpace(key) != "members" {
+			continue
+		}
+		for !strings.Contains(value, "]") && sc.Scan() {
+			value += " " + sc.Text()
+		}
+		members = append(members, quoted(value)...)
+	}
+	return members
+}
+
+// pnpmMembers reads the packages list of pnpm-workspace.yaml; "!"
+// exclusions are dropped.
+func pnpmMembers(data string) []string {
+	var members []string
+	in := false
+	for _, line := range strings.Split(data, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "packages:"):
+			in = true
+		case in && strings.HasPrefix(trimmed, "- "):
+			item := strings.Trim(strings.TrimSpace(strings.TrimPrefix(trimmed, "- ")), `"'`)
+			if item != "" && !strings.HasPrefix(item, "!") {
+				members = append(members, item)
+			}
+		case in && trimmed != "" && !strings.HasPrefix(trimmed, "#"):
+			in = false
+		}
+	}
+	return members
+}
+
+// goWorkMembers reads the use directives of a go.work file.
+func goWorkMembers(data string) []string {
+	var members []string
+	block := false
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		switch {
+		case line == "use (":
+			block = true
+		case block && line == ")":
+			block = false
+		case block && line != "":
+			members = append(members, strings.Trim(line, `"`))
+		case strings.HasPrefix(line, "use "):
+			members = append(members, strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "use ")), `"`))
+		}
+	}
+	return members
+}
+
+// npmMembers reads the workspaces of a package.json: an array, or yarn's
+// {"packages": [...]}.
+func npmMembers(data string) []string {
+	var pkg struct {
+		Workspaces json.RawMessage `json:"workspaces"`
+	}
+	if json.Unmarshal([]byte(data), &pkg) != nil || len(pkg.Workspaces) == 0 {
+		return nil
+	}
+	var list []string
+	if json.Unmarshal(pkg.Workspaces, &list) == nil {
+		return list
+	}
+	var obj struct {
+		Packages []string `json:"packages"`
+	}
+	json.Unmarshal(pkg.Workspaces, &obj)
+	return obj.Packages
+}
+
+// workspaceFinder finds the workspace enclosing a path, caching manifests
+// by directory.
+type workspaceFinder struct {
+	mu    sync.Mutex
+	cache map[string]*workspace // nil entry: no workspace rooted there
+}
+
+// find returns the nearest workspace at or above dir, or nil.
+func (f *workspaceFinder) find(dir string) *workspace {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.cache == nil {
+		f.cache = map[string]*workspace{}
+	}
+	for {
+		ws, seen := f.cache[dir]
+		if !seen {
+			ws = loadWorkspace(dir)
+			f.cache[dir] = ws
+		}
+		if ws != nil {
+			return ws
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		dir = parent
+	}
+}
+
+// attributeWorkspaces sets Workspace and Package on records inside a
+// workspace.
+func attributeWorkspaces(records []Record) {
+	var f workspaceFinder
+	for i, r := range records {
+		if ws := f.find(projectDir(r.Path)); ws != nil {
+			records[i].Workspace = ws.root
+			records[i].Package = ws.member(r.Path)
+		}
+	}
+}
+
+// packageTotal is one line of the per-package report.
+type packageTotal struct {
+	name       string
+	items      int
+	size, disk int64
+	types      []string // in order of first appearance
+	typesSeen  map[string]bool
+}
+
+// printWorkspaceReport writes reclaimable space per workspace package, the
+// largest first, for records attributed by attributeWorkspaces.
+func printWorkspaceReport(w io.Writer, records []Record, opts *options) {
+	loc := opts.locale
+	byWorkspace := map[string]map[string]*packageTotal{}
+	var roots []string
+	for _, r := range records {
+		pkgs := byWorkspace[r.Workspace]
+		if pkgs == nil {
+			pkgs = map[string]*packageTotal{}
+			byWorkspace[r.Workspace] = pkgs
+			roots = append(roots, r.Workspace)
+		}
+		p := pkgs[r.Package]
+		if p == nil {
+			p = &packageTotal{name: r.Package, typesSeen: map[string]bool{}}
+			pkgs[r.Package] = p
+		}
+		p.items++
+		p.size += r.Size
+		p.disk += r.DiskSize
+		if !p.typesSeen[r.Type] {
+			p.typesSeen[r.Type] = true
+			p.types = append(p.types, r.Type)
+		}
+	}
+	sort.Strings(roots)
+	for _, root := range roots {
+		var pkgs []*packageTotal
+		var total int64
+		for _, p := range byWorkspace[root] {
+			pkgs = append(pkgs, p)
+			total += p.size
+		}
+		sort.Slice(pkgs, func(i, j int) bool {
+			if pkgs[i].size != pkgs[j].size {
+				return pkgs[i].size > pkgs[j].size
+			}
+			return pkgs[i].name < pkgs[j].name
+		})
+		fmt.Fprintf(w, "Workspace %s (%s in %d packages)\n", displayPath(Record{Path: root}, opts), loc.bytes(total), len(pkgs))
+		fmt.Fprintf(w, "%-10s %-10s %6s  %-30s  %s\n", "SIZE", "ON DISK", "ITEMS", "PACKAGE", "TYPES")
+		for _, p := range pkgs {
+			name := p.name
+			if name == "." {
+				name = "(workspace root)"
+			}
+			fmt.Fprintf(w, "%-10s %-10s %6d  %-30s  %s\n", loc.bytes(p.size), loc.bytes(p.disk), p.items, name, strings.Join(p.types, ", "))
+		}
+		fmt.Fprintln(w)
+	}
+}
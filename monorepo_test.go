@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWorkspaceManifestParsers(t *testing.T) {
+	cases := []struct {
+		name string
+		got  []string
+		want []string
+	}{
+		{"uv", tomlMembers("[project]\nname = \"root\"\n\n[tool.uv.workspace]\nmembers = [\n  \"packages/*\",\n  'libs/core',\n]\nexclude = [\"packages/old\"]\n", "[tool.uv.workspace]"), []string{"packages/*", "libs/core"}},
+		{"cargo", tomlMembers("[workspace]\nmembers = [\"crates/*\"]\n\n[workspace.dependencies]\nmembers = 1\n", "[workspace]"), []string{"crates/*"}},
+		{"cargo member", tomlMembers("[package]\nname = \"x\"\n", "[workspace]"), nil},
+		{"pnpm", pnpmMembers("packages:\n  # apps\n  - 'apps/*'\n  - \"packages/**\"\n  - '!**/test/**'\ncatalog:\n  - nope\n"), []string{"apps/*", "packages/**"}},
+		{"go.work", goWorkMembers("go 1.22\n\nuse (\n\t./api // service\n\t./cli\n)\nuse ./tools\n"), []string{"./api", "./cli", "./tools"}},
+		{"npm", npmMembers(`{"name": "root", "workspaces": ["web", "packages/*"]}`), []string{"web", "packages/*"}},
+		{"yarn", npmMembers(`{"workspaces": {"packages": ["apps/*"], "nohoist": ["**/x"]}}`), []string{"apps/*"}},
+		{"plain package.json", npmMembers(`{"name": "leaf"}`), nil},
+	}
+	for _, c := range cases {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%s: got %q, want %q", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestAttributeWorkspaces(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "pnpm-workspace.yaml"), []byte("packages:\n  - 'packages/*'\n"), 0644)
+	for _, dir := range []string{"packages/web/node_modules", "packages/api/dist", "node_modules"} {
+		os.MkdirAll(filepath.Join(root, dir), 0755)
+	}
+	// A member's own package.json is not a workspace of its own.
+	os.WriteFile(filepath.Join(root, "packages", "web", "package.json"), []byte(`{"name": "web"}`), 0644)
+	outside := t.TempDir()
+
+	records := []Record{
+		{Type: "node_modules", Path: filepath.Join(root, "packages", "web", "node_modules"), Size: 300},
+		{Type: "dist", Path: filepath.Join(root, "packages", "api", "dist"), Size: 50},
+		{Type: "node_modules", Path: filepath.Join(root, "node_modules"), Size: 100},
+		{Type: "venv", Path: filepath.Join(outside, ".venv"), Size: 10},
+	}
+	attributeWorkspaces(records)
+	var got []string
+	for _, r := range records {
+		got = append(got, r.Package)
+	}
+	if want := []string{"packages/web", "packages/api", ".", ""}; !reflect.DeepEqual(got, want) {
+		t.Errorf("packages = %q, want %q", got, want)
+	}
+	if records[0].Workspace != root || records[3].Workspace != "" {
+		t.Errorf("workspaces = %q, %q", records[0].Workspace, records[3].Workspace)
+	}
+
+	var buf bytes.Buffer
+	printText(&buf, records, totalSize(records), &options{monorepo: true})
+	out := buf.String()
+	web, api, top := strings.Index(out, "packages/web"), strings.Index(out, "packages/api"), strings.Index(out, "(workspace root)")
+	if web < 0 || api < 0 || top < 0 || !(web < top && top < api) {
+		t.Errorf("want packages largest first:\n%s", out)
+	}
+	if strings.Contains(out, filepath.Join(root, "packages", "web", "node_modules")) || !strings.Contains(out, filepath.Join(outside, ".venv")) {
+		t.Errorf("want workspace items summed and others listed:\n%s", out)
+	}
+}
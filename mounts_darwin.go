@@ -0,0 +1,37 @@
+package main
+
+import "syscall"
+
+// mountTable lists the mount points getfsstat(2) reports.
+func mountTable() ([]string, bool) {
+	n, err := syscall.Getfsstat(nil, 0)
+	if err != nil {
+		return nil, false
+	}
+	buf := make([]syscall.Statfs_t, n)
+	if n, err = syscall.Getfsstat(buf, 2 /* MNT_NOWAIT */); err != nil {
+		return nil, false
+	}
+	var mounts []string
+	for _, st := range buf[:n] {
+		name := make([]byte, 0, len(st.Mntonname))
+		for _, c := range st.Mntonname {
+			if c == 0 {
+				break
+			}
+			name = append(name, byte(c))
+		}
+		mounts = append(mounts, string(name))
+	}
+	return mounts, true
+}
+
+// volumeSpace returns the size of the filesystem mounted at mount and the
+// space available to unprivileged users on it.
+func volumeSpace(mount string) (total, free uint64, ok bool) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(mount, &st); err != nil {
+		return 0, 0, false
+	}
+	return st.Blocks * uint64(st.Bsize), st.Bavail * uint64(st.Bsize), true
+}
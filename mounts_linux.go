@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// mountTable lists the mount points in /proc/self/mountinfo, which unlike
+// device numbers also shows bind mounts of the same filesystem.
+func mountTable() ([]string, bool) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+	var mounts []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		// id parent major:minor root mountpoint options ...
+		fields := strings.Fields(sc.Text())
+		if len(fields) > 4 {
+			mounts = append(mounts, unescapeMountinfo(fields[4]))
+		}
+	}
+	return mounts, sc.Err() == nil
+}
+
+// unescapeMountinfo decodes the octal escapes (\040 for a space, ...) the
+// kernel writes for whitespace and backslashes in paths.
+func unescapeMountinfo(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// volumeSpace returns the size of the filesystem mounted at mount and the
+// space available to unprivileged users on it.
+func volumeSpace(mount string) (total, free uint64, ok bool) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(mount, &st); err != nil {
+		return 0, 0, false
+	}
+	return st.Blocks * uint64(st.Bsize), st.Bavail * uint64(st.Bsize), true
+}
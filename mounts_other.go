@@ -0,0 +1,15 @@
+//go:build !linux && !darwin
+
+package main
+
+// mountTable is unavailable here; the mount guard falls back to comparing
+// device numbers, which catches mounted disks but not bind mounts.
+func mountTable() ([]string, bool) {
+	return nil, false
+}
+
+// volumeSpace is unavailable here; the preview lists freed bytes per
+// volume without free-space percentages.
+func volumeSpace(mount string) (total, free uint64, ok bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Scheduled scans run while someone is working. -nice keeps them in the
+// background: the process drops to a low CPU priority (and, on Linux, the
+// idle I/O class, like `ionice -c3`), and walks and deletions are paced to
+// niceOpsPerSec file system operations. `tidyup serve` turns it on by itself
+// whenever the machine runs on battery. Background work nobody is waiting
+// for -- a -nice run, the scan serve starts with -- goes further and waits
+// for AC power and a cool machine (see backgroundBlocker) unless -force.
+
+// niceOpsPerSec bounds the directory entries visited, and the entries
+// removed, per second under -nice.
+const niceOpsPerSec = 2000
+
+// throttle paces operations to a fixed rate. Concurrent callers share it.
+type throttle struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newThrottle(perSec int) *throttle {
+	return &throttle{interval: time.Second / time.Duration(perSec)}
+}
+
+// wait blocks until the caller's turn.
+func (t *throttle) wait() {
+	t.mu.Lock()
+	now := time.Now()
+	if t.next.Before(now) {
+		t.next = now
+	}
+	d := t.next.Sub(now)
+	t.next = t.next.Add(t.interval)
+	t.mu.Unlock()
+	if d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// ioThrottle is the throttle in force; nil when not -nice.
+var ioThrottle atomic.Pointer[throttle]
+
+// lowerOnce lowers the process priority at most once: an unprivileged
+// process cannot raise it again.
+var lowerOnce sync.Once
+
+// setNice turns nice mode on or off for the operations that follow.
+func setNice(on bool) {
+	if !on {
+		ioThrottle.Store(nil)
+		return
+	}
+	lowerOnce.Do(func() {
+		if err := lowerPriority(); err != nil {
+			stderr.warnf("-nice: could not lower priority: %v", err)
+		}
+	})
+	if ioThrottle.Load() == nil {
+		ioThrottle.Store(newThrottle(niceOpsPerSec))
+	}
+}
+
+// pace waits for the next file system operation's turn under -nice, and
+// holds it while an idle-triggered scan is paused (see watchIdle).
+func pace() {
+	for scanPaused.Load() {
+		time.Sleep(200 * time.Millisecond)
+	}
+	if t := ioThrottle.Load(); t != nil {
+		t.wait()
+	}
+}
+
+// powerSupplyDir is where Linux lists power supplies; a seam for tests.
+var powerSupplyDir = "/sys/class/power_supply"
+
+// onBattery reports whether the machine is running on battery power. It is
+// false wherever that cannot be told.
+func onBattery() bool {
+	switch runtime.GOOS {
+	case "linux":
+		supplies, _ := os.ReadDir(powerSupplyDir)
+		sawMains := false
+		for _, s := range supplies {
+			read := func(name string) string {
+				data, _ := os.ReadFile(filepath.Join(powerSupplyDir, s.Name(), name))
+				return strings.TrimSpace(string(data))
+			}
+			switch read("type") {
+			case "Mains":
+				if read("online") == "1" {
+					return false
+				}
+				sawMains = true
+			case "Battery":
+				if read("status") == "Discharging" {
+					return true
+				}
+			}
+		}
+		return sawMains
+	case "darwin":
+		out, err := exec.Command("pmset", "-g", "batt").Output()
+		return err == nil && strings.Contains(string(out), "'Battery Power'")
+	}
+	return false
+}
+
+// thermalPressure reports whether macOS is throttling the CPU for heat, per
+// `pmset -g therm`. It is false elsewhere.
+func thermalPressure() bool {
+	if runtime.GOOS != "darwin" {
+		return false
+	}
+	out, err := exec.Command("pmset", "-g", "therm").Output()
+	return err == nil && parseThermal(string(out))
+}
+
+// parseThermal reads `pmset -g therm` output: a CPU speed limit below 100
+// or a recorded thermal warning level means pressure.
+func parseThermal(out string) bool {
+	for _, line := range strings.Split(out, "\n") {
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			if strings.Contains(line, "warning level") && !strings.Contains(line, "No ") {
+				return true
+			}
+			continue
+		}
+		if strings.TrimSpace(key) == "CPU_Speed_Limit" {
+			if n, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && n < 100 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// backgroundBlocker returns why heavy unattended work should wait -- battery
+// power or thermal pressure -- or "" when it can go ahead. A var so tests
+// can stand in for the machine's power state.
+var backgroundBlocker = func() string {
+	switch {
+	case onBattery():
+		return "running on battery power"
+	case thermalPressure():
+		return "the system is under thermal pressure"
+	}
+	return ""
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// ioprioIdle is IOPRIO_PRIO_VALUE(IOPRIO_CLASS_IDLE, 0).
+const ioprioIdle = 3 << 13
+
+// lowerPriority renices every thread of the process and puts it in the
+// idle I/O class. Both are per-thread on Linux; threads started later
+// inherit them from the thread that starts them.
+func lowerPriority() error {
+	tasks, err := os.ReadDir("/proc/self/task")
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, t := range tasks {
+		tid, err := strconv.Atoi(t.Name())
+		if err != nil {
+			continue
+		}
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, tid, 10); err != nil {
+			errs = append(errs, err)
+		}
+		// IOPRIO_WHO_PROCESS is 1; failure only means no I/O priority.
+		syscall.Syscall(syscall.SYS_IOPRIO_SET, 1, uintptr(tid), ioprioIdle)
+	}
+	return errors.Join(errs...)
+}
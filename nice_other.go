@@ -0,0 +1,7 @@
+//go:build !unix
+
+package main
+
+// lowerPriority is a no-op where there is no nice value; -nice still paces
+// I/O there.
+func lowerPriority() error { return nil }
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestThrottle_Paces(t *testing.T) {
+	th := newThrottle(100)
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		th.wait()
+	}
+	if elapsed := time.Since(start); elapsed < 45*time.Millisecond {
+		t.Errorf("6 operations at 100/s took %v, want at least 50ms", elapsed)
+	}
+}
+
+func TestOnBattery_Linux(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("reads /sys/class/power_supply")
+	}
+	saved := powerSupplyDir
+	t.Cleanup(func() { powerSupplyDir = saved })
+	powerSupplyDir = t.TempDir()
+	write := func(supply, name, value string) {
+		os.MkdirAll(filepath.Join(powerSupplyDir, supply), 0755)
+		os.WriteFile(filepath.Join(powerSupplyDir, supply, name), []byte(value+"\n"), 0644)
+	}
+
+	if onBattery() {
+		t.Error("no power supplies (a desktop or VM): want false")
+	}
+	write("AC", "type", "Mains")
+	write("AC", "online", "1")
+	write("BAT0", "type", "Battery")
+	write("BAT0", "status", "Charging")
+	if onBattery() {
+		t.Error("on mains: want false")
+	}
+	write("AC", "online", "0")
+	write("BAT0", "status", "Discharging")
+	if !onBattery() {
+		t.Error("unplugged and discharging: want true")
+	}
+}
+
+func TestParseThermal(t *testing.T) {
+	cases := map[string]bool{
+		"Note: No thermal warning level has been recorded\nNote: No performance warning level has been recorded\n": false,
+		"CPU_Scheduler_Limit \t= 100\nCPU_Available_CPUs \t= 8\nCPU_Speed_Limit \t= 100\n":                         false,
+		"CPU_Scheduler_Limit \t= 100\nCPU_Speed_Limit \t= 62\n":                                                    true,
+		"Thermal warning level set to 2.\n":                                                                        true,
+	}
+	for out, want := range cases {
+		if got := parseThermal(out); got != want {
+			t.Errorf("parseThermal(%q) = %v, want %v", out, got, want)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Nix profiles are reported, never deleted: removing generation links by hand
+// bypasses Nix's own bookkeeping, and the space only comes back once the
+// garbage collector runs. Records point at nix-collect-garbage instead.
+
+// generationLink matches Nix generation symlinks such as "profile-42-link".
+var generationLink = regexp.MustCompile(`^(.+)-(\d+)-link$`)
+
+// nixGeneration is one profile generation.
+type nixGeneration struct {
+	number  int
+	link    string    // the <profile>-<n>-link symlink
+	target  string    // store path it points at
+	created time.Time // link mtime: when the generation was built
+}
+
+// nixProfileDirs lists directories that hold Nix profile generations for the
+// current user (and the system profile on NixOS).
+func nixProfileDirs(home string) []string {
+	dirs := []string{
+		filepath.Join(home, ".local/state/nix/profiles"),
+		"/nix/var/nix/profiles",
+	}
+	if u, err := user.Current(); err == nil {
+		dirs = append(dirs, filepath.Join("/nix/var/nix/profiles/per-user", u.Username))
+	}
+	return dirs
+}
+
+// nixGenerations groups the generation links in dir by profile name and
+// reports each profile's current generation number.
+func nixGenerations(dir string) (map[string][]nixGeneration, map[string]int) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil
+	}
+	profiles := map[string][]nixGeneration{}
+	for _, e := range entries {
+		m := generationLink.FindStringSubmatch(e.Name())
+		if m == nil {
+			continue
+		}
+		n, _ := strconv.Atoi(m[2])
+		link := filepath.Join(dir, e.Name())
+		info, err := os.Lstat(link)
+		if err != nil {
+			continue
+		}
+		target, _ := os.Readlink(link)
+		profiles[m[1]] = append(profiles[m[1]], nixGeneration{n, link, target, info.ModTime()})
+	}
+
+	current := map[string]int{}
+	for name, gens := range profiles {
+		sort.Slice(gens, func(i, j int) bool { return gens[i].number < gens[j].number })
+		if target, err := os.Readlink(filepath.Join(dir, name)); err == nil {
+			if m := generationLink.FindStringSubmatch(filepath.Base(target)); m != nil {
+				current[name], _ = strconv.Atoi(m[2])
+			}
+		}
+		if current[name] == 0 {
+			current[name] = gens[len(gens)-1].number
+		}
+	}
+	return profiles, current
+}
+
+// nixExclusiveSize sums the store paths reachable from old generations but
+// not from the current one -- roughly what garbage collection would free
+// once they are deleted. ok is false when nix-store is unavailable.
+func nixExclusiveSize(old []string, current string) (int64, bool) {
+	if _, err := exec.LookPath("nix-store"); err != nil || len(old) == 0 {
+		return 0, false
+	}
+	closure := func(paths ...string) (map[string]bool, bool) {
+		out, err := exec.Command("nix-store", append([]string{"--query", "--requisites"}, paths...)...).Output()
+		if err != nil {
+			return nil, false
+		}
+		set := map[string]bool{}
+		sc := bufio.NewScanner(bytes.NewReader(out))
+		for sc.Scan() {
+			set[sc.Text()] = true
+		}
+		return set, true
+	}
+	oldSet, ok := closure(old...)
+	if !ok {
+		return 0, false
+	}
+	keep, ok := closure(current)
+	if !ok {
+		return 0, false
+	}
+	var exclusive []string
+	for p := range oldSet {
+		if !keep[p] {
+			exclusive = append(exclusive, p)
+		}
+	}
+	if len(exclusive) == 0 {
+		return 0, true
+	}
+	out, err := exec.Command("nix-store", append([]string{"--query", "--size"}, exclusive...)...).Output()
+	if err != nil {
+		return 0, false
+	}
+	var total int64
+	for _, line := range strings.Fields(string(out)) {
+		n, _ := strconv.ParseInt(line, 10, 64)
+		total += n
+	}
+	return total, true
+}
+
+// scanNixProfiles adds one advisory record per Nix profile that has
+// generations older than -age besides the current one.
+func (s *scanner) scanNixProfiles(home string) {
+	opts := s.opts
+	for _, dir := range nixProfileDirs(home) {
+		profiles, current := nixGenerations(dir)
+		for name, gens := range profiles {
+			var old []string
+			var newestOld time.Time
+			for _, g := range gens {
+				if g.number == current[name] || opts.ageDays(g.created) < float64(opts.minAge) {
+					continue
+				}
+				old = append(old, g.target)
+				if g.created.After(newestOld) {
+					newestOld = g.created
+				}
+			}
+			if len(old) == 0 {
+				continue
+			}
+
+			var currentTarget string
+			for _, g := range gens {
+				if g.number == current[name] {
+					currentTarget = g.target
+				}
+			}
+			notes := []string{fmt.Sprintf("%d old generations of %s", len(old), name)}
+			size, ok := nixExclusiveSize(old, currentTarget)
+			if !ok {
+				notes = append(notes, "size unknown: nix-store not available")
+			}
+			profile := filepath.Join(dir, name)
+			s.mu.Lock()
+			s.add(Record{
+				Type:            "nix",
+				Path:            profile,
+				Size:            size,
+				SizeHuman:       formatBytes(size),
+				DiskSize:        size,
+				DiskHuman:       formatBytes(size),
+				LastUsed:        newestOld.Truncate(time.Second).Format(time.RFC3339),
+				LastUsedDisplay: newestOld.Format("2006-01-02"),
+				AgeDays:         opts.ageDays(newestOld),
+				Notes:           notes,
+				Command:         fmt.Sprintf("nix-collect-garbage --delete-older-than %dd", opts.minAge),
+				Advisory:        true,
+				Confidence:      1, // generation links are dated by Nix itself
+			})
+			s.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// makeProfile creates generation links profile-1..n-link in dir, with
+// profile -> the newest generation. Link mtimes are "now"; tests move the
+// clock instead.
+func makeProfile(t *testing.T, dir string, n int) {
+	t.Helper()
+	os.MkdirAll(dir, 0755)
+	for i := 1; i <= n; i++ {
+		os.Symlink(fmt.Sprintf("/nix/store/%032d-profile", i), filepath.Join(dir, fmt.Sprintf("profile-%d-link", i)))
+	}
+	os.Symlink(fmt.Sprintf("profile-%d-link", n), filepath.Join(dir, "profile"))
+}
+
+func TestNixGenerations(t *testing.T) {
+	dir := t.TempDir()
+	makeProfile(t, dir, 3)
+	profiles, current := nixGenerations(dir)
+	if len(profiles["profile"]) != 3 || current["profile"] != 3 {
+		t.Errorf("profiles=%v current=%v, want 3 generations, current 3", profiles, current)
+	}
+}
+
+func TestScanNixProfiles_Advisory(t *testing.T) {
+	home := t.TempDir()
+	makeProfile(t, filepath.Join(home, ".local/state/nix/profiles"), 3)
+
+	opts := &options{minAge: 30}
+	s := &scanner{opts: opts}
+	s.scanNixProfiles(home)
+	if len(s.records) != 0 {
+		t.Fatalf("fresh generations: got %d records, want 0", len(s.records))
+	}
+
+	later := time.Now().AddDate(0, 0, 60)
+	opts.now = func() time.Time { return later }
+	s.scanNixProfiles(home)
+	if len(s.records) != 1 {
+		t.Fatalf("got %d records, want 1", len(s.records))
+	}
+	r := s.records[0]
+	if r.Type != "nix" || !r.Advisory || !strings.HasPrefix(r.Command, "nix-collect-garbage") {
+		t.Errorf("record = %+v, want advisory nix record", r)
+	}
+	if !strings.Contains(strings.Join(r.Notes, ";"), "2 old generations") {
+		t.Errorf("notes = %v, want 2 old generations", r.Notes)
+	}
+	if safe, skipped := filterSafeRecords([]Record{r}); len(safe) != 0 || skipped[ruleAdvisory] != 1 {
+		t.Errorf("advisory record must never reach deletion: safe=%v skipped=%v", safe, skipped)
+	}
+}
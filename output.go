@@ -3,27 +3,99 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"sort"
+	"strings"
+	"time"
 )
 
 // Record holds metadata about a found item for evaluation.
 type Record struct {
-	Type      string  `json:"type"`
-	Path      string  `json:"path"`
-	Size      int64   `json:"size_bytes"`
-	SizeHuman string  `json:"size_human"`
-	LastUsed  string  `json:"last_used"`
-	AgeDays   float64 `json:"age_days"`
+	Type            string   `json:"type"`
+	Path            string   `json:"path"`
+	Size            int64    `json:"size_bytes"`
+	SizeHuman       string   `json:"size_human"`
+	DiskSize        int64    `json:"disk_bytes"`
+	DiskHuman       string   `json:"disk_human"`
+	LastUsed        string   `json:"last_used"`                   // RFC3339
+	LastUsedDisplay string   `json:"last_used_display"`           // YYYY-MM-DD
+	LastUsedUnix    int64    `json:"last_used_unix,omitempty"`    // LastUsed in seconds since the epoch, filled in for -json
+	LastUsedUTC     string   `json:"last_used_rfc3339,omitempty"` // LastUsed as RFC3339 in UTC, filled in for -json
+	AgeDays         float64  `json:"age_days"`
+	Notes           []string `json:"notes,omitempty"`               // heuristic explanations, shown by -explain
+	Review          string   `json:"review,omitempty"`              // why the item needs a human look; never deleted while set
+	NewestSibling   string   `json:"newest_sibling,omitempty"`      // venvs: the project's most recently used venv, when not this one
+	Python          string   `json:"python,omitempty"`              // venvs: interpreter version, e.g. 3.11.6
+	Interpreter     string   `json:"interpreter,omitempty"`         // venvs: end of the bin/python symlink chain
+	InterpreterGone bool     `json:"interpreter_missing,omitempty"` // venvs: that interpreter no longer exists
+	Tool            string   `json:"tool,omitempty"`                // tools: installed package and version, e.g. "ruff 0.4.1"; library_cache: owning app; huggingface: repository
+	Command         string   `json:"command,omitempty"`             // the owning tool's own removal command, when there is one
+	Advisory        bool     `json:"advisory,omitempty"`            // informational only: tidyup never deletes it (see Command)
+	File            bool     `json:"file,omitempty"`                // a single file rather than a directory tree
+	Project         string   `json:"project,omitempty"`             // name the owning Python project declares (pyproject.toml, setup.cfg)
+	ProjectDir      string   `json:"project_dir,omitempty"`         // Poetry venvs: the project directory, kept apart from the environment
+	Workspace       string   `json:"workspace,omitempty"`           // -monorepo: root of the enclosing workspace
+	Package         string   `json:"package,omitempty"`             // -monorepo: member package, relative to Workspace ("." for the root)
+	Growth          int64    `json:"growth_bytes,omitempty"`        // -baseline: bytes added since the baseline (the whole size for new items)
+	EditableUsers   []string `json:"editable_users,omitempty"`      // venv/build/dist: environments with an editable install of the project
+	Tags            []string `json:"tags,omitempty"`                // labels from tags.json whose patterns cover the path
+	Owner           string   `json:"owner,omitempty"`               // inside a git checkout: its configured user.name <user.email>
+	Upstream        string   `json:"upstream,omitempty"`            // inside a git checkout: its origin (else first) remote URL, credentials removed
+	Trend           string   `json:"trend,omitempty"`               // size history across scans: growing, stable, shrinking, untouched
+	Confidence      float64  `json:"confidence,omitempty"`          // 0-1: how well the usage evidence supports "stale"
+
+	root        string  // absolute scan root the record was found under (not serialized)
+	sizeHistory []int64 // sizes from the persistent history, oldest first, for the text sparkline
+	pinned      string  // where the path resolved to when the safety checks passed it (see pinRecord)
+	pinnedID    fileID  // the inode found there then; zero if unknown
+}
+
+// lastUsedTime parses LastUsed, accepting the date-only form written by
+// schema_version 1 producers.
+func (r Record) lastUsedTime() (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, r.LastUsed); err == nil {
+		return t, true
+	}
+	if t, err := time.ParseInLocation("2006-01-02", r.LastUsed, time.Local); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
 }
 
 // JSONOutput is the top-level structure for --json output.
+// Any change here or in Record must be mirrored in jsonSchema (schema.go).
 type JSONOutput struct {
-	Count      int      `json:"count"`
-	TotalBytes int64    `json:"total_bytes"`
-	TotalHuman string   `json:"total_human"`
-	Records    []Record `json:"records"`
-	DryRun     bool     `json:"dry_run"`
+	SchemaVersion  int      `json:"schema_version"`
+	Count          int      `json:"count"`
+	TotalBytes     int64    `json:"total_bytes"`
+	TotalHuman     string   `json:"total_human"`
+	TotalDiskBytes int64    `json:"total_disk_bytes"`
+	TotalDiskHuman string   `json:"total_disk_human"`
+	Records        []Record `json:"records"`
+	DryRun         bool     `json:"dry_run"`
+	AsOf           string   `json:"as_of,omitempty"`
+	ScannedAt      string   `json:"scanned_at,omitempty"` // RFC3339 in UTC
+	Host           string   `json:"host,omitempty"`
+	// ByTag totals the records per tags.json tag; absent when none is tagged.
+	ByTag map[string]groupTotal `json:"by_tag,omitempty"`
+	// ByOwner totals the records per git checkout owner; absent when none
+	// has one.
+	ByOwner map[string]groupTotal `json:"by_owner,omitempty"`
+	// Warnings are what stderr would show, such as items -delete would skip
+	// as protected or active; always present, possibly empty.
+	Warnings []Warning `json:"warnings"`
+}
+
+// hostnameFunc names the scanning host in -json output; a seam for tests.
+var hostnameFunc = os.Hostname
+
+// summaryJSONOutput is JSONOutput without the records array, for -summary-only.
+// The shallower Records field shadows the embedded one and is always empty.
+type summaryJSONOutput struct {
+	JSONOutput
+	Records []Record `json:"records,omitempty"`
 }
 
 // formatBytes provides human-readable output (MB, GB, etc.)
@@ -42,19 +114,38 @@ func formatBytes(b int64) string {
 
 // sortRecords sorts records by the given field.
 func sortRecords(records []Record, field string) {
-	switch field {
-	case "age":
-		sort.Slice(records, func(i, j int) bool {
-			return records[i].AgeDays > records[j].AgeDays
-		})
-	case "path":
-		sort.Slice(records, func(i, j int) bool {
-			return records[i].Path < records[j].Path
-		})
-	default: // "size"
-		sort.Slice(records, func(i, j int) bool {
-			return records[i].Size > records[j].Size
-		})
+	less := recordLess(field)
+	sort.SliceStable(records, func(i, j int) bool { return less(&records[i], &records[j]) })
+}
+
+// recordLess orders records by the given field, as sortRecords does. Ties
+// go by path, then type, so the order does not depend on which sizing
+// goroutine finished first and consecutive reports diff cleanly.
+func recordLess(field string) func(a, b *Record) bool {
+	return func(a, b *Record) bool {
+		switch field {
+		case "age":
+			if a.AgeDays != b.AgeDays {
+				return a.AgeDays > b.AgeDays
+			}
+		case "path":
+		case "disk":
+			if a.DiskSize != b.DiskSize {
+				return a.DiskSize > b.DiskSize
+			}
+		case "confidence":
+			if a.Confidence != b.Confidence {
+				return a.Confidence > b.Confidence
+			}
+		default: // "size"
+			if a.Size != b.Size {
+				return a.Size > b.Size
+			}
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Type < b.Type
 	}
 }
 
@@ -67,19 +158,82 @@ func totalSize(records []Record) int64 {
 	return total
 }
 
-// printJSON writes machine-readable JSON output.
-func printJSON(records []Record, total int64, dryRun bool) int {
+// buildJSONOutput assembles the --json document.
+func buildJSONOutput(records []Record, total int64, dryRun bool, opts *options) JSONOutput {
 	out := JSONOutput{
-		Count:      len(records),
-		TotalBytes: total,
-		TotalHuman: formatBytes(total),
-		Records:    records,
-		DryRun:     dryRun,
+		SchemaVersion:  schemaVersion,
+		Count:          len(records),
+		TotalBytes:     total,
+		TotalHuman:     formatBytes(total),
+		TotalDiskBytes: totalDiskSize(records),
+		TotalDiskHuman: formatBytes(totalDiskSize(records)),
+		Records:        withUnambiguousTimes(records),
+		DryRun:         dryRun,
+		ByTag:          tagTotals(records),
+		ByOwner:        ownerTotals(records),
+		Warnings:       stderr.takeWarnings(),
+	}
+	out.ScannedAt, out.Host, out.AsOf = scanProvenance(opts)
+	return out
+}
+
+// scanProvenance returns when and where the scan ran, and -as-of, for the
+// JSON summaries.
+func scanProvenance(opts *options) (scannedAt, host, asOf string) {
+	// The scan's own time, not -as-of's simulated one.
+	scanned := time.Now()
+	if opts.now != nil {
+		scanned = opts.now()
 	}
-	enc := json.NewEncoder(os.Stdout)
+	host, _ = hostnameFunc()
+	if !opts.asOf.IsZero() {
+		asOf = opts.asOf.Format("2006-01-02")
+	}
+	return scanned.UTC().Format(time.RFC3339), host, asOf
+}
+
+// withUnambiguousTimes returns a copy of records with LastUsedUnix and
+// LastUsedUTC filled in, so output from hosts in different time zones sorts
+// and compares without parsing offsets.
+func withUnambiguousTimes(records []Record) []Record {
+	if records == nil {
+		return nil
+	}
+	out := make([]Record, len(records))
+	for i, r := range records {
+		if t, ok := r.lastUsedTime(); ok {
+			r.LastUsedUnix = t.Unix()
+			r.LastUsedUTC = t.UTC().Format(time.RFC3339)
+		}
+		out[i] = r
+	}
+	return out
+}
+
+// writeJSON encodes v as indented JSON.
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
 	enc.SetIndent("", "  ")
-	if err := enc.Encode(out); err != nil {
-		fmt.Fprintf(os.Stderr, "Error encoding JSON: %v\n", err)
+	return enc.Encode(v)
+}
+
+// totalDiskSize sums the on-disk size of all records.
+func totalDiskSize(records []Record) int64 {
+	var total int64
+	for _, r := range records {
+		total += r.DiskSize
+	}
+	return total
+}
+
+// printJSON writes machine-readable JSON output.
+func printJSON(records []Record, total int64, dryRun bool, opts *options) int {
+	var out interface{} = buildJSONOutput(records, total, dryRun, opts)
+	if opts.summaryOnly {
+		out = summaryJSONOutput{JSONOutput: out.(JSONOutput)}
+	}
+	if err := writeJSON(os.Stdout, out); err != nil {
+		stderr.printf("Error encoding JSON: %v", err)
 		return exitError
 	}
 	if len(records) == 0 {
@@ -88,14 +242,180 @@ func printJSON(records []Record, total int64, dryRun bool) int {
 	return exitFound
 }
 
+// Path styles for the -path-style flag.
+const (
+	pathAbsolute = "absolute"
+	pathHome     = "home"
+	pathRelative = "relative"
+)
+
+// displayPath renders a record's path for human output according to
+// -path-style. JSON output and deletion logs always use the absolute path.
+func displayPath(r Record, opts *options) string {
+	switch opts.pathStyle {
+	case pathHome:
+		if home, err := os.UserHomeDir(); err == nil {
+			home = filepath.Clean(home)
+			if r.Path == home {
+				return "~"
+			}
+			if strings.HasPrefix(r.Path, home+string(filepath.Separator)) {
+				return "~" + r.Path[len(home):]
+			}
+		}
+	case pathRelative:
+		if r.root != "" {
+			if rel, err := filepath.Rel(r.root, r.Path); err == nil && !strings.HasPrefix(rel, "..") {
+				return rel
+			}
+		}
+	}
+	return r.Path
+}
+
+// Date styles for the -dates flag.
+const (
+	datesRelative = "relative"
+	datesAbsolute = "absolute"
+	datesBoth     = "both"
+)
+
+// usedLabel renders when a record was last used according to -dates,
+// e.g. "94d ago", "2025-11-03", or "2025-11-03 (94d ago)".
+func usedLabel(r Record, opts *options) string {
+	relative := opts.locale.days(r.AgeDays) + " ago"
+	if opts.dateStyle == "" || opts.dateStyle == datesRelative {
+		return relative
+	}
+	t, ok := r.lastUsedTime()
+	if !ok {
+		return relative
+	}
+	if opts.dateStyle == datesAbsolute {
+		return opts.locale.date(t)
+	}
+	return fmt.Sprintf("%s (%s)", opts.locale.date(t), relative)
+}
+
+// usedColumn returns the header and width of the last-used column.
+func usedColumn(opts *options) (string, int) {
+	switch opts.dateStyle {
+	case datesAbsolute:
+		return "LAST USED", 10
+	case datesBoth:
+		return "LAST USED", 21
+	}
+	return "UNUSED", 9
+}
+
 // printText writes human-readable text output.
-func printText(records []Record, total int64) {
-	for _, r := range records {
-		fmt.Printf("%-10s %-4.0fd ago  %-12s  %s\n", r.SizeHuman, r.AgeDays, "["+r.Type+"]", r.Path)
+func printText(w io.Writer, records []Record, total int64, opts *options) {
+	loc := opts.locale
+	header, width := usedColumn(opts)
+	if len(records) > 0 && !opts.quiet {
+		// Low-confidence items go in their own section so they are not
+		// mistaken for the well-evidenced ones above. With -monorepo, items
+		// inside a workspace are summed per package instead.
+		var confident, doubtful, inWorkspace []Record
+		for _, r := range records {
+			if opts.monorepo && r.Workspace != "" {
+				inWorkspace = append(inWorkspace, r)
+				continue
+			}
+			if isLowConfidence(r) {
+				doubtful = append(doubtful, r)
+			} else {
+				confident = append(confident, r)
+			}
+		}
+		if len(inWorkspace) > 0 {
+			printWorkspaceReport(w, inWorkspace, opts)
+		}
+		if len(confident)+len(doubtful) > 0 {
+			fmt.Fprintf(w, "%-10s %-10s %-*s  %-12s  %s\n", "SIZE", "ON DISK", width, header, "TYPE", "PATH")
+			for _, r := range confident {
+				printRecordText(w, r, width, opts)
+			}
+			if len(doubtful) > 0 {
+				fmt.Fprintf(w, "\nLow confidence (too little usage evidence; held for review):\n")
+				for _, r := range doubtful {
+					printRecordText(w, r, width, opts)
+				}
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	asOf := ""
+	if !opts.asOf.IsZero() {
+		asOf = " as of " + loc.date(opts.asOf)
 	}
 	if len(records) > 0 {
-		fmt.Printf("\nFound %d items totaling %s\n", len(records), formatBytes(total))
+		fmt.Fprintf(w, "Found %s items totaling %s (%s on disk)%s\n", loc.integer(int64(len(records))), loc.bytes(total), loc.bytes(totalDiskSize(records)), asOf)
+		if !opts.quiet {
+			printGroupTotals(w, "By tag", tagTotals(records), opts)
+			if owners := ownerTotals(records); len(owners) > 1 {
+				printGroupTotals(w, "By owner", owners, opts)
+			}
+			printVolumePreview(w, records, opts)
+		}
 	} else {
-		fmt.Println("No unused items found.")
+		fmt.Fprintf(w, "No unused items found%s.\n", asOf)
+	}
+}
+
+// printRecordText writes one record's row and its detail lines.
+func printRecordText(w io.Writer, r Record, width int, opts *options) {
+	loc := opts.locale
+	fmt.Fprintf(w, "%-10s %-10s %-*s  %-12s  %s\n", loc.bytes(r.Size), loc.bytes(r.DiskSize), width, usedLabel(r, opts), "["+r.Type+"]", displayPath(r, opts))
+	if r.Review != "" && !isLowConfidence(r) {
+		fmt.Fprintf(w, "%*s  review required: %s\n", 10, "", r.Review)
+	}
+	if r.Tool != "" {
+		fmt.Fprintf(w, "%*s  tool %s\n", 10, "", r.Tool)
+	}
+	if len(r.Tags) > 0 {
+		fmt.Fprintf(w, "%*s  tags %s\n", 10, "", strings.Join(r.Tags, ", "))
+	}
+	switch {
+	case r.Owner != "" && r.Upstream != "":
+		fmt.Fprintf(w, "%*s  checkout of %s by %s\n", 10, "", r.Upstream, r.Owner)
+	case r.Upstream != "":
+		fmt.Fprintf(w, "%*s  checkout of %s\n", 10, "", r.Upstream)
+	case r.Owner != "":
+		fmt.Fprintf(w, "%*s  checkout by %s\n", 10, "", r.Owner)
+	}
+	if r.ProjectDir != "" {
+		fmt.Fprintf(w, "%*s  project %s (%s)\n", 10, "", r.Project, displayPath(Record{Path: r.ProjectDir, root: r.root}, opts))
+	} else if r.Project != "" {
+		fmt.Fprintf(w, "%*s  project %s\n", 10, "", r.Project)
+	}
+	if r.Advisory && r.Command == "" {
+		fmt.Fprintf(w, "%*s  advisory only: review manually, never deleted by tidyup\n", 10, "")
+	} else if r.Advisory {
+		fmt.Fprintf(w, "%*s  advisory only, not deleted by tidyup; reclaim with: %s\n", 10, "", r.Command)
+	} else if r.Command != "" {
+		fmt.Fprintf(w, "%*s  remove cleanly with: %s\n", 10, "", r.Command)
+	}
+	if r.InterpreterGone {
+		fmt.Fprintf(w, "%*s  broken venv: interpreter %s no longer exists\n", 10, "", r.Interpreter)
+	}
+	if r.Growth > 0 && r.Growth == r.Size {
+		fmt.Fprintf(w, "%*s  new since the baseline\n", 10, "")
+	} else if r.Growth > 0 {
+		fmt.Fprintf(w, "%*s  grew by %s since the baseline\n", 10, "", loc.bytes(r.Growth))
+	}
+	if r.NewestSibling != "" {
+		fmt.Fprintf(w, "%*s  older venv; project's newest is %s\n", 10, "", displayPath(Record{Path: r.NewestSibling, root: r.root}, opts))
+	}
+	if line := trendLine(r, loc); line != "" {
+		fmt.Fprintf(w, "%*s  %s\n", 10, "", line)
+	}
+	if isLowConfidence(r) {
+		fmt.Fprintf(w, "%*s  %s\n", 10, "", r.Review)
+	}
+	if opts.explain {
+		for _, n := range r.Notes {
+			fmt.Fprintf(w, "%*s  note: %s\n", 10, "", n)
+		}
 	}
 }
@@ -2,7 +2,9 @@ package main
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestRecordJSONMarshal(t *testing.T) {
@@ -54,3 +56,144 @@ func TestFormatBytes(t *testing.T) {
 		}
 	}
 }
+
+func TestRecordLastUsedTime(t *testing.T) {
+	r := Record{LastUsed: "2026-01-02T03:04:05Z"}
+	got, ok := r.lastUsedTime()
+	if !ok || !got.Equal(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("RFC3339 parse = %v, %v", got, ok)
+	}
+
+	// schema_version 1 producers wrote a bare date.
+	r = Record{LastUsed: "2025-01-01"}
+	if got, ok := r.lastUsedTime(); !ok || got.Year() != 2025 {
+		t.Errorf("date-only parse = %v, %v", got, ok)
+	}
+
+	if _, ok := (Record{}).lastUsedTime(); ok {
+		t.Error("expected empty LastUsed to fail")
+	}
+}
+
+func TestUsedLabel(t *testing.T) {
+	r := Record{LastUsed: "2025-11-03T10:00:00Z", AgeDays: 94.2}
+	tests := []struct {
+		style string
+		want  string
+	}{
+		{"", "94d ago"},
+		{datesRelative, "94d ago"},
+		{datesAbsolute, "2025-11-03"},
+		{datesBoth, "2025-11-03 (94d ago)"},
+	}
+	for _, tt := range tests {
+		opts := &options{dateStyle: tt.style}
+		if got := usedLabel(r, opts); got != tt.want {
+			t.Errorf("usedLabel(%q) = %q, want %q", tt.style, got, tt.want)
+		}
+	}
+
+	// Unparseable dates degrade to the relative form.
+	bad := Record{LastUsed: "yesterday", AgeDays: 1}
+	if got := usedLabel(bad, &options{dateStyle: datesBoth}); got != "1d ago" {
+		t.Errorf("unparseable date: got %q", got)
+	}
+}
+
+func TestSummaryJSONOutput_OmitsRecords(t *testing.T) {
+	records := []Record{{Type: "venv", Path: "/p/.venv", Size: 10}}
+	full := buildJSONOutput(records, totalSize(records), true, &options{})
+	data, err := json.Marshal(summaryJSONOutput{JSONOutput: full})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]interface{}
+	json.Unmarshal(data, &m)
+	if _, ok := m["records"]; ok {
+		t.Errorf("summary output should omit records: %s", data)
+	}
+	if m["count"].(float64) != 1 || m["total_bytes"].(float64) != 10 {
+		t.Errorf("summary totals wrong: %s", data)
+	}
+}
+
+func TestWithUnambiguousTimes_AgreeAcrossZones(t *testing.T) {
+	records := []Record{
+		{Path: "/a", LastUsed: "2026-03-01T09:00:00+09:00"},
+		{Path: "/b", LastUsed: "2026-03-01T00:00:00Z"},
+		{Path: "/c", LastUsed: "not a date"},
+	}
+	out := withUnambiguousTimes(records)
+	if out[0].LastUsedUnix != out[1].LastUsedUnix || out[0].LastUsedUTC != "2026-03-01T00:00:00Z" || out[1].LastUsedUTC != out[0].LastUsedUTC {
+		t.Errorf("same instant, different zones: %+v / %+v", out[0], out[1])
+	}
+	if out[2].LastUsedUnix != 0 || out[2].LastUsedUTC != "" {
+		t.Errorf("unparseable last_used should leave the fields empty: %+v", out[2])
+	}
+	if records[0].LastUsedUnix != 0 {
+		t.Error("input records were modified")
+	}
+}
+
+func TestDisplayPath(t *testing.T) {
+	t.Setenv("HOME", "/home/user")
+	r := Record{Path: "/home/user/dev/app/.venv", root: "/home/user/dev"}
+	tests := []struct {
+		style string
+		want  string
+	}{
+		{"", "/home/user/dev/app/.venv"},
+		{pathAbsolute, "/home/user/dev/app/.venv"},
+		{pathHome, "~/dev/app/.venv"},
+		{pathRelative, "app/.venv"},
+	}
+	for _, tt := range tests {
+		if got := displayPath(r, &options{pathStyle: tt.style}); got != tt.want {
+			t.Errorf("displayPath(%q) = %q, want %q", tt.style, got, tt.want)
+		}
+	}
+
+	// Outside $HOME, or imported without a root: stay absolute.
+	other := Record{Path: "/srv/app/node_modules"}
+	for _, style := range []string{pathHome, pathRelative} {
+		if got := displayPath(other, &options{pathStyle: style}); got != other.Path {
+			t.Errorf("displayPath(%q) = %q, want absolute", style, got)
+		}
+	}
+	// A sibling that merely shares the $HOME prefix is not under it.
+	sibling := Record{Path: "/home/username/x"}
+	if got := displayPath(sibling, &options{pathStyle: pathHome}); got != sibling.Path {
+		t.Errorf("prefix sibling rendered as %q", got)
+	}
+}
+
+func TestSortRecords_TiesByPath(t *testing.T) {
+	in := []Record{
+		{Type: "venv", Path: "/p/c", Size: 10, AgeDays: 40},
+		{Type: "build", Path: "/p/a", Size: 10, AgeDays: 40},
+		{Type: "venv", Path: "/p/b", Size: 20, AgeDays: 40},
+		{Type: "dist", Path: "/p/a", Size: 10, AgeDays: 40},
+	}
+	for field, want := range map[string]string{
+		"size": "/p/b build:/p/a dist:/p/a /p/c",
+		"age":  "build:/p/a dist:/p/a /p/b /p/c",
+		"path": "build:/p/a dist:/p/a /p/b /p/c",
+	} {
+		// Every starting order must give the same result.
+		for shift := range in {
+			records := append(append([]Record{}, in[shift:]...), in[:shift]...)
+			sortRecords(records, field)
+			var got []string
+			for _, r := range records {
+				if r.Path == "/p/a" {
+					got = append(got, r.Type+":"+r.Path)
+				} else {
+					got = append(got, r.Path)
+				}
+			}
+			if s := strings.Join(got, " "); s != want {
+				t.Errorf("-sort %s from shift %d: %s, want %s", field, shift, s, want)
+			}
+		}
+	}
+}
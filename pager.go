@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// pagerCommand returns the pager to use: $TIDYUP_PAGER, then $PAGER, then
+// "less". An explicit empty or "cat" value disables paging.
+func pagerCommand() string {
+	for _, key := range []string{"TIDYUP_PAGER", "PAGER"} {
+		if v, ok := os.LookupEnv(key); ok {
+			if v == "cat" {
+				return ""
+			}
+			return v
+		}
+	}
+	return "less"
+}
+
+// shouldPage reports whether text needs a pager on this stdout.
+func shouldPage(text []byte, disabled bool) bool {
+	if disabled || runtime.GOOS == "windows" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	rows, ok := terminalRows()
+	return ok && bytes.Count(text, []byte("\n")) >= rows
+}
+
+// pageOutput writes text to stdout, through a pager when stdout is a
+// terminal and text is taller than it (like git). Falls back to writing
+// directly if the pager cannot be started.
+func pageOutput(text []byte, disabled bool) {
+	pager := pagerCommand()
+	if pager == "" || !shouldPage(text, disabled) {
+		os.Stdout.Write(text)
+		return
+	}
+
+	cmd := exec.Command("sh", "-c", pager)
+	cmd.Stdin = bytes.NewReader(text)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	// Same defaults as git: quit if one screen, keep colors, no clear on exit.
+	if _, ok := os.LookupEnv("LESS"); !ok {
+		cmd.Env = append(os.Environ(), "LESS=FRX")
+	}
+	if err := cmd.Run(); err != nil {
+		if _, started := err.(*exec.ExitError); !started {
+			os.Stdout.Write(text)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestPagerCommand(t *testing.T) {
+	os.Unsetenv("TIDYUP_PAGER")
+	t.Setenv("PAGER", "most")
+	if got := pagerCommand(); got != "most" {
+		t.Errorf("got %q, want $PAGER", got)
+	}
+	t.Setenv("TIDYUP_PAGER", "less -S")
+	if got := pagerCommand(); got != "less -S" {
+		t.Errorf("got %q, want $TIDYUP_PAGER to win", got)
+	}
+	t.Setenv("TIDYUP_PAGER", "cat")
+	if got := pagerCommand(); got != "" {
+		t.Errorf("got %q, want paging disabled for cat", got)
+	}
+}
+
+func TestShouldPage_Disabled(t *testing.T) {
+	long := make([]byte, 10000)
+	for i := range long {
+		long[i] = '\n'
+	}
+	if shouldPage(long, true) {
+		t.Error("-no-pager must disable paging")
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// installMarkers are files package managers write into node_modules at the
+// end of an install; anything modified after them was changed by hand.
+var installMarkers = []string{".package-lock.json", ".modules.yaml", ".yarn-state.yml"}
+
+// installSlack absorbs files a package manager finishes writing just after
+// its marker.
+const installSlack = time.Minute
+
+// nodeModulesCacheDirs are tool caches inside node_modules that legitimately
+// change after install.
+var nodeModulesCacheDirs = map[string]bool{".cache": true, ".vite": true}
+
+// nodeModulesPatches explains why deleting a node_modules tree may lose
+// changes that "npm ci" will not bring back: patch-package, pnpm, or yarn
+// patches in the project, or files edited after the last install.
+func nodeModulesPatches(path string) []string {
+	var reasons []string
+	project := filepath.Dir(path)
+
+	if m, _ := filepath.Glob(filepath.Join(project, "patches", "*.patch")); len(m) > 0 {
+		reasons = append(reasons, "patch-package patches in "+filepath.Join(project, "patches")+" (reapplied only by a postinstall hook)")
+	}
+	if m, _ := filepath.Glob(filepath.Join(project, ".yarn", "patches", "*")); len(m) > 0 {
+		reasons = append(reasons, "yarn patches in "+filepath.Join(project, ".yarn", "patches"))
+	}
+	if data, err := os.ReadFile(filepath.Join(project, "package.json")); err == nil &&
+		bytes.Contains(data, []byte(`"patchedDependencies"`)) {
+		reasons = append(reasons, "pnpm patchedDependencies in package.json")
+	}
+
+	if edited := editedSinceInstall(path); edited != "" {
+		reasons = append(reasons, "file modified after install: "+edited)
+	}
+	return reasons
+}
+
+// editedSinceInstall returns the first file in node_modules modified after
+// the package manager's install marker, or "" (also when there is no marker).
+func editedSinceInstall(path string) string {
+	var installed time.Time
+	for _, name := range installMarkers {
+		if info, err := os.Stat(filepath.Join(path, name)); err == nil && info.ModTime().After(installed) {
+			installed = info.ModTime()
+		}
+	}
+	if installed.IsZero() {
+		return ""
+	}
+	cutoff := installed.Add(installSlack)
+
+	var edited string
+	_ = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if nodeModulesCacheDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.ModTime().After(cutoff) {
+			edited, _ = filepath.Rel(path, p)
+			return fs.SkipAll
+		}
+		return nil
+	})
+	return edited
+}
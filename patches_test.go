@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// makeNodeModules creates project/node_modules with an npm install marker
+// and one package file, all dated installed.
+func makeNodeModules(t *testing.T, project string, installed time.Time) string {
+	t.Helper()
+	nm := filepath.Join(project, "node_modules")
+	os.MkdirAll(filepath.Join(nm, "left-pad"), 0755)
+	for _, f := range []string{".package-lock.json", "left-pad/index.js"} {
+		p := filepath.Join(nm, f)
+		os.WriteFile(p, []byte("x"), 0644)
+		os.Chtimes(p, installed, installed)
+	}
+	return nm
+}
+
+func TestNodeModulesPatches_Clean(t *testing.T) {
+	nm := makeNodeModules(t, t.TempDir(), time.Now().AddDate(0, 0, -60))
+	if reasons := nodeModulesPatches(nm); len(reasons) != 0 {
+		t.Errorf("clean install: got %v", reasons)
+	}
+}
+
+func TestNodeModulesPatches_PatchFiles(t *testing.T) {
+	project := t.TempDir()
+	nm := makeNodeModules(t, project, time.Now().AddDate(0, 0, -60))
+	os.MkdirAll(filepath.Join(project, "patches"), 0755)
+	os.WriteFile(filepath.Join(project, "patches", "left-pad+1.3.0.patch"), []byte("diff"), 0644)
+	os.WriteFile(filepath.Join(project, "package.json"), []byte(`{"pnpm": {"patchedDependencies": {}}}`), 0644)
+
+	reasons := strings.Join(nodeModulesPatches(nm), "\n")
+	for _, want := range []string{"patch-package", "pnpm patchedDependencies"} {
+		if !strings.Contains(reasons, want) {
+			t.Errorf("reasons missing %q:\n%s", want, reasons)
+		}
+	}
+}
+
+func TestNodeModulesPatches_EditedAfterInstall(t *testing.T) {
+	installed := time.Now().AddDate(0, 0, -60)
+	nm := makeNodeModules(t, t.TempDir(), installed)
+
+	// Tool caches change after install without being local edits.
+	os.MkdirAll(filepath.Join(nm, ".cache", "babel"), 0755)
+	os.WriteFile(filepath.Join(nm, ".cache", "babel", "x.json"), []byte("x"), 0644)
+	if reasons := nodeModulesPatches(nm); len(reasons) != 0 {
+		t.Fatalf("cache write: got %v", reasons)
+	}
+
+	edited := installed.AddDate(0, 0, 3)
+	p := filepath.Join(nm, "left-pad", "index.js")
+	os.Chtimes(p, edited, edited)
+	reasons := nodeModulesPatches(nm)
+	if len(reasons) != 1 || !strings.Contains(reasons[0], filepath.Join("left-pad", "index.js")) {
+		t.Errorf("edited file: got %v", reasons)
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"text/tabwriter"
+)
+
+// tidyup's own files live in three per-user directories, following the XDG
+// base directory spec on Linux and the platform conventions elsewhere:
+//
+//	config  settings the user edits
+//	state   history, decisions, manifests, the approval queue, logs,
+//	        quarantine, and the server's status/token/socket
+//	cache   the scan index, which can always be rebuilt
+//
+// Everything else names files relative to these through statePath and
+// friends, so `tidyup paths` can list the whole layout. Read-modify-write
+// updates of shared state files happen under lockState.
+
+// stateDir returns where tidyup keeps state between runs:
+// $XDG_STATE_HOME/tidyup, ~/Library/Application Support/tidyup on macOS,
+// %LocalAppData%\tidyup on Windows, else ~/.local/state/tidyup.
+func stateDir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "tidyup"), nil
+	}
+	switch runtime.GOOS {
+	case "darwin":
+		home, err := os.UserHomeDir()
+		return filepath.Join(home, "Library", "Application Support", "tidyup"), err
+	case "windows":
+		if dir := os.Getenv("LocalAppData"); dir != "" {
+			return filepath.Join(dir, "tidyup"), nil
+		}
+	}
+	home, err := os.UserHomeDir()
+	return filepath.Join(home, ".local", "state", "tidyup"), err
+}
+
+// configDir returns $XDG_CONFIG_HOME/tidyup, else the platform's config
+// directory (~/Library/Application Support/tidyup on macOS, %AppData%\tidyup
+// on Windows, ~/.config/tidyup elsewhere).
+func configDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "tidyup"), nil
+	}
+	dir, err := os.UserConfigDir()
+	return filepath.Join(dir, "tidyup"), err
+}
+
+// cacheDir returns $XDG_CACHE_HOME/tidyup, else the platform's cache
+// directory (~/Library/Caches/tidyup on macOS, %LocalAppData%\tidyup on
+// Windows, ~/.cache/tidyup elsewhere).
+func cacheDir() (string, error) {
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		return filepath.Join(dir, "tidyup"), nil
+	}
+	dir, err := os.UserCacheDir()
+	return filepath.Join(dir, "tidyup"), err
+}
+
+// statePath names a file or directory inside the state directory.
+func statePath(elem ...string) (string, error) {
+	dir, err := stateDir()
+	return filepath.Join(append([]string{dir}, elem...)...), err
+}
+
+// recoveryDirs are the state subdirectories holding what tidyup needs to
+// undo its own removals (archives is reserved for archived copies).
+var recoveryDirs = []string{"quarantine", "manifests", "archives"}
+
+// ownStateDirs returns tidyup's state directory and its recovery
+// subdirectories, as configured and with symlinks resolved, so a relocated
+// or linked quarantine is recognized too. A scan never enters them and
+// deletion never touches them or anything holding them.
+func ownStateDirs() []string {
+	state, err := stateDir()
+	if err != nil {
+		return nil
+	}
+	var dirs []string
+	for _, dir := range append([]string{state}, recoveryDirs...) {
+		if dir != state {
+			dir = filepath.Join(state, dir)
+		}
+		dirs = append(dirs, filepath.Clean(dir))
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil && resolved != filepath.Clean(dir) {
+			dirs = append(dirs, resolved)
+		}
+	}
+	return dirs
+}
+
+// insideOwnState reports whether path is one of tidyup's own directories
+// or inside one.
+func insideOwnState(path string, own []string) bool {
+	for _, dir := range own {
+		if within(filepath.Clean(path), dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// ownStateConflict returns the state directory path is inside or holds, or "".
+func ownStateConflict(path string, own []string) string {
+	path = filepath.Clean(path)
+	for _, dir := range own {
+		if within(path, dir) || within(dir, path) {
+			return dir
+		}
+	}
+	return ""
+}
+
+// pathEntry is one line of `tidyup paths`.
+type pathEntry struct {
+	Name        string `json:"name"`
+	Path        string `json:"path"`
+	Description string `json:"description"`
+	Exists      bool   `json:"exists"`
+}
+
+// layout lists every location tidyup reads or writes on its own behalf.
+func layout() ([]pathEntry, error) {
+	config, err := configDir()
+	if err != nil {
+		return nil, err
+	}
+	state, err := stateDir()
+	if err != nil {
+		return nil, err
+	}
+	cache, err := cacheDir()
+	if err != nil {
+		return nil, err
+	}
+	entries := []pathEntry{
+		{Name: "config", Path: config, Description: "configuration"},
+		{Name: "heuristics", Path: filepath.Join(config, "heuristics.json"), Description: "per-type usage signals (optional)"},
+		{Name: "exclusions", Path: filepath.Join(config, "exclusions.json"), Description: "per-type exclusions (optional)"},
+		{Name: "tags", Path: filepath.Join(config, "tags.json"), Description: "path tags for reports and -tag (optional)"},
+		{Name: "state", Path: state, Description: "state kept between runs"},
+		{Name: "cache", Path: cache, Description: "rebuildable caches"},
+		{Name: "index", Path: filepath.Join(cache, "index"), Description: "scan index"},
+		{Name: "history", Path: filepath.Join(state, "history.json"), Description: "per-item size history"},
+		{Name: "activity", Path: filepath.Join(state, "activity.log"), Description: "shell hook: environment activations and directory changes"},
+		{Name: "decisions", Path: filepath.Join(state, "decisions.jsonl"), Description: "keep/delete/restore decisions"},
+		{Name: "manifests", Path: filepath.Join(state, "manifests"), Description: "records of removed items (trashed.jsonl, receipts.jsonl)"},
+		{Name: "quarantine", Path: filepath.Join(state, "quarantine"), Description: "items set aside before final removal"},
+		{Name: "baselines", Path: filepath.Join(state, "baselines"), Description: "saved baselines for -baseline"},
+		{Name: "logs", Path: filepath.Join(state, "logs"), Description: "deletion logs for -log state"},
+		{Name: "queue", Path: filepath.Join(state, "queue.json"), Description: "approval queue"},
+		{Name: "status", Path: filepath.Join(state, "status.json"), Description: "tidyup serve: latest summary"},
+		{Name: "token", Path: filepath.Join(state, "serve.token"), Description: "tidyup serve: API token"},
+		{Name: "events", Path: filepath.Join(state, "events.sock"), Description: "tidyup serve: event socket"},
+		{Name: "lock", Path: filepath.Join(state, "tidyup.lock"), Description: "serializes state updates"},
+	}
+	for i := range entries {
+		_, err := os.Lstat(entries[i].Path)
+		entries[i].Exists = err == nil
+	}
+	return entries, nil
+}
+
+// legacyStatePaths maps state files from earlier layouts, relative to the
+// state directory, to where they live now.
+var legacyStatePaths = map[string]string{
+	"trashed.jsonl": filepath.Join("manifests", "trashed.jsonl"),
+}
+
+// migrateLegacyState moves files from earlier layouts into place. A file is
+// only moved when nothing is at its new location yet.
+func migrateLegacyState() error {
+	var errs []error
+	for from, to := range legacyStatePaths {
+		src, err := statePath(from)
+		if err != nil {
+			return err
+		}
+		dst, _ := statePath(to)
+		if _, err := os.Lstat(src); err != nil {
+			continue
+		}
+		if _, err := os.Lstat(dst); err == nil {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := os.Rename(src, dst); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+var migrateOnce sync.Once
+
+// migrateState runs migrateLegacyState once per process.
+func migrateState() {
+	migrateOnce.Do(func() {
+		if err := migrateLegacyState(); err != nil {
+			stderr.warnf("could not migrate old state files: %v", err)
+		}
+	})
+}
+
+// lockState takes the state lock and returns the function that releases it.
+func lockState() func() {
+	path, err := statePath("tidyup.lock")
+	if err != nil {
+		stderr.warnf("could not lock the state directory: %v", err)
+		return func() {}
+	}
+	return lockPath(path)
+}
+
+// lockPath takes an exclusive lock on the lock file at path, creating it
+// and its directory if needed, and returns the function that releases it.
+// Without a lock (an unwritable directory, or no locking on this platform)
+// it warns and carries on: the updates it guards are best-effort anyway.
+func lockPath(path string) func() {
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	var f *os.File
+	if err == nil {
+		f, err = os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	}
+	if err == nil {
+		err = lockFile(f)
+	}
+	if err != nil {
+		stderr.warnf("could not lock %s: %v", path, err)
+		if f != nil {
+			f.Close()
+		}
+		return func() {}
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}
+}
+
+// runPaths implements `tidyup paths`.
+func runPaths(args []string) int {
+	flags := flag.NewFlagSet("paths", flag.ContinueOnError)
+	jsonOut := flags.Bool("json", false, "Output as JSON")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tidyup paths [-json]\n\n")
+		fmt.Fprintf(os.Stderr, "Lists where tidyup keeps its own configuration, state, and caches.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	entries, err := layout()
+	if err != nil {
+		stderr.errorf("%v", err)
+		return exitError
+	}
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(entries)
+		return exitOK
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, e := range entries {
+		missing := ""
+		if !e.Exists {
+			missing = " (not created yet)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s%s\n", e.Name, e.Path, e.Description, missing)
+	}
+	tw.Flush()
+	return exitOK
+}
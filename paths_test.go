@@ -0,0 +1,106 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLayout_FollowsXDG(t *testing.T) {
+	base := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(base, "config"))
+	t.Setenv("XDG_STATE_HOME", filepath.Join(base, "state"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(base, "cache"))
+	os.MkdirAll(filepath.Join(base, "state", "tidyup"), 0700)
+
+	entries, err := layout()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]pathEntry{}
+	for _, e := range entries {
+		got[e.Name] = e
+	}
+	want := map[string]string{
+		"config":  filepath.Join(base, "config", "tidyup"),
+		"state":   filepath.Join(base, "state", "tidyup"),
+		"index":   filepath.Join(base, "cache", "tidyup", "index"),
+		"history": filepath.Join(base, "state", "tidyup", "history.json"),
+	}
+	for name, path := range want {
+		if got[name].Path != path {
+			t.Errorf("%s = %q, want %q", name, got[name].Path, path)
+		}
+	}
+	if !got["state"].Exists || got["config"].Exists {
+		t.Errorf("exists: state %v, config %v; want true, false", got["state"].Exists, got["config"].Exists)
+	}
+}
+
+func TestMigrateLegacyState(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	old, _ := statePath("trashed.jsonl")
+	os.MkdirAll(filepath.Dir(old), 0700)
+	os.WriteFile(old, []byte("{}\n"), 0600)
+
+	if err := migrateLegacyState(); err != nil {
+		t.Fatal(err)
+	}
+	moved, _ := trashedPath()
+	if data, err := os.ReadFile(moved); err != nil || string(data) != "{}\n" {
+		t.Errorf("manifest not moved to %s: %q, %v", moved, data, err)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Error("legacy manifest left behind")
+	}
+
+	// An existing file at the new location is never overwritten.
+	os.WriteFile(old, []byte("stale\n"), 0600)
+	migrateLegacyState()
+	if data, _ := os.ReadFile(moved); string(data) != "{}\n" {
+		t.Errorf("migration overwrote the current manifest: %q", data)
+	}
+}
+
+func TestLockPath_Release(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dir", "x.lock")
+	unlock := lockPath(path)
+	unlock()
+	// Released: a second lock does not block.
+	lockPath(path)()
+}
+
+func TestScanRoots_SkipsOwnState(t *testing.T) {
+	base := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", filepath.Join(base, "state"))
+	state := filepath.Join(base, "state", "tidyup")
+	old := time.Now().AddDate(0, 0, -90)
+	makeVenv(t, filepath.Join(state, "manifests", "x", ".venv"), old)
+	// A quarantine linked elsewhere under the scan root is still recognized.
+	makeVenv(t, filepath.Join(base, "qstore", "y", ".venv"), old)
+	if err := os.Symlink(filepath.Join(base, "qstore"), filepath.Join(state, "quarantine")); err != nil {
+		t.Skip("symlinks unavailable:", err)
+	}
+	project := filepath.Join(base, "proj", ".venv")
+	makeVenv(t, project, old)
+
+	opts := &options{minAge: 30, maxDepth: 8, scanTypes: map[string]bool{"venv": true}}
+	records, _ := scanRoots([]string{base}, opts)
+	if len(records) != 1 || records[0].Path != project {
+		t.Fatalf("got %+v, want only %s", records, project)
+	}
+}
+
+func TestFilterSafeRecords_SkipsOwnState(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", "/srv/st")
+	saved := mountTableFunc
+	t.Cleanup(func() { mountTableFunc = saved })
+	mountTableFunc = func() ([]string, bool) { return nil, true }
+
+	records := []Record{{Path: "/srv/st"}, {Path: "/srv/st/tidyup/quarantine/a"}, {Path: "/srv/p/a"}}
+	safe, skipped := filterSafeRecords(records)
+	if len(safe) != 1 || safe[0].Path != "/srv/p/a" || skipped[ruleOwnState] != 2 {
+		t.Errorf("safe = %+v, skipped = %v", safe, skipped)
+	}
+}
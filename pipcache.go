@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// pip's cache holds two things that age differently: wheels/, the wheels
+// pip built from sdists (slow to rebuild, worth keeping longer), and http/
+// (http-v2/ since pip 23.3), the downloaded responses (fetched again in
+// seconds). Each is its own pip_cache item, and -pip-wheels-age and
+// -pip-http-age give each its own threshold in place of -age.
+
+// pipCacheParts maps the cache subdirectories reported to their part.
+var pipCacheParts = map[string]string{"wheels": "wheels", "http": "http", "http-v2": "http"}
+
+// pipCacheDirs returns pip's cache directory: $PIP_CACHE_DIR, else the
+// per-OS defaults (~/.cache/pip or $XDG_CACHE_HOME/pip,
+// ~/Library/Caches/pip, %LocalAppData%\pip\Cache).
+func pipCacheDirs(home string) []string {
+	if dir := os.Getenv("PIP_CACHE_DIR"); dir != "" {
+		return existingDirs(dir)
+	}
+	xdg := filepath.Join(home, ".cache", "pip")
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		xdg = filepath.Join(dir, "pip")
+	}
+	return existingDirs(
+		xdg,
+		filepath.Join(home, "Library", "Caches", "pip"),
+		filepath.Join(home, "AppData", "Local", "pip", "Cache"),
+	)
+}
+
+// findPipCaches returns the wheels/ and http/ directories of pip's caches.
+func findPipCaches(home string) []string {
+	var items []string
+	for _, dir := range pipCacheDirs(home) {
+		for _, name := range []string{"wheels", "http", "http-v2"} {
+			items = append(items, existingDirs(filepath.Join(dir, name))...)
+		}
+	}
+	return items
+}
+
+// minAgeFor returns the -age threshold for an item: the pip cache's wheels
+// and HTTP parts have their own when set, and stale clones -repo-months
+// (of 30 days).
+func (o *options) minAgeFor(typeName, path string) int {
+	if typeName == "stale_repo" && o.repoMonths > 0 {
+		return 30 * o.repoMonths
+	}
+	if typeName == "pip_cache" {
+		if days := o.pipCacheAges[pipCacheParts[filepath.Base(path)]]; days > 0 {
+			return days
+		}
+	}
+	return o.minAge
+}
+
+// annotatePipCache says what the part holds.
+func annotatePipCache(r *Record) {
+	switch pipCacheParts[filepath.Base(r.Path)] {
+	case "wheels":
+		r.Notes = append(r.Notes, "wheels pip built from source; rebuilt on the next install that needs them")
+	case "http":
+		r.Notes = append(r.Notes, "pip's download cache; downloaded again on the next install that needs it")
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestScanRoots_PipCacheAges(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("PIP_CACHE_DIR", "")
+	t.Setenv("XDG_CACHE_HOME", "")
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	cache := filepath.Join(home, ".cache", "pip")
+	write := func(rel string, days int) {
+		p := filepath.Join(cache, rel)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte("x"), 0644)
+		mtime := time.Now().AddDate(0, 0, -days)
+		os.Chtimes(p, mtime, mtime)
+	}
+	write("wheels/ab/cd/pkg-1.0-py3-none-any.whl", 60)
+	write("http-v2/a/b/c/entry", 20)
+	write("selfcheck/state.json", 90)
+
+	if got := findPipCaches(home); len(got) != 2 {
+		t.Fatalf("findPipCaches = %v, want wheels and http-v2", got)
+	}
+	opts := &options{minAge: 30, maxDepth: 1, systemScan: true, scanTypes: map[string]bool{"pip_cache": true}}
+	found := func() map[string]bool {
+		records, _ := scanRoots(nil, opts)
+		got := map[string]bool{}
+		for _, r := range records {
+			got[filepath.Base(r.Path)] = true
+		}
+		return got
+	}
+	if got := found(); len(got) != 1 || !got["wheels"] {
+		t.Errorf("-age 30: got %v, want only wheels", got)
+	}
+	opts.pipCacheAges = map[string]int{"wheels": 90, "http": 14}
+	if got := found(); len(got) != 1 || !got["http-v2"] {
+		t.Errorf("-pip-wheels-age 90 -pip-http-age 14: got %v, want only http-v2", got)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// -plan and `tidyup apply` split a deletion into review and execution, in
+// the manner of terraform plan/apply: -plan runs the normal selection but
+// writes the chosen records and the action for each to a file instead of
+// acting, and apply executes exactly that file -- nothing more -- after
+// checking that every item is still there and unchanged.
+
+// planVersion is the format version of plan files.
+const planVersion = 1
+
+// Plan actions, as executor.remove would carry them out.
+const (
+	actionDelete   = "delete"
+	actionTrash    = "trash"
+	actionDelegate = "delegate"
+)
+
+// plan is a -plan file.
+type plan struct {
+	PlanVersion int        `json:"plan_version"`
+	Created     string     `json:"created"` // RFC3339
+	Host        string     `json:"host"`
+	Items       []planItem `json:"items"`
+}
+
+// planItem is a record and what apply will do with it.
+type planItem struct {
+	Action string `json:"action"`
+	Record
+}
+
+// planAction is what executor.remove does with r under opts.
+func planAction(r Record, opts *options) string {
+	switch {
+	case opts.delegate && r.Command != "":
+		return actionDelegate
+	case opts.useTrash:
+		return actionTrash
+	}
+	return actionDelete
+}
+
+// writePlan saves the selected records and their actions to opts.planFile.
+func writePlan(records []Record, opts *options, now time.Time) error {
+	host, _ := os.Hostname()
+	p := plan{PlanVersion: planVersion, Created: now.Format(time.RFC3339), Host: host}
+	for _, r := range records {
+		p.Items = append(p.Items, planItem{Action: planAction(r, opts), Record: r})
+	}
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(opts.planFile, append(data, '\n'), 0644)
+}
+
+// loadPlan reads a plan file, refusing other versions and other hosts.
+func loadPlan(path string) (*plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p plan
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("decoding plan: %w", err)
+	}
+	if p.PlanVersion != planVersion {
+		return nil, fmt.Errorf("plan_version %d; this tidyup applies version %d", p.PlanVersion, planVersion)
+	}
+	if host, _ := os.Hostname(); p.Host != host {
+		return nil, fmt.Errorf("plan was made on %q, not this host (%q)", p.Host, host)
+	}
+	if _, err := time.Parse(time.RFC3339, p.Created); err != nil {
+		return nil, fmt.Errorf("plan has no valid creation time: %w", err)
+	}
+	for i, it := range p.Items {
+		switch it.Action {
+		case actionDelete, actionTrash, actionDelegate:
+		default:
+			return nil, fmt.Errorf("item %d: unknown action %q", i+1, it.Action)
+		}
+	}
+	return &p, nil
+}
+
+// revalidatePlan drops items that no longer exist or have been modified
+// since the plan was made: the plan approved them as they were then. The
+// rest are detected again, and each keeps its action but acts on the record
+// detection finds now: a plan file is not trusted for commands or holds.
+func revalidatePlan(p *plan) ([]planItem, []string) {
+	created, _ := time.Parse(time.RFC3339, p.Created)
+	var present []planItem
+	var warnings []string
+	for _, it := range p.Items {
+		if err := changedSince(it.Path, created); err != nil {
+			warnings = append(warnings, fmt.Sprintf("skipping %s: %v", it.Path, err))
+			continue
+		}
+		present = append(present, it)
+	}
+
+	records := make([]Record, len(present))
+	for i, it := range present {
+		records[i] = it.Record
+	}
+	detected := redetect(records)
+	var kept []planItem
+	for _, it := range present {
+		r, ok := detected[it.Type+" "+it.Path]
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("skipping %s: no longer detected as %s", it.Path, it.Type))
+			continue
+		}
+		kept = append(kept, planItem{Action: it.Action, Record: r})
+	}
+	return kept, warnings
+}
+
+// changedSince reports why path can no longer be acted on as it was at t:
+// it is gone, or something in it was modified after t.
+func changedSince(path string, t time.Time) error {
+	if isDockerPath(path) {
+		return nil // the daemon refuses to remove what a container uses
+	}
+	if _, err := os.Lstat(path); err != nil {
+		return err
+	}
+	if newest, ok := getCacheUsage(path); ok && newest.After(t) {
+		return fmt.Errorf("modified since %s", t.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// planOptions sets -trash and -delegate on opts to reproduce the plan's
+// actions. -plan derives every action from one set of flags, so an item
+// whose action those flags would not produce means the file was edited.
+func planOptions(items []planItem, opts *options) error {
+	for _, it := range items {
+		opts.useTrash = opts.useTrash || it.Action == actionTrash
+		opts.delegate = opts.delegate || it.Action == actionDelegate
+	}
+	for _, it := range items {
+		if want := planAction(it.Record, opts); it.Action != want {
+			return fmt.Errorf("plan is inconsistent: %s is planned as %s, but the plan's other actions imply %s", it.Path, it.Action, want)
+		}
+	}
+	return nil
+}
+
+// runApply implements `tidyup apply <plan.json>`.
+func runApply(args []string) int {
+	flags := flag.NewFlagSet("apply", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "Show what the plan would do without doing it")
+	logFile := flags.String("log", "", "Write deletion log to this file (\"state\": logs/ in the state directory)")
+	maxDeleteBytesRaw := flags.String("max-delete-bytes", "", "Abort without deleting anything if the selection exceeds this size (e.g. 20G)")
+	maxDeleteItems := flags.Int("max-delete-items", 0, "Abort without deleting anything if the selection has more items than this")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tidyup apply [flags] <plan.json>\n\n")
+		fmt.Fprintf(os.Stderr, "Executes a plan written by 'tidyup -plan', skipping items that are gone,\n")
+		fmt.Fprintf(os.Stderr, "were modified after the plan was made, or are no longer detected as\n")
+		fmt.Fprintf(os.Stderr, "their type. There is no further prompt.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitError
+	}
+
+	maxDeleteBytes, err := parseByteSize(*maxDeleteBytesRaw)
+	if err != nil {
+		stderr.errorf("-max-delete-bytes: %v", err)
+		return exitError
+	}
+	p, err := loadPlan(flags.Arg(0))
+	if err != nil {
+		stderr.errorf("%v", err)
+		return exitError
+	}
+	items, warnings := revalidatePlan(p)
+	for _, w := range warnings {
+		stderr.warnf("%s", w)
+	}
+
+	opts := &options{confirm: true, logFile: *logFile, logFormat: logFormatText, logMaxSize: 10 << 20, logKeep: 5,
+		maxDeleteBytes: maxDeleteBytes, maxDeleteItems: *maxDeleteItems}
+	if err := planOptions(items, opts); err != nil {
+		stderr.errorf("%v", err)
+		return exitError
+	}
+	var records []Record
+	for _, it := range items {
+		records = append(records, it.Record)
+	}
+
+	fmt.Printf("Plan from %s: %d of %d items still apply.\n", p.Created, len(records), len(p.Items))
+	if len(records) == 0 {
+		return exitOK
+	}
+	if *dryRun {
+		for _, it := range items {
+			fmt.Printf("  would %s %s (%s)\n", it.Action, it.Path, formatBytes(it.Size))
+		}
+		return exitFound
+	}
+	return deleteRecords(records, opts)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlan_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	opts := &options{planFile: filepath.Join(dir, "plan.json"), delegate: true}
+	records := []Record{
+		{Type: "venv", Path: "/p/.venv", Size: 100},
+		{Type: "tools", Path: "/t/black", Tool: "black", Command: "pipx uninstall black"},
+	}
+	if err := writePlan(records, opts, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	p, err := loadPlan(opts.planFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Items) != 2 || p.Items[0].Action != actionDelete || p.Items[1].Action != actionDelegate {
+		t.Fatalf("items = %+v", p.Items)
+	}
+	if p.Items[1].Tool != "black" {
+		t.Errorf("record fields not preserved: %+v", p.Items[1].Record)
+	}
+
+	var applied options
+	if err := planOptions(p.Items, &applied); err != nil {
+		t.Fatal(err)
+	}
+	if !applied.delegate || applied.useTrash {
+		t.Errorf("apply options = delegate %v, trash %v; want delegate only", applied.delegate, applied.useTrash)
+	}
+}
+
+func TestLoadPlan_Rejects(t *testing.T) {
+	host, _ := os.Hostname()
+	now := time.Now().Format(time.RFC3339)
+	cases := map[string]string{
+		"version": `{"plan_version": 99, "created": "` + now + `", "host": "` + host + `"}`,
+		"host":    `{"plan_version": 1, "created": "` + now + `", "host": "elsewhere.invalid"}`,
+		"created": `{"plan_version": 1, "created": "yesterday", "host": "` + host + `"}`,
+		"action":  `{"plan_version": 1, "created": "` + now + `", "host": "` + host + `", "items": [{"action": "shred", "type": "venv", "path": "/x"}]}`,
+	}
+	for name, doc := range cases {
+		path := filepath.Join(t.TempDir(), "plan.json")
+		os.WriteFile(path, []byte(doc), 0644)
+		if _, err := loadPlan(path); err == nil {
+			t.Errorf("%s: loadPlan accepted %s", name, doc)
+		}
+	}
+}
+
+func TestPlanOptions_Inconsistent(t *testing.T) {
+	items := []planItem{
+		{Action: actionTrash, Record: Record{Path: "/a"}},
+		{Action: actionDelete, Record: Record{Path: "/b"}},
+	}
+	if err := planOptions(items, &options{}); err == nil || !strings.Contains(err.Error(), "/b") {
+		t.Errorf("mixed trash and delete: err = %v", err)
+	}
+	items = []planItem{{Action: actionDelegate, Record: Record{Path: "/c"}}}
+	if err := planOptions(items, &options{}); err == nil {
+		t.Error("delegate without a command was accepted")
+	}
+}
+
+func TestRevalidatePlan(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	dir := t.TempDir()
+	created := time.Now().Add(-time.Hour)
+	old := created.Add(-24 * time.Hour)
+	unchanged := filepath.Join(dir, "a", ".venv")
+	makeVenv(t, unchanged, old)
+	touched := filepath.Join(dir, "touched")
+	os.MkdirAll(touched, 0755)
+	os.WriteFile(filepath.Join(touched, "f"), nil, 0644)
+
+	// An edited plan: a command added to the venv, and a directory that
+	// is not what the plan says it is.
+	p := &plan{Created: created.Format(time.RFC3339), Items: []planItem{
+		{Action: actionDelete, Record: Record{Type: "venv", Path: unchanged, Command: "touch " + filepath.Join(dir, "pwned")}},
+		{Action: actionDelete, Record: Record{Type: "node_modules", Path: touched}},
+		{Action: actionDelete, Record: Record{Type: "venv", Path: filepath.Join(dir, "gone")}},
+		{Action: actionDelete, Record: Record{Type: "node_modules", Path: filepath.Join(dir, "a")}},
+	}}
+	kept, warnings := revalidatePlan(p)
+	if len(kept) != 1 || kept[0].Path != unchanged || kept[0].Command != "" {
+		t.Errorf("kept %+v; want only %s, as detected", kept, unchanged)
+	}
+	if len(warnings) != 3 || !strings.Contains(warnings[0], "modified since") || !strings.Contains(warnings[2], "no longer detected as node_modules") {
+		t.Errorf("warnings = %q", warnings)
+	}
+}
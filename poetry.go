@@ -0,0 +1,131 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Poetry keeps project environments outside the project, in its cache's
+// virtualenvs/ directory, named <name>-<hash>-py<X.Y>: the project name
+// (lowercased, cut to 42 characters), the first 8 characters of the
+// URL-safe base64 SHA-256 of the project directory's path, and the Python
+// version. The hash cannot be reversed, but directories tidyup already
+// knows about -- projects with a pyproject.toml seen during the scan, the
+// working directory and the shell hook's directories with their parents --
+// can be hashed and matched against it.
+
+// poetryEnvPattern matches a Poetry environment's directory name.
+var poetryEnvPattern = regexp.MustCompile(`^(.+)-([A-Za-z0-9_-]{8})-py\d+\.\d+$`)
+
+// poetryEnv reports whether path is a Poetry-managed environment, with the
+// project name and path hash from its directory name.
+func poetryEnv(path string) (name, hash string, ok bool) {
+	if !isPoetryVirtualenvsDir(filepath.Dir(path)) {
+		return "", "", false
+	}
+	m := poetryEnvPattern.FindStringSubmatch(filepath.Base(path))
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
+
+// isPoetryVirtualenvsDir reports whether dir is where Poetry keeps its
+// environments: $POETRY_VIRTUALENVS_PATH, or virtualenvs/ in a pypoetry
+// cache directory.
+func isPoetryVirtualenvsDir(dir string) bool {
+	if env := os.Getenv("POETRY_VIRTUALENVS_PATH"); env != "" && filepath.Clean(env) == filepath.Clean(dir) {
+		return true
+	}
+	p := filepath.ToSlash(dir)
+	return strings.HasSuffix(p, "/pypoetry/virtualenvs") || strings.HasSuffix(p, "/pypoetry/Cache/virtualenvs")
+}
+
+// poetryHomes lists Poetry's environment directories under home, added as
+// roots by -system when scanning for venvs.
+func poetryHomes(home string) []string {
+	dirs := []string{
+		filepath.Join(home, "Library/Caches/pypoetry/virtualenvs"),
+		filepath.Join(home, ".cache/pypoetry/virtualenvs"),
+		filepath.Join(home, "AppData/Local/pypoetry/Cache/virtualenvs"),
+	}
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		dirs = append(dirs, filepath.Join(dir, "pypoetry/virtualenvs"))
+	}
+	if dir := os.Getenv("POETRY_CACHE_DIR"); dir != "" {
+		dirs = append(dirs, filepath.Join(dir, "virtualenvs"))
+	}
+	if dir := os.Getenv("POETRY_VIRTUALENVS_PATH"); dir != "" {
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// poetryHash is the hash Poetry puts in the names of the environments of
+// the project at dir.
+func poetryHash(dir string) string {
+	sum := sha256.Sum256([]byte(dir))
+	return base64.URLEncoding.EncodeToString(sum[:])[:8]
+}
+
+// notePoetryProject remembers a project directory seen during the scan, so
+// Poetry environments can be traced back to it.
+func (s *scanner) notePoetryProject(dir string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.poetryDirs == nil {
+		s.poetryDirs = map[string]string{}
+	}
+	s.poetryDirs[poetryHash(dir)] = dir
+}
+
+// poetryProject returns the project directory whose path hashes to hash,
+// or "".
+func (s *scanner) poetryProject(hash string) string {
+	if dir := s.poetryDirs[hash]; dir != "" {
+		return dir
+	}
+	var candidates []string
+	if wd, err := os.Getwd(); err == nil {
+		candidates = append(candidates, wd)
+	}
+	if s.activity != nil {
+		for dir := range s.activity.visited {
+			candidates = append(candidates, dir)
+		}
+	}
+	for _, dir := range candidates {
+		for ; dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+			if poetryHash(dir) == hash {
+				return dir
+			}
+		}
+	}
+	return ""
+}
+
+// resolvePoetryEnv names the project a Poetry environment belongs to and,
+// where one of the candidate directories hashes to it, its directory.
+func (s *scanner) resolvePoetryEnv(r *Record) {
+	if r.Type != "venv" {
+		return
+	}
+	name, hash, ok := poetryEnv(r.Path)
+	if !ok {
+		return
+	}
+	dir := s.poetryProject(hash)
+	if dir == "" {
+		r.Project = name
+		r.Notes = append(r.Notes, "Poetry environment; its project directory was not among the scanned trees")
+		return
+	}
+	r.ProjectDir = dir
+	if r.Project = projectName(dir); r.Project == "" {
+		r.Project = name
+	}
+}
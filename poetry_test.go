@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPoetryEnv(t *testing.T) {
+	// Computed with Poetry's own formula in Python.
+	if got := poetryHash("/srv/dev/web"); got != "feiyutQt" {
+		t.Errorf("poetryHash = %q, want feiyutQt", got)
+	}
+	tests := []struct {
+		path, name, hash string
+		ok               bool
+	}{
+		{"/srv/home/.cache/pypoetry/virtualenvs/web-app-feiyutQt-py3.11", "web-app", "feiyutQt", true},
+		{"/srv/home/Library/Caches/pypoetry/virtualenvs/web-feiyutQt-py3.9", "web", "feiyutQt", true},
+		{"/srv/home/.cache/pypoetry/virtualenvs/web", "", "", false},
+		{"/srv/dev/web/web-feiyutQt-py3.11", "", "", false},
+	}
+	for _, tt := range tests {
+		name, hash, ok := poetryEnv(tt.path)
+		if name != tt.name || hash != tt.hash || ok != tt.ok {
+			t.Errorf("poetryEnv(%s) = %q, %q, %v", tt.path, name, hash, ok)
+		}
+	}
+}
+
+func TestScanRoots_PoetryEnvs(t *testing.T) {
+	dev := t.TempDir()
+	cache := filepath.Join(t.TempDir(), "pypoetry", "virtualenvs")
+	web := filepath.Join(dev, "web")
+	os.MkdirAll(web, 0755)
+	os.WriteFile(filepath.Join(web, "pyproject.toml"), []byte("[tool.poetry]\nname = \"web-app\"\n"), 0644)
+	hash := poetryHash(web)
+	old := time.Now().AddDate(0, 0, -90)
+	env310 := filepath.Join(cache, "web-app-"+hash+"-py3.10")
+	env311 := filepath.Join(cache, "web-app-"+hash+"-py3.11")
+	lost := filepath.Join(cache, "gone-AAAAAAAA-py3.11")
+	makeVenv(t, env310, old)
+	makeVenv(t, env311, old.AddDate(0, 0, 10))
+	makeVenv(t, lost, old.AddDate(0, 0, 20))
+
+	opts := &options{minAge: 30, maxDepth: 5, scanTypes: map[string]bool{"venv": true}}
+	records, _ := scanRoots([]string{dev, cache}, opts)
+	found := map[string]Record{}
+	for _, r := range records {
+		found[r.Path] = r
+	}
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want 3: %+v", len(records), records)
+	}
+	if r := found[env310]; r.ProjectDir != web || r.Project != "web-app" || r.NewestSibling != env311 {
+		t.Errorf("py3.10 env: project %q in %q, newest sibling %q", r.Project, r.ProjectDir, r.NewestSibling)
+	}
+	if r := found[lost]; r.ProjectDir != "" || r.Project != "gone" || r.NewestSibling != "" {
+		t.Errorf("unresolved env: project %q in %q, newest sibling %q", r.Project, r.ProjectDir, r.NewestSibling)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Every record belongs to a project: the directory it sits in, or for
+// environments under .tox/.nox/.direnv, the one above (see venvProject).
+// For Python projects, the name declared in pyproject.toml or setup.cfg
+// reads better in reports than a path, and the project's lockfile is one
+// more activity signal: uv, Poetry, PDM, and pipenv rewrite it whenever
+// the dependencies change, commit or no commit.
+
+// pythonLockfiles are lockfiles rewritten when a project's dependencies
+// change.
+var pythonLockfiles = []string{"uv.lock", "poetry.lock", "pdm.lock", "Pipfile.lock", "pylock.toml"}
+
+// projectDir returns the project directory a record at path belongs to; a
+// wheel or sdist in dist/ belongs to dist/'s parent.
+func projectDir(path string) string {
+	dir := venvProject(path)
+	if filepath.Base(dir) == "dist" {
+		return filepath.Dir(dir)
+	}
+	return dir
+}
+
+// projectName returns the name a Python project in dir declares, or "".
+func projectName(dir string) string {
+	name, _ := projectMetadata(dir)
+	return name
+}
+
+// lockfileActivity returns the newest lockfile in dir and when it was
+// written, or false when there is none.
+func lockfileActivity(dir string) (name string, mtime time.Time, ok bool) {
+	for _, lock := range pythonLockfiles {
+		info, err := os.Stat(filepath.Join(dir, lock))
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if !ok || info.ModTime().After(mtime) {
+			name, mtime, ok = lock, info.ModTime(), true
+		}
+	}
+	return name, mtime, ok
+}
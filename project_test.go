@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProjectDir(t *testing.T) {
+	cases := map[string]string{
+		"/src/app/.venv":                 "/src/app",
+		"/src/app/.tox/py311":            "/src/app",
+		"/src/app/dist/app-1.0.tar.gz":   "/src/app",
+		"/src/app/web/node_modules":      "/src/app/web",
+		"/src/app/paper/paper.aux":       "/src/app/paper",
+		"/src/app/dist/app-1.0-py3.whl":  "/src/app",
+		"/src/app/.direnv/python-3.11.6": "/src/app",
+	}
+	for path, want := range cases {
+		if got := projectDir(filepath.FromSlash(path)); got != filepath.FromSlash(want) {
+			t.Errorf("projectDir(%s) = %s, want %s", path, got, want)
+		}
+	}
+}
+
+func TestLockfileActivity_Newest(t *testing.T) {
+	dir := t.TempDir()
+	if _, _, ok := lockfileActivity(dir); ok {
+		t.Fatal("no lockfiles: want not ok")
+	}
+	older, newer := time.Now().AddDate(0, 0, -10), time.Now().AddDate(0, 0, -1)
+	for name, mtime := range map[string]time.Time{"poetry.lock": older, "uv.lock": newer} {
+		p := filepath.Join(dir, name)
+		os.WriteFile(p, []byte("x"), 0644)
+		os.Chtimes(p, mtime, mtime)
+	}
+	if name, mtime, ok := lockfileActivity(dir); !ok || name != "uv.lock" || !mtime.Equal(newer) {
+		t.Errorf("got %s %v %v, want uv.lock", name, mtime, ok)
+	}
+}
+
+func TestScanRoots_ProjectNameAndLockfile(t *testing.T) {
+	root := t.TempDir()
+	proj := filepath.Join(root, "proj")
+	makeVenv(t, filepath.Join(proj, ".venv"), time.Now().AddDate(0, 0, -90))
+	os.WriteFile(filepath.Join(proj, "pyproject.toml"), []byte("[project]\nname = \"acme-tools\"\n"), 0644)
+	opts := &options{minAge: 30, maxDepth: 5, scanTypes: map[string]bool{"venv": true}}
+
+	records, _ := scanRoots([]string{root}, opts)
+	if len(records) != 1 || records[0].Project != "acme-tools" {
+		t.Fatalf("got %+v, want project acme-tools", records)
+	}
+	calm := records[0].Confidence
+
+	// A freshly rewritten uv.lock says the project is alive.
+	os.WriteFile(filepath.Join(proj, "uv.lock"), []byte("version = 1\n"), 0644)
+	records, _ = scanRoots([]string{root}, opts)
+	if len(records) != 1 || records[0].Confidence >= calm || !strings.Contains(strings.Join(records[0].Notes, ";"), "uv.lock updated 0 days ago") {
+		t.Errorf("after uv.lock: confidence %.2f (was %.2f), notes %v", records[0].Confidence, calm, records[0].Notes)
+	}
+}
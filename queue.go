@@ -0,0 +1,402 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// The approval queue separates proposing a deletion from carrying it out,
+// for shared machines where automation may find items but only a person may
+// remove them. `tidyup -propose` adds the selection to the queue as pending,
+// `tidyup queue approve` / `reject` decide each item, and `tidyup queue run`
+// executes only approved items that are unchanged since they were proposed.
+// The queue is one JSON file in the state directory; -queue points several
+// users at a shared one. Anyone who can write that file can edit it, so
+// `queue run` checks each item against the hash stored with its approval,
+// re-detects it and takes its command from detection, and runs no native
+// commands from a shared queue.
+
+// queueVersion is the format version of the queue file.
+const queueVersion = 1
+
+// Queue item states.
+const (
+	queuePending  = "pending"
+	queueApproved = "approved"
+	queueRejected = "rejected"
+)
+
+// queueItem is a proposed action and the decision on it.
+type queueItem struct {
+	ID         int    `json:"id"`
+	Status     string `json:"status"`
+	ProposedAt string `json:"proposed_at"` // RFC3339
+	ProposedBy string `json:"proposed_by"`
+	DecidedAt  string `json:"decided_at,omitempty"`
+	DecidedBy  string `json:"decided_by,omitempty"`
+	Approval   string `json:"approval_sha256,omitempty"` // approvalHash when approved
+	planItem
+}
+
+// approvalQueue is the queue file.
+type approvalQueue struct {
+	Version int         `json:"version"`
+	NextID  int         `json:"next_id"`
+	Items   []queueItem `json:"items"`
+}
+
+// queuePath returns -queue, or queue.json in the state directory.
+func queuePath(override string) (string, error) {
+	if override != "" {
+		return override, nil
+	}
+	return statePath("queue.json")
+}
+
+// loadQueue reads the queue; a missing file is an empty queue. A corrupt
+// one is set aside and the queue starts empty: approvals are lost, which
+// only means items must be proposed and approved again.
+func loadQueue(path string) (*approvalQueue, error) {
+	q := &approvalQueue{Version: queueVersion, NextID: 1}
+	err := loadState(path, q)
+	if os.IsNotExist(err) {
+		return q, nil
+	}
+	if errors.Is(err, errStateCorrupt) {
+		setAsideCorrupt(path, err)
+		return &approvalQueue{Version: queueVersion, NextID: 1}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if q.Version != queueVersion {
+		return nil, fmt.Errorf("%s has version %d; this tidyup understands %d", path, q.Version, queueVersion)
+	}
+	return q, nil
+}
+
+// save writes the queue. A shared queue (-queue) stays group-writable so
+// approvers other than the proposer can update it.
+func (q *approvalQueue) save(path string, shared bool) error {
+	perm := os.FileMode(0600)
+	if shared {
+		perm = 0660
+	}
+	return saveState(path, q, perm)
+}
+
+// propose adds records as pending items. A path already in the queue keeps
+// its entry and decision; only its record is refreshed, so re-running the
+// same scan does not pile up duplicates or resurrect rejected items. An
+// approval covers the action and item that were approved: if either has
+// changed, the item is pending again.
+func (q *approvalQueue) propose(records []Record, opts *options, by string, now time.Time) (added int) {
+	byPath := map[string]int{}
+	for i, it := range q.Items {
+		byPath[it.Path] = i
+	}
+	for _, r := range records {
+		item := planItem{Action: planAction(r, opts), Record: r}
+		if i, ok := byPath[r.Path]; ok {
+			it := &q.Items[i]
+			if it.Status == queueRejected {
+				continue
+			}
+			if it.Status == queueApproved && !sameProposal(it.planItem, item) {
+				it.Status, it.DecidedAt, it.DecidedBy, it.Approval = queuePending, "", "", ""
+			}
+			it.planItem = item
+			continue
+		}
+		q.Items = append(q.Items, queueItem{
+			ID:         q.NextID,
+			Status:     queuePending,
+			ProposedAt: now.Format(time.RFC3339),
+			ProposedBy: by,
+			planItem:   item,
+		})
+		q.NextID++
+		added++
+	}
+	return added
+}
+
+// sameProposal reports whether a re-proposed item is what was approved:
+// the same action on an item of the same type, size, last use, and
+// removal.
+func sameProposal(a, b planItem) bool {
+	return a.Action == b.Action && a.Type == b.Type && a.Size == b.Size && a.LastUsed == b.LastUsed &&
+		a.Command == b.Command && a.File == b.File && a.Advisory == b.Advisory && a.Review == b.Review
+}
+
+// approvalHash fingerprints what an approval covers: the action on an item
+// of a type at a path, and its command.
+func approvalHash(p planItem) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{p.Action, p.Type, p.Path, p.Command}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// decide sets the status of the items with the given IDs ("all": every
+// pending item) and returns the records it changed.
+func (q *approvalQueue) decide(ids []string, status, by string, now time.Time) ([]Record, error) {
+	want := map[int]bool{}
+	all := false
+	for _, s := range ids {
+		if s == "all" {
+			all = true
+			continue
+		}
+		id, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid item id %q", s)
+		}
+		want[id] = true
+	}
+	var changed []Record
+	for i := range q.Items {
+		it := &q.Items[i]
+		if !want[it.ID] && !(all && it.Status == queuePending) {
+			continue
+		}
+		delete(want, it.ID)
+		if it.Status == status {
+			continue
+		}
+		it.Status, it.DecidedAt, it.DecidedBy, it.Approval = status, now.Format(time.RFC3339), by, ""
+		if status == queueApproved {
+			it.Approval = approvalHash(it.planItem)
+		}
+		changed = append(changed, it.Record)
+	}
+	for id := range want {
+		return changed, fmt.Errorf("no item %d in the queue", id)
+	}
+	return changed, nil
+}
+
+// currentUser names whoever proposes or decides, for the queue's record.
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// proposeRecords adds the final -propose selection to the queue.
+func proposeRecords(records []Record, opts *options) int {
+	path, err := queuePath(opts.queueFile)
+	if err != nil {
+		stderr.errorf("%v", err)
+		return exitError
+	}
+	defer lockPath(path + ".lock")()
+	q, err := loadQueue(path)
+	if err != nil {
+		stderr.errorf("%v", err)
+		return exitError
+	}
+	added := q.propose(records, opts, currentUser(), time.Now())
+	if err := q.save(path, opts.queueFile != ""); err != nil {
+		stderr.errorf("writing queue: %v", err)
+		return exitError
+	}
+	fmt.Printf("\nProposed %d items (%d new) in %s. Nothing was deleted.\n", len(records), added, path)
+	fmt.Println("Review with 'tidyup queue', then 'tidyup queue approve <id>...' and 'tidyup queue run'.")
+	return exitFound
+}
+
+// printQueue lists the queue.
+func printQueue(q *approvalQueue) {
+	if len(q.Items) == 0 {
+		fmt.Println("The approval queue is empty.")
+		return
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSTATUS\tACTION\tSIZE\tPROPOSED BY\tPATH")
+	for _, it := range q.Items {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", it.ID, it.Status, it.Action, formatBytes(it.Size), it.ProposedBy, it.Path)
+	}
+	tw.Flush()
+}
+
+// runApproved executes the approved items, each through the normal deletion
+// flow with the options its action needs, acting on the record detection
+// finds now rather than the one in the file. Items edited since their
+// approval, removed, changed since they were proposed, or no longer
+// detected leave the queue, as do delegated items in a shared one;
+// failures stay approved for the next run. The -max-delete-* caps in base
+// apply to all approved items together, before any is run.
+func runApproved(q *approvalQueue, base *options, dryRun, shared bool) int {
+	var remaining, approved []queueItem
+	for _, it := range q.Items {
+		if it.Status != queueApproved {
+			remaining = append(remaining, it)
+			continue
+		}
+		if it.Approval != approvalHash(it.planItem) {
+			stderr.warnf("dropping item %d, %s: edited since it was approved", it.ID, it.Path)
+			continue
+		}
+		if shared && it.Action == actionDelegate {
+			stderr.warnf("dropping item %d, %s: a shared queue does not run native commands", it.ID, it.Path)
+			continue
+		}
+		proposed, _ := time.Parse(time.RFC3339, it.ProposedAt)
+		if err := changedSince(it.Path, proposed); err != nil {
+			stderr.warnf("dropping item %d, %s: %v", it.ID, it.Path, err)
+			continue
+		}
+		approved = append(approved, it)
+	}
+
+	records := make([]Record, len(approved))
+	for i, it := range approved {
+		records[i] = it.Record
+	}
+	detected := redetect(records)
+	groups := map[string][]Record{}
+	for _, it := range approved {
+		r, ok := detected[it.Type+" "+it.Path]
+		if !ok {
+			stderr.warnf("dropping item %d, %s: no longer detected as %s", it.ID, it.Path, it.Type)
+			continue
+		}
+		remaining = append(remaining, it)
+		if dryRun {
+			fmt.Printf("  would %s %s (%s)\n", it.Action, it.Path, formatBytes(r.Size))
+			continue
+		}
+		groups[it.Action] = append(groups[it.Action], r)
+	}
+	var all []Record
+	for _, records := range groups {
+		all = append(all, records...)
+	}
+	if err := checkDeleteCaps(all, base); err != nil {
+		stderr.errorf("%v; nothing was deleted. Raise the cap to override.", err)
+		q.Items = remaining
+		return exitError
+	}
+
+	code := exitOK
+	done := map[string]bool{}
+	for _, action := range []string{actionDelete, actionTrash, actionDelegate} {
+		if len(groups[action]) == 0 {
+			continue
+		}
+		opts := *base
+		opts.confirm = true
+		opts.useTrash = action == actionTrash
+		opts.delegate = action == actionDelegate
+		c, removed := deleteSelected(groups[action], &opts)
+		if c > code {
+			code = c
+		}
+		for _, r := range removed {
+			done[r.Path] = true
+		}
+	}
+
+	// Approved items the executor removed have been carried out.
+	q.Items = q.Items[:0]
+	for _, it := range remaining {
+		if it.Status == queueApproved && done[it.Path] {
+			continue
+		}
+		q.Items = append(q.Items, it)
+	}
+	return code
+}
+
+// runQueue implements `tidyup queue [list|approve|reject|run]`.
+func runQueue(args []string) int {
+	flags := flag.NewFlagSet("queue", flag.ContinueOnError)
+	queueFile := flags.String("queue", "", "Approval queue file (default: queue.json in the state directory)")
+	dryRun := flags.Bool("dry-run", false, "With run: show what would be executed")
+	logFile := flags.String("log", "", "With run: write deletion log to this file (\"state\": logs/ in the state directory)")
+	maxDeleteBytesRaw := flags.String("max-delete-bytes", "", "With run: abort without deleting anything if the approved items exceed this size (e.g. 20G)")
+	maxDeleteItems := flags.Int("max-delete-items", 0, "With run: abort without deleting anything if more items than this are approved")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tidyup queue [flags] [list]\n")
+		fmt.Fprintf(os.Stderr, "       tidyup queue [flags] approve|reject <id>...|all\n")
+		fmt.Fprintf(os.Stderr, "       tidyup queue [flags] run\n\n")
+		fmt.Fprintf(os.Stderr, "Reviews deletions proposed with 'tidyup -propose'. Only approved items\n")
+		fmt.Fprintf(os.Stderr, "that are unchanged since they were proposed are executed.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	maxDeleteBytes, err := parseByteSize(*maxDeleteBytesRaw)
+	if err != nil {
+		stderr.errorf("-max-delete-bytes: %v", err)
+		return exitError
+	}
+	verb, ids := "list", []string(nil)
+	if flags.NArg() > 0 {
+		verb, ids = flags.Arg(0), flags.Args()[1:]
+	}
+
+	path, err := queuePath(*queueFile)
+	if err != nil {
+		stderr.errorf("%v", err)
+		return exitError
+	}
+	defer lockPath(path + ".lock")()
+	q, err := loadQueue(path)
+	if err != nil {
+		stderr.errorf("%v", err)
+		return exitError
+	}
+
+	code := exitOK
+	switch verb {
+	case "list":
+		printQueue(q)
+		return exitOK
+	case "approve", "reject":
+		if len(ids) == 0 {
+			flags.Usage()
+			return exitError
+		}
+		status := queueApproved
+		if verb == "reject" {
+			status = queueRejected
+		}
+		now := time.Now()
+		changed, err := q.decide(ids, status, currentUser(), now)
+		if err != nil {
+			stderr.errorf("%v", err)
+			return exitError
+		}
+		// Rejecting a proposal is a deliberate keep.
+		if status == queueRejected {
+			recordDecisions(decisionKept, changed, now)
+		}
+		fmt.Printf("%s %d items.\n", map[string]string{queueApproved: "Approved", queueRejected: "Rejected"}[status], len(changed))
+	case "run":
+		opts := &options{logFile: *logFile, logFormat: logFormatText, logMaxSize: 10 << 20, logKeep: 5,
+			maxDeleteBytes: maxDeleteBytes, maxDeleteItems: *maxDeleteItems}
+		code = runApproved(q, opts, *dryRun, *queueFile != "")
+		if *dryRun {
+			return code
+		}
+	default:
+		flags.Usage()
+		return exitError
+	}
+	if err := q.save(path, *queueFile != ""); err != nil {
+		stderr.errorf("writing queue: %v", err)
+		return exitError
+	}
+	return code
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestQueue_ProposeDedupesAndKeepsDecisions(t *testing.T) {
+	q := &approvalQueue{Version: queueVersion, NextID: 1}
+	now := time.Now()
+	a := Record{Type: "venv", Path: "/p/a/.venv", Size: 1}
+	b := Record{Type: "venv", Path: "/p/b/.venv", Size: 2}
+	if added := q.propose([]Record{a, b}, &options{}, "ci", now); added != 2 {
+		t.Fatalf("added %d, want 2", added)
+	}
+	if _, err := q.decide([]string{"2"}, queueRejected, "alice", now); err != nil {
+		t.Fatal(err)
+	}
+
+	// A rescan proposes both again: no duplicates, b stays rejected.
+	a.Size, b.Size = 10, 20
+	if added := q.propose([]Record{a, b}, &options{}, "ci", now); added != 0 {
+		t.Errorf("re-proposal added %d items", added)
+	}
+	if len(q.Items) != 2 {
+		t.Fatalf("queue has %d items, want 2", len(q.Items))
+	}
+	if q.Items[0].Size != 10 || q.Items[0].Status != queuePending {
+		t.Errorf("pending item not refreshed: %+v", q.Items[0])
+	}
+	if q.Items[1].Size != 2 || q.Items[1].Status != queueRejected || q.Items[1].DecidedBy != "alice" {
+		t.Errorf("rejected item changed: %+v", q.Items[1])
+	}
+}
+
+func TestQueue_ProposeChangeNeedsReapproval(t *testing.T) {
+	q := &approvalQueue{Version: queueVersion, NextID: 1}
+	now := time.Now()
+	a := Record{Type: "venv", Path: "/p/a/.venv", Size: 1, LastUsed: "2025-01-01T00:00:00Z"}
+	b := Record{Type: "venv", Path: "/p/b/.venv", Size: 2, LastUsed: "2025-01-01T00:00:00Z"}
+	c := Record{Type: "venv", Path: "/p/c/.venv", Size: 3, LastUsed: "2025-01-01T00:00:00Z"}
+	trash := &options{useTrash: true}
+	q.propose([]Record{a, b, c}, trash, "ci", now)
+	if _, err := q.decide([]string{"all"}, queueApproved, "alice", now); err != nil {
+		t.Fatal(err)
+	}
+
+	// a unchanged keeps its approval; b now a permanent delete, and c
+	// grown, go back to pending.
+	c.Size = 30
+	q.propose([]Record{a, c}, trash, "ci", now)
+	q.propose([]Record{b}, &options{}, "ci", now)
+	if it := q.Items[0]; it.Status != queueApproved || it.DecidedBy != "alice" {
+		t.Errorf("unchanged item: %+v, want still approved by alice", it)
+	}
+	for _, it := range q.Items[1:] {
+		if it.Status != queuePending || it.DecidedAt != "" || it.DecidedBy != "" {
+			t.Errorf("changed item %s: %+v, want pending and undecided", it.Path, it)
+		}
+	}
+	if q.Items[1].Action != actionDelete {
+		t.Errorf("item b action = %s, want %s", q.Items[1].Action, actionDelete)
+	}
+}
+
+func TestQueue_Decide(t *testing.T) {
+	q := &approvalQueue{Version: queueVersion, NextID: 1}
+	now := time.Now()
+	q.propose([]Record{{Path: "/a"}, {Path: "/b"}, {Path: "/c"}}, &options{}, "ci", now)
+	q.decide([]string{"3"}, queueRejected, "bob", now)
+
+	changed, err := q.decide([]string{"all"}, queueApproved, "bob", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 2 || q.Items[2].Status != queueRejected {
+		t.Errorf("'all' approved %d items, item 3 is %s; want 2 and rejected", len(changed), q.Items[2].Status)
+	}
+	if _, err := q.decide([]string{"9"}, queueApproved, "bob", now); err == nil {
+		t.Error("unknown id accepted")
+	}
+	if _, err := q.decide([]string{"x"}, queueApproved, "bob", now); err == nil {
+		t.Error("non-numeric id accepted")
+	}
+}
+
+func TestQueue_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "queue.json")
+	if q, err := loadQueue(path); err != nil || len(q.Items) != 0 || q.NextID != 1 {
+		t.Fatalf("missing queue: %+v, %v", q, err)
+	}
+	q := &approvalQueue{Version: queueVersion, NextID: 1}
+	q.propose([]Record{{Type: "tools", Path: "/t", Command: "pipx uninstall t"}}, &options{delegate: true}, "ci", time.Now())
+	if err := q.save(path, false); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadQueue(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.NextID != 2 || len(loaded.Items) != 1 || loaded.Items[0].Action != actionDelegate || loaded.Items[0].ProposedBy != "ci" {
+		t.Errorf("round trip = %+v", loaded)
+	}
+}
+
+func TestRunApproved_RefusesEditedAndUndetectedItems(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	dir := t.TempDir()
+	venv := filepath.Join(dir, "a", ".venv")
+	makeVenv(t, venv, time.Now().AddDate(0, 0, -90))
+	now := time.Now()
+	q := &approvalQueue{Version: queueVersion, NextID: 1}
+	q.propose([]Record{{Type: "venv", Path: venv}}, &options{}, "ci", now)
+	q.propose([]Record{{Type: "pipx", Path: filepath.Join(dir, "tool"), Command: "pipx uninstall tool"}}, &options{delegate: true}, "ci", now)
+	q.propose([]Record{{Type: "node_modules", Path: venv}}, &options{}, "ci", now)
+	q.decide([]string{"all"}, queueApproved, "alice", now)
+	os.MkdirAll(filepath.Join(dir, "tool"), 0755)
+
+	// Someone with write access to the queue changes an approved item.
+	q.Items[0].Command = "touch " + filepath.Join(dir, "pwned")
+	runApproved(q, &options{}, true, true)
+	if len(q.Items) != 0 {
+		t.Errorf("queue after run: %+v; want the edited item, the shared delegate, and the undetected item dropped", q.Items)
+	}
+
+	// Unedited, the venv runs as detected now.
+	q.propose([]Record{{Type: "venv", Path: venv, Command: "touch " + filepath.Join(dir, "pwned")}}, &options{}, "ci", now)
+	q.decide([]string{"all"}, queueApproved, "alice", now)
+	runApproved(q, &options{}, true, true)
+	if len(q.Items) != 1 {
+		t.Errorf("queue after dry run: %+v; want the venv kept", q.Items)
+	}
+}
+
+func TestRunApproved_DeleteCaps(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	dir := t.TempDir()
+	var records []Record
+	for _, name := range []string{"a", "b"} {
+		venv := filepath.Join(dir, name, ".venv")
+		makeVenv(t, venv, time.Now().AddDate(0, 0, -90))
+		records = append(records, Record{Type: "venv", Path: venv})
+	}
+	now := time.Now()
+	q := &approvalQueue{Version: queueVersion, NextID: 1}
+	q.propose(records, &options{}, "ci", now)
+	q.decide([]string{"all"}, queueApproved, "alice", now)
+
+	if code := runApproved(q, &options{maxDeleteItems: 1}, false, false); code != exitError {
+		t.Errorf("runApproved over -max-delete-items = %d, want exitError", code)
+	}
+	for _, r := range records {
+		if _, err := os.Stat(r.Path); err != nil {
+			t.Errorf("%s removed despite the cap: %v", r.Path, err)
+		}
+	}
+	if len(q.Items) != 2 || q.Items[0].Status != queueApproved {
+		t.Errorf("queue = %+v, want both items still approved", q.Items)
+	}
+}
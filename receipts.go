@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// "Did that directory have anything else in it?" is the usual worry after a
+// cleanup. With -receipts, tidyup records a receipt for every item it
+// removes: the top-level entries of the tree with their sizes, plus a
+// SHA-256 over that listing, appended to manifests/receipts.jsonl in the
+// state directory. `tidyup receipts PATTERN` searches them. Receipts are a
+// listing, not a backup: they cannot bring anything back, and they only
+// answer questions about the first level of each tree.
+
+// receiptMaxEntries caps the entries listed per receipt; the rest are
+// counted.
+const receiptMaxEntries = 500
+
+// receiptEntry is one top-level entry of a removed tree.
+type receiptEntry struct {
+	Name string `json:"name"`
+	Size int64  `json:"size_bytes"`
+	Dir  bool   `json:"dir,omitempty"`
+}
+
+// receipt is one line of receipts.jsonl.
+type receipt struct {
+	Time       string         `json:"time"` // RFC3339
+	Action     string         `json:"action"`
+	Type       string         `json:"type"`
+	Path       string         `json:"path"`
+	Size       int64          `json:"size_bytes"`
+	Entries    []receiptEntry `json:"entries"`
+	More       int            `json:"more,omitempty"` // entries beyond receiptMaxEntries
+	ListingSHA string         `json:"listing_sha256"`
+}
+
+// receiptsPath is the receipt manifest.
+func receiptsPath() (string, error) {
+	return statePath("manifests", "receipts.jsonl")
+}
+
+// buildReceipt lists r's tree before it is removed. A file (or an unreadable
+// directory) is its own single entry.
+func buildReceipt(r Record) receipt {
+	rc := receipt{Type: r.Type, Path: r.Path, Size: r.Size, Entries: []receiptEntry{}}
+	entries, err := os.ReadDir(r.Path)
+	if err != nil {
+		rc.Entries = append(rc.Entries, receiptEntry{Name: filepath.Base(r.Path), Size: r.Size})
+	}
+	for _, e := range entries {
+		if len(rc.Entries) == receiptMaxEntries {
+			rc.More = len(entries) - receiptMaxEntries
+			break
+		}
+		entry := receiptEntry{Name: e.Name(), Dir: e.IsDir()}
+		if e.IsDir() {
+			entry.Size, _ = dirSize(filepath.Join(r.Path, e.Name()))
+		} else if info, err := e.Info(); err == nil {
+			entry.Size = info.Size()
+		}
+		rc.Entries = append(rc.Entries, entry)
+	}
+	h := sha256.New()
+	for _, e := range rc.Entries {
+		fmt.Fprintf(h, "%s\t%d\t%v\n", e.Name, e.Size, e.Dir)
+	}
+	rc.ListingSHA = hex.EncodeToString(h.Sum(nil))
+	return rc
+}
+
+// recordReceipt appends rc for a completed removal. Failures are reported
+// but never affect the removal.
+func recordReceipt(rc receipt, action string, now time.Time) {
+	rc.Time, rc.Action = now.Format(time.RFC3339), strings.ToLower(action)
+	path, err := receiptsPath()
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0700)
+	}
+	var f *os.File
+	if err == nil {
+		f, err = os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	}
+	if err != nil {
+		stderr.warnf("could not record receipt: %v", err)
+		return
+	}
+	defer f.Close()
+	json.NewEncoder(f).Encode(rc)
+}
+
+// loadReceipts reads the receipt manifest, skipping malformed lines.
+func loadReceipts(path string) ([]receipt, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var receipts []receipt
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		var rc receipt
+		if json.Unmarshal(sc.Bytes(), &rc) == nil {
+			receipts = append(receipts, rc)
+		}
+	}
+	return receipts, sc.Err()
+}
+
+// matchReceipt returns the entries of rc whose name matches pattern, a
+// glob or a plain substring, and whether rc's own path matches it.
+func matchReceipt(rc receipt, pattern string) (entries []receiptEntry, pathMatch bool) {
+	match := func(name string) bool {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+		return strings.Contains(name, pattern)
+	}
+	for _, e := range rc.Entries {
+		if match(e.Name) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, match(rc.Path)
+}
+
+// runReceipts implements `tidyup receipts [PATTERN]`.
+func runReceipts(args []string) int {
+	flags := flag.NewFlagSet("receipts", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tidyup receipts [PATTERN]\n\n")
+		fmt.Fprintf(os.Stderr, "Lists receipts of items removed with -receipts. With PATTERN (a glob or\n")
+		fmt.Fprintf(os.Stderr, "substring), shows removed trees whose path or top-level entries match it.\n")
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return exitError
+	}
+	pattern := flags.Arg(0)
+
+	path, err := receiptsPath()
+	if err != nil {
+		stderr.errorf("%v", err)
+		return exitError
+	}
+	receipts, err := loadReceipts(path)
+	if os.IsNotExist(err) {
+		fmt.Println("No receipts yet; run deletions with -receipts to record them.")
+		return exitOK
+	}
+	if err != nil {
+		stderr.errorf("reading %s: %v", path, err)
+		return exitError
+	}
+	sort.SliceStable(receipts, func(i, j int) bool { return receipts[i].Time > receipts[j].Time })
+
+	found := 0
+	for _, rc := range receipts {
+		var entries []receiptEntry
+		if pattern != "" {
+			var pathMatch bool
+			entries, pathMatch = matchReceipt(rc, pattern)
+			if len(entries) == 0 && !pathMatch {
+				continue
+			}
+		}
+		found++
+		more := ""
+		if rc.More > 0 {
+			more = fmt.Sprintf(", %d more not listed", rc.More)
+		}
+		fmt.Printf("%s  %s %s (%s, %d entries%s)\n", rc.Time, rc.Action, rc.Path, formatBytes(rc.Size), len(rc.Entries)+rc.More, more)
+		for _, e := range entries {
+			suffix := ""
+			if e.Dir {
+				suffix = "/"
+			}
+			fmt.Printf("    %s%s  %s\n", e.Name, suffix, formatBytes(e.Size))
+		}
+	}
+	if found == 0 {
+		fmt.Printf("No receipt matches %q.\n", pattern)
+	}
+	return exitOK
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBuildReceipt(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "build")
+	os.MkdirAll(filepath.Join(dir, "lib"), 0755)
+	os.WriteFile(filepath.Join(dir, "lib", "a.o"), make([]byte, 300), 0644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), make([]byte, 12), 0644)
+
+	rc := buildReceipt(Record{Type: "build", Path: dir, Size: 312})
+	want := map[string]receiptEntry{
+		"lib":       {Name: "lib", Size: 300, Dir: true},
+		"notes.txt": {Name: "notes.txt", Size: 12},
+	}
+	if len(rc.Entries) != len(want) {
+		t.Fatalf("entries = %+v", rc.Entries)
+	}
+	for _, e := range rc.Entries {
+		if want[e.Name] != e {
+			t.Errorf("entry %+v, want %+v", e, want[e.Name])
+		}
+	}
+	if len(rc.ListingSHA) != 64 {
+		t.Errorf("listing hash = %q", rc.ListingSHA)
+	}
+	if again := buildReceipt(Record{Path: dir}); again.ListingSHA != rc.ListingSHA {
+		t.Error("listing hash is not stable")
+	}
+}
+
+func TestReceipts_RecordAndMatch(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	rc := receipt{Type: "dist", Path: "/p/dist", Entries: []receiptEntry{{Name: "app-1.0.whl"}, {Name: "README.md"}}}
+	recordReceipt(rc, "Trashed", time.Now())
+
+	path, _ := receiptsPath()
+	receipts, err := loadReceipts(path)
+	if err != nil || len(receipts) != 1 || receipts[0].Action != "trashed" {
+		t.Fatalf("receipts = %+v, %v", receipts, err)
+	}
+	if entries, _ := matchReceipt(receipts[0], "*.whl"); len(entries) != 1 || entries[0].Name != "app-1.0.whl" {
+		t.Errorf("glob match = %+v", entries)
+	}
+	if entries, pathMatch := matchReceipt(receipts[0], "dist"); len(entries) != 0 || !pathMatch {
+		t.Errorf("substring match on path: entries %+v, path %v", entries, pathMatch)
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// `tidyup restore` brings removed items back to where they were. It looks
+// in every place tidyup keeps removed items recoverably -- so far only the
+// Trash, through the trash manifest; a quarantine or archive source plugs
+// into restoreSources -- and restores the items matching -type, -project,
+// and -since. If something has since been recreated at an item's original
+// path, the item is restored next to it under a .restored-<time> suffix
+// (-on-conflict rename) or left where it is (-on-conflict skip).
+
+// restorable is a removed item that can still be brought back.
+type restorable struct {
+	Source   string // "trash"
+	Time     time.Time
+	Type     string
+	Original string // where it was
+	Location string // where it is now
+	Size     int64
+}
+
+// restoreSource lists the items one source can restore, and forgets those
+// that were restored. Both run under the state lock.
+type restoreSource struct {
+	name   string
+	list   func() ([]restorable, error)
+	forget func(restored map[string]bool) error // keyed by Location
+}
+
+// restoreSources are consulted in order.
+var restoreSources = []restoreSource{
+	{name: "trash", list: listTrashed, forget: forgetTrashed},
+}
+
+// listTrashed returns the items in the trash manifest that are still in the
+// Trash.
+func listTrashed() ([]restorable, error) {
+	path, err := trashedPath()
+	if err != nil {
+		return nil, err
+	}
+	items, err := loadTrashed(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []restorable
+	for _, it := range items {
+		if _, err := os.Lstat(it.Trash); err != nil {
+			continue
+		}
+		t, _ := time.Parse(time.RFC3339, it.Time)
+		out = append(out, restorable{Source: "trash", Time: t, Type: it.Type, Original: it.Original, Location: it.Trash, Size: it.Size})
+	}
+	return out, nil
+}
+
+// forgetTrashed drops restored items from the trash manifest.
+func forgetTrashed(restored map[string]bool) error {
+	path, err := trashedPath()
+	if err != nil {
+		return err
+	}
+	items, err := loadTrashed(path)
+	if err != nil {
+		return err
+	}
+	var keep []trashedItem
+	for _, it := range items {
+		if !restored[it.Trash] {
+			keep = append(keep, it)
+		}
+	}
+	return saveTrashed(path, keep)
+}
+
+// restoreFilter selects what to restore. Zero fields match everything.
+type restoreFilter struct {
+	types   map[string]bool
+	project string        // original path is this directory or inside it
+	since   time.Duration // removed at most this long ago
+}
+
+// match reports whether r passes the filter at time now.
+func (f restoreFilter) match(r restorable, now time.Time) bool {
+	if len(f.types) > 0 && !f.types[r.Type] {
+		return false
+	}
+	if f.project != "" && r.Original != f.project && !strings.HasPrefix(r.Original, f.project+string(filepath.Separator)) {
+		return false
+	}
+	if f.since > 0 && now.Sub(r.Time) > f.since {
+		return false
+	}
+	return true
+}
+
+// Conflict policies for -on-conflict.
+const (
+	conflictRename = "rename"
+	conflictSkip   = "skip"
+)
+
+// restoreTarget is where r goes back to: its original path, or, when
+// something is there again, a sibling with a .restored-<time> suffix. It
+// returns "" when the policy says to leave r alone.
+func restoreTarget(r restorable, policy string, now time.Time) string {
+	if _, err := os.Lstat(r.Original); err != nil {
+		return r.Original
+	}
+	if policy == conflictSkip {
+		return ""
+	}
+	base := r.Original + ".restored-" + now.Format("20060102-150405")
+	dest := base
+	for i := 2; ; i++ {
+		if _, err := os.Lstat(dest); err != nil {
+			return dest
+		}
+		dest = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+// expandHome expands a leading ~ to the home directory.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// runRestore implements `tidyup restore`.
+func runRestore(args []string) int {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	typeFlag := flags.String("type", "", "Comma-separated types to restore")
+	project := flags.String("project", "", "Restore only items that were inside this directory")
+	sinceRaw := flags.String("since", "", "Restore only items removed within this long (e.g. 7d)")
+	all := flags.Bool("all", false, "Restore everything restorable (required without a filter)")
+	onConflict := flags.String("on-conflict", conflictRename, "When the original path exists again: rename (restore beside it) or skip")
+	dryRun := flags.Bool("dry-run", false, "Show what would be restored")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tidyup restore [-type T] [-project DIR] [-since 7d] [-all] [-dry-run]\n\n")
+		fmt.Fprintf(os.Stderr, "Moves items tidyup trashed back to where they were.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return exitError
+	}
+	since, err := parseRetention(*sinceRaw)
+	if err != nil {
+		stderr.errorf("-since: %v", err)
+		return exitError
+	}
+	if *onConflict != conflictRename && *onConflict != conflictSkip {
+		stderr.errorf("unknown -on-conflict %q (want rename or skip)", *onConflict)
+		return exitError
+	}
+	filter := restoreFilter{types: nameSet(splitList(*typeFlag)), since: since}
+	if *project != "" {
+		abs, err := filepath.Abs(expandHome(*project))
+		if err != nil {
+			stderr.errorf("-project: %v", err)
+			return exitError
+		}
+		filter.project = abs
+	}
+	if len(filter.types) == 0 && filter.project == "" && since == 0 && !*all {
+		stderr.errorf("restore needs a filter (-type, -project, -since) or -all")
+		return exitError
+	}
+
+	// Moving an item back can remove the copy it leaves across filesystems.
+	x, err := newExecutor(&options{}, nil)
+	if err != nil && !*dryRun {
+		stderr.errorf("%v", err)
+		return exitError
+	}
+
+	defer lockState()()
+	now := time.Now()
+	code := exitOK
+	matched := 0
+	var restored []Record
+	for _, src := range restoreSources {
+		items, err := src.list()
+		if err != nil {
+			stderr.warnf("could not read %s items: %v", src.name, err)
+			code = exitError
+			continue
+		}
+		sort.SliceStable(items, func(i, j int) bool { return items[i].Time.Before(items[j].Time) })
+		done := map[string]bool{}
+		for _, r := range items {
+			if !filter.match(r, now) {
+				continue
+			}
+			matched++
+			dest := restoreTarget(r, *onConflict, now)
+			if dest == "" {
+				fmt.Printf("  skipped %s (it exists again)\n", r.Original)
+				continue
+			}
+			note := ""
+			if dest != r.Original {
+				note = fmt.Sprintf(" (as %s; the original path exists again)", filepath.Base(dest))
+			}
+			if *dryRun {
+				fmt.Printf("  would restore %s from %s%s\n", r.Original, src.name, note)
+				continue
+			}
+			err := os.MkdirAll(filepath.Dir(dest), 0755)
+			if err == nil {
+				err = x.moveTree(r.Location, dest)
+			}
+			if err != nil {
+				stderr.printf("Error restoring %s: %v", r.Original, err)
+				code = exitError
+				continue
+			}
+			fmt.Printf("  restored %s (%s)%s\n", r.Original, formatBytes(r.Size), note)
+			done[r.Location] = true
+			restored = append(restored, Record{Type: r.Type, Path: r.Original, Size: r.Size})
+		}
+		if len(done) > 0 {
+			if err := src.forget(done); err != nil {
+				stderr.warnf("could not update the %s manifest: %v", src.name, err)
+			}
+		}
+	}
+	if matched == 0 {
+		fmt.Println("Nothing to restore.")
+		return code
+	}
+	if *dryRun {
+		return code
+	}
+	recordDecisions(decisionRestored, restored, now)
+	fmt.Printf("Restored %d items.\n", len(restored))
+	return code
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRestoreFilter(t *testing.T) {
+	now := time.Now()
+	r := restorable{Type: "venv", Original: "/dev/foo/.venv", Time: now.Add(-48 * time.Hour)}
+	cases := []struct {
+		f    restoreFilter
+		want bool
+	}{
+		{restoreFilter{}, true},
+		{restoreFilter{types: map[string]bool{"venv": true}}, true},
+		{restoreFilter{types: map[string]bool{"dist": true}}, false},
+		{restoreFilter{project: "/dev/foo"}, true},
+		{restoreFilter{project: "/dev/fo"}, false},
+		{restoreFilter{since: 72 * time.Hour}, true},
+		{restoreFilter{since: 24 * time.Hour}, false},
+	}
+	for _, c := range cases {
+		if got := c.f.match(r, now); got != c.want {
+			t.Errorf("%+v.match = %v, want %v", c.f, got, c.want)
+		}
+	}
+}
+
+func TestRestoreTarget(t *testing.T) {
+	dir := t.TempDir()
+	orig := filepath.Join(dir, ".venv")
+	r := restorable{Original: orig}
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	if got := restoreTarget(r, conflictRename, now); got != orig {
+		t.Errorf("free path: got %q", got)
+	}
+	os.Mkdir(orig, 0755)
+	if got := restoreTarget(r, conflictSkip, now); got != "" {
+		t.Errorf("skip: got %q", got)
+	}
+	want := orig + ".restored-20260501-120000"
+	if got := restoreTarget(r, conflictRename, now); got != want {
+		t.Errorf("rename: got %q, want %q", got, want)
+	}
+	os.Mkdir(want, 0755)
+	if got := restoreTarget(r, conflictRename, now); got != want+"-2" {
+		t.Errorf("second rename: got %q", got)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A dist/ or build/ in use keeps growing one release at a time, and -age
+// never flags it: the newest build keeps the whole directory fresh. With
+// -keep-builds N, the builds inside it are the items instead. A build is a
+// per-version subdirectory (1.4.0/, v2.0.0-rc1/) or a release's wheels and
+// sdist (grouped by distribution and version). The newest N of each
+// distribution (the version subdirectories count as one), by their newest
+// file, are kept whatever their age; the older ones are flagged whatever
+// theirs. Directories with no builds recognized in them are judged as a
+// whole, as without the flag.
+
+// versionDirName matches a subdirectory named for a version alone.
+var versionDirName = regexp.MustCompile(`^v?\d+(\.\d+)+([-+][0-9A-Za-z.]+)?$`)
+
+// buildSet is one build in a dist/ or build/: its files or directory.
+type buildSet struct {
+	dist    string // normalized distribution name; "" for a version subdirectory
+	version string
+	paths   []string
+	newest  time.Time
+}
+
+// findBuilds returns the builds in dir by distribution, each distribution's
+// newest first.
+func findBuilds(dir string) []buildSet {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	byBuild := map[string]*buildSet{}
+	var builds []*buildSet
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		var dist, version string
+		var lastUsed time.Time
+		switch {
+		case e.IsDir() && versionDirName.MatchString(e.Name()):
+			version = e.Name()
+			lastUsed, _ = getBuildUsage(path)
+		case e.Type().IsRegular():
+			name, v, ok := pythonArtifact(e.Name())
+			info, err := e.Info()
+			if !ok || err != nil {
+				continue
+			}
+			dist, version, lastUsed = normalizeDistName(name), v, info.ModTime()
+		default:
+			continue
+		}
+		key := dist + " " + version
+		b := byBuild[key]
+		if b == nil {
+			b = &buildSet{dist: dist, version: version}
+			byBuild[key] = b
+			builds = append(builds, b)
+		}
+		b.paths = append(b.paths, path)
+		if lastUsed.After(b.newest) {
+			b.newest = lastUsed
+		}
+	}
+	sort.Slice(builds, func(i, j int) bool {
+		if builds[i].dist != builds[j].dist {
+			return builds[i].dist < builds[j].dist
+		}
+		if !builds[i].newest.Equal(builds[j].newest) {
+			return builds[i].newest.After(builds[j].newest)
+		}
+		return compareVersions(strings.TrimPrefix(builds[i].version, "v"), strings.TrimPrefix(builds[j].version, "v")) > 0
+	})
+	sets := make([]buildSet, len(builds))
+	for i, b := range builds {
+		sets[i] = *b
+	}
+	return sets
+}
+
+// keepNewestBuilds applies -keep-builds to a dist/ or build/ directory:
+// it dispatches each distribution's builds past its newest -keep-builds as
+// items of typeName and reports whether dir had any builds, i.e. whether it
+// was handled and is not an item itself.
+func (s *scanner) keepNewestBuilds(dir, typeName string) bool {
+	builds := findBuilds(dir)
+	if len(builds) == 0 {
+		return false
+	}
+	keep := s.opts.keepBuilds
+	seen := map[string]int{}
+	for _, b := range builds {
+		if seen[b.dist]++; seen[b.dist] <= keep {
+			continue
+		}
+		for _, p := range b.paths {
+			if s.retired == nil {
+				s.retired = map[string]string{}
+			}
+			s.retired[p] = fmt.Sprintf("build %s, older than the newest %d kept by -keep-builds", b.version, keep)
+			s.dispatch(p, typeName, getBuildUsage)
+		}
+	}
+	return true
+}
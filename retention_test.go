@@ -0,0 +1,113 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFindBuilds(t *testing.T) {
+	dist := t.TempDir()
+	write := func(rel string, days int) {
+		p := filepath.Join(dist, rel)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte("x"), 0644)
+		mtime := time.Now().AddDate(0, 0, -days)
+		os.Chtimes(p, mtime, mtime)
+	}
+	write("pkg-1.0.0-py3-none-any.whl", 300)
+	write("pkg-1.0.0.tar.gz", 299)
+	write("pkg-1.1.0-py3-none-any.whl", 100)
+	write("v2.0.0-rc1/app.bin", 10)
+	write("notes/readme.txt", 500)
+	write("stray.txt", 500)
+
+	builds := findBuilds(dist)
+	var versions []string
+	for _, b := range builds {
+		versions = append(versions, b.version)
+	}
+	want := []string{"v2.0.0-rc1", "1.1.0", "1.0.0"}
+	if len(versions) != len(want) {
+		t.Fatalf("builds = %v, want %v", versions, want)
+	}
+	for i := range want {
+		if versions[i] != want[i] {
+			t.Errorf("build %d = %s, want %s", i, versions[i], want[i])
+		}
+	}
+	if len(builds[2].paths) != 2 {
+		t.Errorf("1.0.0 has %v, want its wheel and sdist", builds[2].paths)
+	}
+}
+
+func TestFindBuilds_PerDistribution(t *testing.T) {
+	dist := t.TempDir()
+	same := time.Now().AddDate(0, 0, -50)
+	for _, name := range []string{"foo-0.9-py3-none-any.whl", "foo-0.10-py3-none-any.whl", "Bar_Plugin-0.3-py3-none-any.whl"} {
+		p := filepath.Join(dist, name)
+		os.WriteFile(p, []byte("x"), 0644)
+		os.Chtimes(p, same, same)
+	}
+	var got []string
+	for _, b := range findBuilds(dist) {
+		got = append(got, b.dist+" "+b.version)
+	}
+	// Distributions apart; equally new builds by version, numerically.
+	want := []string{"bar-plugin 0.3", "foo 0.10", "foo 0.9"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("builds = %v, want %v", got, want)
+	}
+}
+
+func TestScanRoots_KeepBuilds(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	root := t.TempDir()
+	proj := filepath.Join(root, "proj")
+	os.MkdirAll(proj, 0755)
+	os.WriteFile(filepath.Join(proj, "pyproject.toml"), []byte("[project]\nname = \"pkg\"\n"), 0644)
+	for _, b := range []struct {
+		name string
+		days int
+	}{
+		{"pkg-0.9.0-py3-none-any.whl", 400},
+		{"pkg-1.0.0-py3-none-any.whl", 200},
+		{"pkg-1.1.0-py3-none-any.whl", 5},
+		{"pkg-1.2.0-py3-none-any.whl", 3},
+		{"pkg_plugin-0.3-py3-none-any.whl", 300}, // its only release: kept
+	} {
+		p := filepath.Join(proj, "dist", b.name)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte("x"), 0644)
+		mtime := time.Now().AddDate(0, 0, -b.days)
+		os.Chtimes(p, mtime, mtime)
+	}
+
+	opts := &options{minAge: 30, maxDepth: 5, scanTypes: map[string]bool{"dist": true}}
+	found := func() []string {
+		records, _ := scanRoots([]string{root}, opts)
+		var got []string
+		for _, r := range records {
+			got = append(got, filepath.Base(r.Path))
+		}
+		sort.Strings(got)
+		return got
+	}
+	// Without -keep-builds the fresh 1.2.0 keeps dist/ unflagged.
+	if got := found(); len(got) != 0 {
+		t.Errorf("without -keep-builds: got %v, want nothing", got)
+	}
+	// The newest two are kept, the older ones flagged; with one kept, so
+	// is the 5-day-old 1.1.0.
+	opts.keepBuilds = 2
+	if got := found(); len(got) != 2 || got[0] != "pkg-0.9.0-py3-none-any.whl" || got[1] != "pkg-1.0.0-py3-none-any.whl" {
+		t.Errorf("-keep-builds 2: got %v, want 0.9.0 and 1.0.0", got)
+	}
+	opts.keepBuilds = 1
+	if got := found(); len(got) != 3 {
+		t.Errorf("-keep-builds 1: got %v, want all but 1.2.0", got)
+	}
+}
@@ -1,21 +1,78 @@
 package main
 
 import (
+	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 )
 
-// isActiveVenv returns true if path matches $VIRTUAL_ENV.
+// activeEnvs returns the activated Python environments: $VIRTUAL_ENV and
+// conda's $CONDA_PREFIX.
+func activeEnvs() []string {
+	var envs []string
+	for _, key := range []string{"VIRTUAL_ENV", "CONDA_PREFIX"} {
+		if env := os.Getenv(key); env != "" {
+			envs = append(envs, filepath.Clean(env))
+		}
+	}
+	return envs
+}
+
+// isActiveVenv returns true if path is an activated environment.
 func isActiveVenv(path string) bool {
-	venv := os.Getenv("VIRTUAL_ENV")
-	if venv == "" {
-		return false
+	for _, env := range activeEnvs() {
+		if filepath.Clean(path) == env {
+			return true
+		}
+	}
+	return false
+}
+
+// containsActiveVenv returns true if an activated environment is strictly
+// inside path.
+func containsActiveVenv(path string) bool {
+	path = filepath.Clean(path)
+	for _, env := range activeEnvs() {
+		if env != path && within(env, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// envRootFor returns the environment a walked entry marks: the directory
+// holding a pyvenv.cfg, or the parent of a conda-meta/. Otherwise "".
+func envRootFor(path string, d fs.DirEntry) string {
+	switch {
+	case !d.IsDir() && d.Name() == "pyvenv.cfg":
+		return filepath.Dir(path)
+	case d.IsDir() && d.Name() == "conda-meta":
+		return filepath.Dir(path)
 	}
-	// Clean both paths for reliable comparison.
-	return filepath.Clean(path) == filepath.Clean(venv)
+	return ""
+}
+
+// nestedEnvReason returns why a candidate must be kept for the environments
+// nested inside it: one is the active venv, or one was used within -age. A
+// stale wrapper (a .direnv, a build tree, a conda base with envs/) judged by
+// its own usage can still hold a fresh environment; "" means none does.
+func nestedEnvReason(envs []string, opts *options) string {
+	for _, env := range envs {
+		if isActiveVenv(env) {
+			return "contains the active venv " + env
+		}
+		if lastUsed, ok := getVenvActivity(env); ok {
+			if days := opts.ageDays(lastUsed); days < float64(opts.minAge) {
+				return fmt.Sprintf("contains the environment %s, used %d days ago", env, int(days))
+			}
+		}
+	}
+	return ""
 }
 
 // protectedPrefixes are system-critical path prefixes that should never be deleted.
@@ -60,9 +117,9 @@ func isProtectedPath(path string) bool {
 }
 
 // isValidVenv returns true if the directory looks like a real venv
-// (has pyvenv.cfg AND bin/ or Scripts/ directory).
+// (a venvKind marker AND bin/ or Scripts/ directory).
 func isValidVenv(path string) bool {
-	if _, err := os.Stat(filepath.Join(path, "pyvenv.cfg")); err != nil {
+	if venvKind(path) == "" {
 		return false
 	}
 	if _, err := os.Stat(filepath.Join(path, "bin")); err == nil {
@@ -74,6 +131,27 @@ func isValidVenv(path string) bool {
 	return false
 }
 
+// sitePackagesPatterns are where installed packages live across layouts:
+// CPython and conda on Unix, Windows (CPython and conda), PyPy 3.8+, and
+// older PyPy (site-packages at the env root, plus its lib_pypy stdlib).
+var sitePackagesPatterns = []string{
+	"lib/python*/site-packages",
+	"Lib/site-packages",
+	"lib/pypy*/site-packages",
+	"site-packages",
+	"lib_pypy",
+}
+
+// sitePackagesDirs returns the package directories present in a venv.
+func sitePackagesDirs(path string) []string {
+	var dirs []string
+	for _, pattern := range sitePackagesPatterns {
+		matches, _ := filepath.Glob(filepath.Join(path, filepath.FromSlash(pattern)))
+		dirs = append(dirs, matches...)
+	}
+	return dirs
+}
+
 // getSitePackagesUsage checks site-packages for the newest mtime among
 // installed packages, providing a better "last used" signal than activation
 // script timestamps alone.
@@ -81,10 +159,8 @@ func getSitePackagesUsage(path string) (time.Time, bool) {
 	var latest time.Time
 	found := false
 
-	// Look for lib/python*/site-packages pattern.
-	pattern := filepath.Join(path, "lib", "python*", "site-packages")
-	matches, err := filepath.Glob(pattern)
-	if err != nil || len(matches) == 0 {
+	matches := sitePackagesDirs(path)
+	if len(matches) == 0 {
 		return latest, false
 	}
 
@@ -107,3 +183,181 @@ func getSitePackagesUsage(path string) (time.Time, bool) {
 
 	return latest, found
 }
+
+// Safety rules applied by filterSafeRecords, as reported in the safety summary.
+const (
+	ruleActiveVenv    = "active venv"
+	ruleProtectedPath = "protected path"
+	ruleReview        = "review required"
+	ruleAdvisory      = "advisory only"
+	ruleMount         = "mount point"
+	ruleOwnState      = "tidyup state"
+)
+
+// mountPoint returns the root of the filesystem holding path, or "" where
+// device numbers are unavailable.
+func mountPoint(path string) string {
+	dev, ok := deviceOf(path)
+	if !ok {
+		return ""
+	}
+	dir := filepath.Clean(path)
+	for {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		if pdev, ok := deviceOf(parent); !ok || pdev != dev {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+// mountTableFunc lists mount points; a seam for tests.
+var mountTableFunc = mountTable
+
+// mountConflict explains why removing path would reach into another
+// filesystem: path is a mount point itself, or something is mounted inside
+// it. A bind mount or a mounted disk image forgotten inside a build
+// directory would otherwise be emptied along with it. mounts is the mount
+// table, if there is one. It returns "" when path holds no mounts.
+func mountConflict(path string, mounts []string) string {
+	path = filepath.Clean(path)
+	if dev, ok := deviceOf(path); ok {
+		if pdev, ok := deviceOf(filepath.Dir(path)); ok && pdev != dev {
+			return "it is a mount point"
+		}
+	}
+	for _, m := range mounts {
+		m = filepath.Clean(m)
+		if m == path {
+			return "it is a mount point"
+		}
+		if strings.HasPrefix(m, path+string(filepath.Separator)) {
+			return "it contains the mount point " + m
+		}
+	}
+	return ""
+}
+
+// safetySummary is what the person confirming -delete needs to know.
+type safetySummary struct {
+	count       int
+	total       int64
+	largest     Record
+	skipped     map[string]int      // safety rule -> records it filtered out
+	filesystems []string            // mount points of the candidates, sorted
+	dirtyRepos  []string            // repos with uncommitted changes that contain candidates
+	dirtyItems  int                 // candidates inside those repos
+	patched     map[string][]string // node_modules path -> why a reinstall may not restore it
+	editable    map[string][]string // item path -> environments with an editable install of its project
+}
+
+// summarizeSafety gathers the safety summary for records that passed
+// filterSafeRecords. skipped comes from the same call.
+func summarizeSafety(records []Record, skipped map[string]int) safetySummary {
+	sum := safetySummary{count: len(records), skipped: skipped, patched: map[string][]string{}, editable: map[string][]string{}}
+	mounts := map[string]bool{}
+	dirty := map[string]bool{} // repo root -> has uncommitted changes
+	for _, r := range records {
+		sum.total += r.Size
+		if r.Size > sum.largest.Size {
+			sum.largest = r
+		}
+		if m := mountPoint(r.Path); m != "" {
+			mounts[m] = true
+		}
+		if r.Type == "node_modules" {
+			if reasons := nodeModulesPatches(r.Path); len(reasons) > 0 {
+				sum.patched[r.Path] = reasons
+			}
+		}
+		if len(r.EditableUsers) > 0 {
+			sum.editable[r.Path] = r.EditableUsers
+		}
+		repo := gitRepoRoot(filepath.Dir(r.Path))
+		if repo == "" {
+			continue
+		}
+		isDirty, seen := dirty[repo]
+		if !seen {
+			entries, ok := gitStatus(repo, "")
+			isDirty = ok && len(entries) > 0
+			dirty[repo] = isDirty
+			if isDirty {
+				sum.dirtyRepos = append(sum.dirtyRepos, repo)
+			}
+		}
+		if isDirty {
+			sum.dirtyItems++
+		}
+	}
+	for m := range mounts {
+		sum.filesystems = append(sum.filesystems, m)
+	}
+	sort.Strings(sum.filesystems)
+	sort.Strings(sum.dirtyRepos)
+	return sum
+}
+
+// printSafetySummary writes the summary shown before selection or -confirm.
+func printSafetySummary(w io.Writer, sum safetySummary, opts *options) {
+	fmt.Fprintln(w, "\nSafety summary:")
+	fmt.Fprintf(w, "  Candidates:  %s items, %s\n", opts.locale.integer(int64(sum.count)), opts.locale.bytes(sum.total))
+	if sum.count > 0 {
+		fmt.Fprintf(w, "  Largest:     %s %s\n", opts.locale.bytes(sum.largest.Size), displayPath(sum.largest, opts))
+	}
+
+	var rules []string
+	for _, rule := range []string{ruleAdvisory, ruleActiveVenv, ruleProtectedPath, ruleOwnState, ruleMount, ruleReview} {
+		if n := sum.skipped[rule]; n > 0 {
+			rules = append(rules, fmt.Sprintf("%d %s", n, rule))
+		}
+	}
+	if len(rules) == 0 {
+		rules = []string{"none"}
+	}
+	fmt.Fprintf(w, "  Filtered:    %s\n", strings.Join(rules, ", "))
+
+	if len(sum.filesystems) > 0 {
+		fmt.Fprintf(w, "  Filesystems: %s\n", strings.Join(sum.filesystems, ", "))
+	}
+	if len(sum.dirtyRepos) > 0 {
+		var repos []string
+		for _, repo := range sum.dirtyRepos {
+			repos = append(repos, displayPath(Record{Path: repo}, opts))
+		}
+		fmt.Fprintf(w, "  Git:         %d items inside repos with uncommitted changes: %s\n", sum.dirtyItems, strings.Join(repos, ", "))
+	} else {
+		fmt.Fprintln(w, "  Git:         no items inside repos with uncommitted changes")
+	}
+	if len(sum.patched) > 0 {
+		fmt.Fprintf(w, "  Patched:     %d node_modules may hold changes a reinstall will not restore:\n", len(sum.patched))
+		var paths []string
+		for p := range sum.patched {
+			paths = append(paths, p)
+		}
+		sort.Strings(paths)
+		for _, p := range paths {
+			for _, reason := range sum.patched[p] {
+				fmt.Fprintf(w, "               %s: %s\n", displayPath(Record{Path: p}, opts), reason)
+			}
+		}
+	}
+	if len(sum.editable) > 0 {
+		fmt.Fprintf(w, "  Editable:    %d items belong to projects other environments have editable installs of; removing them may break those:\n", len(sum.editable))
+		var paths []string
+		for p := range sum.editable {
+			paths = append(paths, p)
+		}
+		sort.Strings(paths)
+		for _, p := range paths {
+			var users []string
+			for _, u := range sum.editable[p] {
+				users = append(users, displayPath(Record{Path: u}, opts))
+			}
+			fmt.Fprintf(w, "               %s: used by %s\n", displayPath(Record{Path: p}, opts), strings.Join(users, ", "))
+		}
+	}
+}
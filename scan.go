@@ -5,7 +5,7 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
-	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -14,21 +14,23 @@ import (
 // getVenvUsage inspects specific venv markers to determine the last time it was actually "used".
 // Returns the latest mtime found and whether any marker was found at all.
 func getVenvUsage(path string) (time.Time, bool) {
-	binDir := "bin"
-	if runtime.GOOS == "windows" {
-		binDir = "Scripts"
-	}
-
+	// Check both layouts regardless of GOOS: a Windows venv on a shared or
+	// WSL-mounted drive has Scripts/ even when scanned from Unix.
 	targets := []string{
-		filepath.Join(path, binDir, "activate"),
 		filepath.Join(path, "pyvenv.cfg"),
-		filepath.Join(path, binDir, "python"),
+		filepath.Join(path, "bin", "activate"),
+		filepath.Join(path, "bin", "python"),
+		filepath.Join(path, "Scripts", "activate"),
+		filepath.Join(path, "Scripts", "python.exe"),
+		filepath.Join(path, "conda-meta", "history"), // appended on every conda install
 	}
 
 	var latest time.Time
 	found := false
 	for _, t := range targets {
-		if info, err := os.Stat(t); err == nil {
+		// Lstat: bin/python is usually a symlink, and the interpreter it points
+		// at changes with Homebrew upgrades, not with use of this venv.
+		if info, err := os.Lstat(t); err == nil {
 			found = true
 			if mtime := info.ModTime(); mtime.After(latest) {
 				latest = mtime
@@ -46,6 +48,19 @@ func getVenvUsage(path string) (time.Time, bool) {
 	return latest, found
 }
 
+// getVenvActivity combines venv markers with site-packages mtimes, so a venv
+// created long ago but recently installed into is not treated as stale.
+func getVenvActivity(path string) (time.Time, bool) {
+	lastUsed, found := getVenvUsage(path)
+	if !found {
+		return lastUsed, false
+	}
+	if spTime, ok := getSitePackagesUsage(path); ok && spTime.After(lastUsed) {
+		lastUsed = spTime
+	}
+	return lastUsed, true
+}
+
 // getNodeModulesUsage determines when a node_modules directory was last used.
 // Checks .package-lock.json (npm >=7), parent lockfiles, then falls back to dir mtime.
 func getNodeModulesUsage(path string) (time.Time, bool) {
@@ -75,7 +90,8 @@ func getCacheUsage(path string) (time.Time, bool) {
 	var latest time.Time
 	found := false
 
-	_ = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+	_ = walkTree(path, func(p string, d fs.DirEntry, err error) error {
+		pace()
 		if err != nil {
 			return nil
 		}
@@ -118,24 +134,145 @@ func hasBuildParent(path string) bool {
 	return false
 }
 
-// isVenv identifies if a directory is a Python virtual environment via the pyvenv.cfg marker.
+// venvKind identifies a Python environment by its contents rather than its
+// name: "pyvenv" (PEP 405 pyvenv.cfg), "conda" (conda-meta/), or
+// "virtualenv" (legacy virtualenv: activate script plus site-packages).
+// Returns "" for anything else.
+func venvKind(path string) string {
+	if _, err := os.Stat(filepath.Join(path, "pyvenv.cfg")); err == nil {
+		return "pyvenv"
+	}
+	if info, err := os.Stat(filepath.Join(path, "conda-meta")); err == nil && info.IsDir() {
+		return "conda"
+	}
+	for _, activate := range []string{"bin/activate", "Scripts/activate"} {
+		if _, err := os.Stat(filepath.Join(path, activate)); err != nil {
+			continue
+		}
+		if len(sitePackagesDirs(path)) > 0 {
+			return "virtualenv"
+		}
+	}
+	return ""
+}
+
+// isVenv identifies if a directory is a Python environment (see venvKind).
 func isVenv(path string) bool {
-	_, err := os.Stat(filepath.Join(path, "pyvenv.cfg"))
-	return err == nil
+	return venvKind(path) != ""
+}
+
+// hasInterpreter reports whether path contains a Python executable, for
+// directories matched by name through -venv-names.
+func hasInterpreter(path string) bool {
+	for _, exe := range []string{"bin/python", "bin/python3", "Scripts/python.exe", "python.exe"} {
+		if _, err := os.Stat(filepath.Join(path, exe)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// dirSize recursively calculates the apparent (logical) and on-disk bytes of a directory.
+func dirSize(path string) (apparent, disk int64) {
+	tree := inspectTree(path, time.Now())
+	return tree.apparent, tree.disk
+}
+
+// treeInfo is what a single walk of a candidate directory learns about it.
+type treeInfo struct {
+	apparent, disk int64
+	busy           string   // why another tool seems to be writing the tree; "" if idle
+	envs           []string // Python environments nested below the root
+}
+
+// inspectTree sizes path and, in the same walk, looks for signs that a sync
+// client or transfer is writing into it right now (see inFlightReason), and
+// for environments nested inside it (see nestedEnvReason).
+func inspectTree(path string, now time.Time) treeInfo {
+	var tree treeInfo
+	seen := make(map[fileID]bool)
+	_ = walkTree(path, func(p string, d fs.DirEntry, err error) error {
+		pace()
+		if err != nil {
+			return nil
+		}
+		if env := envRootFor(p, d); env != "" && env != path {
+			tree.envs = append(tree.envs, env)
+		}
+		if !d.IsDir() {
+			if info, err := d.Info(); err == nil {
+				tree.apparent += info.Size()
+				tree.disk += diskBytes(info, seen)
+				if tree.busy == "" {
+					tree.busy = inFlightReason(d.Name(), info, now)
+				}
+			}
+		}
+		return nil
+	})
+	return tree
 }
 
-// dirSize recursively calculates total bytes in a directory.
-func dirSize(path string) int64 {
-	var size int64
-	_ = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
-		if err == nil && !d.IsDir() {
+// smallTreeEntries is how many entries belowMinSize looks at before it
+// leaves the question to sizing.
+const smallTreeEntries = 4096
+
+// belowMinSize reports whether path's apparent size, as inspectTree counts
+// it, is known to be under minSize: the walk stops as soon as the total
+// reaches minSize or it has seen smallTreeEntries entries, and only a walk
+// that finished says yes.
+func belowMinSize(path string, minSize int64) bool {
+	if minSize <= 0 {
+		return false
+	}
+	var total int64
+	entries, complete := 0, true
+	_ = walkTree(path, func(p string, d fs.DirEntry, err error) error {
+		pace()
+		if err != nil {
+			return nil
+		}
+		if entries++; entries > smallTreeEntries {
+			complete = false
+			return filepath.SkipAll
+		}
+		if !d.IsDir() {
 			if info, err := d.Info(); err == nil {
-				size += info.Size()
+				if total += info.Size(); total >= minSize {
+					complete = false
+					return filepath.SkipAll
+				}
 			}
 		}
 		return nil
 	})
-	return size
+	return complete
+}
+
+// defaultSkipDirs are directory names the walk never enters: VCS internals,
+// macOS's Library (app data, not projects), and the Trash. -skip adds names,
+// -no-skip removes them.
+var defaultSkipDirs = map[string]bool{".git": true, "Library": true, ".Trash": true}
+
+// librarySensitive are Library subdirectories that stay skipped even when
+// -no-skip Library opens it up: mail, messages, keychains, iCloud and cloud
+// storage mirrors, and app sandboxes hold user data, never build artifacts.
+var librarySensitive = map[string]bool{
+	"Mail": true, "Messages": true, "Keychains": true, "Mobile Documents": true,
+	"CloudStorage": true, "Containers": true, "Group Containers": true,
+	"Photos": true, "Safari": true, "Calendars": true,
+}
+
+// alwaysSkipped reports whether the walk must not enter the directory at
+// path, per the skip list.
+func alwaysSkipped(path, name string, opts *options) bool {
+	if opts.skipDirs[name] {
+		return true
+	}
+	if defaultSkipDirs[name] {
+		return !opts.noSkipDirs[name]
+	}
+	return filepath.Base(filepath.Dir(path)) == "Library" && librarySensitive[name]
 }
 
 // matchesExclude checks if a path matches any of the exclude patterns.
@@ -151,56 +288,436 @@ func matchesExclude(path string, patterns []string) bool {
 	return false
 }
 
+// recentlyCreated reports whether path was created less than
+// -min-creation-age days ago. Restored or copied trees keep their old mtimes
+// but get a fresh birth time, so this catches them regardless of markers.
+// Always false where birth time is unavailable.
+func recentlyCreated(path string, opts *options) bool {
+	if opts.minCreationAge <= 0 {
+		return false
+	}
+	born, ok := birthTime(path)
+	return ok && opts.ageDays(born) < float64(opts.minCreationAge)
+}
+
+// restoredTreeSlack is how far usage markers may predate an item's creation
+// before the tree is treated as copied or restored with preserved mtimes.
+const restoredTreeSlack = 24 * time.Hour
+
+// adjustForRestoredTree detects the "mtimes older than birth time" pattern
+// left by cp -p, rsync -a, or a backup restore, and returns the creation time
+// (or ctime where birth time is unavailable) instead, with a note for -explain.
+func adjustForRestoredTree(path string, lastUsed time.Time) (time.Time, string) {
+	created, ok := birthTime(path)
+	source := "creation"
+	if !ok {
+		if created, ok = changeTime(path); !ok {
+			return lastUsed, ""
+		}
+		source = "inode change"
+	}
+	if !lastUsed.Before(created.Add(-restoredTreeSlack)) {
+		return lastUsed, ""
+	}
+	note := fmt.Sprintf("restored/copied tree: usage markers (%s) predate the directory's %s time (%s); using %s time",
+		lastUsed.Format("2006-01-02"), source, created.Format("2006-01-02"), source)
+	return created, note
+}
+
+// annotators add type-specific details to a freshly sized record. They run
+// in the sizing goroutine, so they may walk the tree or run git.
+var annotators = map[string]func(r *Record){
+	// Build output may hold hand-edited files; inside a git repository it is
+	// checked for uncommitted work and marked for review.
+	"dist":  annotateUncommitted,
+	"build": annotateUncommitted,
+	"node_modules": func(r *Record) {
+		for _, reason := range nodeModulesPatches(r.Path) {
+			r.Notes = append(r.Notes, "may not reinstall cleanly: "+reason)
+		}
+	},
+	"direnv": func(r *Record) {
+		if m, _ := filepath.Glob(filepath.Join(r.Path, "flake-profile*")); len(m) > 0 {
+			r.Notes = append(r.Notes, "holds nix-direnv GC roots; run nix-collect-garbage afterwards to reclaim store space")
+		}
+	},
+	"tools": func(r *Record) {
+		name, version := toolInfo(r.Path)
+		r.Tool = strings.TrimSpace(name + " " + version)
+		r.Command = toolUninstallCommand(toolManager(r.Path), name)
+	},
+	"android_sdk": func(r *Record) {
+		r.Command = sdkmanagerCommand(r.Path)
+	},
+	"pub_cache": func(r *Record) {
+		r.Command = "dart pub cache clean"
+	},
+	"renv": func(r *Record) {
+		if filepath.Base(r.Path) == "library" {
+			r.Notes = append(r.Notes, "run renv::restore() in the project to reinstall")
+		} else {
+			r.Notes = append(r.Notes, "project libraries link into this cache; run renv::restore() in each project afterwards")
+		}
+	},
+	"julia": func(r *Record) {
+		if filepath.Base(r.Path) == "compiled" {
+			r.Notes = append(r.Notes, "precompile caches; rebuilt on the next `using`")
+		} else {
+			r.Notes = append(r.Notes, "run Pkg.instantiate() in each environment to reinstall; Pkg.gc() frees only unreferenced versions")
+		}
+	},
+	"db_data": func(r *Record) {
+		r.Review, r.Advisory = dbDataKind(r.Path), true
+		if dbServerRunning(r.Path) {
+			r.Notes = append(r.Notes, "server appears to be running (pid/lock file present)")
+		}
+	},
+	"vm_image":      annotateVMImage,
+	"library_cache": annotateLibraryCache,
+	"app_leftovers": annotateAppLeftover,
+	"downloads":     annotateDownload,
+	"crash":         annotateCrash,
+	"tmp":           annotateTmp,
+	"wheel":         annotateWheel,
+	"media":         annotateMedia,
+	"stale_repo":    annotateStaleRepo,
+	"unity": func(r *Record) {
+		r.Notes = append(r.Notes, "Unity re-imports all assets on the next open, which can take a while")
+	},
+	"conda":       annotateConda,
+	"tox":         annotateTox,
+	"gradle":      annotateGradle,
+	"pip_cache":   annotatePipCache,
+	"js_cache":    annotateJSCache,
+	"huggingface": annotateHF,
+	"nox":         annotateNox,
+	"venv": func(r *Record) {
+		interp, _ := venvInterpreter(r.Path)
+		r.Python, r.Interpreter, r.InterpreterGone = interp.version, interp.path, interp.missing
+		if interp.path != "" && !interp.missing {
+			r.Notes = append(r.Notes, "interpreter "+interp.path)
+		}
+	},
+}
+
+// annotateUncommitted marks a record for review when git reports
+// uncommitted or untracked files inside it.
+func annotateUncommitted(r *Record) {
+	r.Review = uncommittedWork(r.Path)
+}
+
 // usageFunc is the signature for type-specific usage heuristic functions.
 type usageFunc func(string) (time.Time, bool)
 
-// dispatchRecord calculates size and usage for a detected item and appends a Record.
-func dispatchRecord(path, typeName string, usage usageFunc,
-	opts *options, wg *sync.WaitGroup, mu *sync.Mutex, records *[]Record, scanned *int64) {
+// scanner holds the state of one scan: records collected by concurrent
+// sizing goroutines, plus the root currently being walked.
+type scanner struct {
+	opts    *options
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	records []Record
+	// spool, when set, takes records instead of records (see -stream).
+	spool   *recordSpool
+	scanned int64
+	sizing  int // items dispatched and still being sized
+	root    string
+	// deferred explains items skipped because another tool is mid-write.
+	deferred []string
+	// venvs counts the venvs seen per project, stale or not, and keeps the
+	// most recently used, so stale ones can point at it.
+	venvs map[string]*venvSiblings
+	// editables maps venvs seen with editable installs to their sources
+	// (see editable.go).
+	editables map[string][]string
+	// evidence caches process and git state for confidence scoring.
+	evidence evidence
+	// own is tidyup's own state (see ownStateDirs), never entered.
+	own []string
+	// rules recompose usage signals per type (see heuristics.json).
+	rules heuristicsConfig
+	// exclusions adjust the per-type exclusions (see exclusions.go).
+	exclusions exclusionsConfig
+	// tags label paths (see tags.json).
+	tags tagsConfig
+	// activity is what the shell hook recorded (see activity.log), or nil.
+	activity *activityLog
+	// poetryDirs maps Poetry path hashes to project directories seen
+	// during the scan (see poetry.go).
+	poetryDirs map[string]string
+	// retired maps builds past -keep-builds to why they are flagged
+	// whatever their age (see retention.go).
+	retired map[string]string
+}
 
-	lastUsed, found := usage(path)
-	if !found {
+// venvSiblings is what a scan saw of one project's venvs: how many, and
+// the most recently used.
+type venvSiblings struct {
+	count    int
+	newest   string
+	lastUsed time.Time
+}
+
+// venvProject returns the project directory a venv belongs to; tool-managed
+// env directories (.tox/py311, .nox/tests, .direnv/python-3.11) belong to
+// the project above them. A Poetry environment's project is elsewhere; its
+// environments for other Python versions share the name without the
+// -pyX.Y suffix, which stands in for it.
+func venvProject(path string) string {
+	if _, hash, ok := poetryEnv(path); ok {
+		base := filepath.Base(path)
+		return filepath.Join(filepath.Dir(path), base[:strings.LastIndex(base, hash)+len(hash)])
+	}
+	dir := filepath.Dir(path)
+	switch filepath.Base(dir) {
+	case ".tox", ".nox", ".direnv":
+		return filepath.Dir(dir)
+	}
+	return dir
+}
+
+// markSiblingVenv points a stale venv at its project's most recently used
+// environment, when the project has more than one.
+func (s *scanner) markSiblingVenv(r *Record) {
+	if r.Type != "venv" {
 		return
 	}
+	if sib := s.venvs[venvProject(r.Path)]; sib != nil && sib.count > 1 && sib.newest != r.Path {
+		r.NewestSibling = sib.newest
+	}
+}
 
-	age := time.Since(lastUsed).Hours() / 24
-	if age < float64(opts.minAge) {
+// finish applies what needs the whole scan to a record: the project of a
+// Poetry environment, the venv's newest sibling, and editable installs of
+// its project elsewhere.
+func (s *scanner) finish(r *Record) {
+	s.resolvePoetryEnv(r)
+	s.markSiblingVenv(r)
+	if users := s.editableUsers(*r); users != nil {
+		r.EditableUsers = users
+	}
+	if r.Type == "tmp" && s.opts.tmpDelete {
+		r.Advisory = false
+	}
+}
+
+// add keeps a record found by the scan, tagged, unless -tag asks for other
+// tags. The caller holds s.mu.
+func (s *scanner) add(r Record) {
+	r.Tags = s.tags.tagsFor(r.Path)
+	if len(s.opts.tagFilter) > 0 && !hasAnyTag(r, s.opts.tagFilter) {
+		return
+	}
+	if s.spool != nil {
+		s.spool.add(r)
 		return
 	}
+	s.records = append(s.records, r)
+}
+
+// found is how many records the scan has kept. The caller holds s.mu.
+func (s *scanner) found() int {
+	if s.spool != nil {
+		return s.spool.count
+	}
+	return len(s.records)
+}
+
+// dispatch calculates size and usage for a detected item and appends a Record.
+// It reports whether the item is old enough to be one; the record may still
+// be dropped once sized (-min-size, busy trees, -min-confidence).
+func (s *scanner) dispatch(path, typeName string, usage usageFunc) bool {
+	opts := s.opts
+	if s.full() {
+		return false
+	}
+	if why := s.exclusions.excludedBy(path, typeName); why != "" {
+		if opts.verbose {
+			stderr.verbosef("skipping (%s): %s", why, path)
+		}
+		return false
+	}
+	if recentlyCreated(path, opts) {
+		if opts.verbose {
+			stderr.verbosef("skipping (created within %d days): %s", opts.minCreationAge, path)
+		}
+		return false
+	}
+	// Dating a candidate can walk it as fully as sizing does; one under
+	// -min-size is dropped once sized, so say so first with a walk that
+	// stops early. Venvs are dated regardless: a fresh one is still a
+	// sibling for markSiblingVenvs.
+	if typeName != "venv" && belowMinSize(path, opts.minSize) {
+		if opts.verbose {
+			stderr.verbosef("skipping (under -min-size): %s", path)
+		}
+		return false
+	}
+
+	lastUsed, found := s.usageFor(typeName, usage)(path)
+	var notes []string
+	if t, how, ok := s.activity.lastUsed(path, typeName); ok && t.After(lastUsed) {
+		lastUsed, found = t, true
+		notes = append(notes, "last used per the shell hook: "+how)
+	}
+	if !found {
+		return false
+	}
+
+	if opts.trustCreationTime {
+		var note string
+		if lastUsed, note = adjustForRestoredTree(path, lastUsed); note != "" {
+			notes = append(notes, note)
+		}
+	}
+
+	if typeName == "venv" {
+		project := venvProject(path)
+		sib := s.venvs[project]
+		if sib == nil {
+			sib = &venvSiblings{}
+			s.venvs[project] = sib
+		}
+		if sib.count++; sib.newest == "" || lastUsed.After(sib.lastUsed) {
+			sib.newest, sib.lastUsed = path, lastUsed
+		}
+		s.noteEditables(path)
+	}
+
+	age := opts.ageDays(lastUsed)
+	if why, ok := s.retired[path]; ok {
+		notes = append(notes, why)
+	} else if age < float64(opts.minAgeFor(typeName, path)) {
+		return false
+	}
 
-	wg.Add(1)
-	go func(p string, lu time.Time, ad float64) {
-		defer wg.Done()
-		sz := dirSize(p)
+	s.wg.Add(1)
+	s.mu.Lock()
+	s.sizing++
+	s.mu.Unlock()
+	go func(p, root string, lu time.Time, ad float64) {
+		defer s.wg.Done()
+		defer func() {
+			s.mu.Lock()
+			s.sizing--
+			s.mu.Unlock()
+		}()
+		// Real time, not opts.currentTime: this is about activity right now.
+		tree := inspectTree(p, time.Now())
+		if tree.busy != "" {
+			if client := syncClientFor(p); client != "" {
+				tree.busy += " (inside " + client + " folder)"
+			}
+			s.mu.Lock()
+			s.deferred = append(s.deferred, fmt.Sprintf("deferred %s until a later run: %s", p, tree.busy))
+			s.mu.Unlock()
+			return
+		}
+		sz, disk := tree.apparent, tree.disk
 		if sz < opts.minSize {
 			return
 		}
-		mu.Lock()
-		*records = append(*records, Record{
-			Type:      typeName,
-			Path:      p,
-			Size:      sz,
-			SizeHuman: formatBytes(sz),
-			LastUsed:  lu.Format("2006-01-02"),
-			AgeDays:   ad,
-		})
-		mu.Unlock()
+		rec := Record{
+			Type:            typeName,
+			Path:            p,
+			Size:            sz,
+			SizeHuman:       formatBytes(sz),
+			DiskSize:        disk,
+			DiskHuman:       formatBytes(disk),
+			LastUsed:        lu.Truncate(time.Second).Format(time.RFC3339),
+			LastUsedDisplay: lu.Format("2006-01-02"),
+			AgeDays:         ad,
+			Notes:           notes,
+			root:            root,
+		}
+		if info, err := os.Lstat(p); err == nil && info.Mode().IsRegular() {
+			rec.File = true
+		}
+		rec.Project = projectName(projectDir(p))
+		if annotate := annotators[typeName]; annotate != nil {
+			annotate(&rec)
+		}
+		ownerDir := filepath.Dir(p)
+		if typeName == "stale_repo" {
+			ownerDir = p // the clone is its own checkout
+		}
+		owner := s.evidence.repoOwner(ownerDir)
+		rec.Owner, rec.Upstream = owner.owner, owner.upstream
+		// A stale clone is report-only, and its environments items of their own.
+		if why := nestedEnvReason(tree.envs, opts); why != "" && rec.Review == "" && typeName != "stale_repo" {
+			rec.Review = why
+		}
+		scoreConfidence(&rec, opts, &s.evidence)
+		if rec.Confidence < opts.minConfidence {
+			return
+		}
+		s.mu.Lock()
+		s.add(rec)
+		s.mu.Unlock()
 		if opts.verbose {
-			mu.Lock()
-			*scanned++
-			fmt.Fprintf(os.Stderr, "  found %d stale items so far...\r", *scanned)
-			mu.Unlock()
+			s.mu.Lock()
+			s.scanned++
+			stderr.status("  found %d stale items so far...", s.scanned)
+			s.mu.Unlock()
 		}
-	}(path, lastUsed, age)
+	}(path, s.root, lastUsed, age)
+	return true
+}
+
+// full reports whether -limit items have been found, so the scan can stop.
+// Items still being sized may yet be dropped, so once they could make up
+// the limit it waits for them rather than stop on a guess. Called only
+// from scanRoots' goroutine, the one that starts sizing.
+func (s *scanner) full() bool {
+	if s.opts.limit == 0 {
+		return false
+	}
+	s.mu.Lock()
+	n := s.found() + s.sizing
+	s.mu.Unlock()
+	if n < s.opts.limit {
+		return false
+	}
+	s.wg.Wait()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.found() >= s.opts.limit
 }
 
 // scanRoots walks all root directories and returns matching Records.
 func scanRoots(roots []string, opts *options) ([]Record, []string) {
-	var records []Record
-	var mu sync.Mutex
-	var wg sync.WaitGroup
+	s := newScanner(opts)
+	scanErrors := s.scan(roots)
+	for i := range s.records {
+		s.finish(&s.records[i])
+	}
+	// Sizing goroutines append in whatever order they finish.
+	sortRecords(s.records, "path")
+	return s.records, scanErrors
+}
+
+func newScanner(opts *options) *scanner {
+	return &scanner{opts: opts, venvs: map[string]*venvSiblings{}, own: ownStateDirs()}
+}
+
+// scan walks the roots, keeping what it finds in s.records (or s.spool)
+// for finish, and returns the scan's errors.
+func (s *scanner) scan(roots []string) []string {
+	opts := s.opts
 	var scanErrors []string
-	var scanned int64
+	rules, err := loadHeuristics()
+	if err != nil {
+		scanErrors = append(scanErrors, fmt.Sprintf("ignoring heuristics configuration: %v", err))
+	}
+	s.rules = rules
+	if s.exclusions, err = loadExclusions(); err != nil {
+		scanErrors = append(scanErrors, fmt.Sprintf("ignoring exclusions configuration: %v", err))
+	}
+	if s.tags, err = loadTags(); err != nil {
+		scanErrors = append(scanErrors, fmt.Sprintf("ignoring tags configuration: %v", err))
+	}
+	if s.activity, err = loadActivity(); err != nil {
+		scanErrors = append(scanErrors, fmt.Sprintf("ignoring the shell activity log: %v", err))
+	}
 
 	// Map directory names to their scan type keys and skip behavior.
 	// If we're scanning for the type, detect+dispatch. Otherwise, skip.
@@ -223,9 +740,39 @@ func scanRoots(roots []string, opts *options) ([]Record, []string) {
 			scanErrors = append(scanErrors, fmt.Sprintf("path not accessible %q: %v", absRoot, err))
 			continue
 		}
+		s.root = absRoot
 
-		_ = filepath.WalkDir(absRoot, func(path string, d fs.DirEntry, err error) error {
-			if err != nil || !d.IsDir() {
+		_ = walkTree(absRoot, func(path string, d fs.DirEntry, err error) error {
+			pace()
+			if err != nil {
+				return nil
+			}
+			if s.full() {
+				return filepath.SkipAll
+			}
+
+			// File-level types: LaTeX aux files sit beside their .tex source,
+			// and VM disk images and crash debris anywhere, not in a
+			// directory of their own.
+			if !d.IsDir() {
+				if matchesExclude(path, opts.excludePatterns) {
+					return nil
+				}
+				if opts.scanTypes["latex"] && latexSource(path) != "" {
+					s.dispatch(path, "latex", getLatexUsage)
+				}
+				if opts.scanTypes["vm_image"] && isVMImageFile(path) {
+					s.dispatch(path, "vm_image", getVMUsage)
+				}
+				if opts.scanTypes["crash"] && crashKind(path, d) != "" {
+					s.dispatch(path, "crash", getCacheUsage)
+				}
+				if opts.scanTypes["wheel"] && isStrayArtifact(path, d) {
+					s.dispatch(path, "wheel", getCacheUsage)
+				}
+				if opts.scanTypes["venv"] && d.Name() == "pyproject.toml" {
+					s.notePoetryProject(filepath.Dir(path))
+				}
 				return nil
 			}
 
@@ -238,9 +785,20 @@ func scanRoots(roots []string, opts *options) ([]Record, []string) {
 				}
 			}
 
-			// Always skip these.
-			switch d.Name() {
-			case ".git", "Library", ".Trash":
+			// Unity's Library/ is an import cache, unlike every other Library.
+			if d.Name() == "Library" && opts.scanTypes["unity"] && isUnityProject(filepath.Dir(path)) {
+				s.dispatch(path, "unity", getCacheUsage)
+				return filepath.SkipDir
+			}
+
+			// Always skip these (see defaultSkipDirs), and tidyup's own state.
+			if alwaysSkipped(path, d.Name(), opts) {
+				return filepath.SkipDir
+			}
+			if insideOwnState(path, s.own) {
+				if opts.verbose {
+					stderr.verbosef("skipping tidyup's own state: %s", path)
+				}
 				return filepath.SkipDir
 			}
 
@@ -251,6 +809,24 @@ func scanRoots(roots []string, opts *options) ([]Record, []string) {
 
 			name := d.Name()
 
+			// Whole clones -- report only. Keep walking: the artifacts inside
+			// are items of their own.
+			if opts.scanTypes["stale_repo"] && isGitClone(path) {
+				s.dispatch(path, "stale_repo", getRepoUsage)
+			}
+
+			// Whole VMs (VirtualBox folders, UTM bundles) -- report only.
+			if opts.scanTypes["vm_image"] && isVMBundle(path) {
+				s.dispatch(path, "vm_image", getVMUsage)
+				return filepath.SkipDir
+			}
+
+			// Database data directories -- report only, see dbdata.go.
+			if opts.scanTypes["db_data"] && dbDataKind(path) != "" {
+				s.dispatch(path, "db_data", getCacheUsage)
+				return filepath.SkipDir
+			}
+
 			// Unified name-based detection and skip logic.
 			if typeKey, ok := skipUnlessScanning[name]; ok {
 				if opts.scanTypes[typeKey] {
@@ -261,7 +837,7 @@ func scanRoots(roots []string, opts *options) ([]Record, []string) {
 					default:
 						fn = getCacheUsage
 					}
-					dispatchRecord(path, typeKey, fn, opts, &wg, &mu, &records, &scanned)
+					s.dispatch(path, typeKey, fn)
 				}
 				return filepath.SkipDir
 			}
@@ -269,68 +845,124 @@ func scanRoots(roots []string, opts *options) ([]Record, []string) {
 			// dist/ and build/ -- require parent validation.
 			if name == "dist" {
 				if opts.scanTypes["dist"] && hasBuildParent(path) {
-					dispatchRecord(path, "dist", getBuildUsage, opts, &wg, &mu, &records, &scanned)
-					return filepath.SkipDir
+					if opts.keepBuilds > 0 && s.keepNewestBuilds(path, "dist") {
+						return filepath.SkipDir
+					}
+					// A dist/ still in use can hold old releases; walk it
+					// for those when scanning for wheel.
+					if s.dispatch(path, "dist", getBuildUsage) || !opts.scanTypes["wheel"] {
+						return filepath.SkipDir
+					}
 				}
 				// Don't skip -- could be a normal directory.
 			}
+			if name == "build" && opts.scanTypes["gradle"] && isGradleBuild(path) {
+				s.dispatch(path, "gradle", getJVMBuildUsage)
+				return filepath.SkipDir
+			}
 			if name == "build" {
 				if opts.scanTypes["build"] && hasBuildParent(path) {
-					dispatchRecord(path, "build", getBuildUsage, opts, &wg, &mu, &records, &scanned)
+					if opts.keepBuilds > 0 && s.keepNewestBuilds(path, "build") {
+						return filepath.SkipDir
+					}
+					s.dispatch(path, "build", getBuildUsage)
 					return filepath.SkipDir
 				}
 			}
 
-			// Content-based detection: venv (needs file check).
-			if opts.scanTypes["venv"] && isVenv(path) {
-				if !isValidVenv(path) {
-					if opts.verbose {
-						fmt.Fprintf(os.Stderr, "  skipping (invalid venv, no bin/Scripts): %s\n", path)
-					}
+			// target/ -- only beside a Cargo.toml or a pom.xml.
+			if name == "target" && opts.scanTypes["cargo"] && isCargoTarget(path) {
+				s.dispatch(path, "cargo", getCargoUsage)
+				return filepath.SkipDir
+			}
+			if name == "target" && opts.scanTypes["maven"] && isMavenTarget(path) {
+				s.dispatch(path, "maven", getJVMBuildUsage)
+				return filepath.SkipDir
+			}
+
+			// renv/library -- only in a project with renv.lock to restore from.
+			if name == "library" && opts.scanTypes["renv"] && isRenvLibrary(path) {
+				s.dispatch(path, "renv", getCacheUsage)
+				return filepath.SkipDir
+			}
+
+			// _minted-<job>/ -- the minted package's highlighting cache.
+			if strings.HasPrefix(name, "_minted-") && opts.scanTypes["latex"] && latexSource(path) != "" {
+				s.dispatch(path, "latex", getLatexUsage)
+				return filepath.SkipDir
+			}
+
+			// .tox/ and .nox/ -- each environment inside is an item. When not
+			// scanning for tox or nox, keep walking so they are still found
+			// as venvs.
+			if name == ".tox" && opts.scanTypes["tox"] {
+				for _, env := range toxEnvs(path) {
+					s.dispatch(env, "tox", getToxUsage)
+				}
+				return filepath.SkipDir
+			}
+			if name == ".nox" && opts.scanTypes["nox"] {
+				for _, env := range toxEnvs(path) {
+					s.dispatch(env, "nox", getVenvActivity)
+				}
+				return filepath.SkipDir
+			}
+
+			// .direnv/ -- only with a .envrc beside it. When not scanning for
+			// direnv, keep walking so the venvs inside are still found.
+			if name == ".direnv" && opts.scanTypes["direnv"] {
+				if _, err := os.Stat(filepath.Join(filepath.Dir(path), ".envrc")); err == nil {
+					s.dispatch(path, "direnv", getCacheUsage)
 					return filepath.SkipDir
 				}
+			}
 
-				lastUsed, found := getVenvUsage(path)
-				if !found {
+			// Content-based detection: conda and venv (need file checks).
+			if !opts.scanTypes["venv"] && !opts.scanTypes["tools"] && !opts.scanTypes["conda"] {
+				return nil
+			}
+			kind := venvKind(path)
+			if kind == "conda" {
+				if !opts.scanTypes["conda"] {
+					return filepath.SkipDir
+				}
+				if isCondaBase(path) {
+					for _, env := range condaEnvs(path) {
+						s.dispatch(env, "conda", getVenvActivity)
+					}
+				} else if isValidVenv(path) {
+					s.dispatch(path, "conda", getVenvActivity)
+				}
+				return filepath.SkipDir
+			}
+			named := opts.venvNames[name] && hasInterpreter(path)
+			if (opts.scanTypes["venv"] || opts.scanTypes["tools"]) && (named || kind != "") {
+				// pipx/uv tool environments are tools, not project venvs.
+				if toolManager(path) != "" {
+					if opts.scanTypes["tools"] {
+						s.dispatch(path, "tools", getToolUsage)
+					}
+					return filepath.SkipDir
+				}
+				if !opts.scanTypes["venv"] {
+					return filepath.SkipDir
+				}
+				if !named && !isValidVenv(path) {
 					if opts.verbose {
-						fmt.Fprintf(os.Stderr, "  skipping (no markers): %s\n", path)
+						stderr.verbosef("skipping (invalid venv, no bin/Scripts): %s", path)
 					}
+					stderr.note("invalid_venv", path, "skipping (invalid venv, no bin/Scripts): %s", path)
 					return filepath.SkipDir
 				}
 
-				// Check site-packages for a more recent usage signal.
-				if spTime, ok := getSitePackagesUsage(path); ok && spTime.After(lastUsed) {
-					lastUsed = spTime
+				if _, found := getVenvUsage(path); !found {
+					if opts.verbose {
+						stderr.verbosef("skipping (no markers): %s", path)
+					}
+					return filepath.SkipDir
 				}
 
-				age := time.Since(lastUsed).Hours() / 24
-
-				if age >= float64(opts.minAge) {
-					wg.Add(1)
-					go func(p string, lu time.Time, ad float64) {
-						defer wg.Done()
-						sz := dirSize(p)
-						if sz < opts.minSize {
-							return
-						}
-						mu.Lock()
-						records = append(records, Record{
-							Type:      "venv",
-							Path:      p,
-							Size:      sz,
-							SizeHuman: formatBytes(sz),
-							LastUsed:  lu.Format("2006-01-02"),
-							AgeDays:   ad,
-						})
-						mu.Unlock()
-						if opts.verbose {
-							mu.Lock()
-							scanned++
-							fmt.Fprintf(os.Stderr, "  found %d stale items so far...\r", scanned)
-							mu.Unlock()
-						}
-					}(path, lastUsed, age)
-				}
+				s.dispatch(path, "venv", getVenvActivity)
 				return filepath.SkipDir
 			}
 
@@ -338,6 +970,69 @@ func scanRoots(roots []string, opts *options) ([]Record, []string) {
 		})
 	}
 
-	wg.Wait()
-	return records, scanErrors
+	if opts.systemScan {
+		if home, err := os.UserHomeDir(); err == nil {
+			s.root = home
+			for _, loc := range homeLocations {
+				if opts.scanTypes[loc.typeName] {
+					for _, p := range loc.find(home) {
+						s.dispatch(p, loc.typeName, loc.usage)
+					}
+				}
+			}
+			if opts.scanTypes["nix"] {
+				s.scanNixProfiles(home)
+			}
+		}
+	}
+
+	// Opt-in: capture folders are scanned whenever media is asked for.
+	if opts.scanTypes["media"] {
+		if home, err := os.UserHomeDir(); err == nil {
+			s.root = home
+			for _, p := range findMedia(home, opts.mediaDirs) {
+				s.dispatch(p, "media", getCacheUsage)
+			}
+		}
+	}
+
+	// Opt-in: the temp directories are scanned whenever tmp is asked for.
+	if opts.scanTypes["tmp"] {
+		for _, root := range tmpRoots() {
+			s.root = root
+			for _, p := range findTmpEntries(root) {
+				s.dispatch(p, "tmp", getCacheUsage)
+			}
+		}
+	}
+
+	// Opt-in: the Docker daemon is asked whenever docker is asked for.
+	if opts.scanTypes["docker"] {
+		home, _ := os.UserHomeDir()
+		if err := s.scanDocker(home); err != nil {
+			scanErrors = append(scanErrors, err.Error())
+		}
+	}
+
+	// Explicit only: never part of -all or -system.
+	if opts.scanTypes["library_cache"] {
+		if home, err := os.UserHomeDir(); err == nil {
+			s.root = home
+			for _, p := range findLibraryCaches(home) {
+				// app_leftovers reports orphaned caches itself.
+				if opts.systemScan && opts.scanTypes["app_leftovers"] && isOrphanedBundle(filepath.Base(p)) {
+					continue
+				}
+				s.dispatch(p, "library_cache", getCacheUsage)
+			}
+		}
+	}
+
+	s.wg.Wait()
+	stderr.endStatus()
+	if opts.verbose && s.full() {
+		stderr.verbosef("stopped scanning at -limit %d items", opts.limit)
+	}
+	sort.Strings(s.deferred)
+	return append(scanErrors, s.deferred...)
 }
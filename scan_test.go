@@ -1,8 +1,12 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 )
@@ -30,6 +34,16 @@ func TestIsActiveVenv_Unset(t *testing.T) {
 	}
 }
 
+func TestContainsActiveVenv(t *testing.T) {
+	t.Setenv("VIRTUAL_ENV", "/home/user/project/.direnv/python-3.11")
+	if !containsActiveVenv("/home/user/project/.direnv") {
+		t.Error("expected a match for a parent of the active venv")
+	}
+	if containsActiveVenv("/home/user/project/.direnv/python-3.11") || containsActiveVenv("/home/user/project/.dir") {
+		t.Error("expected no match for the venv itself or a name prefix")
+	}
+}
+
 func TestIsProtectedPath_System(t *testing.T) {
 	tests := []struct {
 		path string
@@ -75,6 +89,35 @@ func TestIsProtectedPath_HomeAncestor(t *testing.T) {
 	}
 }
 
+func TestMountConflict(t *testing.T) {
+	dir := t.TempDir()
+	build := filepath.Join(dir, "build")
+	os.MkdirAll(filepath.Join(build, "img"), 0755)
+	mounts := []string{"/", filepath.Join(build, "img")}
+
+	if why := mountConflict(build, mounts); !strings.Contains(why, "contains the mount point") {
+		t.Errorf("nested mount: %q", why)
+	}
+	if why := mountConflict(filepath.Join(build, "img"), mounts); why != "it is a mount point" {
+		t.Errorf("mount point itself: %q", why)
+	}
+	if why := mountConflict(filepath.Join(dir, "buil"), mounts); why != "" {
+		t.Errorf("sibling prefix: %q", why)
+	}
+}
+
+func TestFilterSafeRecords_SkipsMounts(t *testing.T) {
+	saved := mountTableFunc
+	t.Cleanup(func() { mountTableFunc = saved })
+	mountTableFunc = func() ([]string, bool) { return []string{"/srv/p/a/mnt"}, true }
+
+	records := []Record{{Path: "/srv/p/a"}, {Path: "/srv/p/b"}}
+	safe, skipped := filterSafeRecords(records)
+	if len(safe) != 1 || safe[0].Path != records[1].Path || skipped[ruleMount] != 1 {
+		t.Errorf("safe = %+v, skipped = %v", safe, skipped)
+	}
+}
+
 func TestIsValidVenv_OnlyPyvenvCfg(t *testing.T) {
 	dir := t.TempDir()
 	os.WriteFile(filepath.Join(dir, "pyvenv.cfg"), []byte("home = /usr/bin\n"), 0644)
@@ -128,6 +171,42 @@ func TestGetSitePackagesUsage(t *testing.T) {
 	}
 }
 
+func TestGetSitePackagesUsage_OtherLayouts(t *testing.T) {
+	for _, layout := range []string{
+		"Lib/site-packages",            // Windows CPython and conda
+		"lib/pypy3.10/site-packages",   // PyPy 3.8+
+		"site-packages",                // older PyPy
+		"lib/python3.12/site-packages", // conda on Unix
+	} {
+		dir := t.TempDir()
+		pkg := filepath.Join(dir, filepath.FromSlash(layout), "somepkg")
+		os.MkdirAll(pkg, 0755)
+		f := filepath.Join(pkg, "__init__.py")
+		os.WriteFile(f, []byte("# test"), 0644)
+		target := time.Now().Add(-72 * time.Hour).Truncate(time.Second)
+		os.Chtimes(f, target, target)
+
+		got, ok := getSitePackagesUsage(dir)
+		if !ok || got.Sub(target).Abs() > time.Second {
+			t.Errorf("%s: got %v (found=%v), want ~%v", layout, got, ok, target)
+		}
+	}
+}
+
+func TestGetVenvUsage_WindowsLayoutOnAnyOS(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "Scripts"), 0755)
+	f := filepath.Join(dir, "Scripts", "activate")
+	os.WriteFile(f, []byte("x"), 0644)
+	target := time.Now().Add(-96 * time.Hour).Truncate(time.Second)
+	os.Chtimes(f, target, target)
+
+	got, ok := getVenvUsage(dir)
+	if !ok || !got.Equal(target) {
+		t.Errorf("got %v (found=%v), want %v", got, ok, target)
+	}
+}
+
 func TestGetSitePackagesUsage_NoSitePackages(t *testing.T) {
 	dir := t.TempDir()
 	_, ok := getSitePackagesUsage(dir)
@@ -272,3 +351,362 @@ func TestHasBuildParent_NoBuildFiles(t *testing.T) {
 		t.Error("expected hasBuildParent=false with no build files in parent")
 	}
 }
+
+// --- Clock tests ---
+
+// makeVenv creates a minimal valid venv whose markers all have the given mtime.
+func makeVenv(t *testing.T, dir string, mtime time.Time) {
+	t.Helper()
+	os.MkdirAll(filepath.Join(dir, "bin"), 0755)
+	for _, f := range []string{"pyvenv.cfg", "bin/activate", "bin/python"} {
+		p := filepath.Join(dir, f)
+		os.WriteFile(p, []byte("x"), 0644)
+		os.Chtimes(p, mtime, mtime)
+	}
+}
+
+func TestScanRoots_FakeClock(t *testing.T) {
+	root := t.TempDir()
+	lastUsed := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	makeVenv(t, filepath.Join(root, ".venv"), lastUsed)
+
+	opts := &options{
+		minAge:    30,
+		maxDepth:  5,
+		scanTypes: map[string]bool{"venv": true},
+		now:       func() time.Time { return lastUsed.AddDate(0, 0, 10) },
+	}
+	if records, _ := scanRoots([]string{root}, opts); len(records) != 0 {
+		t.Fatalf("expected no records 10 days after last use, got %d", len(records))
+	}
+
+	opts.now = func() time.Time { return lastUsed.AddDate(0, 0, 45) }
+	records, _ := scanRoots([]string{root}, opts)
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record 45 days after last use, got %d", len(records))
+	}
+	if records[0].AgeDays != 45 {
+		t.Errorf("AgeDays = %v, want 45", records[0].AgeDays)
+	}
+}
+
+func TestScanRoots_AsOfOverridesClock(t *testing.T) {
+	root := t.TempDir()
+	lastUsed := time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local)
+	makeVenv(t, filepath.Join(root, ".venv"), lastUsed)
+
+	opts := &options{
+		minAge:    30,
+		maxDepth:  5,
+		scanTypes: map[string]bool{"venv": true},
+		now:       func() time.Time { return lastUsed },
+		asOf:      time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local),
+	}
+	if records, _ := scanRoots([]string{root}, opts); len(records) != 1 {
+		t.Fatalf("expected -as-of to flag the venv, got %d records", len(records))
+	}
+}
+
+// --- Sizing tests ---
+
+func TestDirSize_HardLinksCountedOnceOnDisk(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("disk accounting falls back to apparent size on Windows")
+	}
+	dir := t.TempDir()
+	f := filepath.Join(dir, "a.bin")
+	os.WriteFile(f, make([]byte, 64<<10), 0644)
+	if err := os.Link(f, filepath.Join(dir, "b.bin")); err != nil {
+		t.Skipf("hard links unsupported: %v", err)
+	}
+
+	apparent, disk := dirSize(dir)
+	if apparent != 128<<10 {
+		t.Errorf("apparent = %d, want %d (both links)", apparent, 128<<10)
+	}
+	if disk >= apparent {
+		t.Errorf("disk = %d, want less than apparent %d (link counted once)", disk, apparent)
+	}
+}
+
+func TestScanRoots_MinCreationAge(t *testing.T) {
+	root := t.TempDir()
+	venv := filepath.Join(root, ".venv")
+	// Markers look 100 days old, as after restoring from a backup.
+	makeVenv(t, venv, time.Now().AddDate(0, 0, -100))
+	if _, ok := birthTime(venv); !ok {
+		t.Skip("birth time not available on this filesystem")
+	}
+
+	opts := &options{minAge: 30, maxDepth: 5, scanTypes: map[string]bool{"venv": true}}
+	if records, _ := scanRoots([]string{root}, opts); len(records) != 1 {
+		t.Fatalf("without grace period: got %d records, want 1", len(records))
+	}
+
+	opts.minCreationAge = 7
+	if records, _ := scanRoots([]string{root}, opts); len(records) != 0 {
+		t.Errorf("venv created just now was flagged despite -min-creation-age 7")
+	}
+}
+
+func TestAdjustForRestoredTree(t *testing.T) {
+	dir := t.TempDir()
+	created, ok := birthTime(dir)
+	if !ok {
+		if created, ok = changeTime(dir); !ok {
+			t.Skip("neither birth nor change time available")
+		}
+	}
+
+	old := time.Now().AddDate(-2, 0, 0)
+	got, note := adjustForRestoredTree(dir, old)
+	if note == "" {
+		t.Fatal("expected a restored-tree note for markers two years older than the directory")
+	}
+	if !got.Equal(created) {
+		t.Errorf("got %v, want creation/change time %v", got, created)
+	}
+
+	// Markers newer than the directory are left alone.
+	recent := time.Now()
+	if got, note := adjustForRestoredTree(dir, recent); note != "" || !got.Equal(recent) {
+		t.Errorf("fresh markers adjusted: %v, %q", got, note)
+	}
+}
+
+func TestScanRoots_TrustCreationTime(t *testing.T) {
+	root := t.TempDir()
+	makeVenv(t, filepath.Join(root, ".venv"), time.Now().AddDate(0, 0, -200))
+
+	opts := &options{minAge: 30, maxDepth: 5, scanTypes: map[string]bool{"venv": true}}
+	if records, _ := scanRoots([]string{root}, opts); len(records) != 1 {
+		t.Fatalf("by mtime alone: got %d records, want 1", len(records))
+	}
+	opts.trustCreationTime = true
+	if records, _ := scanRoots([]string{root}, opts); len(records) != 0 {
+		if _, ok := birthTime(root); ok {
+			t.Errorf("venv created just now flagged despite -trust-creation-time")
+		}
+	}
+}
+
+func TestVenvKind(t *testing.T) {
+	root := t.TempDir()
+	mk := func(dir string, files ...string) string {
+		p := filepath.Join(root, dir)
+		for _, f := range files {
+			os.MkdirAll(filepath.Dir(filepath.Join(p, f)), 0755)
+			os.WriteFile(filepath.Join(p, f), []byte("x"), 0644)
+		}
+		return p
+	}
+	tests := []struct {
+		dir  string
+		want string
+	}{
+		{mk("env", "pyvenv.cfg", "bin/python"), "pyvenv"},
+		{mk("envs/ml", "conda-meta/history", "bin/python"), "conda"},
+		{mk("old-venv", "bin/activate", "lib/python2.7/site-packages/six.py"), "virtualenv"},
+		{mk("win-venv", "Scripts/activate", "Lib/site-packages/six.py"), "virtualenv"},
+		{mk("tools", "bin/activate"), ""},
+	}
+	for _, tt := range tests {
+		if got := venvKind(tt.dir); got != tt.want {
+			t.Errorf("venvKind(%s) = %q, want %q", filepath.Base(tt.dir), got, tt.want)
+		}
+	}
+}
+
+func TestScanRoots_VenvNames(t *testing.T) {
+	root := t.TempDir()
+	env := filepath.Join(root, "proj", "pyenv-local")
+	os.MkdirAll(filepath.Join(env, "bin"), 0755)
+	old := time.Now().AddDate(0, 0, -90)
+	p := filepath.Join(env, "bin", "python")
+	os.WriteFile(p, []byte("x"), 0755)
+	os.Chtimes(p, old, old)
+
+	opts := &options{minAge: 30, maxDepth: 5, scanTypes: map[string]bool{"venv": true}}
+	if records, _ := scanRoots([]string{root}, opts); len(records) != 0 {
+		t.Fatalf("without -venv-names: got %d records, want 0", len(records))
+	}
+	opts.venvNames = map[string]bool{"pyenv-local": true}
+	records, _ := scanRoots([]string{root}, opts)
+	if len(records) != 1 || records[0].Path != env {
+		t.Errorf("with -venv-names: got %+v, want %s", records, env)
+	}
+}
+
+func TestScanRoots_SiblingVenvs(t *testing.T) {
+	root := t.TempDir()
+	proj := filepath.Join(root, "proj")
+	now := time.Now()
+	makeVenv(t, filepath.Join(proj, ".venv"), now.AddDate(0, 0, -2))
+	makeVenv(t, filepath.Join(proj, "venv"), now.AddDate(0, 0, -200))
+	makeVenv(t, filepath.Join(proj, ".tox", "py311"), now.AddDate(0, 0, -90))
+	makeVenv(t, filepath.Join(root, "solo", ".venv"), now.AddDate(0, 0, -90))
+
+	opts := &options{minAge: 30, maxDepth: 5, scanTypes: map[string]bool{"venv": true}}
+	records, _ := scanRoots([]string{root}, opts)
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want 3", len(records))
+	}
+	for _, r := range records {
+		want := filepath.Join(proj, ".venv")
+		if strings.HasPrefix(r.Path, filepath.Join(root, "solo")) {
+			want = ""
+		}
+		if r.NewestSibling != want {
+			t.Errorf("%s: NewestSibling = %q, want %q", r.Path, r.NewestSibling, want)
+		}
+	}
+}
+
+func TestScanRoots_Direnv(t *testing.T) {
+	root := t.TempDir()
+	proj := filepath.Join(root, "proj")
+	old := time.Now().AddDate(0, 0, -90)
+	venv := filepath.Join(proj, ".direnv", "python-3.11")
+	makeVenv(t, venv, old)
+	rc := filepath.Join(proj, ".direnv", "flake-profile-a5d5b61a.rc")
+	os.WriteFile(rc, []byte("export PATH=..."), 0644)
+	os.Chtimes(rc, old, old)
+	os.WriteFile(filepath.Join(proj, ".envrc"), []byte("layout python\n"), 0644)
+
+	// Without the direnv type, the layout venv is still found on its own.
+	opts := &options{minAge: 30, maxDepth: 5, scanTypes: map[string]bool{"venv": true}}
+	if records, _ := scanRoots([]string{root}, opts); len(records) != 1 || records[0].Path != venv {
+		t.Fatalf("venv only: got %+v", records)
+	}
+
+	opts.scanTypes = map[string]bool{"venv": true, "direnv": true}
+	records, _ := scanRoots([]string{root}, opts)
+	if len(records) != 1 || records[0].Type != "direnv" {
+		t.Fatalf("direnv: got %+v, want the whole .direnv", records)
+	}
+	if !strings.Contains(strings.Join(records[0].Notes, ";"), "nix-direnv GC roots") {
+		t.Errorf("notes = %v, want GC root hint", records[0].Notes)
+	}
+}
+
+func TestScanRoots_NestedFreshEnv(t *testing.T) {
+	root := t.TempDir()
+	proj := filepath.Join(root, "proj")
+	old := time.Now().AddDate(0, 0, -90)
+	venv := filepath.Join(proj, ".direnv", "python-3.11")
+	makeVenv(t, venv, old)
+	os.WriteFile(filepath.Join(proj, ".envrc"), []byte("layout python\n"), 0644)
+	opts := &options{minAge: 30, maxDepth: 5, scanTypes: map[string]bool{"direnv": true}}
+
+	records, _ := scanRoots([]string{root}, opts)
+	if len(records) != 1 || records[0].Review != "" {
+		t.Fatalf("stale venv inside: got %+v", records)
+	}
+
+	// The wrapper is judged on its own, but the active venv inside keeps it.
+	t.Setenv("VIRTUAL_ENV", venv)
+	records, _ = scanRoots([]string{root}, opts)
+	if len(records) != 1 || !strings.Contains(records[0].Review, "active venv") {
+		t.Fatalf("active venv inside: got %+v", records)
+	}
+}
+
+func TestNestedEnvReason(t *testing.T) {
+	dir := t.TempDir()
+	fresh, stale := filepath.Join(dir, "fresh"), filepath.Join(dir, "stale")
+	makeVenv(t, fresh, time.Now().AddDate(0, 0, -2))
+	makeVenv(t, stale, time.Now().AddDate(0, 0, -90))
+	opts := &options{minAge: 30}
+
+	if why := nestedEnvReason([]string{stale}, opts); why != "" {
+		t.Errorf("stale env: got %q", why)
+	}
+	if why := nestedEnvReason([]string{stale, fresh}, opts); !strings.Contains(why, fresh) {
+		t.Errorf("fresh env: got %q", why)
+	}
+	if tree := inspectTree(dir, time.Now()); len(tree.envs) != 2 {
+		t.Errorf("inspectTree envs = %v, want both venvs", tree.envs)
+	}
+	if tree := inspectTree(fresh, time.Now()); len(tree.envs) != 0 {
+		t.Errorf("a venv is not nested in itself: %v", tree.envs)
+	}
+}
+
+func TestScanRoots_SkipList(t *testing.T) {
+	root := t.TempDir()
+	old := time.Now().AddDate(0, 0, -90)
+	cached := filepath.Join(root, "Library", "Caches", "tool", ".venv")
+	mail := filepath.Join(root, "Library", "Mail", "tool", ".venv")
+	synced := filepath.Join(root, "CorpSync", "proj", ".venv")
+	for _, v := range []string{cached, mail, synced} {
+		makeVenv(t, v, old)
+	}
+	paths := func(opts *options) []string {
+		records, _ := scanRoots([]string{root}, opts)
+		var got []string
+		for _, r := range records {
+			got = append(got, r.Path)
+		}
+		sort.Strings(got)
+		return got
+	}
+
+	opts := &options{minAge: 30, maxDepth: 6, scanTypes: map[string]bool{"venv": true}}
+	if got := paths(opts); len(got) != 1 || got[0] != synced {
+		t.Errorf("defaults: got %v, want only %s", got, synced)
+	}
+	opts.skipDirs = map[string]bool{"CorpSync": true}
+	opts.noSkipDirs = map[string]bool{"Library": true}
+	if got := paths(opts); len(got) != 1 || got[0] != cached {
+		t.Errorf("-skip CorpSync -no-skip Library: got %v, want only %s (Mail stays skipped)", got, cached)
+	}
+}
+
+func TestScanRoots_Limit(t *testing.T) {
+	root := t.TempDir()
+	old := time.Now().AddDate(0, 0, -90)
+	for _, name := range []string{"a", "b", "c", "d"} {
+		makeVenv(t, filepath.Join(root, name, ".venv"), old)
+	}
+	small := filepath.Join(root, "0", ".venv")
+	makeVenv(t, small, old)
+
+	opts := &options{minAge: 30, maxDepth: 5, scanTypes: map[string]bool{"venv": true}, limit: 2}
+	if records, _ := scanRoots([]string{root}, opts); len(records) != 2 {
+		t.Errorf("-limit 2: got %d records", len(records))
+	}
+
+	// Items dropped once sized do not count towards the limit.
+	os.WriteFile(filepath.Join(root, "a", ".venv", "big"), make([]byte, 4096), 0644)
+	os.Chtimes(filepath.Join(root, "a", ".venv", "big"), old, old)
+	opts.limit, opts.minSize = 1, 1000
+	records, _ := scanRoots([]string{root}, opts)
+	if len(records) != 1 || records[0].Path != filepath.Join(root, "a", ".venv") {
+		t.Errorf("-first-match with -min-size: got %+v, want only the big venv", records)
+	}
+}
+
+func TestBelowMinSize(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.pyc"), make([]byte, 600), 0644)
+	os.MkdirAll(filepath.Join(dir, "sub"), 0755)
+	os.WriteFile(filepath.Join(dir, "sub", "b.pyc"), make([]byte, 600), 0644)
+
+	if !belowMinSize(dir, 2000) {
+		t.Error("1200 bytes should be below 2000")
+	}
+	if belowMinSize(dir, 1000) {
+		t.Error("1200 bytes should not be below 1000")
+	}
+	if belowMinSize(dir, 0) {
+		t.Error("without -min-size nothing is below it")
+	}
+
+	many := t.TempDir()
+	for i := 0; i <= smallTreeEntries; i++ {
+		os.WriteFile(filepath.Join(many, fmt.Sprintf("%05d", i)), nil, 0644)
+	}
+	if belowMinSize(many, 1<<20) {
+		t.Error("a tree with more than smallTreeEntries entries is left to sizing")
+	}
+}
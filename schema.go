@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// schemaVersion is emitted as schema_version in --json output.
+// Bump it only for breaking changes (removed, renamed, or retyped fields);
+// new optional fields are additive and keep the version.
+//
+// History: 2 -- last_used became RFC3339 (date moved to last_used_display).
+const schemaVersion = 2
+
+// jsonSchema describes the --json document (JSON Schema draft 2020-12).
+// TestJSONSchemaMatchesStructs keeps it in sync with JSONOutput and Record.
+const jsonSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "$id": "https://github.com/fblissjr/tidyup/schema/v2.json",
+  "title": "tidyup scan output",
+  "type": "object",
+  "required": ["schema_version", "count", "total_bytes", "total_human", "dry_run"],
+  "properties": {
+    "schema_version": {"type": "integer", "const": 2},
+    "count": {"type": "integer", "minimum": 0},
+    "total_bytes": {"type": "integer", "minimum": 0},
+    "total_human": {"type": "string"},
+    "total_disk_bytes": {"type": "integer", "minimum": 0, "description": "Sum of disk_bytes"},
+    "total_disk_human": {"type": "string"},
+    "records": {"type": ["array", "null"], "items": {"$ref": "#/$defs/record"}, "description": "Absent with -summary-only"},
+    "dry_run": {"type": "boolean"},
+    "as_of": {"type": "string", "format": "date", "description": "Simulated reference date from -as-of"},
+    "scanned_at": {"type": "string", "format": "date-time", "description": "When the output was produced, RFC3339 in UTC (the real time, even with -as-of)"},
+    "host": {"type": "string", "description": "Host name of the machine that produced the output"},
+    "by_tag": {"type": "object", "additionalProperties": {"$ref": "#/$defs/group_total"}, "description": "Item count and total_bytes per tags.json tag; absent when no item is tagged"},
+    "by_owner": {"type": "object", "additionalProperties": {"$ref": "#/$defs/group_total"}, "description": "Item count and total_bytes per git checkout owner (the owner field); absent when no item has one"},
+    "warnings": {"type": "array", "items": {"$ref": "#/$defs/warning"}, "description": "Warnings stderr would otherwise show"}
+  },
+  "$defs": {
+    "group_total": {
+      "type": "object",
+      "required": ["count", "total_bytes"],
+      "properties": {
+        "count": {"type": "integer", "minimum": 0},
+        "total_bytes": {"type": "integer", "minimum": 0}
+      }
+    },
+    "warning": {
+      "type": "object",
+      "required": ["kind", "message"],
+      "properties": {
+        "kind": {"type": "string", "enum": ["active_venv", "protected_path", "tidyup_state", "mount_point", "invalid_venv", "scan_error", "general"]},
+        "path": {"type": "string", "description": "Path the warning concerns"},
+        "message": {"type": "string", "description": "The warning as text output shows it"}
+      }
+    },
+    "record": {
+      "type": "object",
+      "required": ["type", "path", "size_bytes", "size_human", "last_used", "last_used_display", "age_days"],
+      "properties": {
+        "type": {"type": "string", "description": "Scan type, e.g. venv or node_modules"},
+        "path": {"type": "string", "description": "Absolute path of the item"},
+        "size_bytes": {"type": "integer", "minimum": 0, "description": "Apparent (logical) size"},
+        "size_human": {"type": "string"},
+        "disk_bytes": {"type": "integer", "minimum": 0, "description": "Allocated size on disk, hard links counted once"},
+        "disk_human": {"type": "string"},
+        "last_used": {"type": "string", "format": "date-time", "description": "RFC3339; schema_version 1 used YYYY-MM-DD"},
+        "last_used_display": {"type": "string", "format": "date", "description": "YYYY-MM-DD in the scanning host's time zone"},
+        "last_used_unix": {"type": "integer", "description": "last_used in seconds since the Unix epoch"},
+        "last_used_rfc3339": {"type": "string", "format": "date-time", "description": "last_used as RFC3339 in UTC, for sorting and comparing across hosts and time zones"},
+        "age_days": {"type": "number"},
+        "notes": {"type": "array", "items": {"type": "string"}, "description": "Heuristic explanations (also shown by -explain)"},
+        "review": {"type": "string", "description": "Why the item needs human review, e.g. uncommitted git changes; such items are never deleted"},
+        "newest_sibling": {"type": "string", "description": "For venvs: path of the most recently used venv in the same project, when it is not this one"},
+        "python": {"type": "string", "description": "For venvs: interpreter version from pyvenv.cfg or the interpreter path"},
+        "interpreter": {"type": "string", "description": "For venvs: final target of the bin/python symlink chain"},
+        "interpreter_missing": {"type": "boolean", "description": "For venvs: the interpreter no longer exists (e.g. removed by a Homebrew upgrade)"},
+        "tool": {"type": "string", "description": "For tools: installed package name and version; for library_cache: the owning app and its cache directory name"},
+        "command": {"type": "string", "description": "Native removal command for the item (e.g. pipx uninstall), which also cleans up shims"},
+        "advisory": {"type": "boolean", "description": "Informational only; tidyup never deletes it (use command)"},
+        "editable_users": {"type": "array", "items": {"type": "string"}, "description": "For venv, build, and dist: other environments with an editable install of this item's project, which removing it may break"},
+        "tags": {"type": "array", "items": {"type": "string"}, "description": "Labels from tags.json whose patterns cover the path, sorted"},
+        "owner": {"type": "string", "description": "Inside a git checkout: user.name <user.email> from the repository's own config (not the global one)"},
+        "upstream": {"type": "string", "description": "Inside a git checkout: the origin remote URL, else the first remote's, without credentials"},
+        "project": {"type": "string", "description": "Name declared by the Python project the item belongs to (pyproject.toml [project] or [tool.poetry], setup.cfg [metadata])"},
+        "project_dir": {"type": "string", "description": "Poetry venvs: directory of the project the environment belongs to, when found"},
+        "workspace": {"type": "string", "description": "With -monorepo: root of the enclosing workspace (uv, pnpm, npm/yarn, Cargo, go.work)"},
+        "package": {"type": "string", "description": "With -monorepo: the workspace member the item belongs to, relative to workspace, or \".\" for the workspace root"},
+        "growth_bytes": {"type": "integer", "minimum": 0, "description": "With -baseline: bytes added since the baseline was saved; equal to size_bytes for items that are new since then"},
+        "file": {"type": "boolean", "description": "The item is a single file (an installer, a LaTeX aux file, a disk image) rather than a directory"},
+        "confidence": {"type": "number", "minimum": 0, "maximum": 1, "description": "How well the usage evidence (agreeing markers, git activity, running processes) supports calling the item stale; below 0.4 it is held for review"},
+        "trend": {"type": "string", "enum": ["growing", "stable", "shrinking", "untouched"], "description": "Size and usage across recent scans, from the persistent history; absent on an item's first scan"}
+      }
+    }
+  }
+}
+`
+
+// runSchema implements `tidyup schema`: prints the JSON Schema for --json output.
+func runSchema(args []string) int {
+	if len(args) != 0 {
+		fmt.Fprintf(os.Stderr, "Usage: tidyup schema\n")
+		return exitError
+	}
+	fmt.Print(jsonSchema)
+	return exitOK
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden files in testdata/")
+
+// jsonFieldNames returns the JSON keys of a struct type.
+func jsonFieldNames(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("json")
+		name := strings.Split(tag, ",")[0]
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestJSONSchemaMatchesStructs(t *testing.T) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(jsonSchema), &schema); err != nil {
+		t.Fatalf("jsonSchema is not valid JSON: %v", err)
+	}
+
+	top := sortedKeys(schema["properties"].(map[string]interface{}))
+	if want := jsonFieldNames(reflect.TypeOf(JSONOutput{})); !reflect.DeepEqual(top, want) {
+		t.Errorf("schema top-level properties %v, JSONOutput fields %v", top, want)
+	}
+
+	rec := schema["$defs"].(map[string]interface{})["record"].(map[string]interface{})
+	recProps := sortedKeys(rec["properties"].(map[string]interface{}))
+	if want := jsonFieldNames(reflect.TypeOf(Record{})); !reflect.DeepEqual(recProps, want) {
+		t.Errorf("schema record properties %v, Record fields %v", recProps, want)
+	}
+
+	version := schema["properties"].(map[string]interface{})["schema_version"].(map[string]interface{})["const"]
+	if version.(float64) != schemaVersion {
+		t.Errorf("schema const %v, schemaVersion %d", version, schemaVersion)
+	}
+}
+
+// checkGolden compares got to testdata/name, rewriting it with -update.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *updateGolden {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file (run with -update to create): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s mismatch; if the change is intended, run go test -run %s -update\n--- got ---\n%s\n--- want ---\n%s",
+			path, t.Name(), got, want)
+	}
+}
+
+func TestJSONOutputGolden(t *testing.T) {
+	records := []Record{
+		{Type: "venv", Path: "/home/user/project/.venv", Size: 1048576, SizeHuman: "1.0 MB", DiskSize: 524288, DiskHuman: "512.0 KB", LastUsed: "2026-01-01T09:30:00Z", LastUsedDisplay: "2026-01-01", AgeDays: 90},
+		{Type: "node_modules", Path: "/home/user/web/node_modules", Size: 2048, SizeHuman: "2.0 KB", DiskSize: 8192, DiskHuman: "8.0 KB", LastUsed: "2025-12-01T18:00:00Z", LastUsedDisplay: "2025-12-01", AgeDays: 121},
+	}
+	opts := &options{
+		asOf: time.Date(2026, 4, 1, 0, 0, 0, 0, time.Local),
+		now:  func() time.Time { return time.Date(2026, 4, 20, 8, 15, 0, 0, time.UTC) },
+	}
+	orig := hostnameFunc
+	hostnameFunc = func() (string, error) { return "build-01", nil }
+	defer func() { hostnameFunc = orig }()
+
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, buildJSONOutput(records, totalSize(records), true, opts)); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "output.golden.json", buf.Bytes())
+}
+
+func TestSchemaGolden(t *testing.T) {
+	checkGolden(t, "schema.golden.json", []byte(jsonSchema))
+}
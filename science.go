@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// isRenvLibrary reports whether path is a project's renv/library, i.e. its
+// parent is renv/ and the project root has renv.lock to restore from.
+func isRenvLibrary(path string) bool {
+	renvDir := filepath.Dir(path)
+	if filepath.Base(path) != "library" || filepath.Base(renvDir) != "renv" {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(filepath.Dir(renvDir), "renv.lock"))
+	return err == nil
+}
+
+// findRenvCache returns renv's global package cache ($RENV_PATHS_CACHE or
+// the per-OS default).
+func findRenvCache(home string) []string {
+	if dir := os.Getenv("RENV_PATHS_CACHE"); dir != "" {
+		return existingDirs(dir)
+	}
+	return existingDirs(
+		filepath.Join(home, ".cache/R/renv"),
+		filepath.Join(home, "Library/Caches/org.R-project.R/R/renv"),
+		filepath.Join(home, "AppData/Local/R/cache/R/renv"),
+	)
+}
+
+// findJuliaDepot returns the regenerable parts of the user's Julia depot
+// (the first JULIA_DEPOT_PATH entry, default ~/.julia).
+func findJuliaDepot(home string) []string {
+	depot := filepath.Join(home, ".julia")
+	if env := os.Getenv("JULIA_DEPOT_PATH"); env != "" {
+		if first := filepath.SplitList(env)[0]; first != "" {
+			depot = first
+		}
+	}
+	return existingDirs(
+		filepath.Join(depot, "packages"),
+		filepath.Join(depot, "artifacts"),
+		filepath.Join(depot, "compiled"),
+	)
+}
+
+// latexAuxSuffixes are files LaTeX, BibTeX/biber, and latexmk regenerate on
+// every build.
+var latexAuxSuffixes = []string{
+	".aux", ".log", ".fls", ".fdb_latexmk", ".synctex.gz", ".out", ".toc",
+	".bbl", ".blg", ".bcf", ".run.xml", ".lof", ".lot", ".nav", ".snm", ".xdv",
+}
+
+// latexSource returns the .tex file a build artifact belongs to ("paper.aux"
+// -> "paper.tex"; "_minted-paper" -> "paper.tex"), or "" when path is not a
+// LaTeX artifact or its source is missing. Requiring the source keeps
+// unrelated .log and .out files out.
+func latexSource(path string) string {
+	dir, name := filepath.Split(path)
+	var job string
+	if strings.HasPrefix(name, "_minted-") {
+		job = strings.TrimPrefix(name, "_minted-")
+	} else {
+		for _, suffix := range latexAuxSuffixes {
+			if strings.HasSuffix(name, suffix) && len(name) > len(suffix) {
+				job = strings.TrimSuffix(name, suffix)
+				break
+			}
+		}
+	}
+	if job == "" {
+		return ""
+	}
+	tex := filepath.Join(dir, job+".tex")
+	if _, err := os.Stat(tex); err != nil {
+		return ""
+	}
+	return tex
+}
+
+// getLatexUsage dates build debris by the newer of the artifact and its
+// .tex source: a document still being edited keeps its aux files.
+func getLatexUsage(path string) (time.Time, bool) {
+	lastUsed, found := getCacheUsage(path)
+	if info, err := os.Stat(latexSource(path)); err == nil && info.ModTime().After(lastUsed) {
+		lastUsed, found = info.ModTime(), true
+	}
+	return lastUsed, found
+}
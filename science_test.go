@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestLatexSource(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "paper.tex"), []byte(`\documentclass{article}`), 0644)
+	tests := map[string]bool{
+		"paper.aux":         true,
+		"paper.synctex.gz":  true,
+		"paper.run.xml":     true,
+		"_minted-paper":     true,
+		"paper.tex":         false,
+		"paper.pdf":         false,
+		"server.log":        false, // no server.tex
+		".aux":              false,
+		"paper.fdb_latexmk": true,
+	}
+	for name, want := range tests {
+		if got := latexSource(filepath.Join(dir, name)) != ""; got != want {
+			t.Errorf("latexSource(%s) found = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestScanRoots_LatexDebris(t *testing.T) {
+	root := t.TempDir()
+	old := time.Now().AddDate(0, 0, -90)
+	write := func(name string, mtime time.Time) {
+		p := filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte("x"), 0644)
+		os.Chtimes(p, mtime, mtime)
+	}
+	write("thesis/thesis.tex", old)
+	write("thesis/thesis.aux", old)
+	write("thesis/thesis.log", old)
+	write("thesis/_minted-thesis/default.pygstyle", old)
+	write("thesis/notes.log", old) // not a LaTeX job
+	// Still being edited: the aux files stay.
+	write("draft/draft.tex", time.Now())
+	write("draft/draft.aux", old)
+
+	opts := &options{minAge: 30, maxDepth: 5, scanTypes: map[string]bool{"latex": true}}
+	records, _ := scanRoots([]string{root}, opts)
+	var got []string
+	for _, r := range records {
+		got = append(got, filepath.Base(r.Path))
+		if isDir := filepath.Base(r.Path) == "_minted-thesis"; r.File == isDir {
+			t.Errorf("%s: file = %v", r.Path, r.File)
+		}
+	}
+	sort.Strings(got)
+	want := []string{"_minted-thesis", "thesis.aux", "thesis.log"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("record %d = %s, want %s", i, got[i], want[i])
+		}
+	}
+}
+
+func TestScanRoots_RenvLibrary(t *testing.T) {
+	root := t.TempDir()
+	old := time.Now().AddDate(0, 0, -90)
+	for _, proj := range []string{"analysis", "unlocked"} {
+		pkg := filepath.Join(root, proj, "renv", "library", "R-4.3", "x86_64-pc-linux-gnu", "dplyr", "DESCRIPTION")
+		os.MkdirAll(filepath.Dir(pkg), 0755)
+		os.WriteFile(pkg, []byte("Package: dplyr\n"), 0644)
+		os.Chtimes(pkg, old, old)
+	}
+	os.WriteFile(filepath.Join(root, "analysis", "renv.lock"), []byte("{}"), 0644)
+
+	opts := &options{minAge: 30, maxDepth: 5, scanTypes: map[string]bool{"renv": true}}
+	records, _ := scanRoots([]string{root}, opts)
+	if len(records) != 1 || records[0].Path != filepath.Join(root, "analysis", "renv", "library") {
+		t.Fatalf("got %+v, want only the locked project's renv/library", records)
+	}
+	if len(records[0].Notes) == 0 {
+		t.Error("expected a renv::restore() note")
+	}
+}
+
+func TestFindJuliaDepot(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("JULIA_DEPOT_PATH", "")
+	for _, d := range []string{"packages", "artifacts", "environments", "registries"} {
+		os.MkdirAll(filepath.Join(home, ".julia", d), 0755)
+	}
+	got := findJuliaDepot(home)
+	if len(got) != 2 || filepath.Base(got[0]) != "packages" || filepath.Base(got[1]) != "artifacts" {
+		t.Errorf("got %v, want packages and artifacts only", got)
+	}
+
+	depot := t.TempDir()
+	os.MkdirAll(filepath.Join(depot, "compiled"), 0755)
+	t.Setenv("JULIA_DEPOT_PATH", depot+string(os.PathListSeparator)+"/usr/share/julia")
+	if got := findJuliaDepot(home); len(got) != 1 || got[0] != filepath.Join(depot, "compiled") {
+		t.Errorf("JULIA_DEPOT_PATH: got %v", got)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// homeLocation is a per-user SDK or toolchain cache found at a well-known
+// location rather than by walking roots. -system scans these when the type
+// is selected.
+type homeLocation struct {
+	typeName string
+	find     func(home string) []string // candidate item paths
+	usage    usageFunc
+}
+
+var homeLocations = []homeLocation{
+	{"android_sdk", findAndroidSDKItems, getCacheUsage},
+	{"pub_cache", findPubCache, getCacheUsage},
+	{"pip_cache", findPipCaches, getCacheUsage},
+	{"js_cache", findJSCaches, getCacheUsage},
+	{"gradle_wrapper", findGradleDists, getCacheUsage},
+	{"gradle", findGradleCaches, getJVMBuildUsage},
+	{"huggingface", findHFRepos, getHFUsage},
+	{"renv", findRenvCache, getCacheUsage},
+	{"julia", findJuliaDepot, getCacheUsage},
+	{"vm_image", findVMImages, getVMUsage},
+	{"app_leftovers", findAppLeftovers, getCacheUsage},
+	{"downloads", findDownloads, getDownloadUsage},
+	{"crash", findCrashFiles, getCacheUsage},
+}
+
+// existingDirs filters paths down to directories that exist.
+func existingDirs(paths ...string) []string {
+	var dirs []string
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		if info, err := os.Stat(p); err == nil && info.IsDir() {
+			dirs = append(dirs, p)
+		}
+	}
+	return dirs
+}
+
+// androidSDKRoots lists Android SDK installs: $ANDROID_HOME,
+// $ANDROID_SDK_ROOT, and the Android Studio defaults per OS.
+func androidSDKRoots(home string) []string {
+	roots := existingDirs(
+		os.Getenv("ANDROID_HOME"),
+		os.Getenv("ANDROID_SDK_ROOT"),
+		filepath.Join(home, "Library/Android/sdk"),
+		filepath.Join(home, "Android/Sdk"),
+		filepath.Join(home, "AppData/Local/Android/Sdk"),
+	)
+	seen := map[string]bool{}
+	var unique []string
+	for _, r := range roots {
+		if real, err := filepath.EvalSymlinks(r); err == nil && !seen[real] {
+			seen[real] = true
+			unique = append(unique, r)
+		}
+	}
+	return unique
+}
+
+// avdSystemImages returns the system image directories (relative to the SDK,
+// slash-separated) that existing emulators boot from.
+func avdSystemImages(home string) map[string]bool {
+	avdHome := os.Getenv("ANDROID_AVD_HOME")
+	if avdHome == "" {
+		avdHome = filepath.Join(home, ".android", "avd")
+	}
+	used := map[string]bool{}
+	configs, _ := filepath.Glob(filepath.Join(avdHome, "*.avd", "config.ini"))
+	for _, cfg := range configs {
+		f, err := os.Open(cfg)
+		if err != nil {
+			continue
+		}
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			key, value, ok := strings.Cut(sc.Text(), "=")
+			if ok && strings.HasPrefix(strings.TrimSpace(key), "image.sysdir.") {
+				used[strings.Trim(filepath.ToSlash(strings.TrimSpace(value)), "/")] = true
+			}
+		}
+		f.Close()
+	}
+	return used
+}
+
+// compareVersions orders dotted versions numerically ("30.0.3" < "34.0.0");
+// non-numeric parts such as "-rc1" sort before the release.
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y string
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		xn, xerr := strconv.Atoi(strings.SplitN(x, "-", 2)[0])
+		yn, yerr := strconv.Atoi(strings.SplitN(y, "-", 2)[0])
+		switch {
+		case xerr == nil && yerr == nil && xn != yn:
+			if xn < yn {
+				return -1
+			}
+			return 1
+		case x != y:
+			// Same number: a pre-release suffix sorts first.
+			if strings.Contains(x, "-") != strings.Contains(y, "-") {
+				if strings.Contains(x, "-") {
+					return -1
+				}
+				return 1
+			}
+			return strings.Compare(x, y)
+		}
+	}
+	return 0
+}
+
+// findAndroidSDKItems returns system images no emulator uses and every
+// build-tools version except the newest.
+func findAndroidSDKItems(home string) []string {
+	var items []string
+	used := avdSystemImages(home)
+	for _, sdk := range androidSDKRoots(home) {
+		images, _ := filepath.Glob(filepath.Join(sdk, "system-images", "*", "*", "*"))
+		for _, img := range images {
+			rel, _ := filepath.Rel(sdk, img)
+			if !used[filepath.ToSlash(rel)] {
+				items = append(items, img)
+			}
+		}
+
+		tools, _ := filepath.Glob(filepath.Join(sdk, "build-tools", "*"))
+		sort.Slice(tools, func(i, j int) bool {
+			return compareVersions(filepath.Base(tools[i]), filepath.Base(tools[j])) < 0
+		})
+		if len(tools) > 1 {
+			items = append(items, tools[:len(tools)-1]...)
+		}
+	}
+	return items
+}
+
+// sdkmanagerCommand is the delegated removal for an SDK package directory:
+// <sdk>/system-images/android-33/google_apis/x86_64 becomes the package
+// "system-images;android-33;google_apis;x86_64".
+func sdkmanagerCommand(path string) string {
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	for i, part := range parts {
+		if part == "system-images" || part == "build-tools" {
+			return `sdkmanager --uninstall "` + strings.Join(parts[i:], ";") + `"`
+		}
+	}
+	return ""
+}
+
+// findPubCache returns the Dart/Flutter package cache ($PUB_CACHE or the
+// per-OS default).
+func findPubCache(home string) []string {
+	if dir := os.Getenv("PUB_CACHE"); dir != "" {
+		return existingDirs(dir)
+	}
+	return existingDirs(
+		filepath.Join(home, ".pub-cache"),
+		filepath.Join(home, "AppData/Local/Pub/Cache"),
+	)
+}
+
+// findGradleDists returns each Gradle distribution the wrapper downloaded
+// under $GRADLE_USER_HOME (default ~/.gradle).
+func findGradleDists(home string) []string {
+	dists, _ := filepath.Glob(filepath.Join(gradleUserHome(home), "wrapper", "dists", "gradle-*"))
+	return existingDirs(dists...)
+}
+
+// isUnityProject reports whether dir is a Unity project root, whose
+// Library/ is a regenerable import cache.
+func isUnityProject(dir string) bool {
+	if info, err := os.Stat(filepath.Join(dir, "Assets")); err != nil || !info.IsDir() {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(dir, "ProjectSettings", "ProjectVersion.txt"))
+	return err == nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"30.0.3", "34.0.0", -1},
+		{"34.0.0", "33.0.2", 1},
+		{"34.0.0-rc1", "34.0.0", -1},
+		{"9.0.0", "10.0.0", -1},
+		{"33.0.1", "33.0.1", 0},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestFindAndroidSDKItems(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("ANDROID_HOME", "")
+	t.Setenv("ANDROID_SDK_ROOT", "")
+	t.Setenv("ANDROID_AVD_HOME", "")
+	sdk := filepath.Join(home, "Android", "Sdk")
+	for _, d := range []string{
+		"system-images/android-33/google_apis/x86_64",
+		"system-images/android-30/default/x86_64",
+		"build-tools/30.0.3",
+		"build-tools/34.0.0",
+		"build-tools/9.0.0",
+	} {
+		os.MkdirAll(filepath.Join(sdk, d), 0755)
+	}
+	avd := filepath.Join(home, ".android", "avd", "Pixel.avd")
+	os.MkdirAll(avd, 0755)
+	os.WriteFile(filepath.Join(avd, "config.ini"),
+		[]byte("hw.cpu.arch=x86_64\nimage.sysdir.1=system-images/android-33/google_apis/x86_64/\n"), 0644)
+
+	got := findAndroidSDKItems(home)
+	sort.Strings(got)
+	want := []string{
+		filepath.Join(sdk, "build-tools/30.0.3"),
+		filepath.Join(sdk, "build-tools/9.0.0"),
+		filepath.Join(sdk, "system-images/android-30/default/x86_64"),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("item %d = %s, want %s", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSdkmanagerCommand(t *testing.T) {
+	got := sdkmanagerCommand("/home/u/Android/Sdk/system-images/android-30/default/x86_64")
+	if want := `sdkmanager --uninstall "system-images;android-30;default;x86_64"`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestScanRoots_UnityLibrary(t *testing.T) {
+	root := t.TempDir()
+	game := filepath.Join(root, "game")
+	os.MkdirAll(filepath.Join(game, "Assets"), 0755)
+	os.MkdirAll(filepath.Join(game, "ProjectSettings"), 0755)
+	os.WriteFile(filepath.Join(game, "ProjectSettings", "ProjectVersion.txt"), []byte("m_EditorVersion: 2022.3.10f1\n"), 0644)
+	old := time.Now().AddDate(0, 0, -90)
+	artifact := filepath.Join(game, "Library", "Artifacts", "00", "blob")
+	os.MkdirAll(filepath.Dir(artifact), 0755)
+	os.WriteFile(artifact, []byte("x"), 0644)
+	os.Chtimes(artifact, old, old)
+	// A Library/ outside a Unity project is never touched.
+	os.MkdirAll(filepath.Join(root, "docs", "Library"), 0755)
+
+	opts := &options{minAge: 30, maxDepth: 5, scanTypes: map[string]bool{"unity": true}}
+	records, _ := scanRoots([]string{root}, opts)
+	if len(records) != 1 || records[0].Path != filepath.Join(game, "Library") {
+		t.Errorf("got %+v, want only the Unity Library/", records)
+	}
+}
+
+func TestScanRoots_SystemGradleDists(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("GRADLE_USER_HOME", "")
+	dist := filepath.Join(home, ".gradle", "wrapper", "dists", "gradle-7.6-bin", "abc123", "gradle-7.6", "lib", "gradle.jar")
+	os.MkdirAll(filepath.Dir(dist), 0755)
+	os.WriteFile(dist, []byte("x"), 0644)
+	old := time.Now().AddDate(0, 0, -120)
+	os.Chtimes(dist, old, old)
+
+	opts := &options{minAge: 30, maxDepth: 5, systemScan: true, scanTypes: map[string]bool{"gradle_wrapper": true}}
+	records, _ := scanRoots(nil, opts)
+	if len(records) != 1 || filepath.Base(records[0].Path) != "gradle-7.6-bin" {
+		t.Errorf("got %+v, want the gradle-7.6 distribution", records)
+	}
+}
@@ -0,0 +1,633 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// `tidyup serve` is a small local web UI for people who would rather click
+// than type selection syntax. It scans once at startup (and again on
+// request), shows the records in a sortable, filterable table, optionally
+// grouped by project, and deletes a selection after a confirmation page --
+// through deleteRecords, so every safety check of the CLI applies. It only
+// listens on loopback, answers only requests addressed to that loopback
+// address (a DNS-rebound name is refused, so other sites cannot read the
+// page), and serves the UI only to a browser holding the serve token, which
+// only the user can read, so other local users cannot load it. It refuses
+// cross-origin POSTs, and every POST must carry the per-process token
+// embedded in the page, so other sites cannot submit forms to it. On
+// battery (or always, with -nice) scans and deletions run in nice mode, and
+// the startup scan waits for AC power unless -force.
+
+// serveDeleteFunc carries out a confirmed selection; a seam for tests.
+var serveDeleteFunc = deleteSelected
+
+// serveCookie carries the serve token once the browser has opened the
+// page with ?token=.
+const serveCookie = "tidyup_token"
+
+// server holds the latest scan and serializes deletions.
+type server struct {
+	mu       sync.Mutex // guards the fields below; never held while deleting
+	deleteMu sync.Mutex // serializes deletions
+	roots    []string
+	opts     *options
+	records  []Record
+	scanned  time.Time
+	errors   []string
+	token    string // page token for the UI's forms
+
+	apiToken string                    // bearer token for /api/v1/
+	replayMu sync.Mutex                // serializes API deletes
+	replays  map[string]deleteResponse // by Idempotency-Key
+
+	addr    string   // listen address, for status.json
+	events  eventHub // events.sock clients
+	pending string   // why the startup scan is waiting; "" once it ran
+
+	force     bool          // -force: ignore battery and thermal pressure
+	idleFor   time.Duration // -idle: user idle time the startup scan waits for
+	stopWatch func()        // ends watchIdle for a running idle-triggered scan
+}
+
+// newServer returns a server for roots; call scan before serving.
+func newServer(roots []string, opts *options) *server {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return &server{roots: roots, opts: opts, token: hex.EncodeToString(b)}
+}
+
+// scan replaces the records with a fresh scan.
+func (s *server) scan() {
+	setNice(s.opts.nice || onBattery())
+	records, errs := scanRoots(s.roots, s.opts)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records, s.errors, s.scanned, s.pending = records, errs, time.Now(), ""
+	s.publishLocked()
+}
+
+// deferPoll is how often a deferred startup scan checks the power state.
+var deferPoll = time.Minute
+
+// scanWhenClear runs the deferred startup scan once startBlocker clears
+// (pending says why it waits), so an unattended server never starts a full
+// scan on battery, on a hot machine, or, with -idle, while someone is
+// working; with -idle the scan also pauses whenever they come back. A
+// Rescan from the UI or API scans right away and ends the wait.
+func (s *server) scanWhenClear() {
+	for {
+		time.Sleep(deferPoll)
+		s.mu.Lock()
+		done := s.pending == ""
+		s.mu.Unlock()
+		if done {
+			return
+		}
+		if why := s.startBlocker(); why != "" {
+			s.mu.Lock()
+			s.pending = why
+			s.mu.Unlock()
+			continue
+		}
+		if s.idleFor > 0 {
+			s.mu.Lock()
+			s.stopWatch = watchIdle(s.idleFor)
+			s.mu.Unlock()
+			defer s.endIdleWatch()
+		}
+		s.scan()
+		return
+	}
+}
+
+// endIdleWatch stops pausing an idle-triggered scan. A request from the UI
+// or API means someone is there, and their rescan or deletion must not
+// wait on pace() for them to go away.
+func (s *server) endIdleWatch() {
+	s.mu.Lock()
+	stop := s.stopWatch
+	s.stopWatch = nil
+	s.mu.Unlock()
+	if stop != nil {
+		stop()
+	}
+}
+
+// startBlocker returns why the unattended startup scan should wait, or "".
+func (s *server) startBlocker() string {
+	if !s.force {
+		if why := backgroundBlocker(); why != "" {
+			return why
+		}
+	}
+	if s.idleFor > 0 && !isIdle(s.idleFor) {
+		return fmt.Sprintf("waiting for %s of idle time", s.idleFor)
+	}
+	return ""
+}
+
+// publishLocked writes status.json and notifies events.sock clients of the
+// current records. Callers hold mu.
+func (s *server) publishLocked() {
+	sum := summarize(s.records, s.scanned, time.Now(), s.addr)
+	if err := writeStatus(sum); err != nil {
+		stderr.warnf("could not write status: %v", err)
+	}
+	s.events.publish(sum)
+}
+
+// deletable reports whether the UI offers r for selection; the rest are
+// shown for information, with the reason.
+func deletable(r Record) bool {
+	return !r.Advisory && r.Review == ""
+}
+
+// serveRow is a record as the table shows it.
+type serveRow struct {
+	Record
+	Deletable  bool
+	ProjectDir string
+}
+
+// serveGroup is a run of rows under one heading (a project, or everything).
+type serveGroup struct {
+	Name     string // project directory
+	Declared string // the project's declared name, when it has one
+	Total    string
+	Rows     []serveRow
+}
+
+// view selects, sorts, and groups the records for the table.
+func (s *server) view(typ, query, sortField string, byProject bool) ([]serveGroup, int, int64) {
+	s.mu.Lock()
+	records := append([]Record(nil), s.records...)
+	s.mu.Unlock()
+
+	var shown []Record
+	for _, r := range records {
+		if typ != "" && r.Type != typ {
+			continue
+		}
+		if query != "" && !strings.Contains(strings.ToLower(r.Path), strings.ToLower(query)) {
+			continue
+		}
+		shown = append(shown, r)
+	}
+	sortRecords(shown, sortField)
+
+	rows := make([]serveRow, len(shown))
+	for i, r := range shown {
+		rows[i] = serveRow{Record: r, Deletable: deletable(r), ProjectDir: projectDir(r.Path)}
+	}
+	if !byProject {
+		return []serveGroup{{Rows: rows}}, len(rows), totalSize(shown)
+	}
+
+	// Projects in order of their first (best-sorted) record.
+	index := map[string]int{}
+	var groups []serveGroup
+	sizes := map[string]int64{}
+	for _, row := range rows {
+		i, ok := index[row.ProjectDir]
+		if !ok {
+			i = len(groups)
+			index[row.ProjectDir] = i
+			groups = append(groups, serveGroup{Name: row.ProjectDir})
+		}
+		groups[i].Rows = append(groups[i].Rows, row)
+		if groups[i].Declared == "" {
+			groups[i].Declared = row.Project
+		}
+		sizes[row.ProjectDir] += row.Size
+	}
+	for i := range groups {
+		groups[i].Total = formatBytes(sizes[groups[i].Name])
+	}
+	return groups, len(rows), totalSize(shown)
+}
+
+// selected returns the scanned, deletable records for paths. Paths the
+// last scan did not produce are never acted on.
+func (s *server) selected(paths []string) []Record {
+	want := map[string]bool{}
+	for _, p := range paths {
+		want[p] = true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var records []Record
+	for _, r := range s.records {
+		if want[r.Path] && deletable(r) {
+			records = append(records, r)
+		}
+	}
+	return records
+}
+
+// remove runs a confirmed deletion and drops what it removed from the
+// records. It returns the paths removed, as the executor reports them.
+// The scan may be hours old, so anything used since it ran is skipped, as
+// `apply` and `queue run` do. Other requests are answered meanwhile.
+func (s *server) remove(records []Record) map[string]bool {
+	s.endIdleWatch()
+	s.deleteMu.Lock()
+	defer s.deleteMu.Unlock()
+	s.mu.Lock()
+	scanned := s.scanned
+	s.mu.Unlock()
+
+	var fresh []Record
+	for _, r := range records {
+		if err := changedSince(r.Path, scanned); err != nil {
+			stderr.warnf("skipping %s: %v", r.Path, err)
+			continue
+		}
+		fresh = append(fresh, r)
+	}
+	var removed []Record
+	if len(fresh) > 0 {
+		opts := *s.opts
+		opts.confirm = true
+		setNice(opts.nice || onBattery())
+		_, removed = serveDeleteFunc(fresh, &opts)
+	}
+
+	gone := map[string]bool{}
+	for _, r := range removed {
+		gone[r.Path] = true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var kept []Record
+	for _, r := range s.records {
+		if !gone[r.Path] {
+			kept = append(kept, r)
+		}
+	}
+	s.records = kept
+	s.publishLocked()
+	return gone
+}
+
+// checkPost rejects anything but a POST carrying the page token.
+func (s *server) checkPost(w http.ResponseWriter, req *http.Request) bool {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	if req.PostFormValue("token") != s.token {
+		http.Error(w, "missing or stale page token; reload the page", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// checkSession lets a request through to the UI when it carries the serve
+// token: as the session cookie, or as ?token= on a GET, which sets the
+// cookie and redirects to the same page without it. The token is only in
+// serve.token (mode 0600) and in the address serve prints at startup.
+func (s *server) checkSession(w http.ResponseWriter, req *http.Request) bool {
+	q := req.URL.Query()
+	if req.Method == http.MethodGet && s.validToken(q.Get("token")) {
+		http.SetCookie(w, &http.Cookie{Name: serveCookie, Value: s.apiToken, Path: "/", HttpOnly: true, SameSite: http.SameSiteStrictMode})
+		q.Del("token")
+		u := *req.URL
+		u.RawQuery = q.Encode()
+		http.Redirect(w, req, u.RequestURI(), http.StatusSeeOther)
+		return false
+	}
+	if c, err := req.Cookie(serveCookie); err == nil && s.validToken(c.Value) {
+		return true
+	}
+	http.Error(w, "open the address tidyup serve printed, or add ?token= with the contents of serve.token in the state directory", http.StatusUnauthorized)
+	return false
+}
+
+// validToken reports whether token is the serve token.
+func (s *server) validToken(token string) bool {
+	return s.apiToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.apiToken)) == 1
+}
+
+// handler returns the UI's routes.
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleIndex)
+	mux.HandleFunc("/rescan", s.handleRescan)
+	mux.HandleFunc("/delete", s.handleDelete)
+	mux.Handle("/api/", s.apiHandler())
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !s.localHost(req.Host) {
+			http.Error(w, "unexpected Host header", http.StatusMisdirectedRequest)
+			return
+		}
+		if origin := req.Header.Get("Origin"); origin != "" && req.Method != http.MethodGet && req.Method != http.MethodHead {
+			if u, err := url.Parse(origin); err != nil || u.Scheme != "http" || !s.localHost(u.Host) {
+				http.Error(w, "cross-origin request refused", http.StatusForbidden)
+				return
+			}
+		}
+		if !strings.HasPrefix(req.URL.Path, "/api/") && !s.checkSession(w, req) {
+			return
+		}
+		mux.ServeHTTP(w, req)
+	})
+}
+
+// localHost reports whether host (a Host header, host:port) names the
+// listen address: 127.0.0.1, [::1], or localhost with its port. Anything
+// else is a name some other site pointed at loopback (DNS rebinding).
+func (s *server) localHost(host string) bool {
+	_, port, err := net.SplitHostPort(s.addr)
+	if err != nil {
+		return false
+	}
+	for _, h := range []string{"127.0.0.1", "::1", "localhost"} {
+		if strings.EqualFold(host, net.JoinHostPort(h, port)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *server) handleIndex(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/" {
+		http.NotFound(w, req)
+		return
+	}
+	q := req.URL.Query()
+	sortField := q.Get("sort")
+	if sortField == "" {
+		sortField = "size"
+	}
+	groups, count, total := s.view(q.Get("type"), q.Get("q"), sortField, q.Get("group") == "project")
+
+	s.mu.Lock()
+	types := map[string]bool{}
+	for _, r := range s.records {
+		types[r.Type] = true
+	}
+	data := map[string]any{
+		"Token":    s.token,
+		"Roots":    strings.Join(s.roots, " "),
+		"Scanned":  s.scanned.Format("2006-01-02 15:04:05"),
+		"Pending":  s.pending,
+		"Errors":   s.errors,
+		"Groups":   groups,
+		"Count":    count,
+		"Total":    formatBytes(total),
+		"Sort":     sortField,
+		"Type":     q.Get("type"),
+		"Query":    q.Get("q"),
+		"Group":    q.Get("group"),
+		"Columns":  []string{"size", "disk", "age", "confidence", "path"},
+		"ReadOnly": readonlyReason(s.opts),
+	}
+	s.mu.Unlock()
+	var typeNames []string
+	for t := range types {
+		typeNames = append(typeNames, t)
+	}
+	sort.Strings(typeNames)
+	data["Types"] = typeNames
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := indexTemplate.Execute(w, data); err != nil {
+		stderr.errorf("serve: %v", err)
+	}
+}
+
+func (s *server) handleRescan(w http.ResponseWriter, req *http.Request) {
+	if !s.checkPost(w, req) {
+		return
+	}
+	s.endIdleWatch()
+	s.scan()
+	http.Redirect(w, req, "/", http.StatusSeeOther)
+}
+
+func (s *server) handleDelete(w http.ResponseWriter, req *http.Request) {
+	if !s.checkPost(w, req) {
+		return
+	}
+	if why := readonlyReason(s.opts); why != "" {
+		http.Error(w, "read-only: "+why, http.StatusForbidden)
+		return
+	}
+	records := s.selected(req.PostForm["path"])
+	data := map[string]any{"Token": s.token, "Records": records, "Total": formatBytes(totalSize(records))}
+	if req.PostFormValue("confirm") == "yes" && len(records) > 0 {
+		data["Done"], data["Removed"] = true, len(s.remove(records))
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := deleteTemplate.Execute(w, data); err != nil {
+		stderr.errorf("serve: %v", err)
+	}
+}
+
+// isLoopbackAddr reports whether addr (host:port) only accepts local
+// connections.
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// runServe implements `tidyup serve`.
+func runServe(args []string) int {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := flags.String("addr", "127.0.0.1:7777", "Listen address (loopback only)")
+	minAge := flags.Int("age", 30, "Min days since last use")
+	maxDepth := flags.Int("depth", 5, "Scan depth for recursion")
+	typeFlag := flags.String("type", "", "Comma-separated types to scan for (default venv)")
+	allTypes := flags.Bool("all", false, "Scan for all supported types")
+	systemScan := flags.Bool("system", false, "Include well-known per-user locations")
+	useTrash := flags.Bool("trash", false, "Move to the Trash instead of permanent delete (macOS)")
+	delegate := flags.Bool("delegate", false, "Remove items that have a native command by running it")
+	logFile := flags.String("log", "", "Write deletion log to this file (\"state\": logs/ in the state directory)")
+	nice := flags.Bool("nice", false, "Always run scans and deletions with -nice (default: only on battery)")
+	force := flags.Bool("force", false, "Scan at startup even on battery power or under thermal pressure")
+	idle := flags.Int("idle", 0, "Start the startup scan only after this many minutes of user idle time, pausing while the user is active (macOS, Linux with logind)")
+	readonly := flags.Bool("readonly", false, "Review only: refuse deletions from the web UI and the API")
+	maxDeleteBytesRaw := flags.String("max-delete-bytes", "", "Abort without deleting anything if the selection exceeds this size (e.g. 20G)")
+	maxDeleteItems := flags.Int("max-delete-items", 0, "Abort without deleting anything if the selection has more items than this")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tidyup serve [flags] [paths...]\n\n")
+		fmt.Fprintf(os.Stderr, "Scans paths and serves a local web UI for reviewing and deleting the results.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if !isLoopbackAddr(*addr) {
+		stderr.errorf("-addr %s: serve only listens on loopback addresses (127.0.0.1, ::1, localhost)", *addr)
+		return exitError
+	}
+
+	maxDeleteBytes, err := parseByteSize(*maxDeleteBytesRaw)
+	if err != nil {
+		stderr.errorf("-max-delete-bytes: %v", err)
+		return exitError
+	}
+	scanTypes, warnings := parseScanTypes(*typeFlag, *allTypes)
+	for _, w := range warnings {
+		stderr.warnf("%s", w)
+	}
+	opts := &options{
+		minAge:         *minAge,
+		maxDepth:       *maxDepth,
+		systemScan:     *systemScan,
+		scanTypes:      scanTypes,
+		useTrash:       *useTrash,
+		delegate:       *delegate,
+		logFile:        *logFile,
+		logFormat:      logFormatText,
+		logMaxSize:     10 << 20,
+		logKeep:        5,
+		sortField:      "size",
+		nice:           *nice,
+		readonly:       *readonly,
+		maxDeleteBytes: maxDeleteBytes,
+		maxDeleteItems: *maxDeleteItems,
+	}
+	roots := flags.Args()
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+	if opts.systemScan {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			stderr.errorf("could not determine home directory: %v", err)
+			return exitError
+		}
+		roots = append(roots, systemRoots(home, opts)...)
+	}
+
+	s := newServer(roots, opts)
+	token, err := writeServeToken()
+	if err != nil {
+		stderr.errorf("writing API token: %v", err)
+		return exitError
+	}
+	s.apiToken = token
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		stderr.errorf("%v", err)
+		return exitError
+	}
+	s.addr = ln.Addr().String()
+	if sock, err := eventsSocketPath(); err == nil {
+		if err := s.events.listen(sock); err != nil {
+			stderr.warnf("no event stream: %v", err)
+		} else {
+			defer os.Remove(sock)
+		}
+	}
+	s.force, s.idleFor = *force, time.Duration(*idle)*time.Minute
+	if s.idleFor > 0 {
+		if _, ok := userIdleFunc(); !ok {
+			stderr.warnf("-idle: cannot tell user idle time on this system; ignored")
+			s.idleFor = 0
+		}
+	}
+	if why := s.startBlocker(); why != "" {
+		s.pending = why
+		go s.scanWhenClear()
+		fmt.Printf("Serving on http://%s/?token=%s; scan deferred: %s (Ctrl-C to stop)\n", ln.Addr(), token, why)
+	} else {
+		s.scan()
+		fmt.Printf("Serving %d items on http://%s/?token=%s (Ctrl-C to stop)\n", len(s.records), ln.Addr(), token)
+	}
+	if err := http.Serve(ln, s.handler()); err != nil {
+		stderr.errorf("%v", err)
+		return exitError
+	}
+	return exitOK
+}
+
+const servePageStyle = `<style>
+body { font: 14px -apple-system, system-ui, sans-serif; margin: 1.5em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: 3px 8px; border-bottom: 1px solid #ddd; }
+th a { color: inherit; }
+td.num { text-align: right; white-space: nowrap; }
+tr.held { color: #888; }
+tr.group td { background: #f3f3f3; font-weight: bold; }
+.warn { color: #a40; }
+</style>`
+
+var indexTemplate = template.Must(template.New("index").Parse(`<!doctype html>
+<html><head><meta charset="utf-8"><title>tidyup</title>` + servePageStyle + `</head><body>
+<h1>tidyup</h1>
+{{if .Pending}}<p class="warn">Scan of {{.Roots}} deferred: {{.Pending}}. It starts once that clears; Rescan scans now.</p>
+{{else}}<p>{{.Count}} items, {{.Total}} &middot; scanned {{.Roots}} at {{.Scanned}}</p>{{end}}
+<form method="post" action="/rescan"><input type="hidden" name="token" value="{{.Token}}"><button>Rescan</button></form>
+{{range .Errors}}<p class="warn">{{.}}</p>{{end}}
+<form method="get" action="/">
+  <select name="type"><option value="">all types</option>{{range .Types}}<option{{if eq . $.Type}} selected{{end}}>{{.}}</option>{{end}}</select>
+  <input name="q" value="{{.Query}}" placeholder="path contains">
+  <label><input type="checkbox" name="group" value="project"{{if eq .Group "project"}} checked{{end}}> group by project</label>
+  <input type="hidden" name="sort" value="{{.Sort}}">
+  <button>Filter</button>
+</form>
+<form method="post" action="/delete">
+<input type="hidden" name="token" value="{{.Token}}">
+<table>
+<tr><th></th>
+{{range $f := .Columns}}<th><a href="?sort={{$f}}&amp;type={{$.Type}}&amp;q={{$.Query}}&amp;group={{$.Group}}">{{$f}}{{if eq $f $.Sort}} &#9662;{{end}}</a></th>{{end}}
+<th>type</th></tr>
+{{range .Groups}}
+{{if .Name}}<tr class="group"><td></td><td colspan="6">{{if .Declared}}{{.Declared}} &middot; {{end}}{{.Name}} ({{.Total}})</td></tr>{{end}}
+{{range .Rows}}
+<tr{{if not .Deletable}} class="held"{{end}}>
+<td>{{if and .Deletable (not $.ReadOnly)}}<input type="checkbox" name="path" value="{{.Path}}">{{end}}</td>
+<td class="num">{{.SizeHuman}}</td><td class="num">{{.DiskHuman}}</td><td class="num">{{printf "%.0f" .AgeDays}}d</td>
+<td class="num">{{printf "%.2f" .Confidence}}</td>
+<td>{{.Path}}{{if .Review}} <span class="warn">(review: {{.Review}})</span>{{end}}{{if .Advisory}} <span class="warn">(advisory{{if .Command}}: {{.Command}}{{end}})</span>{{end}}</td>
+<td>{{.Type}}</td>
+</tr>
+{{end}}
+{{end}}
+</table>
+{{if .ReadOnly}}<p>Read-only: {{.ReadOnly}}.</p>{{else}}<p><button>Delete selected&hellip;</button></p>{{end}}
+</form>
+</body></html>
+`))
+
+var deleteTemplate = template.Must(template.New("delete").Parse(`<!doctype html>
+<html><head><meta charset="utf-8"><title>tidyup: delete</title>` + servePageStyle + `</head><body>
+{{if .Done}}
+<h1>Removed {{.Removed}} of {{len .Records}} items</h1>
+{{else if not .Records}}
+<h1>Nothing selected</h1>
+{{else}}
+<h1>Delete {{len .Records}} items ({{.Total}})?</h1>
+<table>{{range .Records}}<tr><td class="num">{{.SizeHuman}}</td><td>{{.Path}}</td><td>{{.Type}}</td></tr>{{end}}</table>
+<form method="post" action="/delete">
+<input type="hidden" name="token" value="{{.Token}}">
+<input type="hidden" name="confirm" value="yes">
+{{range .Records}}<input type="hidden" name="path" value="{{.Path}}">{{end}}
+<p><button>Delete</button></p>
+</form>
+{{end}}
+<p><a href="/">Back</a></p>
+</body></html>
+`))
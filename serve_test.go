@@ -0,0 +1,217 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testURL is where testServer listens, as far as its Host checks go.
+const testURL = "http://127.0.0.1:7777"
+
+// testServer returns a server holding records, with deletions captured.
+func testServer(t *testing.T, records ...Record) (*server, *[]Record) {
+	t.Helper()
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	var deleted []Record
+	orig := serveDeleteFunc
+	serveDeleteFunc = func(rs []Record, opts *options) (int, []Record) {
+		if !opts.confirm {
+			t.Error("serve deletion without confirm set")
+		}
+		deleted = append(deleted, rs...)
+		return exitFound, rs
+	}
+	t.Cleanup(func() { serveDeleteFunc = orig })
+	s := newServer([]string{"/p"}, &options{})
+	s.addr = "127.0.0.1:7777"
+	s.apiToken = "secret"
+	s.records, s.scanned = records, time.Now()
+	return s, &deleted
+}
+
+// uiRequest returns a request from a browser that holds the session cookie.
+func uiRequest(s *server, method, path string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, testURL+path, body)
+	req.AddCookie(&http.Cookie{Name: serveCookie, Value: s.apiToken})
+	return req
+}
+
+func post(s *server, path string, form url.Values) *httptest.ResponseRecorder {
+	req := uiRequest(s, http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	s.handler().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestServe_IndexFiltersAndGroups(t *testing.T) {
+	s, _ := testServer(t,
+		Record{Type: "venv", Path: "/p/a/.venv", Size: 300},
+		Record{Type: "node_modules", Path: "/p/a/node_modules", Size: 200},
+		Record{Type: "venv", Path: "/p/b/.venv", Size: 100, Review: "uncommitted files"},
+	)
+	rec := httptest.NewRecorder()
+	s.handler().ServeHTTP(rec, uiRequest(s, http.MethodGet, "/?type=venv&group=project", nil))
+	body := rec.Body.String()
+	if !strings.Contains(body, "/p/a/.venv") || strings.Contains(body, "/p/a/node_modules") {
+		t.Errorf("type filter not applied:\n%s", body)
+	}
+	if !strings.Contains(body, "<td colspan=\"6\">/p/a (300 B)</td>") {
+		t.Errorf("no project group heading:\n%s", body)
+	}
+	// Items held for review are listed without a checkbox.
+	if strings.Contains(body, `value="/p/b/.venv"`) {
+		t.Error("review-held item is selectable")
+	}
+}
+
+func TestServe_ReadonlyRefusesDeletion(t *testing.T) {
+	s, deleted := testServer(t, Record{Type: "venv", Path: "/p/a/.venv", Size: 300})
+	s.opts.readonly = true
+
+	rec := httptest.NewRecorder()
+	s.handler().ServeHTTP(rec, uiRequest(s, http.MethodGet, "/", nil))
+	if body := rec.Body.String(); strings.Contains(body, `value="/p/a/.venv"`) || !strings.Contains(body, "Read-only: "+readonlyReason(s.opts)+".") {
+		t.Errorf("read-only index still offers deletion:\n%s", body)
+	}
+
+	form := url.Values{"path": {"/p/a/.venv"}, "token": {s.token}, "confirm": {"yes"}}
+	if rec := post(s, "/delete", form); rec.Code != http.StatusForbidden || len(*deleted) != 0 {
+		t.Errorf("read-only delete: status %d, deleted %+v", rec.Code, *deleted)
+	}
+}
+
+func TestIsLoopbackAddr(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"127.0.0.1:7777": true,
+		"[::1]:7777":     true,
+		"localhost:80":   true,
+		"0.0.0.0:7777":   false,
+		":7777":          false,
+		"10.0.0.5:7777":  false,
+		"127.0.0.1":      false,
+	} {
+		if got := isLoopbackAddr(addr); got != want {
+			t.Errorf("isLoopbackAddr(%q) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestServe_RefusesForeignHostAndOrigin(t *testing.T) {
+	s, _ := testServer(t, Record{Type: "venv", Path: "/p/a/.venv"})
+	get := func(host string) int {
+		req := uiRequest(s, http.MethodGet, "/", nil)
+		req.Host = host
+		rec := httptest.NewRecorder()
+		s.handler().ServeHTTP(rec, req)
+		return rec.Code
+	}
+	for host, want := range map[string]int{
+		"127.0.0.1:7777":    http.StatusOK,
+		"[::1]:7777":        http.StatusOK,
+		"localhost:7777":    http.StatusOK,
+		"evil.example:7777": http.StatusMisdirectedRequest, // DNS rebinding
+		"127.0.0.1:8888":    http.StatusMisdirectedRequest,
+		"":                  http.StatusMisdirectedRequest,
+	} {
+		if got := get(host); got != want {
+			t.Errorf("Host %q: status %d, want %d", host, got, want)
+		}
+	}
+
+	s.roots = []string{t.TempDir()}
+	for origin, want := range map[string]int{
+		"http://evil.example":   http.StatusForbidden,
+		"http://localhost:7777": http.StatusSeeOther,
+	} {
+		rec := httptest.NewRecorder()
+		req := uiRequest(s, http.MethodPost, "/rescan", strings.NewReader(url.Values{"token": {s.token}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Origin", origin)
+		s.handler().ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("Origin %s: status %d, want %d", origin, rec.Code, want)
+		}
+	}
+}
+
+func TestServe_DeferredStartupScan(t *testing.T) {
+	s, _ := testServer(t)
+	s.roots = []string{t.TempDir()}
+	savedPoll, savedBlocker := deferPoll, backgroundBlocker
+	t.Cleanup(func() { deferPoll, backgroundBlocker = savedPoll, savedBlocker })
+	deferPoll = time.Millisecond
+	var mu sync.Mutex
+	blocked := true
+	backgroundBlocker = func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		if blocked {
+			return "running on battery power"
+		}
+		return ""
+	}
+
+	s.pending = backgroundBlocker()
+	done := make(chan struct{})
+	go func() { s.scanWhenClear(); close(done) }()
+	rec := httptest.NewRecorder()
+	s.handler().ServeHTTP(rec, uiRequest(s, http.MethodGet, "/", nil))
+	if !strings.Contains(rec.Body.String(), "deferred: running on battery power") {
+		t.Errorf("index does not say the scan is deferred:\n%s", rec.Body.String())
+	}
+
+	mu.Lock()
+	blocked = false
+	mu.Unlock()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("deferred scan never ran")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pending != "" || s.scanned.IsZero() {
+		t.Errorf("after AC power: pending %q, scanned %v", s.pending, s.scanned)
+	}
+}
+
+func TestServe_RequiresServeToken(t *testing.T) {
+	s, _ := testServer(t, Record{Type: "venv", Path: "/p/a/.venv"})
+	get := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.handler().ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, path := range []string{"/", "/?token=wrong"} {
+		if rec := get(httptest.NewRequest(http.MethodGet, testURL+path, nil)); rec.Code != http.StatusUnauthorized || strings.Contains(rec.Body.String(), s.token) {
+			t.Errorf("GET %s: status %d, want 401 without the page", path, rec.Code)
+		}
+	}
+	form := url.Values{"path": {"/p/a/.venv"}, "token": {s.token}, "confirm": {"yes"}}
+	req := httptest.NewRequest(http.MethodPost, testURL+"/delete", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if rec := get(req); rec.Code != http.StatusUnauthorized {
+		t.Errorf("POST /delete without the cookie: status %d, want 401", rec.Code)
+	}
+
+	// The address serve prints sets the cookie and drops the token from
+	// the URL.
+	rec := get(httptest.NewRequest(http.MethodGet, testURL+"/?type=venv&token=secret", nil))
+	cookies := rec.Result().Cookies()
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/?type=venv" || len(cookies) != 1 || cookies[0].Value != "secret" || !cookies[0].HttpOnly {
+		t.Fatalf("GET with token: status %d, Location %q, cookies %v", rec.Code, rec.Header().Get("Location"), cookies)
+	}
+	req = httptest.NewRequest(http.MethodGet, testURL+"/?type=venv", nil)
+	req.AddCookie(cookies[0])
+	if rec := get(req); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "/p/a/.venv") {
+		t.Errorf("GET with cookie: status %d", rec.Code)
+	}
+}
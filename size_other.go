@@ -0,0 +1,29 @@
+//go:build !unix
+
+package main
+
+import "io/fs"
+
+// fileID identifies an inode for hard-link deduplication.
+type fileID struct{ dev, ino uint64 }
+
+// diskBytes falls back to the apparent size where allocation info is unavailable.
+func diskBytes(info fs.FileInfo, _ map[fileID]bool) int64 {
+	return info.Size()
+}
+
+// ownedByCurrentUser assumes ownership where it cannot be checked; temp
+// directories are per-user on Windows.
+func ownedByCurrentUser(fs.FileInfo) bool {
+	return true
+}
+
+// deviceOf is unavailable here; callers treat everything as one filesystem.
+func deviceOf(string) (uint64, bool) {
+	return 0, false
+}
+
+// identity is unavailable here; removal skips the replaced-item check.
+func identity(fs.FileInfo) (fileID, bool) {
+	return fileID{}, false
+}
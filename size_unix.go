@@ -0,0 +1,54 @@
+//go:build unix
+
+package main
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// fileID identifies an inode for hard-link deduplication.
+type fileID struct{ dev, ino uint64 }
+
+// diskBytes returns the allocated size of a file (st_blocks * 512), which is
+// smaller than the apparent size for sparse, compressed, or cloned files.
+// Hard-linked files are counted once per seen map, like du.
+func diskBytes(info fs.FileInfo, seen map[fileID]bool) int64 {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return info.Size()
+	}
+	if st.Nlink > 1 {
+		id := fileID{uint64(st.Dev), uint64(st.Ino)}
+		if seen[id] {
+			return 0
+		}
+		seen[id] = true
+	}
+	return int64(st.Blocks) * 512
+}
+
+// ownedByCurrentUser reports whether info belongs to the running user.
+func ownedByCurrentUser(info fs.FileInfo) bool {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	return ok && int(st.Uid) == os.Getuid()
+}
+
+// deviceOf returns the device number of the filesystem holding path.
+func deviceOf(path string) (uint64, bool) {
+	var st syscall.Stat_t
+	if err := syscall.Lstat(path, &st); err != nil {
+		return 0, false
+	}
+	return uint64(st.Dev), true
+}
+
+// identity returns the inode info describes.
+func identity(info fs.FileInfo) (fileID, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileID{}, false
+	}
+	return fileID{uint64(st.Dev), uint64(st.Ino)}, true
+}
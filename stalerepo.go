@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Once the caches are gone, what is left is often whole clones nobody has
+// worked in for months. stale_repo (opt-in, -type stale_repo) reports
+// each git clone with no commit, fetch, or file change in -repo-months
+// months, sized with its history, as a candidate for archiving. It is
+// report-only: a clone can hold unpushed branches and stashes only it has.
+// The walk keeps going inside a clone, so the artifacts in it are still
+// items of their own (and counted in the clone's size too).
+
+// isGitClone reports whether path is the working tree of a clone: .git is
+// a directory. Worktrees and submodules (a .git file) share another
+// repository's history and are not clones on their own.
+func isGitClone(path string) bool {
+	info, err := os.Lstat(filepath.Join(path, ".git"))
+	return err == nil && info.IsDir()
+}
+
+// reflogTime returns the time of the last entry in a reflog, whose lines
+// end "<name> <email> <unix time> <zone>\t<message>".
+func reflogTime(path string) (time.Time, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	defer f.Close()
+	var last string
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		if line := sc.Text(); line != "" {
+			last = line
+		}
+	}
+	head, _, _ := strings.Cut(last, "\t")
+	fields := strings.Fields(head)
+	if len(fields) < 2 {
+		return time.Time{}, false
+	}
+	secs, err := strconv.ParseInt(fields[len(fields)-2], 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(secs, 0), true
+}
+
+// getRepoUsage dates a clone by the newest of its last HEAD reflog entry
+// (commits, checkouts, merges, resets, the clone itself), FETCH_HEAD's
+// mtime (fetches and pulls), and the newest file mtime in its working
+// tree. The reflog's own timestamps are used rather than its mtime, which
+// a copied or restored clone resets.
+func getRepoUsage(path string) (time.Time, bool) {
+	dotGit := filepath.Join(path, ".git")
+	lastUsed, found := reflogTime(filepath.Join(dotGit, "logs", "HEAD"))
+	if info, err := os.Stat(filepath.Join(dotGit, "FETCH_HEAD")); err == nil && info.ModTime().After(lastUsed) {
+		lastUsed, found = info.ModTime(), true
+	}
+	_ = walkTree(path, func(p string, d fs.DirEntry, err error) error {
+		pace()
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if p == dotGit {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.ModTime().After(lastUsed) {
+			lastUsed, found = info.ModTime(), true
+		}
+		return nil
+	})
+	return lastUsed, found
+}
+
+// annotateStaleRepo names the clone's project and holds it for review.
+func annotateStaleRepo(r *Record) {
+	r.Advisory = true
+	if name := projectName(r.Path); name != "" {
+		r.Project = name
+	}
+	if t, ok := reflogTime(filepath.Join(r.Path, ".git", "logs", "HEAD")); ok {
+		r.Notes = append(r.Notes, "last commit or checkout "+t.Format("2006-01-02"))
+	}
+	r.Notes = append(r.Notes, "whole clone, history included; archive it (git bundle, or push its branches) before removing it")
+}
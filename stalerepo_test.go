@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// makeClone writes a clone whose last reflog entry and files date from
+// days ago.
+func makeClone(t *testing.T, dir string, days int) {
+	t.Helper()
+	then := time.Now().AddDate(0, 0, -days)
+	reflog := fmt.Sprintf("0000 1111 T <t@example.com> %d +0000\tclone: from example\n", then.Unix())
+	for rel, data := range map[string]string{
+		".git/HEAD":      "ref: refs/heads/main\n",
+		".git/logs/HEAD": reflog,
+		"README":         "x",
+		"src/main.go":    "package main",
+	} {
+		p := filepath.Join(dir, rel)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte(data), 0644)
+		os.Chtimes(p, then, then)
+	}
+}
+
+func TestGetRepoUsage(t *testing.T) {
+	repo := t.TempDir()
+	makeClone(t, repo, 400)
+	got, ok := getRepoUsage(repo)
+	if !ok || got.After(time.Now().AddDate(0, 0, -399)) {
+		t.Fatalf("getRepoUsage = %v, %v; want the reflog's time 400 days ago", got, ok)
+	}
+
+	// The reflog's timestamps count, not its mtime (a restored clone).
+	now := time.Now()
+	os.Chtimes(filepath.Join(repo, ".git", "logs", "HEAD"), now, now)
+	if got, _ := getRepoUsage(repo); got.After(time.Now().AddDate(0, 0, -399)) {
+		t.Errorf("after touching the reflog: %v, want still 400 days ago", got)
+	}
+
+	fetched := time.Now().AddDate(0, 0, -3)
+	fetchHead := filepath.Join(repo, ".git", "FETCH_HEAD")
+	os.WriteFile(fetchHead, nil, 0644)
+	os.Chtimes(fetchHead, fetched, fetched)
+	if got, _ := getRepoUsage(repo); !got.Equal(fetched.Truncate(time.Second)) && !got.Equal(fetched) {
+		t.Errorf("after a fetch: %v, want %v", got, fetched)
+	}
+}
+
+func TestScanRoots_StaleRepo(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	root := t.TempDir()
+	makeClone(t, filepath.Join(root, "old"), 400)
+	makeClone(t, filepath.Join(root, "edited"), 400)
+	edit := filepath.Join(root, "edited", "src", "main.go")
+	recent := time.Now().AddDate(0, 0, -10)
+	os.Chtimes(edit, recent, recent)
+	makeClone(t, filepath.Join(root, "young"), 60)
+	// A worktree shares another clone's history.
+	worktree := filepath.Join(root, "wt")
+	os.MkdirAll(worktree, 0755)
+	os.WriteFile(filepath.Join(worktree, ".git"), []byte("gitdir: ../old/.git/worktrees/wt\n"), 0644)
+	// Artifacts inside a stale clone are still found.
+	mods := filepath.Join(root, "old", "node_modules")
+	os.MkdirAll(filepath.Join(mods, "pkg"), 0755)
+	old := time.Now().AddDate(0, 0, -400)
+	for _, rel := range []string{".package-lock.json", "pkg/index.js"} {
+		os.WriteFile(filepath.Join(mods, rel), []byte("x"), 0644)
+		os.Chtimes(filepath.Join(mods, rel), old, old)
+	}
+
+	opts := &options{minAge: 30, maxDepth: 5, repoMonths: 6, scanTypes: map[string]bool{"stale_repo": true, "node_modules": true}}
+	records, _ := scanRoots([]string{root}, opts)
+	got := map[string]Record{}
+	for _, r := range records {
+		rel, _ := filepath.Rel(root, r.Path)
+		got[r.Type+" "+rel] = r
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %v, want the old clone and its node_modules", got)
+	}
+	repo, ok := got["stale_repo old"]
+	if !ok || !repo.Advisory || repo.Size == 0 {
+		t.Errorf("stale_repo old = %+v, want an advisory, sized record", repo)
+	}
+	if _, ok := got["node_modules old/node_modules"]; !ok {
+		t.Errorf("node_modules inside the stale clone not reported: %v", got)
+	}
+
+	// -repo-months 1 takes in the younger clone too.
+	opts.repoMonths = 1
+	records, _ = scanRoots([]string{root}, opts)
+	if len(records) != 3 {
+		t.Errorf("-repo-months 1: got %d records, want 3", len(records))
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// State files are written unattended by scheduled runs and `tidyup serve`,
+// so a crash or full disk must never leave one half-written: every rewrite
+// goes to a temporary file that is synced and then renamed over the old one.
+// Files only tidyup reads (the history and the approval queue) also carry a
+// SHA-256 of their contents, and a file that fails the check is moved aside
+// and started afresh rather than failing the run. Append-only logs
+// (decisions, the trash manifest, deletion logs) are not rewritten; their
+// readers skip a torn last line instead.
+
+// errStateCorrupt reports a state file whose checksum does not match.
+var errStateCorrupt = errors.New("checksum mismatch")
+
+// stateEnvelope wraps a checksummed state file's contents.
+type stateEnvelope struct {
+	SHA256 string          `json:"sha256"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// writeFileAtomic replaces path with data: written to a temporary file in
+// the same directory, synced, and renamed into place, so readers see the old
+// contents or the new, never a mix.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, perm)
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	// Make the rename itself durable. Directories cannot be synced on
+	// every platform; the rename is still atomic there.
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// saveState writes v as a checksummed state file.
+func saveState(path string, v any, perm os.FileMode) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	out, err := json.Marshal(stateEnvelope{SHA256: hex.EncodeToString(sum[:]), Data: data})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(out, '\n'), perm)
+}
+
+// loadState reads a state file written by saveState into v. Files from
+// before checksums (no envelope) are read as they are. It returns
+// errStateCorrupt, wrapped, for files that are truncated, garbled, or fail
+// the checksum; callers recover with setAsideCorrupt.
+func loadState(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var env stateEnvelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return fmt.Errorf("%s: %w (%v)", path, errStateCorrupt, err)
+	}
+	if env.SHA256 == "" {
+		// Unversioned legacy file.
+		if err := json.Unmarshal(data, v); err != nil {
+			return fmt.Errorf("%s: %w (%v)", path, errStateCorrupt, err)
+		}
+		return nil
+	}
+	sum := sha256.Sum256(env.Data)
+	if want, _ := hex.DecodeString(env.SHA256); !bytes.Equal(sum[:], want) {
+		return fmt.Errorf("%s: %w", path, errStateCorrupt)
+	}
+	if err := json.Unmarshal(env.Data, v); err != nil {
+		return fmt.Errorf("%s: %w (%v)", path, errStateCorrupt, err)
+	}
+	return nil
+}
+
+// setAsideCorrupt moves a corrupt state file to path.corrupt, for
+// inspection, so the next write starts clean.
+func setAsideCorrupt(path string, err error) {
+	stderr.warnf("%v; starting over (the old file is kept as %s.corrupt)", err, filepath.Base(path))
+	os.Rename(path, path+".corrupt")
+}
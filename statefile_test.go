@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sub", "f.json")
+	for _, content := range []string{"one", "two"} {
+		if err := writeFileAtomic(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if data, _ := os.ReadFile(path); string(data) != content {
+			t.Errorf("content = %q, want %q", data, content)
+		}
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestLoadState_Checksum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "s.json")
+	type doc struct{ N int }
+	if err := saveState(path, doc{N: 7}, 0600); err != nil {
+		t.Fatal(err)
+	}
+	var got doc
+	if err := loadState(path, &got); err != nil || got.N != 7 {
+		t.Fatalf("round trip = %+v, %v", got, err)
+	}
+
+	// A flipped byte inside the data fails the checksum.
+	data, _ := os.ReadFile(path)
+	os.WriteFile(path, []byte(strings.Replace(string(data), `"N":7`, `"N":8`, 1)), 0600)
+	if err := loadState(path, &got); !errors.Is(err, errStateCorrupt) {
+		t.Errorf("tampered file: err = %v, want errStateCorrupt", err)
+	}
+
+	// So does a torn write.
+	os.WriteFile(path, data[:len(data)/2], 0600)
+	if err := loadState(path, &got); !errors.Is(err, errStateCorrupt) {
+		t.Errorf("truncated file: err = %v, want errStateCorrupt", err)
+	}
+
+	// Files from before checksums are read as they are.
+	os.WriteFile(path, []byte(`{"N": 3}`), 0600)
+	if err := loadState(path, &got); err != nil || got.N != 3 {
+		t.Errorf("legacy file = %+v, %v", got, err)
+	}
+}
+
+func TestLoadHistory_RecoversFromCorruption(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	os.WriteFile(path, []byte(`{"sha256": "00", "data": {"version": 1`), 0600)
+
+	h := loadHistory(path)
+	if len(h.Items) != 0 {
+		t.Errorf("corrupt history loaded items: %v", h.Items)
+	}
+	if _, err := os.Stat(path + ".corrupt"); err != nil {
+		t.Errorf("corrupt history not set aside: %v", err)
+	}
+	h.record([]Record{{Path: "/p", Size: 1, LastUsed: time.Now().Format(time.RFC3339)}}, time.Now())
+	if err := h.save(path, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if len(loadHistory(path).Items) != 1 {
+		t.Error("history not rebuilt after corruption")
+	}
+}
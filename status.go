@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Menu-bar apps need two things from a running `tidyup serve`: the current
+// reclaimable total without waiting for a scan, and a nudge when it
+// changes. serve writes a summary to status.json in the state directory
+// after every scan and deletion, which `tidyup status` prints, and streams
+// the same summary as JSON lines to every client of events.sock there,
+// whenever the total changes and once on connect.
+
+// statusSummary is status.json and each event on events.sock.
+type statusSummary struct {
+	Event          string           `json:"event,omitempty"` // events only: "reclaimable_changed"
+	Time           string           `json:"time"`            // RFC3339, when the summary was made
+	ScannedAt      string           `json:"scanned_at"`      // RFC3339, the scan it describes
+	PID            int              `json:"pid"`
+	Addr           string           `json:"addr,omitempty"`
+	Count          int              `json:"count"`
+	TotalBytes     int64            `json:"total_bytes"`
+	TotalDiskBytes int64            `json:"total_disk_bytes"`
+	ByType         map[string]int64 `json:"by_type"` // apparent bytes per type
+}
+
+// eventReclaimableChanged is the only event type so far.
+const eventReclaimableChanged = "reclaimable_changed"
+
+// statusPath and eventsSocketPath are the daemon's files in the state
+// directory.
+func statusPath() (string, error) {
+	return statePath("status.json")
+}
+
+func eventsSocketPath() (string, error) {
+	return statePath("events.sock")
+}
+
+// summarize describes records for status.json.
+func summarize(records []Record, scanned, now time.Time, addr string) statusSummary {
+	sum := statusSummary{
+		Time:      now.Format(time.RFC3339),
+		ScannedAt: scanned.Format(time.RFC3339),
+		PID:       os.Getpid(),
+		Addr:      addr,
+		Count:     len(records),
+		ByType:    map[string]int64{},
+	}
+	for _, r := range records {
+		sum.TotalBytes += r.Size
+		sum.TotalDiskBytes += r.DiskSize
+		sum.ByType[r.Type] += r.Size
+	}
+	return sum
+}
+
+// eventHub fans summaries out to events.sock clients.
+type eventHub struct {
+	mu      sync.Mutex
+	clients map[net.Conn]bool
+	last    *statusSummary
+}
+
+// listen accepts clients on a Unix socket at path, replacing a stale one.
+func (h *eventHub) listen(path string) error {
+	os.Remove(path)
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			h.mu.Lock()
+			if h.clients == nil {
+				h.clients = map[net.Conn]bool{}
+			}
+			h.clients[conn] = true
+			if h.last != nil {
+				h.send(conn, *h.last)
+			}
+			h.mu.Unlock()
+		}
+	}()
+	return nil
+}
+
+// send writes one event line, dropping clients that do not keep up.
+// Callers hold mu.
+func (h *eventHub) send(conn net.Conn, sum statusSummary) {
+	sum.Event = eventReclaimableChanged
+	line, _ := json.Marshal(sum)
+	conn.SetWriteDeadline(time.Now().Add(time.Second))
+	if _, err := conn.Write(append(line, '\n')); err != nil {
+		conn.Close()
+		delete(h.clients, conn)
+	}
+}
+
+// publish records sum and notifies clients if the reclaimable total moved.
+func (h *eventHub) publish(sum statusSummary) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	changed := h.last == nil || h.last.TotalBytes != sum.TotalBytes || h.last.Count != sum.Count
+	h.last = &sum
+	if !changed {
+		return
+	}
+	for conn := range h.clients {
+		h.send(conn, sum)
+	}
+}
+
+// writeStatus saves sum to status.json.
+func writeStatus(sum statusSummary) error {
+	path, err := statusPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(sum, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'), 0600)
+}
+
+// runStatus implements `tidyup status`: the running server's latest
+// summary, read from disk without scanning.
+func runStatus(args []string) int {
+	flags := flag.NewFlagSet("status", flag.ContinueOnError)
+	jsonOut := flags.Bool("json", false, "Print status.json as is")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tidyup status [-json]\n\n")
+		fmt.Fprintf(os.Stderr, "Prints the latest summary written by 'tidyup serve' without scanning.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	path, err := statusPath()
+	if err != nil {
+		stderr.errorf("%v", err)
+		return exitError
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		stderr.errorf("no status yet (%v); start 'tidyup serve'", err)
+		return exitError
+	}
+	if *jsonOut {
+		os.Stdout.Write(data)
+		return exitOK
+	}
+	var sum statusSummary
+	if err := json.Unmarshal(data, &sum); err != nil {
+		stderr.errorf("decoding %s: %v", path, err)
+		return exitError
+	}
+	fmt.Printf("%d items, %s reclaimable (scanned %s by pid %d", sum.Count, formatBytes(sum.TotalBytes), sum.ScannedAt, sum.PID)
+	if sum.Addr != "" {
+		fmt.Printf(", http://%s/", sum.Addr)
+	}
+	fmt.Println(")")
+	types := make([]string, 0, len(sum.ByType))
+	for t := range sum.ByType {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if sum.ByType[types[i]] != sum.ByType[types[j]] {
+			return sum.ByType[types[i]] > sum.ByType[types[j]]
+		}
+		return types[i] < types[j]
+	})
+	for _, t := range types {
+		fmt.Printf("  %-16s %s\n", t, formatBytes(sum.ByType[t]))
+	}
+	return exitOK
+}
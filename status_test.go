@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSummarize(t *testing.T) {
+	now := time.Now()
+	sum := summarize([]Record{
+		{Type: "venv", Size: 100, DiskSize: 4096},
+		{Type: "venv", Size: 50, DiskSize: 4096},
+		{Type: "node_modules", Size: 1000, DiskSize: 8192},
+	}, now, now, "127.0.0.1:7777")
+	if sum.Count != 3 || sum.TotalBytes != 1150 || sum.TotalDiskBytes != 16384 || sum.ByType["venv"] != 150 {
+		t.Errorf("summary = %+v", sum)
+	}
+}
+
+func TestServer_PublishesStatusAndEvents(t *testing.T) {
+	s, _ := testServer(t, Record{Type: "venv", Path: t.TempDir(), Size: 300})
+	sock := filepath.Join(t.TempDir(), "events.sock")
+	if err := s.events.listen(sock); err != nil {
+		t.Skipf("no unix sockets: %v", err)
+	}
+	s.mu.Lock()
+	s.publishLocked()
+	s.mu.Unlock()
+
+	path, _ := statusPath()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sum statusSummary
+	json.Unmarshal(data, &sum)
+	if sum.Count != 1 || sum.TotalBytes != 300 || sum.PID != os.Getpid() {
+		t.Errorf("status.json = %+v", sum)
+	}
+
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	lines := bufio.NewScanner(conn)
+
+	// A new client gets the current summary, then each change.
+	if !lines.Scan() {
+		t.Fatalf("no event on connect: %v", lines.Err())
+	}
+	json.Unmarshal(lines.Bytes(), &sum)
+	if sum.Event != eventReclaimableChanged || sum.TotalBytes != 300 {
+		t.Errorf("connect event = %+v", sum)
+	}
+	s.remove(s.records)
+	if !lines.Scan() {
+		t.Fatalf("no event after delete: %v", lines.Err())
+	}
+	json.Unmarshal(lines.Bytes(), &sum)
+	if sum.Count != 0 || sum.TotalBytes != 0 {
+		t.Errorf("delete event = %+v", sum)
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// -stream keeps a scan of a very large tree (a fileserver with hundreds of
+// thousands of candidates) from holding every Record in memory. Records go
+// to a recordSpool as they are sized; once a chunk of them has piled up,
+// the chunk is sorted and written out as a run to the scan index in the
+// cache directory. After the scan the runs are merged back in -sort order
+// and written to stdout as JSON Lines, one record per line, then a summary
+// line. Only a chunk's worth of records is held at a time, plus one per
+// run during the merge.
+
+// spoolChunk is how many records a recordSpool holds before spilling them.
+var spoolChunk = 10000
+
+// recordSpool collects a streaming scan's records, spilling sorted runs of
+// them to files in dir.
+type recordSpool struct {
+	dir   string
+	less  func(a, b *Record) bool
+	chunk []Record
+	runs  []string
+	err   error // the spill that failed; later records stay in memory
+
+	count     int
+	total     int64
+	totalDisk int64
+	byTag     map[string]groupTotal
+	byOwner   map[string]groupTotal
+}
+
+func newRecordSpool(dir, sortField string) *recordSpool {
+	return &recordSpool{dir: dir, less: recordLess(sortField)}
+}
+
+// add takes a record, spilling the chunk when it is full.
+func (sp *recordSpool) add(r Record) {
+	sp.chunk = append(sp.chunk, r)
+	sp.count++
+	sp.total += r.Size
+	sp.totalDisk += r.DiskSize
+	sp.byTag = addGroupTotals(sp.byTag, tagTotals([]Record{r}))
+	sp.byOwner = addGroupTotals(sp.byOwner, ownerTotals([]Record{r}))
+	if len(sp.chunk) >= spoolChunk && sp.err == nil {
+		if sp.err = sp.spill(); sp.err != nil {
+			stderr.warnf("-stream: keeping records in memory: %v", sp.err)
+		}
+	}
+}
+
+// addGroupTotals adds totals into sums, which it allocates on first use.
+func addGroupTotals(sums, totals map[string]groupTotal) map[string]groupTotal {
+	for name, t := range totals {
+		if sums == nil {
+			sums = map[string]groupTotal{}
+		}
+		sum := sums[name]
+		sum.Count += t.Count
+		sum.Bytes += t.Bytes
+		sums[name] = sum
+	}
+	return sums
+}
+
+// spill writes the chunk, sorted, to a new run file.
+func (sp *recordSpool) spill() error {
+	if err := os.MkdirAll(sp.dir, 0700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(sp.dir, "run-*.jsonl")
+	if err != nil {
+		return err
+	}
+	sp.runs = append(sp.runs, f.Name())
+	sp.sort()
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for i := range sp.chunk {
+		if err := enc.Encode(&sp.chunk[i]); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	sp.chunk = sp.chunk[:0]
+	return f.Close()
+}
+
+func (sp *recordSpool) sort() {
+	sort.Slice(sp.chunk, func(i, j int) bool { return sp.less(&sp.chunk[i], &sp.chunk[j]) })
+}
+
+// each calls fn with every record in sort order, merging the spilled runs
+// with the records still in memory.
+func (sp *recordSpool) each(fn func(Record) error) error {
+	sp.sort()
+	type source struct {
+		dec  *json.Decoder // nil: the in-memory chunk
+		next int
+		head Record
+		ok   bool
+	}
+	advance := func(s *source) error {
+		if s.dec == nil {
+			if s.ok = s.next < len(sp.chunk); s.ok {
+				s.head = sp.chunk[s.next]
+				s.next++
+			}
+			return nil
+		}
+		s.head = Record{}
+		err := s.dec.Decode(&s.head)
+		if err == io.EOF {
+			s.ok = false
+			return nil
+		}
+		s.ok = err == nil
+		return err
+	}
+
+	sources := []*source{{}}
+	for _, run := range sp.runs {
+		f, err := os.Open(run)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		sources = append(sources, &source{dec: json.NewDecoder(bufio.NewReader(f))})
+	}
+	for _, s := range sources {
+		if err := advance(s); err != nil {
+			return fmt.Errorf("reading the scan index: %w", err)
+		}
+	}
+	// Runs are few (one per spoolChunk records), so a linear pick of the
+	// smallest head does as well as a heap.
+	for {
+		var min *source
+		for _, s := range sources {
+			if s.ok && (min == nil || sp.less(&s.head, &min.head)) {
+				min = s
+			}
+		}
+		if min == nil {
+			return nil
+		}
+		if err := fn(min.head); err != nil {
+			return err
+		}
+		if err := advance(min); err != nil {
+			return fmt.Errorf("reading the scan index: %w", err)
+		}
+	}
+}
+
+// close removes the spilled runs.
+func (sp *recordSpool) close() {
+	for _, run := range sp.runs {
+		os.Remove(run)
+	}
+	sp.runs = nil
+}
+
+// streamSummary is the last line of -stream output: the -json summary
+// fields, without the records.
+type streamSummary struct {
+	Summary        bool                  `json:"summary"` // always true: tells this line from the records
+	SchemaVersion  int                   `json:"schema_version"`
+	Count          int                   `json:"count"`
+	TotalBytes     int64                 `json:"total_bytes"`
+	TotalHuman     string                `json:"total_human"`
+	TotalDiskBytes int64                 `json:"total_disk_bytes"`
+	TotalDiskHuman string                `json:"total_disk_human"`
+	DryRun         bool                  `json:"dry_run"` // always true: -stream never deletes
+	AsOf           string                `json:"as_of,omitempty"`
+	ScannedAt      string                `json:"scanned_at,omitempty"` // RFC3339 in UTC
+	Host           string                `json:"host,omitempty"`
+	ByTag          map[string]groupTotal `json:"by_tag,omitempty"`
+	ByOwner        map[string]groupTotal `json:"by_owner,omitempty"`
+	Warnings       []Warning             `json:"warnings"`
+}
+
+// streamIndexDir is where -stream spills records: the scan index in the
+// cache directory, which nothing else needs to survive.
+func streamIndexDir() (string, error) {
+	dir, err := cacheDir()
+	return filepath.Join(dir, "index"), err
+}
+
+// streamRoots scans roots for -stream, writing the records to w as JSON
+// Lines in opts.sortField order followed by a streamSummary line. It
+// returns the spool, for its totals.
+func streamRoots(w io.Writer, roots []string, opts *options) (*recordSpool, error) {
+	dir, err := streamIndexDir()
+	if err != nil {
+		return nil, err
+	}
+	s := newScanner(opts)
+	s.spool = newRecordSpool(dir, opts.sortField)
+	defer s.spool.close()
+	scanErrors := s.scan(roots)
+	for _, e := range scanErrors {
+		stderr.warn("scan_error", "", "%s", e)
+	}
+	tally.failed(len(scanErrors))
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	err = s.spool.each(func(r Record) error {
+		s.finish(&r)
+		return enc.Encode(withUnambiguousTimes([]Record{r})[0])
+	})
+	if err != nil {
+		return nil, err
+	}
+	sp := s.spool
+	sum := streamSummary{
+		Summary:        true,
+		SchemaVersion:  schemaVersion,
+		Count:          sp.count,
+		TotalBytes:     sp.total,
+		TotalHuman:     formatBytes(sp.total),
+		TotalDiskBytes: sp.totalDisk,
+		TotalDiskHuman: formatBytes(sp.totalDisk),
+		DryRun:         true,
+		ByTag:          sp.byTag,
+		ByOwner:        sp.byOwner,
+		Warnings:       stderr.takeWarnings(),
+	}
+	sum.ScannedAt, sum.Host, sum.AsOf = scanProvenance(opts)
+	enc.Encode(sum)
+	return sp, bw.Flush()
+}
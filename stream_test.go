@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordSpool_MergesRuns(t *testing.T) {
+	defer func(n int) { spoolChunk = n }(spoolChunk)
+	spoolChunk = 3
+	dir := t.TempDir()
+	sp := newRecordSpool(dir, "size")
+	for _, size := range []int64{5, 9, 1, 7, 3, 8, 2, 6, 4, 10} {
+		sp.add(Record{Path: fmt.Sprintf("/p/%d", size), Size: size})
+	}
+	if len(sp.runs) != 3 || len(sp.chunk) != 1 {
+		t.Fatalf("runs %d, in memory %d; want 3 spilled runs and 1 record held", len(sp.runs), len(sp.chunk))
+	}
+	var got []int64
+	if err := sp.each(func(r Record) error { got = append(got, r.Size); return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != "[10 9 8 7 6 5 4 3 2 1]" {
+		t.Errorf("merged sizes = %v, want largest first", got)
+	}
+	if sp.count != 10 || sp.total != 55 {
+		t.Errorf("count %d, total %d; want 10, 55", sp.count, sp.total)
+	}
+	sp.close()
+	if left, _ := os.ReadDir(dir); len(left) != 0 {
+		t.Errorf("runs left behind after close: %v", left)
+	}
+}
+
+func TestStreamRoots(t *testing.T) {
+	defer func(n int) { spoolChunk = n }(spoolChunk)
+	spoolChunk = 1
+	cache := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cache)
+	root := t.TempDir()
+	old, older := time.Now().AddDate(0, 0, -60), time.Now().AddDate(0, 0, -90)
+	makeVenv(t, filepath.Join(root, "web", ".venv"), old)
+	makeVenv(t, filepath.Join(root, "web", "venv"), older)
+	makeVenv(t, filepath.Join(root, "api", ".venv"), older)
+
+	var out bytes.Buffer
+	opts := &options{minAge: 30, maxDepth: 5, sortField: "path", scanTypes: map[string]bool{"venv": true}}
+	sp, err := streamRoots(&out, []string{root}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []map[string]any
+	sc := bufio.NewScanner(&out)
+	for sc.Scan() {
+		var line map[string]any
+		if err := json.Unmarshal(sc.Bytes(), &line); err != nil {
+			t.Fatalf("line %q: %v", sc.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 4 || sp.count != 3 {
+		t.Fatalf("got %d lines for %d records, want 3 records and a summary:\n%s", len(lines), sp.count, out.String())
+	}
+	want := []string{
+		filepath.Join(root, "api", ".venv"),
+		filepath.Join(root, "web", ".venv"),
+		filepath.Join(root, "web", "venv"),
+	}
+	for i, path := range want {
+		if lines[i]["path"] != path {
+			t.Errorf("line %d path = %v, want %s", i, lines[i]["path"], path)
+		}
+	}
+	if lines[2]["newest_sibling"] != want[1] {
+		t.Errorf("older web venv: newest_sibling = %v, want %s", lines[2]["newest_sibling"], want[1])
+	}
+	if lines[3]["summary"] != true || lines[3]["count"] != 3.0 || lines[3]["dry_run"] != true {
+		t.Errorf("summary line = %v", lines[3])
+	}
+	// The same provenance as the batch -json summary.
+	if host, _ := hostnameFunc(); lines[3]["host"] != host {
+		t.Errorf("summary host = %v, want %s", lines[3]["host"], host)
+	}
+	if at, _ := lines[3]["scanned_at"].(string); at == "" {
+		t.Errorf("summary has no scanned_at: %v", lines[3])
+	} else if _, err := time.Parse(time.RFC3339, at); err != nil {
+		t.Errorf("summary scanned_at: %v", err)
+	}
+	if left, _ := os.ReadDir(filepath.Join(cache, "tidyup", "index")); len(left) != 0 {
+		t.Errorf("runs left in the scan index: %v", left)
+	}
+}
+
+func TestRecordSpool_OwnerTotals(t *testing.T) {
+	sp := newRecordSpool(t.TempDir(), "path")
+	sp.add(Record{Path: "/a", Size: 10, Owner: "jane"})
+	sp.add(Record{Path: "/b", Size: 5, Owner: "jane"})
+	sp.add(Record{Path: "/c", Size: 1})
+	defer sp.close()
+	if got := sp.byOwner["jane"]; len(sp.byOwner) != 1 || got.Count != 2 || got.Bytes != 15 {
+		t.Errorf("byOwner = %+v, want jane with 2 items, 15 bytes", sp.byOwner)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Every scan ends with one machine-readable line on stderr, whatever the
+// stdout format -- text, -json, or an interactive deletion -- so wrapper
+// scripts can always capture the outcome:
+//
+//	tidyup: found=12 bytes=4831838208 deleted=3 freed=1073741824 errors=0 duration=2.417
+//
+// bytes is the apparent size of what was found, freed the allocated size of
+// what was removed (or trashed, or delegated), and duration is in seconds.
+
+// runTally counts what a run removed and what failed along the way.
+type runTally struct {
+	mu      sync.Mutex
+	deleted int
+	freed   int64
+	errors  int
+}
+
+// tally is the process's tally.
+var tally = &runTally{}
+
+// removed counts a removed record.
+func (t *runTally) removed(r Record) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.deleted++
+	if r.DiskSize > 0 {
+		t.freed += r.DiskSize
+	} else {
+		t.freed += r.Size
+	}
+}
+
+// failed counts n errors.
+func (t *runTally) failed(n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.errors += n
+}
+
+// summaryLine formats the closing line for found records.
+func (t *runTally) summaryLine(found []Record, elapsed time.Duration) string {
+	return t.summaryCounts(len(found), totalSize(found), elapsed)
+}
+
+// summaryCounts is summaryLine for records counted as they went by
+// (-stream).
+func (t *runTally) summaryCounts(found int, bytes int64, elapsed time.Duration) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return fmt.Sprintf("tidyup: found=%d bytes=%d deleted=%d freed=%d errors=%d duration=%.3f",
+		found, bytes, t.deleted, t.freed, t.errors, elapsed.Seconds())
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRunTally_SummaryLine(t *testing.T) {
+	tl := &runTally{}
+	found := []Record{
+		{Path: "/a", Size: 1000, DiskSize: 4096},
+		{Path: "/b", Size: 500},
+		{Path: "/c", Size: 20},
+	}
+	tl.removed(found[0])
+	tl.removed(found[1])
+	tl.failed(1)
+	tl.failed(2)
+
+	got := tl.summaryLine(found, 2417*time.Millisecond)
+	want := "tidyup: found=3 bytes=1520 deleted=2 freed=4596 errors=3 duration=2.417"
+	if got != want {
+		t.Errorf("summaryLine = %q, want %q", got, want)
+	}
+}
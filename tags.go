@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// tags.json in the config directory labels paths for organizational views:
+//
+//	{
+//	  "billable": ["~/clients/**"],
+//	  "archived": ["~/clients/*/archive", "/srv/old"]
+//	}
+//
+// A pattern is a directory, which tags everything inside it (a trailing
+// /** says the same), or a glob matched against each directory from the
+// item up, so ~/clients/*/archive tags whatever is inside any client's
+// archive. A leading ~ is the home directory. Records carry their tags
+// (sorted) into text and JSON output, -tag keeps only items with given
+// tags, and reports total the space per tag.
+
+// tagsConfig maps tag names to patterns.
+type tagsConfig map[string][]string
+
+// tagsPath is where the tags configuration lives.
+func tagsPath() (string, error) {
+	dir, err := configDir()
+	return filepath.Join(dir, "tags.json"), err
+}
+
+// loadTags reads tags.json, expanding ~ in its patterns; a missing file is
+// no configuration.
+func loadTags() (tagsConfig, error) {
+	path, err := tagsPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cfg tagsConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	for tag, patterns := range cfg {
+		if tag == "" || strings.ContainsAny(tag, ", ") {
+			return nil, fmt.Errorf("%s: bad tag name %q", path, tag)
+		}
+		for i, p := range patterns {
+			if _, err := filepath.Match(p, ""); err != nil {
+				return nil, fmt.Errorf("%s: %s: bad pattern %q", path, tag, p)
+			}
+			patterns[i] = expandHome(p)
+		}
+	}
+	return cfg, nil
+}
+
+// tagsFor returns the tags whose patterns match path, sorted.
+func (cfg tagsConfig) tagsFor(path string) []string {
+	var tags []string
+	for tag, patterns := range cfg {
+		for _, p := range patterns {
+			if tagMatches(path, p) {
+				tags = append(tags, tag)
+				break
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// tagMatches reports whether pattern (see tags.json) covers path.
+func tagMatches(path, pattern string) bool {
+	pattern = strings.TrimSuffix(pattern, "/**")
+	if !strings.ContainsAny(pattern, "*?[") {
+		return within(path, filepath.Clean(pattern))
+	}
+	for p := path; ; p = filepath.Dir(p) {
+		if ok, _ := filepath.Match(pattern, p); ok {
+			return true
+		}
+		if p == filepath.Dir(p) {
+			return false
+		}
+	}
+}
+
+// hasAnyTag reports whether r carries one of tags.
+func hasAnyTag(r Record, tags map[string]bool) bool {
+	for _, t := range r.Tags {
+		if tags[t] {
+			return true
+		}
+	}
+	return false
+}
+
+// groupTotal is one group's share of a report: a tag's, or an owner's.
+type groupTotal struct {
+	Count int   `json:"count"`
+	Bytes int64 `json:"total_bytes"`
+}
+
+// groupTotals sums records per group, as keys assigns them; nil when no
+// record is in any.
+func groupTotals(records []Record, keys func(Record) []string) map[string]groupTotal {
+	var totals map[string]groupTotal
+	for _, r := range records {
+		for _, k := range keys(r) {
+			if totals == nil {
+				totals = map[string]groupTotal{}
+			}
+			gt := totals[k]
+			gt.Count++
+			gt.Bytes += r.Size
+			totals[k] = gt
+		}
+	}
+	return totals
+}
+
+// tagTotals sums records per tag.
+func tagTotals(records []Record) map[string]groupTotal {
+	return groupTotals(records, func(r Record) []string { return r.Tags })
+}
+
+// printGroupTotals writes per-group totals under heading, largest first.
+func printGroupTotals(w io.Writer, heading string, totals map[string]groupTotal, opts *options) {
+	if totals == nil {
+		return
+	}
+	names := make([]string, 0, len(totals))
+	for n := range totals {
+		names = append(names, n)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if totals[names[i]].Bytes != totals[names[j]].Bytes {
+			return totals[names[i]].Bytes > totals[names[j]].Bytes
+		}
+		return names[i] < names[j]
+	})
+	loc := opts.locale
+	fmt.Fprintf(w, "%s:\n", heading)
+	for _, n := range names {
+		fmt.Fprintf(w, "  %-30s %s items, %s\n", n, loc.integer(int64(totals[n].Count)), loc.bytes(totals[n].Bytes))
+	}
+}
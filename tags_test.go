@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTagMatches(t *testing.T) {
+	tests := []struct {
+		path, pattern string
+		want          bool
+	}{
+		{"/home/u/clients/acme/.venv", "/home/u/clients/**", true},
+		{"/home/u/clients/acme/.venv", "/home/u/clients", true},
+		{"/home/u/clientsold/.venv", "/home/u/clients", false},
+		{"/home/u/clients/acme/archive/web/node_modules", "/home/u/clients/*/archive", true},
+		{"/home/u/clients/acme/web/node_modules", "/home/u/clients/*/archive", false},
+		{"/srv/old/build", "/srv/old/**", true},
+	}
+	for _, tt := range tests {
+		if got := tagMatches(tt.path, tt.pattern); got != tt.want {
+			t.Errorf("tagMatches(%s, %s) = %v, want %v", tt.path, tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestLoadTags(t *testing.T) {
+	cfgDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", cfgDir)
+	t.Setenv("HOME", "/srv/home")
+	if cfg, err := loadTags(); err != nil || cfg != nil {
+		t.Fatalf("no file: got %v, %v", cfg, err)
+	}
+	path := filepath.Join(cfgDir, "tidyup", "tags.json")
+	os.MkdirAll(filepath.Dir(path), 0755)
+	os.WriteFile(path, []byte(`{"billable": ["~/clients/**"], "archived": ["~/clients/*/archive"]}`), 0644)
+	cfg, err := loadTags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.tagsFor("/srv/home/clients/acme/archive/.venv"); strings.Join(got, ",") != "archived,billable" {
+		t.Errorf("tagsFor = %v, want archived,billable", got)
+	}
+	for _, bad := range []string{`{"a b": ["/x"]}`, `{"x": ["[/x"]}`, `{"x": [`} {
+		os.WriteFile(path, []byte(bad), 0644)
+		if _, err := loadTags(); err == nil {
+			t.Errorf("%s: want an error", bad)
+		}
+	}
+}
+
+func TestScanRoots_Tags(t *testing.T) {
+	cfgDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", cfgDir)
+	root := t.TempDir()
+	old := time.Now().AddDate(0, 0, -90)
+	makeVenv(t, filepath.Join(root, "clients", "acme", ".venv"), old)
+	makeVenv(t, filepath.Join(root, "own", ".venv"), old)
+	path := filepath.Join(cfgDir, "tidyup", "tags.json")
+	os.MkdirAll(filepath.Dir(path), 0755)
+	os.WriteFile(path, []byte(`{"billable": ["`+filepath.ToSlash(filepath.Join(root, "clients"))+`/**"]}`), 0644)
+
+	opts := &options{minAge: 30, maxDepth: 5, scanTypes: map[string]bool{"venv": true}}
+	records, _ := scanRoots([]string{root}, opts)
+	if len(records) != 2 || len(records[0].Tags) != 1 || records[0].Tags[0] != "billable" || records[1].Tags != nil {
+		t.Fatalf("records = %+v, want the client venv tagged billable", records)
+	}
+	var buf bytes.Buffer
+	printText(&buf, records, totalSize(records), opts)
+	if !strings.Contains(buf.String(), "tags billable") || !strings.Contains(buf.String(), "By tag:\n  billable ") {
+		t.Errorf("text report lacks tags:\n%s", buf.String())
+	}
+
+	opts.tagFilter = map[string]bool{"billable": true}
+	if records, _ := scanRoots([]string{root}, opts); len(records) != 1 || records[0].Tags[0] != "billable" {
+		t.Errorf("-tag billable: got %+v", records)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package main
+
+import (
+	"os"
+	"strconv"
+)
+
+// terminalRows falls back to $LINES where the window size ioctl is unavailable.
+func terminalRows() (int, bool) {
+	if n, err := strconv.Atoi(os.Getenv("LINES")); err == nil && n > 0 {
+		return n, true
+	}
+	return 0, false
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Temporary directories are cleaned at reboot, in theory. Machines that
+// never reboot collect pip build trees, pytest's per-user base temp, and
+// archives extracted once and forgotten. The tmp type looks at the top
+// level of $TMPDIR and /tmp for the current user's stale entries. It is
+// opt-in (-type tmp, never -all) and report-only: records are advisory
+// unless -tmp-delete is given, since other programs may still expect them.
+
+// tmpMinSize is the smallest unrecognized entry the tmp type reports;
+// recognized tool debris is reported at any size.
+const tmpMinSize = 100 << 20
+
+// tmpDebrisPrefixes name the temp entries tools leave behind, with what
+// they are.
+var tmpDebrisPrefixes = []struct{ prefix, what string }{
+	{"pip-", "pip build/unpack directory"},
+	{"pytest-of-", "pytest base temp directory"},
+	{"tmp-pytest-", "pytest temp directory"},
+	{"go-build", "Go build directory"},
+	{"npm-", "npm temp directory"},
+}
+
+// tmpRoots returns the temporary directories to look in, without
+// duplicates: $TMPDIR (os.TempDir) and, on Unix, /tmp.
+func tmpRoots() []string {
+	var roots []string
+	seen := map[string]bool{}
+	candidates := []string{os.TempDir()}
+	if filepath.Separator == '/' {
+		candidates = append(candidates, "/tmp")
+	}
+	for _, dir := range candidates {
+		real, err := filepath.EvalSymlinks(dir)
+		if err != nil || seen[real] {
+			continue
+		}
+		seen[real] = true
+		roots = append(roots, filepath.Clean(dir))
+	}
+	return roots
+}
+
+// tmpDebrisKind describes a recognized temp entry, or returns "".
+func tmpDebrisKind(name string) string {
+	for _, d := range tmpDebrisPrefixes {
+		if strings.HasPrefix(name, d.prefix) {
+			return d.what
+		}
+	}
+	return ""
+}
+
+// findTmpEntries returns the current user's recognized or large entries at
+// the top of a temp root.
+func findTmpEntries(root string) []string {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil
+	}
+	var items []string
+	for _, e := range entries {
+		p := filepath.Join(root, e.Name())
+		info, err := os.Lstat(p)
+		if err != nil || info.Mode()&os.ModeSymlink != 0 || !ownedByCurrentUser(info) {
+			continue
+		}
+		if tmpDebrisKind(e.Name()) == "" {
+			if size, _ := dirSize(p); size < tmpMinSize {
+				continue
+			}
+		}
+		items = append(items, p)
+	}
+	return items
+}
+
+// isTmpEntry reports whether r is a tmp record directly inside a temp
+// root: the only place inside a protected path tidyup removes from.
+func isTmpEntry(r Record) bool {
+	if r.Type != "tmp" {
+		return false
+	}
+	parent := filepath.Dir(filepath.Clean(r.Path))
+	for _, root := range tmpRoots() {
+		if parent == root {
+			return true
+		}
+	}
+	return false
+}
+
+// annotateTmp explains the entry and marks it report-only; -tmp-delete
+// lifts that after the scan.
+func annotateTmp(r *Record) {
+	r.Advisory = true
+	what := tmpDebrisKind(filepath.Base(r.Path))
+	if what == "" {
+		what = "large temporary entry"
+	}
+	r.Notes = append(r.Notes, what)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestFindTmpEntries(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"pip-build-abc/setup.py", "pytest-of-me/pytest-3/x", "small-thing/f", "notes.txt"} {
+		p := filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte("x"), 0644)
+	}
+	big := filepath.Join(root, "extracted", "blob.bin")
+	os.MkdirAll(filepath.Dir(big), 0755)
+	os.WriteFile(big, nil, 0644)
+	os.Truncate(big, tmpMinSize)
+	os.Symlink(filepath.Join(root, "pip-build-abc"), filepath.Join(root, "pip-link"))
+
+	var got []string
+	for _, p := range findTmpEntries(root) {
+		got = append(got, filepath.Base(p))
+	}
+	sort.Strings(got)
+	want := []string{"extracted", "pip-build-abc", "pytest-of-me"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("entry %d = %s, want %s", i, got[i], want[i])
+		}
+	}
+}
+
+func TestIsTmpEntry(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	cases := []struct {
+		r    Record
+		want bool
+	}{
+		{Record{Type: "tmp", Path: filepath.Join(tmp, "pip-build-x")}, true},
+		{Record{Type: "tmp", Path: filepath.Join(tmp, "a", "b")}, false},
+		{Record{Type: "tmp", Path: tmp}, false},
+		{Record{Type: "build", Path: filepath.Join(tmp, "pip-build-x")}, false},
+	}
+	for _, c := range cases {
+		if got := isTmpEntry(c.r); got != c.want {
+			t.Errorf("isTmpEntry(%s %s) = %v, want %v", c.r.Type, c.r.Path, got, c.want)
+		}
+	}
+
+	// Deletable despite the protected /tmp prefix once -tmp-delete has
+	// cleared Advisory; still refused while it is report-only.
+	entry := Record{Type: "tmp", Path: filepath.Join(tmp, "pip-build-x")}
+	if safe, _ := filterSafeRecords([]Record{entry}); len(safe) != 1 {
+		t.Error("tmp entry filtered as protected")
+	}
+	entry.Advisory = true
+	if safe, _ := filterSafeRecords([]Record{entry}); len(safe) != 0 {
+		t.Error("report-only tmp entry passed the safety filter")
+	}
+}
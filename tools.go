@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// toolManager reports which installer owns a venv -- "pipx" for
+// <PIPX_HOME>/venvs/<tool>, "uv" for <UV_TOOL_DIR>/<tool> (or the default
+// locations of either) -- or "".
+func toolManager(path string) string {
+	parent := filepath.Dir(path)
+	if dir := os.Getenv("PIPX_HOME"); dir != "" && parent == filepath.Join(dir, "venvs") {
+		return "pipx"
+	}
+	if dir := os.Getenv("UV_TOOL_DIR"); dir != "" && parent == filepath.Clean(dir) {
+		return "uv"
+	}
+	switch p := filepath.ToSlash(parent); {
+	case strings.HasSuffix(p, "/pipx/venvs"):
+		return "pipx"
+	case strings.HasSuffix(p, "/uv/tools"), strings.HasSuffix(p, "/uv/data/tools"):
+		return "uv"
+	}
+	return ""
+}
+
+// toolHomes lists the standard pipx and uv tool directories under home,
+// added as roots by -system when scanning for tools.
+func toolHomes(home string) []string {
+	dirs := []string{
+		filepath.Join(home, ".local/pipx/venvs"),
+		filepath.Join(home, ".local/share/pipx/venvs"),
+		filepath.Join(home, "Library/Application Support/pipx/venvs"),
+		filepath.Join(home, ".local/share/uv/tools"),
+		filepath.Join(home, "AppData/Roaming/uv/data/tools"),
+	}
+	if dir := os.Getenv("PIPX_HOME"); dir != "" {
+		dirs = append(dirs, filepath.Join(dir, "venvs"))
+	}
+	if dir := os.Getenv("UV_TOOL_DIR"); dir != "" {
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// toolBinDirs are where pipx and uv put the shims users actually run.
+func toolBinDirs() []string {
+	var dirs []string
+	for _, key := range []string{"PIPX_BIN_DIR", "UV_TOOL_BIN_DIR", "XDG_BIN_HOME"} {
+		if dir := os.Getenv(key); dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".local", "bin"))
+	}
+	return dirs
+}
+
+// isVenvScript reports whether a bin/ entry comes with every venv rather
+// than being one of the tool's own entry points.
+func isVenvScript(name string) bool {
+	for _, prefix := range []string{"python", "pip", "activate", "deactivate", "pydoc", "easy_install", "wheel"} {
+		if strings.HasPrefix(strings.ToLower(name), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// toolEntryPoints returns the tool's own executables inside env plus any
+// shims in toolBinDirs that link into env.
+func toolEntryPoints(env string) []string {
+	var paths []string
+	for _, bin := range []string{"bin", "Scripts"} {
+		entries, _ := os.ReadDir(filepath.Join(env, bin))
+		for _, e := range entries {
+			if !e.IsDir() && !isVenvScript(e.Name()) {
+				paths = append(paths, filepath.Join(env, bin, e.Name()))
+			}
+		}
+	}
+	for _, dir := range toolBinDirs() {
+		entries, _ := os.ReadDir(dir)
+		for _, e := range entries {
+			shim := filepath.Join(dir, e.Name())
+			if target, err := resolveChain(shim); err == nil && strings.HasPrefix(target, env+string(filepath.Separator)) {
+				paths = append(paths, shim)
+			}
+		}
+	}
+	return paths
+}
+
+// getToolUsage dates a tool environment by when its entry points were last
+// run (atime) or rewritten (mtime), falling back to venv activity for
+// installs and upgrades.
+func getToolUsage(path string) (time.Time, bool) {
+	lastUsed, found := getVenvActivity(path)
+	for _, p := range toolEntryPoints(path) {
+		if info, err := os.Stat(p); err == nil && info.ModTime().After(lastUsed) {
+			lastUsed, found = info.ModTime(), true
+		}
+		if at, ok := accessTime(p); ok && at.After(lastUsed) {
+			lastUsed, found = at, true
+		}
+	}
+	return lastUsed, found
+}
+
+// toolInfo returns the installed tool's name and version: from
+// pipx_metadata.json when present, else the env name and the matching
+// .dist-info directory in site-packages.
+func toolInfo(env string) (name, version string) {
+	if data, err := os.ReadFile(filepath.Join(env, "pipx_metadata.json")); err == nil {
+		var meta struct {
+			MainPackage struct {
+				Package        string `json:"package"`
+				PackageVersion string `json:"package_version"`
+			} `json:"main_package"`
+		}
+		if json.Unmarshal(data, &meta) == nil && meta.MainPackage.Package != "" {
+			return meta.MainPackage.Package, meta.MainPackage.PackageVersion
+		}
+	}
+
+	name = filepath.Base(env)
+	normalized := strings.ToLower(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+	for _, sp := range sitePackagesDirs(env) {
+		matches, _ := filepath.Glob(filepath.Join(sp, "*.dist-info"))
+		for _, m := range matches {
+			dist, ver, ok := strings.Cut(strings.TrimSuffix(filepath.Base(m), ".dist-info"), "-")
+			if ok && strings.ToLower(dist) == normalized {
+				return name, ver
+			}
+		}
+	}
+	return name, ""
+}
+
+// toolUninstallCommand is the installer's own removal command, which also
+// removes the shims that deleting the env directory would leave dangling.
+func toolUninstallCommand(manager, name string) string {
+	switch manager {
+	case "pipx":
+		return "pipx uninstall " + name
+	case "uv":
+		return "uv tool uninstall " + name
+	}
+	return ""
+}
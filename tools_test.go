@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestToolManager(t *testing.T) {
+	t.Setenv("PIPX_HOME", "")
+	t.Setenv("UV_TOOL_DIR", "/opt/uv-tools")
+	tests := map[string]string{
+		"/home/u/.local/pipx/venvs/black":                     "pipx",
+		"/Users/u/Library/Application Support/pipx/venvs/tox": "pipx",
+		"/home/u/.local/share/uv/tools/ruff":                  "uv",
+		"/opt/uv-tools/httpie":                                "uv",
+		"/home/u/proj/.venv":                                  "",
+		"/home/u/proj/tools/.venv":                            "",
+	}
+	for path, want := range tests {
+		if got := toolManager(filepath.FromSlash(path)); got != want {
+			t.Errorf("toolManager(%s) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestToolInfo(t *testing.T) {
+	pipxEnv := filepath.Join(t.TempDir(), "black")
+	os.MkdirAll(pipxEnv, 0755)
+	os.WriteFile(filepath.Join(pipxEnv, "pipx_metadata.json"),
+		[]byte(`{"main_package": {"package": "black", "package_version": "24.3.0"}}`), 0644)
+	if name, ver := toolInfo(pipxEnv); name != "black" || ver != "24.3.0" {
+		t.Errorf("pipx: got %s %s, want black 24.3.0", name, ver)
+	}
+
+	uvEnv := filepath.Join(t.TempDir(), "pre-commit")
+	os.MkdirAll(filepath.Join(uvEnv, "lib", "python3.12", "site-packages", "pre_commit-3.7.0.dist-info"), 0755)
+	if name, ver := toolInfo(uvEnv); name != "pre-commit" || ver != "3.7.0" {
+		t.Errorf("uv: got %s %s, want pre-commit 3.7.0", name, ver)
+	}
+}
+
+func TestGetToolUsage_ShimAccessTime(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	env := filepath.Join(home, ".local", "share", "uv", "tools", "ruff")
+	installed := time.Now().AddDate(0, 0, -200).Truncate(time.Second)
+	makeVenv(t, env, installed)
+	entry := filepath.Join(env, "bin", "ruff")
+	os.WriteFile(entry, []byte("x"), 0755)
+	os.Chtimes(entry, installed, installed)
+
+	if got, _ := getToolUsage(env); !got.Equal(installed) {
+		t.Fatalf("never run: got %v, want install time %v", got, installed)
+	}
+
+	// Running the tool through its ~/.local/bin shim updates the target's atime.
+	bin := filepath.Join(home, ".local", "bin")
+	os.MkdirAll(bin, 0755)
+	os.Symlink(entry, filepath.Join(bin, "ruff"))
+	ran := time.Now().AddDate(0, 0, -3).Truncate(time.Second)
+	os.Chtimes(entry, ran, installed)
+
+	if _, ok := accessTime(entry); !ok {
+		t.Skip("atime unavailable on this platform")
+	}
+	if got, _ := getToolUsage(env); !got.Equal(ran) {
+		t.Errorf("after run: got %v, want %v", got, ran)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// tox keeps one virtualenv per test environment under the project's .tox/
+// (.tox/py311, .tox/lint, and tox 4's .tox/.pkg packaging environment).
+// Each is recreated by the next `tox -e` that needs it, so each is its own
+// item. tox writes a log under <env>/log/ on every run, which dates an
+// environment by use; its venv markers only change when it is recreated.
+
+// nox does the same in .nox/, one virtualenv per session (.nox/tests-3-11),
+// but writes no logs; a session is dated by its venv markers, which change
+// whenever nox recreates it (every run without -r) or installs into it.
+
+// toxEnvs lists the environments in a .tox or .nox directory: the
+// subdirectories holding a Python environment (tox-conda's included). tox
+// 3's shared log/ and tox 4's .tmp/ are left out that way.
+func toxEnvs(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var envs []string
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		if e.IsDir() && venvKind(path) != "" {
+			envs = append(envs, path)
+		}
+	}
+	return envs
+}
+
+// getToxUsage dates a tox environment by its newest log/*.log, falling back
+// to its venv markers for environments tox never ran in.
+func getToxUsage(path string) (time.Time, bool) {
+	var latest time.Time
+	logs, _ := filepath.Glob(filepath.Join(path, "log", "*.log"))
+	for _, log := range logs {
+		if info, err := os.Stat(log); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	if !latest.IsZero() {
+		return latest, true
+	}
+	return getVenvActivity(path)
+}
+
+// annotateTox records a tox environment's interpreter, as for venvs.
+func annotateTox(r *Record) {
+	interp, _ := venvInterpreter(r.Path)
+	r.Python, r.Interpreter, r.InterpreterGone = interp.version, interp.path, interp.missing
+	r.Notes = append(r.Notes, "tox recreates it on the next run of "+filepath.Base(r.Path))
+}
+
+// annotateNox records a nox session's interpreter, as for venvs.
+func annotateNox(r *Record) {
+	interp, _ := venvInterpreter(r.Path)
+	r.Python, r.Interpreter, r.InterpreterGone = interp.version, interp.path, interp.missing
+	r.Notes = append(r.Notes, "nox recreates it on the next run of session "+filepath.Base(r.Path))
+}
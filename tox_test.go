@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestScanRoots_ToxEnvs(t *testing.T) {
+	root := t.TempDir()
+	tox := filepath.Join(root, "proj", ".tox")
+	old := time.Now().AddDate(0, 0, -90)
+	py311, lint, pkg := filepath.Join(tox, "py311"), filepath.Join(tox, "lint"), filepath.Join(tox, ".pkg")
+	makeVenv(t, py311, old)
+	makeVenv(t, lint, old)
+	makeVenv(t, pkg, old)
+	os.MkdirAll(filepath.Join(tox, "log"), 0755)
+	os.MkdirAll(filepath.Join(lint, "log"), 0755)
+	recent := time.Now().AddDate(0, 0, -3)
+	logFile := filepath.Join(lint, "log", "1-commands[0].log")
+	os.WriteFile(logFile, []byte("x"), 0644)
+	os.Chtimes(logFile, recent, recent)
+
+	if got, ok := getToxUsage(lint); !ok || !got.Equal(recent) {
+		t.Errorf("getToxUsage(lint) = %v, %v; want the log's mtime", got, ok)
+	}
+
+	opts := &options{minAge: 30, maxDepth: 5, scanTypes: map[string]bool{"venv": true, "tox": true}}
+	records, _ := scanRoots([]string{root}, opts)
+	found := map[string]string{}
+	for _, r := range records {
+		found[r.Path] = r.Type
+	}
+	if len(found) != 2 || found[py311] != "tox" || found[pkg] != "tox" {
+		t.Errorf("with tox: got %v, want py311 and .pkg as tox (lint ran 3 days ago)", found)
+	}
+
+	opts.scanTypes = map[string]bool{"venv": true}
+	records, _ = scanRoots([]string{root}, opts)
+	for _, r := range records {
+		if r.Type != "venv" {
+			t.Errorf("without tox: %s is %s, want venv", r.Path, r.Type)
+		}
+	}
+	if len(records) != 3 {
+		t.Errorf("without tox: got %d records, want the 3 envs as venvs", len(records))
+	}
+}
+
+func TestScanRoots_NoxSessions(t *testing.T) {
+	root := t.TempDir()
+	nox := filepath.Join(root, "proj", ".nox")
+	tests, lint := filepath.Join(nox, "tests-3-11"), filepath.Join(nox, "lint")
+	makeVenv(t, tests, time.Now().AddDate(0, 0, -90))
+	makeVenv(t, lint, time.Now().AddDate(0, 0, -1))
+
+	opts := &options{minAge: 30, maxDepth: 5, scanTypes: map[string]bool{"venv": true, "nox": true}}
+	records, _ := scanRoots([]string{root}, opts)
+	if len(records) != 1 || records[0].Path != tests || records[0].Type != "nox" {
+		t.Fatalf("got %+v, want only the stale session as nox", records)
+	}
+	if projectDir(tests) != filepath.Join(root, "proj") {
+		t.Errorf("session belongs to %s, want the project above .nox", projectDir(tests))
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Moving an item to the Trash frees nothing until the Trash is emptied, which
+// is confusing when df does not move. tidyup says so after a -trash run, and
+// remembers what it trashed in trashed.jsonl (state directory) so that
+// -empty-trash-after can purge its own items -- and only those -- once they
+// have sat in the Trash long enough.
+
+// finderTrashScript deletes its argument through Finder -- the
+// NSWorkspace/trashItemAtURL path, with Put Back metadata and per-volume
+// .Trashes on external drives -- and prints where the item went.
+var finderTrashScript = []string{
+	"on run argv",
+	`tell application "Finder" to set trashed to delete (POSIX file (item 1 of argv) as alias)`,
+	"return POSIX path of (trashed as alias)",
+	"end run",
+}
+
+// finderFallback warns once per run when Finder cannot be used.
+var finderFallback sync.Once
+
+// finderTrash moves path to the Trash through Finder via osascript and
+// returns the item's path in the Trash.
+func finderTrash(path string) (string, error) {
+	var args []string
+	for _, line := range finderTrashScript {
+		args = append(args, "-e", line)
+	}
+	out, err := exec.Command("osascript", append(args, path)...).Output()
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok && len(ee.Stderr) > 0 {
+			return "", fmt.Errorf("osascript: %s", strings.TrimSpace(string(ee.Stderr)))
+		}
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSpace(string(out)), "/"), nil
+}
+
+// trashedItem is one line of trashed.jsonl.
+type trashedItem struct {
+	Time     string `json:"time"`     // RFC3339, when it was trashed
+	Original string `json:"original"` // where it was
+	Type     string `json:"type,omitempty"`
+	Trash    string `json:"trash"` // where it is now
+	Size     int64  `json:"size"`  // on-disk bytes when trashed
+}
+
+// trashedPath is the trash manifest in the state directory's manifests.
+func trashedPath() (string, error) {
+	return statePath("manifests", "trashed.jsonl")
+}
+
+// recordTrashed appends r, now at dest, to the trash manifest. Failures are
+// reported but only cost -empty-trash-after its knowledge of the item.
+func recordTrashed(r Record, dest string, now time.Time) {
+	path, err := trashedPath()
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0700)
+	}
+	var f *os.File
+	if err == nil {
+		f, err = os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	}
+	if err != nil {
+		stderr.warnf("could not record trashed item: %v", err)
+		return
+	}
+	defer f.Close()
+	size := r.DiskSize
+	if size == 0 {
+		size = r.Size
+	}
+	json.NewEncoder(f).Encode(trashedItem{Time: now.Format(time.RFC3339), Original: r.Path, Type: r.Type, Trash: dest, Size: size})
+}
+
+// loadTrashed reads the trash manifest, skipping malformed lines.
+func loadTrashed(path string) ([]trashedItem, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var items []trashedItem
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var it trashedItem
+		if json.Unmarshal(sc.Bytes(), &it) == nil && it.Trash != "" {
+			items = append(items, it)
+		}
+	}
+	return items, sc.Err()
+}
+
+// parseRetention parses an -empty-trash-after value: days ("7d"), weeks
+// ("2w"), or a Go duration ("36h").
+func parseRetention(value string) (time.Duration, error) {
+	s := strings.TrimSpace(value)
+	if s == "" {
+		return 0, nil
+	}
+	unit := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour}[s[len(s)-1]]
+	if n, err := strconv.Atoi(s[:len(s)-1]); unit > 0 && err == nil && n >= 0 {
+		return time.Duration(n) * unit, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return d, nil
+	}
+	return 0, fmt.Errorf("invalid duration %q (want e.g. 7d, 2w, 36h)", value)
+}
+
+// emptyTrash permanently removes items tidyup trashed more than maxAge ago
+// and drops them, along with items already gone from the Trash, from the
+// manifest. Everything else in the Trash is left alone.
+func (x *executor) emptyTrash(maxAge time.Duration, now time.Time) (count int, freed int64, err error) {
+	path, err := trashedPath()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer lockState()()
+	items, err := loadTrashed(path)
+	if os.IsNotExist(err) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	var keep []trashedItem
+	for _, it := range items {
+		if _, err := os.Lstat(it.Trash); err != nil {
+			continue // emptied or put back by hand
+		}
+		trashed, err := time.Parse(time.RFC3339, it.Time)
+		if err != nil || now.Sub(trashed) < maxAge {
+			keep = append(keep, it)
+			continue
+		}
+		if err := x.rm.removeAll(resolveParent(it.Trash)); err != nil {
+			stderr.printf("Error emptying %s from Trash: %v", it.Trash, err)
+			keep = append(keep, it)
+			continue
+		}
+		count++
+		freed += it.Size
+	}
+
+	return count, freed, saveTrashed(path, keep)
+}
+
+// saveTrashed rewrites the trash manifest with items. Callers hold the
+// state lock.
+func saveTrashed(path string, items []trashedItem) error {
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	for _, it := range items {
+		enc.Encode(it)
+	}
+	return writeFileAtomic(path, []byte(b.String()), 0600)
+}
+
+// runEmptyTrash applies -empty-trash-after before a deletion run.
+func runEmptyTrash(opts *options) {
+	x, err := newExecutor(opts, nil)
+	if err != nil {
+		stderr.warnf("not emptying tidyup's Trash items: %v", err)
+		return
+	}
+	count, freed, err := x.emptyTrash(opts.emptyTrashAfter, opts.currentTime())
+	if err != nil {
+		stderr.warnf("could not empty tidyup's Trash items: %v", err)
+	}
+	if count > 0 {
+		fmt.Printf("Emptied %s items tidyup trashed more than %s ago from the Trash, freeing %s.\n",
+			opts.locale.integer(int64(count)), formatRetention(opts.emptyTrashAfter), opts.locale.bytes(freed))
+	}
+}
+
+// formatRetention prints a retention in whole days where it is one.
+func formatRetention(d time.Duration) string {
+	if d >= 24*time.Hour && d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+	return d.String()
+}
+
+// printTrashNote explains after a -trash run that the space is not free yet.
+func printTrashNote(w io.Writer, opts *options) {
+	home := os.Getenv("HOME")
+	if home == "" {
+		return
+	}
+	_, size := dirSize(filepath.Join(home, ".Trash"))
+	fmt.Fprintf(w, "\nNote: trashed items use disk space until the Trash is emptied; it now holds %s.\n", opts.locale.bytes(size))
+	if opts.emptyTrashAfter == 0 {
+		fmt.Fprintln(w, "Use -empty-trash-after 7d to purge tidyup's own Trash items after a week.")
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRetention(t *testing.T) {
+	cases := map[string]time.Duration{
+		"":    0,
+		"7d":  7 * 24 * time.Hour,
+		"2w":  14 * 24 * time.Hour,
+		"36h": 36 * time.Hour,
+	}
+	for in, want := range cases {
+		if got, err := parseRetention(in); err != nil || got != want {
+			t.Errorf("parseRetention(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"d", "7x", "-3d", "week"} {
+		if _, err := parseRetention(bad); err == nil {
+			t.Errorf("parseRetention(%q) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+//go:build darwin || windows
+
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// On macOS and Windows, walkTree reads directories with a batch API that
+// returns each entry's attributes with its name (readDirBulk, per platform),
+// so sizing a tree takes no per-file stat.
+
+// walkTree walks root like filepath.WalkDir, in directory order rather than
+// lexical order.
+func walkTree(root string, fn fs.WalkDirFunc) error {
+	info, err := os.Lstat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkBulk(root, fs.FileInfoToDirEntry(info), fn)
+	}
+	if err == filepath.SkipDir || err == filepath.SkipAll {
+		return nil
+	}
+	return err
+}
+
+// walkBulk is filepath.WalkDir's walkDir with readDirBulk.
+func walkBulk(path string, d fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := fn(path, d, nil); err != nil || !d.IsDir() {
+		if err == filepath.SkipDir && d.IsDir() {
+			err = nil
+		}
+		return err
+	}
+	entries, err := readDirBulk(path)
+	if err != nil {
+		if err = fn(path, d, err); err != nil {
+			if err == filepath.SkipDir && d.IsDir() {
+				err = nil
+			}
+			return err
+		}
+	}
+	for _, e := range entries {
+		if err := walkBulk(filepath.Join(path, e.Name()), e, fn); err != nil {
+			if err == filepath.SkipDir {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+//go:build !darwin && !windows
+
+package main
+
+import (
+	"io/fs"
+	"path/filepath"
+)
+
+// walkTree walks scan roots and candidates' trees; elsewhere than
+// macOS and Windows (see treewalk_native.go) that is filepath.WalkDir.
+func walkTree(root string, fn fs.WalkDirFunc) error {
+	return filepath.WalkDir(root, fn)
+}
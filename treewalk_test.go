@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// makeTree writes dirs directories of files small files each under root.
+func makeTree(tb testing.TB, root string, dirs, files int) {
+	tb.Helper()
+	for d := 0; d < dirs; d++ {
+		dir := filepath.Join(root, fmt.Sprintf("pkg%03d", d), "lib")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			tb.Fatal(err)
+		}
+		for f := 0; f < files; f++ {
+			os.WriteFile(filepath.Join(dir, fmt.Sprintf("m%03d.js", f)), make([]byte, 100+f), 0644)
+		}
+	}
+}
+
+func TestWalkTree_MatchesWalkDir(t *testing.T) {
+	root := t.TempDir()
+	makeTree(t, root, 5, 20)
+	os.Symlink("pkg000", filepath.Join(root, "link"))
+	os.Link(filepath.Join(root, "pkg000", "lib", "m000.js"), filepath.Join(root, "hard.js"))
+
+	sum := func(walk func(string, fs.WalkDirFunc) error) (n int, apparent, disk int64) {
+		seen := map[fileID]bool{}
+		walk(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				t.Fatal(err)
+			}
+			n++
+			if !d.IsDir() {
+				info, _ := d.Info()
+				apparent += info.Size()
+				disk += diskBytes(info, seen)
+			}
+			return nil
+		})
+		return n, apparent, disk
+	}
+	n1, a1, d1 := sum(filepath.WalkDir)
+	n2, a2, d2 := sum(walkTree)
+	if n1 != n2 || a1 != a2 || d1 != d2 {
+		t.Errorf("walkTree saw %d entries, %d/%d bytes; filepath.WalkDir %d, %d/%d", n2, a2, d2, n1, a1, d1)
+	}
+
+	var visited []string
+	walkTree(root, func(p string, d fs.DirEntry, err error) error {
+		if d.IsDir() && d.Name() == "lib" {
+			return filepath.SkipDir
+		}
+		visited = append(visited, p)
+		return nil
+	})
+	for _, p := range visited {
+		if filepath.Base(filepath.Dir(p)) == "lib" {
+			t.Errorf("walked into a skipped directory: %s", p)
+		}
+	}
+}
+
+// BenchmarkWalkTree compares walkTree, native on macOS, with the portable
+// filepath.WalkDir on the same sizing walk.
+func BenchmarkWalkTree(b *testing.B) {
+	root := b.TempDir()
+	makeTree(b, root, 50, 100)
+	for _, bm := range []struct {
+		name string
+		walk func(string, fs.WalkDirFunc) error
+	}{{"native", walkTree}, {"portable", filepath.WalkDir}} {
+		b.Run(bm.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				seen := map[fileID]bool{}
+				var total int64
+				bm.walk(root, func(p string, d fs.DirEntry, err error) error {
+					if err == nil && !d.IsDir() {
+						if info, err := d.Info(); err == nil {
+							total += diskBytes(info, seen)
+						}
+					}
+					return nil
+				})
+			}
+		})
+	}
+}
+
+// BenchmarkScanRoots_NodeModules guards detection and sizing of a project
+// with a wide node_modules, the walk that is slowest on Windows.
+func BenchmarkScanRoots_NodeModules(b *testing.B) {
+	root := b.TempDir()
+	nm := filepath.Join(root, "web", "node_modules")
+	makeTree(b, nm, 200, 20)
+	old := time.Now().AddDate(0, 0, -90)
+	filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		return os.Chtimes(p, old, old)
+	})
+	opts := &options{minAge: 30, maxDepth: 5, scanTypes: map[string]bool{"node_modules": true}, noHistory: true}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if records, _ := scanRoots([]string{root}, opts); len(records) != 1 {
+			b.Fatalf("got %d records, want 1", len(records))
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// `tidyup usage <path>` lays out everything tidyup knows about when a
+// directory was used, oldest first: installs (marker and lockfile mtimes),
+// shell hook activity (activations, directory changes, package manager
+// runs), scans that flagged it with the size and last use they saw, and
+// decisions about it. These are the same sources scans, -explain, and the
+// confidence score draw on.
+
+// usageEvent is one entry of the timeline.
+type usageEvent struct {
+	Time   time.Time `json:"time"`
+	Kind   string    `json:"kind"` // install, activate, cd, run, scan, deleted, kept, restored
+	Detail string    `json:"detail,omitempty"`
+	Size   int64     `json:"size_bytes,omitempty"` // scan: apparent size then
+}
+
+// timelineMarkers are files whose mtime records an install into the
+// directory, and what that install was.
+var timelineMarkers = []struct{ rel, what string }{
+	{"pyvenv.cfg", "environment created (pyvenv.cfg)"},
+	{"conda-meta/history", "conda install (conda-meta/history)"},
+	{".package-lock.json", "npm install (.package-lock.json)"},
+}
+
+// usageTimeline collects the events for path from the filesystem and the
+// state directory, oldest first.
+func usageTimeline(path string) []usageEvent {
+	var events []usageEvent
+	add := func(t time.Time, kind, detail string) {
+		events = append(events, usageEvent{Time: t, Kind: kind, Detail: detail})
+	}
+
+	for _, m := range timelineMarkers {
+		if info, err := os.Stat(filepath.Join(path, m.rel)); err == nil {
+			add(info.ModTime(), "install", m.what)
+		}
+	}
+	if t, ok := getSitePackagesUsage(path); ok {
+		add(t, "install", "site-packages changed")
+	}
+	project := projectDir(path)
+	for _, dir := range []string{path, project} {
+		for _, lock := range append(append([]string{}, pythonLockfiles...), "package-lock.json", "yarn.lock", "pnpm-lock.yaml", "bun.lockb") {
+			if info, err := os.Stat(filepath.Join(dir, lock)); err == nil && info.Mode().IsRegular() {
+				add(info.ModTime(), "install", "lockfile "+filepath.Join(dir, lock))
+			}
+		}
+	}
+
+	if a, err := loadActivity(); err == nil && a != nil {
+		home, _ := os.UserHomeDir()
+		broad := project == filepath.Dir(project) || project == filepath.Clean(home)
+		relevant := func(dir string) bool {
+			return within(dir, path) || !broad && within(dir, project)
+		}
+		for env, t := range a.activated {
+			if within(env, path) {
+				add(t, "activate", env)
+			}
+		}
+		for dir, t := range a.visited {
+			if relevant(dir) {
+				add(t, "cd", dir)
+			}
+		}
+		for tool, dirs := range a.ran {
+			for dir, t := range dirs {
+				if relevant(dir) {
+					add(t, "run", tool+" in "+dir)
+				}
+			}
+		}
+	}
+
+	if hp, err := historyPath(); err == nil {
+		for _, s := range loadHistory(hp).Items[path] {
+			e := usageEvent{Time: time.Unix(s.Time, 0), Kind: "scan", Size: s.Size}
+			if lu, err := time.Parse(time.RFC3339, s.LastUsed); err == nil {
+				e.Detail = "last used " + lu.Local().Format("2006-01-02")
+			}
+			events = append(events, e)
+		}
+	}
+
+	if dp, err := decisionsPath(); err == nil {
+		decisions, _ := loadDecisions(dp)
+		for _, d := range decisions {
+			if d.Path != path {
+				continue
+			}
+			if t, err := time.Parse(time.RFC3339, d.Time); err == nil {
+				add(t, d.Decision, fmt.Sprintf("%s, %.0f days unused", d.Type, d.AgeDays))
+			}
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	return events
+}
+
+// printUsageTimeline writes the timeline as text.
+func printUsageTimeline(w io.Writer, path string, events []usageEvent) {
+	if len(events) == 0 {
+		fmt.Fprintf(w, "No recorded usage for %s.\n", path)
+		return
+	}
+	fmt.Fprintf(w, "Usage timeline for %s:\n", path)
+	for _, e := range events {
+		detail := e.Detail
+		if e.Kind == "scan" {
+			detail = formatBytes(e.Size) + ", " + detail
+		}
+		fmt.Fprintf(w, "  %s  %-8s  %s\n", e.Time.Local().Format("2006-01-02 15:04"), e.Kind, detail)
+	}
+}
+
+// runUsage implements `tidyup usage [-json] <path>`.
+func runUsage(args []string) int {
+	flags := flag.NewFlagSet("usage", flag.ContinueOnError)
+	jsonOut := flags.Bool("json", false, "Output the timeline as JSON")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tidyup usage [-json] <path>\n\n")
+		fmt.Fprintf(os.Stderr, "Shows the recorded usage of a directory: installs, shell hook activity,\n")
+		fmt.Fprintf(os.Stderr, "scans with the size they saw, and keep/delete decisions.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitError
+	}
+	path, err := filepath.Abs(flags.Arg(0))
+	if err != nil {
+		stderr.errorf("%v", err)
+		return exitError
+	}
+	events := usageTimeline(path)
+	if *jsonOut {
+		if events == nil {
+			events = []usageEvent{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(struct {
+			Path   string       `json:"path"`
+			Events []usageEvent `json:"events"`
+		}{path, events})
+		return exitOK
+	}
+	printUsageTimeline(os.Stdout, path, events)
+	return exitOK
+}
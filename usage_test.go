@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUsageTimeline(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	root := t.TempDir()
+	venv := filepath.Join(root, "web", ".venv")
+	created := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	makeVenv(t, venv, created)
+	lock := filepath.Join(root, "web", "uv.lock")
+	os.WriteFile(lock, nil, 0644)
+	locked := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	os.Chtimes(lock, locked, locked)
+
+	log, _ := activityPath()
+	os.MkdirAll(filepath.Dir(log), 0700)
+	os.WriteFile(log, []byte(fmt.Sprintf("%d\tactivate\t%s\n%d\trun:uv\t%s\n%d\tcd\t%s\n",
+		time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC).Unix(), venv,
+		time.Date(2026, 2, 2, 0, 0, 0, 0, time.UTC).Unix(), filepath.Join(root, "web", "src"),
+		time.Date(2026, 2, 3, 0, 0, 0, 0, time.UTC).Unix(), filepath.Join(root, "elsewhere"))), 0600)
+
+	scanned := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	hp, _ := historyPath()
+	h := loadHistory(hp)
+	h.record([]Record{{Path: venv, Size: 4096, LastUsed: "2026-02-01T00:00:00Z"}}, scanned)
+	h.save(hp, scanned)
+	recordDecisions(decisionKept, []Record{{Path: venv, Type: "venv", AgeDays: 28}}, scanned.Add(time.Hour))
+
+	var kinds []string
+	for _, e := range usageTimeline(venv) {
+		kinds = append(kinds, e.Kind)
+	}
+	want := "install install activate run scan kept"
+	if got := strings.Join(kinds, " "); got != want {
+		t.Errorf("timeline kinds = %q, want %q", got, want)
+	}
+
+	var buf strings.Builder
+	printUsageTimeline(&buf, venv, usageTimeline(venv))
+	if !strings.Contains(buf.String(), "uv in "+filepath.Join(root, "web", "src")) || !strings.Contains(buf.String(), "4.0 KB, last used 2026-02-01") {
+		t.Errorf("text timeline:\n%s", buf.String())
+	}
+}
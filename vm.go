@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// VM and emulator disk images are reported, never deleted: a forgotten box
+// is often the largest single item on a disk, but it may also be the only
+// copy of a configured machine. Records point at the hypervisor's own
+// removal command where there is one.
+
+// vmImageSuffixes are disk image formats of VirtualBox, VMware, QEMU/UTM,
+// and Hyper-V.
+var vmImageSuffixes = []string{".vdi", ".vmdk", ".qcow2", ".vhd", ".vhdx"}
+
+// isVMImageFile reports whether a file found by the walk is a loose VM disk
+// image. Images inside Vagrant's box store are reported per box instead.
+func isVMImageFile(path string) bool {
+	if strings.Contains(filepath.ToSlash(path), "/.vagrant.d/") {
+		return false
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, suffix := range vmImageSuffixes {
+		if ext == suffix {
+			return true
+		}
+	}
+	return false
+}
+
+// isVMBundle reports whether dir is a whole virtual machine: a VirtualBox VM
+// folder (<name>/<name>.vbox) or a UTM bundle (<name>.utm with config.plist).
+func isVMBundle(dir string) bool {
+	marker := filepath.Join(dir, filepath.Base(dir)+".vbox")
+	if strings.HasSuffix(dir, ".utm") {
+		marker = filepath.Join(dir, "config.plist")
+	}
+	_, err := os.Stat(marker)
+	return err == nil
+}
+
+// findVMImages returns VM storage at well-known per-user locations: Vagrant
+// box versions, UTM's sandboxed VMs, and Docker Desktop's VM disk.
+func findVMImages(home string) []string {
+	vagrantHome := os.Getenv("VAGRANT_HOME")
+	if vagrantHome == "" {
+		vagrantHome = filepath.Join(home, ".vagrant.d")
+	}
+	boxes, _ := filepath.Glob(filepath.Join(vagrantHome, "boxes", "*", "*"))
+	items := existingDirs(boxes...)
+
+	utm, _ := filepath.Glob(filepath.Join(home, "Library/Containers/com.utmapp.UTM/Data/Documents/*.utm"))
+	items = append(items, existingDirs(utm...)...)
+
+	for _, disk := range []string{
+		filepath.Join(home, "Library/Containers/com.docker.docker/Data/vms/0/data/Docker.raw"),
+		filepath.Join(home, ".docker/desktop/vms/0/data/Docker.raw"),
+		filepath.Join(home, "AppData/Local/Docker/wsl/disk/docker_data.vhdx"),
+		filepath.Join(home, "AppData/Local/Docker/wsl/data/ext4.vhdx"),
+	} {
+		if info, err := os.Stat(disk); err == nil && info.Mode().IsRegular() {
+			items = append(items, disk)
+		}
+	}
+	return items
+}
+
+// getVMUsage dates an image by its last boot where that can be told: a
+// hypervisor reads the disk on every boot (atime) and VirtualBox rewrites
+// Logs/VBox.log, both covered here alongside the newest file mtime.
+func getVMUsage(path string) (time.Time, bool) {
+	lastUsed, found := getCacheUsage(path)
+	if at, ok := accessTime(path); ok && at.After(lastUsed) {
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			lastUsed, found = at, true
+		}
+	}
+	return lastUsed, found
+}
+
+// vagrantBox splits a box store path (<boxes>/<name>/<version>) into the box
+// name and version; Vagrant encodes "/" in names as "-VAGRANTSLASH-".
+func vagrantBox(path string) (name, version string, ok bool) {
+	parent := filepath.Dir(path)
+	if filepath.Base(filepath.Dir(parent)) != "boxes" {
+		return "", "", false
+	}
+	name = strings.ReplaceAll(filepath.Base(parent), "-VAGRANTSLASH-", "/")
+	return name, filepath.Base(path), true
+}
+
+// vagrantMachinesUsing counts machines in Vagrant's global index that were
+// created from the given box, so a box still backing a VM is flagged.
+func vagrantMachinesUsing(boxPath, name, version string) int {
+	vagrantHome := filepath.Dir(filepath.Dir(filepath.Dir(boxPath)))
+	data, err := os.ReadFile(filepath.Join(vagrantHome, "data", "machine-index", "index"))
+	if err != nil {
+		return 0
+	}
+	var index struct {
+		Machines map[string]struct {
+			ExtraData struct {
+				Box struct {
+					Name    string `json:"name"`
+					Version string `json:"version"`
+				} `json:"box"`
+			} `json:"extra_data"`
+		} `json:"machines"`
+	}
+	if json.Unmarshal(data, &index) != nil {
+		return 0
+	}
+	n := 0
+	for _, m := range index.Machines {
+		if m.ExtraData.Box.Name == name && m.ExtraData.Box.Version == version {
+			n++
+		}
+	}
+	return n
+}
+
+// annotateVMImage marks VM records advisory and adds the hypervisor's
+// removal command where there is one.
+func annotateVMImage(r *Record) {
+	r.Advisory = true
+	if name, version, ok := vagrantBox(r.Path); ok {
+		r.Command = `vagrant box remove "` + name + `" --box-version ` + version
+		if n := vagrantMachinesUsing(r.Path, name, version); n > 0 {
+			r.Review = "box still backs Vagrant machines"
+		}
+		return
+	}
+	switch {
+	case filepath.Base(r.Path) == "Docker.raw" || strings.Contains(filepath.ToSlash(r.Path), "/Docker/wsl/"):
+		r.Command = "docker system prune --all"
+		r.Notes = append(r.Notes, "Docker Desktop's VM disk; prune images and volumes instead of removing it")
+	case strings.HasSuffix(r.Path, ".utm"):
+		r.Notes = append(r.Notes, "remove the VM from UTM")
+	case isVMBundle(r.Path):
+		r.Command = `VBoxManage unregistervm "` + filepath.Base(r.Path) + `" --delete`
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestScanRoots_VMImages(t *testing.T) {
+	root := t.TempDir()
+	old := time.Now().AddDate(0, 0, -300)
+	write := func(name string) {
+		p := filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte("x"), 0644)
+		os.Chtimes(p, old, old)
+	}
+	write("VirtualBox VMs/Ubuntu/Ubuntu.vbox")
+	write("VirtualBox VMs/Ubuntu/Ubuntu.vdi")
+	write("VirtualBox VMs/Ubuntu/Logs/VBox.log")
+	write("qemu/debian.qcow2")
+	write("notes/disk.txt")
+
+	opts := &options{minAge: 30, maxDepth: 5, scanTypes: map[string]bool{"vm_image": true}}
+	records, _ := scanRoots([]string{root}, opts)
+	sort.Slice(records, func(i, j int) bool { return records[i].Path < records[j].Path })
+	if len(records) != 2 {
+		t.Fatalf("got %+v, want the VirtualBox VM and the qcow2 image", records)
+	}
+	vbox, qcow := records[0], records[1]
+	if vbox.Path != filepath.Join(root, "VirtualBox VMs", "Ubuntu") || vbox.Command != `VBoxManage unregistervm "Ubuntu" --delete` {
+		t.Errorf("VirtualBox record = %+v", vbox)
+	}
+	if filepath.Base(qcow.Path) != "debian.qcow2" || qcow.Command != "" {
+		t.Errorf("qcow2 record = %+v", qcow)
+	}
+	for _, r := range records {
+		if !r.Advisory {
+			t.Errorf("%s: VM images must be advisory", r.Path)
+		}
+	}
+}
+
+func TestFindVMImages_VagrantBoxes(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("VAGRANT_HOME", "")
+	box := filepath.Join(home, ".vagrant.d", "boxes", "hashicorp-VAGRANTSLASH-bionic64", "1.0.282")
+	os.MkdirAll(filepath.Join(box, "virtualbox"), 0755)
+	os.WriteFile(filepath.Join(box, "virtualbox", "box.vmdk"), []byte("x"), 0644)
+	index := filepath.Join(home, ".vagrant.d", "data", "machine-index", "index")
+	os.MkdirAll(filepath.Dir(index), 0755)
+	os.WriteFile(index, []byte(`{"version":1,"machines":{"abc":{"extra_data":{"box":{"name":"hashicorp/bionic64","provider":"virtualbox","version":"1.0.282"}}}}}`), 0644)
+
+	items := findVMImages(home)
+	if len(items) != 1 || items[0] != box {
+		t.Fatalf("got %v, want %s", items, box)
+	}
+	r := Record{Path: box}
+	annotateVMImage(&r)
+	if r.Command != `vagrant box remove "hashicorp/bionic64" --box-version 1.0.282` {
+		t.Errorf("command = %q", r.Command)
+	}
+	if r.Review == "" {
+		t.Error("a box backing a machine should be flagged for review")
+	}
+	// The walk leaves box contents to the box record.
+	if isVMImageFile(filepath.Join(box, "virtualbox", "box.vmdk")) {
+		t.Error("vmdk inside the box store should not be reported on its own")
+	}
+}
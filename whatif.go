@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+)
+
+// thresholdTotals is what an -age policy of AgeDays would reclaim.
+type thresholdTotals struct {
+	AgeDays        int    `json:"age_days"`
+	Count          int    `json:"count"`
+	TotalBytes     int64  `json:"total_bytes"`
+	TotalHuman     string `json:"total_human"`
+	TotalDiskBytes int64  `json:"total_disk_bytes"`
+	TotalDiskHuman string `json:"total_disk_human"`
+	Held           int    `json:"held"` // stale by this threshold but advisory or held for review
+}
+
+// parseThresholds parses the comma-separated -age list of whatif.
+func parseThresholds(raw string) ([]int, error) {
+	var ages []int
+	for _, item := range splitList(raw) {
+		n, err := strconv.Atoi(item)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid age %q (want days, e.g. 14,30,60)", item)
+		}
+		ages = append(ages, n)
+	}
+	if len(ages) == 0 {
+		return nil, fmt.Errorf("no ages given")
+	}
+	sort.Ints(ages)
+	return ages, nil
+}
+
+// whatifTotals buckets records scanned at the smallest threshold by each
+// candidate threshold. Items tidyup would not delete (advisory, review)
+// are counted as held rather than reclaimable.
+func whatifTotals(records []Record, ages []int) []thresholdTotals {
+	totals := make([]thresholdTotals, len(ages))
+	for i, age := range ages {
+		t := thresholdTotals{AgeDays: age}
+		for _, r := range records {
+			if r.AgeDays < float64(age) {
+				continue
+			}
+			if r.Advisory || r.Review != "" {
+				t.Held++
+				continue
+			}
+			t.Count++
+			t.TotalBytes += r.Size
+			t.TotalDiskBytes += r.DiskSize
+		}
+		t.TotalHuman, t.TotalDiskHuman = formatBytes(t.TotalBytes), formatBytes(t.TotalDiskBytes)
+		totals[i] = t
+	}
+	return totals
+}
+
+// printWhatif writes the threshold table.
+func printWhatif(w io.Writer, totals []thresholdTotals, loc localeFormat) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "AGE\tITEMS\tSIZE\tON DISK\tHELD")
+	for _, t := range totals {
+		fmt.Fprintf(tw, ">= %dd\t%s\t%s\t%s\t%s\n", t.AgeDays, loc.integer(int64(t.Count)), loc.bytes(t.TotalBytes), loc.bytes(t.TotalDiskBytes), loc.integer(int64(t.Held)))
+	}
+	tw.Flush()
+	fmt.Fprintln(w, "\nHELD items are stale at that age but advisory or held for review; they are never deleted automatically.")
+}
+
+// runWhatif implements `tidyup whatif -age 14,30,60,90 [paths...]`: one scan,
+// reclaimable totals at every candidate threshold.
+func runWhatif(args []string) int {
+	flags := flag.NewFlagSet("whatif", flag.ContinueOnError)
+	agesRaw := flags.String("age", "7,14,30,60,90,180", "Comma-separated candidate -age thresholds in days")
+	maxDepth := flags.Int("depth", 5, "Scan depth for recursion")
+	typeFlag := flags.String("type", "", "Comma-separated types (default venv)")
+	allTypes := flags.Bool("all", false, "Scan for all supported types")
+	systemScan := flags.Bool("system", false, "Include well-known per-user locations")
+	excludeRaw := flags.String("exclude", "", "Comma-separated path patterns to skip")
+	skipRaw := flags.String("skip", "", "Comma-separated directory names never to enter, in addition to .git, Library, .Trash")
+	noSkipRaw := flags.String("no-skip", "", "Comma-separated default skip names to scan after all (e.g. Library)")
+	jsonOut := flags.Bool("json", false, "Output totals as JSON")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tidyup whatif [flags] [paths...]\n\n")
+		fmt.Fprintf(os.Stderr, "Scans once and shows what each candidate -age threshold would reclaim.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	ages, err := parseThresholds(*agesRaw)
+	if err != nil {
+		stderr.errorf("%v", err)
+		return exitError
+	}
+	scanTypes, warnings := parseScanTypes(*typeFlag, *allTypes)
+	for _, w := range warnings {
+		stderr.warnf("%s", w)
+	}
+
+	opts := &options{
+		minAge:          ages[0],
+		maxDepth:        *maxDepth,
+		systemScan:      *systemScan,
+		excludePatterns: splitList(*excludeRaw),
+		skipDirs:        nameSet(splitList(*skipRaw)),
+		noSkipDirs:      nameSet(splitList(*noSkipRaw)),
+		scanTypes:       scanTypes,
+	}
+	roots := flags.Args()
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+	if opts.systemScan {
+		if home, err := os.UserHomeDir(); err == nil {
+			roots = append(roots, systemRoots(home, opts)...)
+		}
+	}
+
+	records, scanErrors := scanRoots(roots, opts)
+	for _, e := range scanErrors {
+		stderr.warnf("%s", e)
+	}
+	totals := whatifTotals(records, ages)
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(struct {
+			Thresholds []thresholdTotals `json:"thresholds"`
+		}{totals}); err != nil {
+			stderr.errorf("%v", err)
+			return exitError
+		}
+		return exitOK
+	}
+	printWhatif(os.Stdout, totals, opts.locale)
+	return exitOK
+}
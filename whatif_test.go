@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseThresholds(t *testing.T) {
+	got, err := parseThresholds("90, 14,30")
+	if err != nil || len(got) != 3 || got[0] != 14 || got[2] != 90 {
+		t.Errorf("got %v, %v; want sorted [14 30 90]", got, err)
+	}
+	for _, bad := range []string{"", "30,soon", "-5"} {
+		if _, err := parseThresholds(bad); err == nil {
+			t.Errorf("parseThresholds(%q) accepted", bad)
+		}
+	}
+}
+
+func TestWhatifTotals(t *testing.T) {
+	records := []Record{
+		{Path: "/a", AgeDays: 20, Size: 100, DiskSize: 80},
+		{Path: "/b", AgeDays: 45, Size: 1000, DiskSize: 900},
+		{Path: "/c", AgeDays: 120, Size: 5000, DiskSize: 5000},
+		{Path: "/d", AgeDays: 200, Size: 9999, Review: "uncommitted changes in git: x"},
+	}
+	totals := whatifTotals(records, []int{14, 30, 90})
+	want := []struct {
+		count int
+		bytes int64
+		held  int
+	}{{3, 6100, 1}, {2, 6000, 1}, {1, 5000, 1}}
+	for i, w := range want {
+		if got := totals[i]; got.Count != w.count || got.TotalBytes != w.bytes || got.Held != w.held {
+			t.Errorf(">= %dd: got %+v, want %+v", got.AgeDays, got, w)
+		}
+	}
+
+	var buf bytes.Buffer
+	printWhatif(&buf, totals, localeFormat{})
+	if !strings.Contains(buf.String(), ">= 30d") {
+		t.Errorf("table:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Every `python -m build` leaves a wheel and an sdist behind, and release
+// after release they pile up in dist/ (or the project root, with older
+// tooling). The wheel type reports them file by file, so a dist/ that is in
+// use still gives up its old releases, and never flags the artifacts of the
+// project's current version: the one in pyproject.toml or setup.cfg, or,
+// when the version is dynamic, the newest artifact beside it. Only the
+// project's own artifacts count: a file whose name does not carry the
+// project's distribution name and a PEP 440 version is someone else's.
+
+// pythonProjectMarkers identify a Python project root.
+var pythonProjectMarkers = []string{"pyproject.toml", "setup.py", "setup.cfg"}
+
+// pythonProjectRoot returns the project an artifact's directory belongs to:
+// the directory itself, or the parent of a dist/. It returns "" when there
+// is no Python project there.
+func pythonProjectRoot(dir string) string {
+	candidates := []string{dir}
+	if filepath.Base(dir) == "dist" {
+		candidates = append(candidates, filepath.Dir(dir))
+	}
+	for _, c := range candidates {
+		for _, m := range pythonProjectMarkers {
+			if _, err := os.Stat(filepath.Join(c, m)); err == nil {
+				return c
+			}
+		}
+	}
+	return ""
+}
+
+// pep440Version matches a version as PEP 440 allows it to be written:
+// epoch, release, pre-, post- and dev-release, and local label.
+var pep440Version = regexp.MustCompile(`(?i)^v?(\d+!)?\d+(\.\d+)*` +
+	`([-_.]?(a|b|c|rc|alpha|beta|pre|preview)[-_.]?\d*)?` +
+	`(-\d+|[-_.]?(post|rev|r)[-_.]?\d*)?` +
+	`([-_.]?dev[-_.]?\d*)?` +
+	`(\+[a-z0-9]+([-_.][a-z0-9]+)*)?$`)
+
+// pythonArtifact splits a wheel or sdist file name into its distribution
+// name and version. Wheels are {name}-{version}(-{build})?-{py}-{abi}-{plat}.whl;
+// sdists are {name}-{version}.tar.gz, where older ones may have hyphens in
+// the name, so the version follows the last one. A name whose version part
+// is not a PEP 440 version (customer-backup.tar.gz) is not an artifact.
+func pythonArtifact(name string) (dist, version string, ok bool) {
+	if base, found := strings.CutSuffix(name, ".whl"); found {
+		parts := strings.Split(base, "-")
+		if len(parts) < 5 || !pep440Version.MatchString(parts[1]) {
+			return "", "", false
+		}
+		return parts[0], parts[1], true
+	}
+	if base, found := strings.CutSuffix(name, ".tar.gz"); found {
+		i := strings.LastIndex(base, "-")
+		if i <= 0 || !pep440Version.MatchString(base[i+1:]) {
+			return "", "", false
+		}
+		return base[:i], base[i+1:], true
+	}
+	return "", "", false
+}
+
+// normalizeDistName compares distribution names the way installers do
+// (PEP 503): case-insensitive, with runs of -, _, and . equivalent.
+func normalizeDistName(name string) string {
+	name = strings.ToLower(name)
+	return strings.Join(strings.FieldsFunc(name, func(r rune) bool { return r == '-' || r == '_' || r == '.' }), "-")
+}
+
+// projectMetadata reads a project's name and static version from
+// pyproject.toml ([project] or [tool.poetry]) or setup.cfg ([metadata]).
+// Either is "" when it is not declared there, e.g. a dynamic version.
+func projectMetadata(root string) (name, version string) {
+	files := []struct {
+		file     string
+		sections map[string]bool
+	}{
+		{"pyproject.toml", map[string]bool{"[project]": true, "[tool.poetry]": true}},
+		{"setup.cfg", map[string]bool{"[metadata]": true}},
+	}
+	for _, f := range files {
+		fh, err := os.Open(filepath.Join(root, f.file))
+		if err != nil {
+			continue
+		}
+		section := ""
+		sc := bufio.NewScanner(fh)
+		for sc.Scan() {
+			line := strings.TrimSpace(sc.Text())
+			if strings.HasPrefix(line, "[") {
+				section = line
+				continue
+			}
+			if !f.sections[section] {
+				continue
+			}
+			key, value, found := strings.Cut(line, "=")
+			if !found {
+				continue
+			}
+			value = strings.Trim(strings.TrimSpace(value), `"'`)
+			switch strings.TrimSpace(key) {
+			case "name":
+				if name == "" {
+					name = value
+				}
+			case "version":
+				if version == "" {
+					version = value
+				}
+			}
+		}
+		fh.Close()
+		if name != "" {
+			return name, version
+		}
+	}
+	return name, version
+}
+
+// isCurrentArtifact reports whether an artifact is the project's current
+// release: its declared version, or with none declared, the newest
+// artifact of the project in the same directory.
+func isCurrentArtifact(path, projectName, projectVersion string) bool {
+	dist, version, ok := pythonArtifact(filepath.Base(path))
+	if !ok || projectName == "" || normalizeDistName(dist) != normalizeDistName(projectName) {
+		return false
+	}
+	if projectVersion != "" {
+		return version == projectVersion
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	for _, e := range entries {
+		other, _, ok := pythonArtifact(e.Name())
+		if !ok || normalizeDistName(other) != normalizeDistName(projectName) {
+			continue
+		}
+		if oi, err := e.Info(); err == nil && oi.ModTime().After(info.ModTime()) {
+			return false
+		}
+	}
+	return true
+}
+
+// isStrayArtifact reports whether a file found by the walk is one of a
+// Python project's own wheels or sdists, in its root or dist/, that is not
+// its current release.
+func isStrayArtifact(path string, d fs.DirEntry) bool {
+	if !d.Type().IsRegular() {
+		return false
+	}
+	dist, _, ok := pythonArtifact(d.Name())
+	if !ok {
+		return false
+	}
+	root := pythonProjectRoot(filepath.Dir(path))
+	if root == "" {
+		return false
+	}
+	name, version := projectMetadata(root)
+	if name == "" || normalizeDistName(dist) != normalizeDistName(name) {
+		return false
+	}
+	return !isCurrentArtifact(path, name, version)
+}
+
+// annotateWheel names the release an artifact belongs to and, where it is
+// known, the project's current version.
+func annotateWheel(r *Record) {
+	dist, version, ok := pythonArtifact(filepath.Base(r.Path))
+	if !ok {
+		return
+	}
+	note := dist + " " + version
+	if root := pythonProjectRoot(filepath.Dir(r.Path)); root != "" {
+		if name, current := projectMetadata(root); current != "" && normalizeDistName(name) == normalizeDistName(dist) {
+			note += "; the project is at " + current
+		}
+	}
+	r.Notes = append(r.Notes, note)
+}
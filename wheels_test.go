@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestPythonArtifact(t *testing.T) {
+	cases := []struct{ name, dist, version string }{
+		{"my_pkg-1.2.0-py3-none-any.whl", "my_pkg", "1.2.0"},
+		{"numpy-1.26.4-1-cp311-cp311-macosx_11_0_arm64.whl", "numpy", "1.26.4"},
+		{"my_pkg-1.2.0.tar.gz", "my_pkg", "1.2.0"},
+		{"legacy-name-0.9.tar.gz", "legacy-name", "0.9"},
+		{"backup.tar.gz", "", ""},
+		{"customer-backup.tar.gz", "", ""},
+		{"pkg-1.0rc1.post2.dev3+local.7.tar.gz", "pkg", "1.0rc1.post2.dev3+local.7"},
+		{"pkg-latest-py3-none-any.whl", "", ""},
+		{"broken-1.0.whl", "", ""},
+	}
+	for _, c := range cases {
+		dist, version, _ := pythonArtifact(c.name)
+		if dist != c.dist || version != c.version {
+			t.Errorf("pythonArtifact(%q) = %q, %q; want %q, %q", c.name, dist, version, c.dist, c.version)
+		}
+	}
+}
+
+func TestScanRoots_StrayWheels(t *testing.T) {
+	root := t.TempDir()
+	old := time.Now().AddDate(0, 0, -90)
+	write := func(name, content string, mtime time.Time) {
+		p := filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte(content), 0644)
+		os.Chtimes(p, mtime, mtime)
+	}
+	// Static version: 1.2.0 is current, however old its files are.
+	write("pkg/pyproject.toml", "[project]\nname = \"My-Pkg\"\nversion = \"1.2.0\"\n", old)
+	write("pkg/dist/my_pkg-1.1.0-py3-none-any.whl", "x", old)
+	write("pkg/dist/my_pkg-1.1.0.tar.gz", "x", old)
+	write("pkg/dist/my_pkg-1.2.0-py3-none-any.whl", "x", old)
+	write("pkg/dist/my_pkg-1.2.0.tar.gz", "x", old)
+	write("pkg/my_pkg-1.0.0.tar.gz", "x", old)             // an old release in the root
+	write("pkg/requests-2.0.0-py3-none-any.whl", "x", old) // someone else's, left in the root
+	write("pkg/customer-backup.tar.gz", "x", old)          // not an artifact at all
+	// Dynamic version: the newest artifact is current.
+	write("dyn/setup.py", "", old)
+	write("dyn/pyproject.toml", "[project]\nname = \"dyn\"\ndynamic = [\"version\"]\n", old)
+	write("dyn/dist/dyn-0.1.tar.gz", "x", old.AddDate(0, 0, -10))
+	write("dyn/dist/dyn-0.2.tar.gz", "x", old)
+	// Not a project: left alone.
+	write("downloads/thing-1.0-py3-none-any.whl", "x", old)
+
+	opts := &options{minAge: 30, maxDepth: 5, scanTypes: map[string]bool{"wheel": true, "dist": true}}
+	// A fresh file keeps pkg/dist/ itself in use, so only its old releases go.
+	write("pkg/dist/.gitignore", "*", time.Now())
+	records, _ := scanRoots([]string{root}, opts)
+	var got []string
+	for _, r := range records {
+		rel, _ := filepath.Rel(root, r.Path)
+		got = append(got, r.Type+" "+filepath.ToSlash(rel))
+	}
+	sort.Strings(got)
+	want := []string{
+		"dist dyn/dist",
+		"wheel pkg/dist/my_pkg-1.1.0-py3-none-any.whl",
+		"wheel pkg/dist/my_pkg-1.1.0.tar.gz",
+		"wheel pkg/my_pkg-1.0.0.tar.gz",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("record %d = %s, want %s", i, got[i], want[i])
+		}
+	}
+
+	// Without dist, dyn/dist/ is walked too, and its newest sdist is kept.
+	opts.scanTypes = map[string]bool{"wheel": true}
+	records, _ = scanRoots([]string{filepath.Join(root, "dyn")}, opts)
+	if len(records) != 1 || filepath.Base(records[0].Path) != "dyn-0.1.tar.gz" {
+		t.Errorf("dynamic version: got %+v, want only dyn-0.1.tar.gz", records)
+	}
+}